@@ -21,24 +21,17 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/costs/clusters/{cluster_id}/namespace": {
+        "/anomalies": {
             "get": {
-                "description": "Get specific cluster namespace costs with time range",
+                "description": "Get the cost spikes of clusters, namespaces and workloads detected in the time range, the latest ones first",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Costs"
+                    "Anomalies"
                 ],
-                "summary": "Get specific cluster namespace costs",
+                "summary": "Get cost anomalies",
                 "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Cluster Id",
-                        "name": "cluster_id",
-                        "in": "path",
-                        "required": true
-                    },
                     {
                         "type": "integer",
                         "description": "The start time to query",
@@ -52,9 +45,27 @@ const docTemplate = `{
                         "in": "query"
                     },
                     {
-                        "type": "integer",
-                        "description": "The step seconds of the data to return",
-                        "name": "stepSeconds",
+                        "type": "string",
+                        "description": "Only return the anomalies of this cluster",
+                        "name": "clusterId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only return the anomalies of this namespace",
+                        "name": "namespace",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only return the anomalies of this scope, cluster/namespace/workload",
+                        "name": "scope",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only return the anomalies of this severity, warning/critical",
+                        "name": "severity",
                         "in": "query"
                     }
                 ],
@@ -62,7 +73,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.ClusterNamespaceCostList"
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.AnomalyList"
                         }
                     },
                     "500": {
@@ -74,24 +85,84 @@ const docTemplate = `{
                 }
             }
         },
-        "/costs/clusters/{cluster_id}/resource": {
+        "/budgets": {
             "get": {
-                "description": "Get detailed information on cluster resource costs, including CPU, memory, and different billing modes.",
+                "description": "Get the actual and forecast cost of all budgets in current period",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Costs"
+                    "Budgets"
                 ],
-                "summary": "Get detailed information on cluster resource costs, including CPU, memory, and different billing modes.",
+                "summary": "Get all budgets status",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.BudgetStatusList"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.StatusError"
+                        }
+                    }
+                }
+            }
+        },
+        "/budgets/{budget_name}": {
+            "get": {
+                "description": "Get the actual and forecast cost of specific budget in current period",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Budgets"
+                ],
+                "summary": "Get specific budget status",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Cluster Id",
-                        "name": "cluster_id",
+                        "description": "Budget Name",
+                        "name": "budget_name",
                         "in": "path",
                         "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.BudgetStatus"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.StatusError"
+                        }
                     },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.StatusError"
+                        }
+                    }
+                }
+            }
+        },
+        "/costs/breakdown": {
+            "get": {
+                "description": "Get the cost of every namespace, label value or resource type aggregated across all clusters or the selected ones",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Costs"
+                ],
+                "summary": "Get the costs breakdown across clusters",
+                "parameters": [
                     {
                         "type": "integer",
                         "description": "The start time to query",
@@ -105,9 +176,39 @@ const docTemplate = `{
                         "in": "query"
                     },
                     {
-                        "type": "integer",
-                        "description": "The step seconds of the data to return",
-                        "name": "stepSeconds",
+                        "type": "string",
+                        "description": "Aggregate the cost by namespace/label/resourceType, namespace by default",
+                        "name": "dimension",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "The pod label to aggregate the cost by, required by the label dimension",
+                        "name": "labelKey",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "The comma separated clusters to aggregate, all clusters by default",
+                        "name": "clusterIds",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "The label selector on the cluster_name/cloud_provider/region labels of the clusters",
+                        "name": "clusterSelector",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only aggregate the cost of this namespace",
+                        "name": "namespace",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only return the items whose names fully match this regex",
+                        "name": "nameRegex",
                         "in": "query"
                     }
                 ],
@@ -115,7 +216,13 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.ClusterResourceCostList"
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.MultiClustersCostBreakdown"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.StatusError"
                         }
                     },
                     "500": {
@@ -127,16 +234,16 @@ const docTemplate = `{
                 }
             }
         },
-        "/costs/clusters/{cluster_id}/summary": {
+        "/costs/clusters/{cluster_id}/compare": {
             "get": {
-                "description": "Get specific cluster costs summary in current two month",
+                "description": "Compare the cost of every namespace, workload, label value or node instance type in specific cluster between two periods",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Costs"
                 ],
-                "summary": "Get specific cluster costs summary",
+                "summary": "Compare specific cluster costs of two periods",
                 "parameters": [
                     {
                         "type": "string",
@@ -144,13 +251,79 @@ const docTemplate = `{
                         "name": "cluster_id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "The start time of the period",
+                        "name": "startTime",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "The end time of the period",
+                        "name": "endTime",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "The start time of the period compared with, the period right before by default",
+                        "name": "baseStartTime",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "The end time of the period compared with",
+                        "name": "baseEndTime",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Compare the cost by namespace/workload/label/instanceType, namespace by default",
+                        "name": "dimension",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "The pod label to compare the cost by, required by the label dimension",
+                        "name": "labelKey",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only compare the cost of this namespace",
+                        "name": "namespace",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only compare the workloads of this type, deployment/statefulset/daemonset",
+                        "name": "workloadType",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only compare the items whose names fully match this regex",
+                        "name": "nameRegex",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.ClusterCostsSummary"
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.CostComparison"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.StatusError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.StatusError"
                         }
                     },
                     "500": {
@@ -162,16 +335,16 @@ const docTemplate = `{
                 }
             }
         },
-        "/costs/clusters/{cluster_id}/workload": {
+        "/costs/clusters/{cluster_id}/efficiency": {
             "get": {
-                "description": "Get specific cluster workloads costs with time range",
+                "description": "Get the workloads of specific cluster wasting the most cost on the cpu and ram requested but not used in the time range",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Costs"
                 ],
-                "summary": "Get specific cluster workloads costs",
+                "summary": "Get the least efficient workloads of specific cluster",
                 "parameters": [
                     {
                         "type": "string",
@@ -193,15 +366,27 @@ const docTemplate = `{
                         "in": "query"
                     },
                     {
-                        "type": "integer",
-                        "description": "The step seconds of the data to return",
-                        "name": "stepSeconds",
+                        "type": "string",
+                        "description": "Only return the workloads of this namespace",
+                        "name": "namespace",
                         "in": "query"
                     },
                     {
                         "type": "string",
-                        "description": "The aggregated way to show workload costs",
-                        "name": "aggregateBy",
+                        "description": "Only return the workloads of this type, pod/deployment/statefulset/daemonset, deployment/statefulset/daemonset by default",
+                        "name": "workloadType",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only return the workloads whose names fully match this regex",
+                        "name": "nameRegex",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "The number of the workloads to return, 10 by default and 0 returns all",
+                        "name": "topN",
                         "in": "query"
                     }
                 ],
@@ -209,7 +394,13 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.ClusterWorkloadCostList"
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.WorkloadEfficiencyList"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.StatusError"
                         }
                     },
                     "500": {
@@ -221,21 +412,36 @@ const docTemplate = `{
                 }
             }
         },
-        "/costs/summary": {
+        "/costs/clusters/{cluster_id}/forecast": {
             "get": {
-                "description": "Get all clusters costs summary in current two month",
+                "description": "Forecast the month end, next 30 days and next 90 days cost of the cluster and its namespaces with trend and weekly seasonality",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Costs"
                 ],
-                "summary": "Get all clusters costs summary",
+                "summary": "Get specific cluster cost forecast",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Cluster Id",
+                        "name": "cluster_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "The days of history to fit the forecast on, 90 by default",
+                        "name": "historyDays",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.ClusterCostsSummaryList"
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.ClusterCostForecast"
                         }
                     },
                     "500": {
@@ -247,16 +453,18 @@ const docTemplate = `{
                 }
             }
         },
-        "/metrics/clusters/{cluster_id}/cpu": {
+        "/costs/clusters/{cluster_id}/namespace": {
             "get": {
-                "description": "Get specific cluster CPU metrics",
+                "description": "Get specific cluster namespace costs with time range",
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
                 ],
                 "tags": [
-                    "Metrics"
+                    "Costs"
                 ],
-                "summary": "Get specific cluster CPU metrics",
+                "summary": "Get specific cluster namespace costs",
                 "parameters": [
                     {
                         "type": "string",
@@ -282,13 +490,67 @@ const docTemplate = `{
                         "description": "The step seconds of the data to return",
                         "name": "stepSeconds",
                         "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only return this namespace",
+                        "name": "namespace",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only return the namespaces whose names fully match this regex",
+                        "name": "nameRegex",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort the namespaces by name/totalCost/cpuCoreRequest/ramGiBRequest/efficiency/wastedCost, by totalCost if topN is set",
+                        "name": "sortBy",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "asc or desc, values are sorted from the highest by default",
+                        "name": "sortOrder",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Only return the first N namespaces after sorting",
+                        "name": "topN",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "The max number of namespaces in a page, all are returned if it's not set",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "The continue token returned with the previous page",
+                        "name": "continue",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "The response format, json/csv/xlsx, the Accept header is used if it's not set",
+                        "name": "format",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.ClusterResourceMetrics"
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.ClusterNamespaceCostList"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.StatusError"
                         }
                     },
                     "500": {
@@ -300,16 +562,18 @@ const docTemplate = `{
                 }
             }
         },
-        "/metrics/clusters/{cluster_id}/memory": {
+        "/costs/clusters/{cluster_id}/nodes": {
             "get": {
-                "description": "Get specific cluster memory metrics",
+                "description": "Get the cost, price, request/usage ratio and idle cost of every node in specific cluster with time range",
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
                 ],
                 "tags": [
-                    "Metrics"
+                    "Costs"
                 ],
-                "summary": "Get specific cluster memory metrics",
+                "summary": "Get specific cluster nodes costs",
                 "parameters": [
                     {
                         "type": "string",
@@ -335,13 +599,316 @@ const docTemplate = `{
                         "description": "The step seconds of the data to return",
                         "name": "stepSeconds",
                         "in": "query"
-                    }
-                ],
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only return the nodes of this instance type",
+                        "name": "instanceType",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only return the nodes of this billing mode",
+                        "name": "billingMode",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort the nodes by name/totalCost/idleCost/hourlyPrice",
+                        "name": "sortBy",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "asc or desc, costs are sorted from the highest by default",
+                        "name": "sortOrder",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "The response format, json/csv/xlsx, the Accept header is used if it's not set",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.ClusterResourceMetrics"
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.ClusterNodeCostList"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.StatusError"
+                        }
+                    }
+                }
+            }
+        },
+        "/costs/clusters/{cluster_id}/resource": {
+            "get": {
+                "description": "Get detailed information on cluster resource costs, including CPU, memory, and different billing modes.",
+                "produces": [
+                    "application/json",
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "tags": [
+                    "Costs"
+                ],
+                "summary": "Get detailed information on cluster resource costs, including CPU, memory, and different billing modes.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Cluster Id",
+                        "name": "cluster_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "The start time to query",
+                        "name": "startTime",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "The end time to query",
+                        "name": "endTime",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "The step seconds of the data to return",
+                        "name": "stepSeconds",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "The response format, json/csv/xlsx, the Accept header is used if it's not set",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.ClusterResourceCostList"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.StatusError"
+                        }
+                    }
+                }
+            }
+        },
+        "/costs/clusters/{cluster_id}/summary": {
+            "get": {
+                "description": "Get specific cluster costs summary in current two month",
+                "produces": [
+                    "application/json",
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "tags": [
+                    "Costs"
+                ],
+                "summary": "Get specific cluster costs summary",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Cluster Id",
+                        "name": "cluster_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "The response format, json/csv/xlsx, the Accept header is used if it's not set",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Add the month end forecast, it's false by default",
+                        "name": "forecast",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.ClusterCostsSummary"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.StatusError"
+                        }
+                    }
+                }
+            }
+        },
+        "/costs/clusters/{cluster_id}/workload": {
+            "get": {
+                "description": "Get specific cluster workloads costs with time range",
+                "produces": [
+                    "application/json",
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "tags": [
+                    "Costs"
+                ],
+                "summary": "Get specific cluster workloads costs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Cluster Id",
+                        "name": "cluster_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "The start time to query",
+                        "name": "startTime",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "The end time to query",
+                        "name": "endTime",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "The step seconds of the data to return",
+                        "name": "stepSeconds",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "The aggregated way to show workload costs",
+                        "name": "aggregateBy",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only return the workloads of this namespace",
+                        "name": "namespace",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only return the workloads of this type, pod/deployment/statefulset/daemonset",
+                        "name": "workloadType",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only return the workloads whose names fully match this regex",
+                        "name": "nameRegex",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort the workloads by name/totalCost/cpuCoreRequest/ramGiBRequest/efficiency/wastedCost, by totalCost if topN is set",
+                        "name": "sortBy",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "asc or desc, values are sorted from the highest by default",
+                        "name": "sortOrder",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Only return the first N workloads after sorting",
+                        "name": "topN",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "The max number of workloads in a page, all are returned if it's not set",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "The continue token returned with the previous page",
+                        "name": "continue",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "The response format, json/csv/xlsx, the Accept header is used if it's not set",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.ClusterWorkloadCostList"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.StatusError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.StatusError"
+                        }
+                    }
+                }
+            }
+        },
+        "/costs/summary": {
+            "get": {
+                "description": "Get all clusters costs summary in current two month",
+                "produces": [
+                    "application/json",
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "tags": [
+                    "Costs"
+                ],
+                "summary": "Get all clusters costs summary",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "The response format, json/csv/xlsx, the Accept header is used if it's not set",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Add the month end forecast, it's false by default",
+                        "name": "forecast",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.ClusterCostsSummaryList"
                         }
                     },
                     "500": {
@@ -353,425 +920,1794 @@ const docTemplate = `{
                 }
             }
         },
-        "/metrics/clusters/{cluster_id}/summary": {
-            "get": {
-                "description": "Get specific cluster metrics summary in current two month",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Metrics"
-                ],
-                "summary": "Get specific cluster metrics summary",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Cluster Id",
-                        "name": "cluster_id",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.ClusterMetricsSummary"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.StatusError"
+        "/ledger/costs": {
+            "get": {
+                "description": "Get the finalized hourly or daily cost recorded in the cost ledger, the records are kept beyond the query backend retention",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Ledger"
+                ],
+                "summary": "Get cost ledger records",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "The start time to query",
+                        "name": "startTime",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "The end time to query",
+                        "name": "endTime",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "The kind of the records, cluster/node/namespace/workload/label, cluster by default",
+                        "name": "kind",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "The granularity of the records, hourly/daily, daily by default",
+                        "name": "granularity",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only return the records of this cluster",
+                        "name": "clusterId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only return the records of this namespace",
+                        "name": "namespace",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only return the label records of the pods with this label",
+                        "name": "labelKey",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "The value of the label",
+                        "name": "labelValue",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.CostLedgerRecordList"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.StatusError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.StatusError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.StatusError"
+                        }
+                    }
+                }
+            }
+        },
+        "/metrics/clusters/{cluster_id}/cpu": {
+            "get": {
+                "description": "Get specific cluster CPU metrics",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Metrics"
+                ],
+                "summary": "Get specific cluster CPU metrics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Cluster Id",
+                        "name": "cluster_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "The start time to query",
+                        "name": "startTime",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "The end time to query",
+                        "name": "endTime",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "The step seconds of the data to return",
+                        "name": "stepSeconds",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.ClusterResourceMetrics"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.StatusError"
+                        }
+                    }
+                }
+            }
+        },
+        "/metrics/clusters/{cluster_id}/memory": {
+            "get": {
+                "description": "Get specific cluster memory metrics",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Metrics"
+                ],
+                "summary": "Get specific cluster memory metrics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Cluster Id",
+                        "name": "cluster_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "The start time to query",
+                        "name": "startTime",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "The end time to query",
+                        "name": "endTime",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "The step seconds of the data to return",
+                        "name": "stepSeconds",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.ClusterResourceMetrics"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.StatusError"
+                        }
+                    }
+                }
+            }
+        },
+        "/metrics/clusters/{cluster_id}/summary": {
+            "get": {
+                "description": "Get specific cluster metrics summary in current two month",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Metrics"
+                ],
+                "summary": "Get specific cluster metrics summary",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Cluster Id",
+                        "name": "cluster_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.ClusterMetricsSummary"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.StatusError"
+                        }
+                    }
+                }
+            }
+        },
+        "/metrics/summary": {
+            "get": {
+                "description": "Get all clusters metrics summary in current two month",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Metrics"
+                ],
+                "summary": "Get all clusters metrics summary",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.ClusterMetricsSummaryList"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.StatusError"
+                        }
+                    }
+                }
+            }
+        },
+        "/recommendations/clusters/{cluster_id}/nodes": {
+            "get": {
+                "description": "Simulate packing the current pod requests of every node pool on the instance types of the catalog, and propose the instance type mixes cheaper than the current nodes",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Recommendations"
+                ],
+                "summary": "Get specific cluster node instance type recommendations",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Cluster Id",
+                        "name": "cluster_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.NodeSimulationResult"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.StatusError"
+                        }
+                    }
+                }
+            }
+        },
+        "/recommendations/clusters/{cluster_id}/nodes/snapshot": {
+            "get": {
+                "description": "Get the current nodes, pod requests and instance type catalog of the cluster, the snapshot could be simulated offline with \"kubefin-cost-analyzer simulate\"",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Recommendations"
+                ],
+                "summary": "Get specific cluster node simulation snapshot",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Cluster Id",
+                        "name": "cluster_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.NodeSimulationSnapshot"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.StatusError"
+                        }
+                    }
+                }
+            }
+        },
+        "/recommendations/clusters/{cluster_id}/spot": {
+            "get": {
+                "description": "Classify whether the deployments/statefulsets could run on spot nodes (replicas \u003e 1, stateless, no local volumes, PodDisruptionBudget present), and price their requests with the spot and on-demand unit prices",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Recommendations"
+                ],
+                "summary": "Get specific cluster spot adoption savings",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Cluster Id",
+                        "name": "cluster_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only return the workloads in this namespace",
+                        "name": "namespace",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.SpotSavingsAnalysis"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.StatusError"
+                        }
+                    }
+                }
+            }
+        },
+        "/recommendations/clusters/{cluster_id}/workloads": {
+            "get": {
+                "description": "Recommend the cpu/memory requests and limits of every workload container from its usage percentiles, with the estimated monthly savings",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Recommendations"
+                ],
+                "summary": "Get specific cluster workloads request recommendations",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Cluster Id",
+                        "name": "cluster_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only return the workloads in this namespace",
+                        "name": "namespace",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.WorkloadRecommendationList"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.StatusError"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "github_com_kubefin_kubefin_pkg_api.Anomaly": {
+            "type": "object",
+            "properties": {
+                "baselineCost": {
+                    "description": "BaselineCost is the median cost of the steps before",
+                    "type": "number"
+                },
+                "clusterId": {
+                    "type": "string"
+                },
+                "contributors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.AnomalyContributor"
+                    }
+                },
+                "cost": {
+                    "type": "number"
+                },
+                "detectedTime": {
+                    "type": "integer"
+                },
+                "granularity": {
+                    "description": "Granularity is the name of the cost series, such as hourly/daily",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "namespace": {
+                    "type": "string"
+                },
+                "scope": {
+                    "description": "Scope could be cluster/namespace/workload",
+                    "type": "string"
+                },
+                "score": {
+                    "description": "Score is how many robust standard deviations the cost is above the baseline",
+                    "type": "number"
+                },
+                "severity": {
+                    "description": "Severity could be warning/critical",
+                    "type": "string"
+                },
+                "stepSeconds": {
+                    "type": "integer"
+                },
+                "tenantId": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "description": "Timestamp is the end of the step the cost is summed over",
+                    "type": "integer"
+                },
+                "workloadName": {
+                    "type": "string"
+                },
+                "workloadType": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_kubefin_kubefin_pkg_api.AnomalyContributor": {
+            "type": "object",
+            "properties": {
+                "baselineCost": {
+                    "type": "number"
+                },
+                "cost": {
+                    "type": "number"
+                },
+                "namespace": {
+                    "type": "string"
+                },
+                "scope": {
+                    "description": "Scope could be namespace/workload",
+                    "type": "string"
+                },
+                "workloadName": {
+                    "type": "string"
+                },
+                "workloadType": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_kubefin_kubefin_pkg_api.AnomalyList": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.Anomaly"
+                    }
+                }
+            }
+        },
+        "github_com_kubefin_kubefin_pkg_api.BudgetStatus": {
+            "type": "object",
+            "properties": {
+                "actualCost": {
+                    "description": "ActualCost means the cost from the period start to the last evaluation",
+                    "type": "number"
+                },
+                "actualPercent": {
+                    "type": "number"
+                },
+                "amount": {
+                    "type": "number"
+                },
+                "clusterId": {
+                    "type": "string"
+                },
+                "error": {
+                    "description": "Error means the last evaluation failed, the costs are from the evaluation before it",
+                    "type": "string"
+                },
+                "forecastCost": {
+                    "description": "ForecastCost means the estimated cost of the whole period",
+                    "type": "number"
+                },
+                "forecastPercent": {
+                    "type": "number"
+                },
+                "labelKey": {
+                    "description": "LabelKey and LabelValue limit the budget to the pods with the label",
+                    "type": "string"
+                },
+                "labelValue": {
+                    "type": "string"
+                },
+                "lastEvaluationTime": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "namespace": {
+                    "description": "Namespace limits the budget to the namespace",
+                    "type": "string"
+                },
+                "period": {
+                    "description": "Period could be monthly/weekly",
+                    "type": "string"
+                },
+                "periodEnd": {
+                    "type": "integer"
+                },
+                "periodStart": {
+                    "type": "integer"
+                },
+                "state": {
+                    "description": "State could be ok/atRisk/exceeded",
+                    "type": "string"
+                },
+                "tenantId": {
+                    "description": "TenantId is the tenant the cluster belongs to, the default tenant is used if it's empty",
+                    "type": "string"
+                },
+                "thresholds": {
+                    "description": "Thresholds are the percentages of the amount to notify at once the actual or forecast cost crosses them",
+                    "type": "array",
+                    "items": {
+                        "type": "number"
+                    }
+                }
+            }
+        },
+        "github_com_kubefin_kubefin_pkg_api.BudgetStatusList": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.BudgetStatus"
+                    }
+                }
+            }
+        },
+        "github_com_kubefin_kubefin_pkg_api.ClusterCostForecast": {
+            "type": "object",
+            "properties": {
+                "cluster": {
+                    "description": "Cluster is not set if only some namespaces of the cluster could be accessed",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.CostForecast"
+                        }
+                    ]
+                },
+                "clusterId": {
+                    "type": "string"
+                },
+                "confidenceLevel": {
+                    "description": "ConfidenceLevel is the confidence level of the lower and upper bounds",
+                    "type": "number"
+                },
+                "historyDays": {
+                    "type": "integer"
+                },
+                "namespaces": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.CostForecast"
+                    }
+                }
+            }
+        },
+        "github_com_kubefin_kubefin_pkg_api.ClusterCostsSummary": {
+            "type": "object",
+            "properties": {
+                "ClusterAvgHourlyCoreCost": {
+                    "description": "ClusterAvgDailyCost means average core/hour costs in current month",
+                    "type": "number"
+                },
+                "cloudProvider": {
+                    "type": "string"
+                },
+                "clusterActiveTime": {
+                    "description": "ClusterActiveTime shows the cluster active time in seconds",
+                    "type": "number"
+                },
+                "clusterAvgDailyCost": {
+                    "description": "ClusterAvgDailyCost means average daily costs in current month",
+                    "type": "number"
+                },
+                "clusterConnectionSate": {
+                    "description": "ClusterConnectionSate can be running/connect_failed",
+                    "type": "string"
+                },
+                "clusterId": {
+                    "type": "string"
+                },
+                "clusterMonthCostCurrent": {
+                    "description": "ClusterMonthCostCurrent means the total cost in current month",
+                    "type": "number"
+                },
+                "clusterMonthEstimateCost": {
+                    "description": "ClusterMonthEstimateCost means the estimating cost with previous 7 days costs",
+                    "type": "number"
+                },
+                "clusterMonthForecast": {
+                    "description": "ClusterMonthForecast means the month end cost forecast with trend and weekly seasonality,\nit's not set if the history is not available",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.CostForecastValue"
+                        }
+                    ]
+                },
+                "clusterName": {
+                    "type": "string"
+                },
+                "clusterRegion": {
+                    "type": "string"
+                },
+                "connectionTime": {
+                    "description": "ConnectionTime shows the time the cluster connected",
+                    "type": "integer"
+                },
+                "lastActiveTime": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_kubefin_kubefin_pkg_api.ClusterCostsSummaryList": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.ClusterCostsSummary"
+                    }
+                }
+            }
+        },
+        "github_com_kubefin_kubefin_pkg_api.ClusterMetricsSummary": {
+            "type": "object",
+            "properties": {
+                "cloudProvider": {
+                    "type": "string"
+                },
+                "clusterActiveTime": {
+                    "description": "ClusterActiveTime shows the cluster active time in seconds",
+                    "type": "number"
+                },
+                "clusterConnectionSate": {
+                    "description": "ClusterConnectionSate can be running/connect_failed",
+                    "type": "string"
+                },
+                "clusterId": {
+                    "type": "string"
+                },
+                "clusterName": {
+                    "type": "string"
+                },
+                "clusterRegion": {
+                    "type": "string"
+                },
+                "connectionTime": {
+                    "description": "ConnectionTime shows the time the cluster connected",
+                    "type": "integer"
+                },
+                "cpuCoreAvailable": {
+                    "description": "CPUCoreAvailable means all nodes' available cpu core",
+                    "type": "number"
+                },
+                "cpuCoreRequest": {
+                    "description": "CPUCoreRequest means all pods' cpu core request",
+                    "type": "number"
+                },
+                "cpuCoreSystemTaken": {
+                    "description": "CPUCoreSystemTaken means the cpu taken by system",
+                    "type": "number"
+                },
+                "cpuCoreTotal": {
+                    "description": "CPUCoreTotal means all nodes' cpu core",
+                    "type": "number"
+                },
+                "cpuCoreUsage": {
+                    "description": "CPUCoreUsage means all pods' cpu core usage",
+                    "type": "number"
+                },
+                "fallbackBillingNodeNumbersCurrent": {
+                    "type": "integer"
+                },
+                "lastActiveTime": {
+                    "type": "integer"
+                },
+                "nodeNumbersCurrent": {
+                    "type": "integer"
+                },
+                "onDemandBillingNodeNumbersCurrent": {
+                    "type": "integer"
+                },
+                "periodBillingNodeNumbersCurrent": {
+                    "type": "integer"
+                },
+                "podScheduledCurrent": {
+                    "type": "integer"
+                },
+                "podTotalCurrent": {
+                    "type": "integer"
+                },
+                "podUnscheduledCurrent": {
+                    "type": "integer"
+                },
+                "ramGiBAvailable": {
+                    "description": "RAMGiBAvailable means all nodes' available ram GiB",
+                    "type": "number"
+                },
+                "ramGiBRequest": {
+                    "description": "RAMGiBRequest all pods' ram GiB request",
+                    "type": "number"
+                },
+                "ramGiBSystemTaken": {
+                    "description": "RAMGiBSystemTaken means the rm taken by system",
+                    "type": "number"
+                },
+                "ramGiBTotal": {
+                    "description": "RAMGiBTotal means all nodes' ram GiB",
+                    "type": "number"
+                },
+                "ramGiBUsage": {
+                    "description": "RAMGiBUsage means all pods' ram GiB usage",
+                    "type": "number"
+                },
+                "spotBillingNodeNumbersCurrent": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_kubefin_kubefin_pkg_api.ClusterMetricsSummaryList": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.ClusterMetricsSummary"
+                    }
+                }
+            }
+        },
+        "github_com_kubefin_kubefin_pkg_api.ClusterNamespaceCost": {
+            "type": "object",
+            "properties": {
+                "costList": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.ClusterNamespaceCostDetail"
+                    }
+                },
+                "namespace": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_kubefin_kubefin_pkg_api.ClusterNamespaceCostDetail": {
+            "type": "object",
+            "properties": {
+                "costEfficiency": {
+                    "type": "number"
+                },
+                "cpuCoreUsage": {
+                    "type": "number"
+                },
+                "cpuEfficiency": {
+                    "type": "number"
+                },
+                "cpuRequest": {
+                    "type": "number"
+                },
+                "podCount": {
+                    "description": "PodCount means the average pod count in this period",
+                    "type": "number"
+                },
+                "ramEfficiency": {
+                    "type": "number"
+                },
+                "ramGiBRequest": {
+                    "type": "number"
+                },
+                "ramGiBUsage": {
+                    "type": "number"
+                },
+                "timestamp": {
+                    "type": "integer"
+                },
+                "totalCost": {
+                    "type": "number"
+                },
+                "wastedCost": {
+                    "type": "number"
+                }
+            }
+        },
+        "github_com_kubefin_kubefin_pkg_api.ClusterNamespaceCostList": {
+            "type": "object",
+            "properties": {
+                "clusterId": {
+                    "type": "string"
+                },
+                "continue": {
+                    "description": "Continue is the token to query the next page with, it's empty on the last page",
+                    "type": "string"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.ClusterNamespaceCost"
+                    }
+                },
+                "totalItems": {
+                    "description": "TotalItems is the number of the items matched before paginating",
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_kubefin_kubefin_pkg_api.ClusterNodeCost": {
+            "type": "object",
+            "properties": {
+                "billingMode": {
+                    "type": "string"
+                },
+                "costList": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.ClusterNodeCostDetail"
+                    }
+                },
+                "instanceType": {
+                    "type": "string"
+                },
+                "nodeName": {
+                    "type": "string"
+                },
+                "region": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_kubefin_kubefin_pkg_api.ClusterNodeCostDetail": {
+            "type": "object",
+            "properties": {
+                "cpuRequestRatio": {
+                    "description": "CPURequestRatio means the average ratio of the allocatable cpu requested by pods in this period",
+                    "type": "number"
+                },
+                "cpuUsageRatio": {
+                    "description": "CPUUsageRatio means the average ratio of the total cpu used in this period",
+                    "type": "number"
+                },
+                "hourlyPrice": {
+                    "description": "HourlyPrice means the average node hourly price in this period",
+                    "type": "number"
+                },
+                "idleCost": {
+                    "description": "IdleCost means the cost of the cpu and ram not requested by any pod in this period",
+                    "type": "number"
+                },
+                "ramRequestRatio": {
+                    "type": "number"
+                },
+                "ramUsageRatio": {
+                    "type": "number"
+                },
+                "timestamp": {
+                    "type": "integer"
+                },
+                "totalCost": {
+                    "type": "number"
+                }
+            }
+        },
+        "github_com_kubefin_kubefin_pkg_api.ClusterNodeCostList": {
+            "type": "object",
+            "properties": {
+                "clusterId": {
+                    "type": "string"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.ClusterNodeCost"
+                    }
+                }
+            }
+        },
+        "github_com_kubefin_kubefin_pkg_api.ClusterResourceCost": {
+            "type": "object",
+            "properties": {
+                "costEfficiency": {
+                    "type": "number"
+                },
+                "costFallbackBillingMode": {
+                    "description": "TODO: implement this type",
+                    "type": "number"
+                },
+                "costOnDemandBillingMode": {
+                    "type": "number"
+                },
+                "costPeriodBillingMode": {
+                    "type": "number"
+                },
+                "costSpotBillingMode": {
+                    "type": "number"
+                },
+                "cpuCoreCount": {
+                    "description": "CPUCoreCount means the average core hour count in this period",
+                    "type": "number"
+                },
+                "cpuCoreRequest": {
+                    "description": "CPUCoreRequest/RAMGiBRequest mean the average resources requested by all pods in this period,\nthe efficiency of the cluster is the usage of the nodes against them",
+                    "type": "number"
+                },
+                "cpuCoreUsage": {
+                    "description": "CPUCoreUsage means the average core hour usage in this period",
+                    "type": "number"
+                },
+                "cpuCost": {
+                    "type": "number"
+                },
+                "cpuEfficiency": {
+                    "type": "number"
+                },
+                "ramCost": {
+                    "type": "number"
+                },
+                "ramEfficiency": {
+                    "type": "number"
+                },
+                "ramGiBCount": {
+                    "description": "RAMGiBCount means the average ram hour count in this period",
+                    "type": "number"
+                },
+                "ramGiBRequest": {
+                    "type": "number"
+                },
+                "ramGiBUsage": {
+                    "description": "RAMGiBUsage means the average ram hour usage in this period",
+                    "type": "number"
+                },
+                "timestamp": {
+                    "description": "Timestamp is in unix timestamp format, you can transform it to any you want",
+                    "type": "integer"
+                },
+                "totalCost": {
+                    "type": "number"
+                },
+                "wastedCost": {
+                    "type": "number"
+                }
+            }
+        },
+        "github_com_kubefin_kubefin_pkg_api.ClusterResourceCostList": {
+            "type": "object",
+            "properties": {
+                "clusterId": {
+                    "type": "string"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.ClusterResourceCost"
+                    }
+                }
+            }
+        },
+        "github_com_kubefin_kubefin_pkg_api.ClusterResourceMetrics": {
+            "type": "object",
+            "properties": {
+                "clusterId": {
+                    "type": "string"
+                },
+                "resourceAvailableValues": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.SamplePair"
+                    }
+                },
+                "resourceRequestValues": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.SamplePair"
+                    }
+                },
+                "resourceSystemTakenValues": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.SamplePair"
+                    }
+                },
+                "resourceTotalValues": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.SamplePair"
+                    }
+                },
+                "resourceType": {
+                    "type": "string"
+                },
+                "resourceUsageValues": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.SamplePair"
+                    }
+                },
+                "unit": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_kubefin_kubefin_pkg_api.ClusterWorkloadCost": {
+            "type": "object",
+            "properties": {
+                "costList": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.ClusterWorkloadCostDetail"
+                    }
+                },
+                "namespace": {
+                    "type": "string"
+                },
+                "workloadName": {
+                    "type": "string"
+                },
+                "workloadType": {
+                    "description": "WorkloadType could be pod/daemonset/statefulset/deployment",
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_kubefin_kubefin_pkg_api.ClusterWorkloadCostDetail": {
+            "type": "object",
+            "properties": {
+                "costEfficiency": {
+                    "type": "number"
+                },
+                "cpuCoreRequest": {
+                    "type": "number"
+                },
+                "cpuCoreUsage": {
+                    "type": "number"
+                },
+                "cpuEfficiency": {
+                    "description": "CPUEfficiency and RAMEfficiency are the usage/request ratios, CostEfficiency is the ratio\nof the cost of the cpu and ram used to the cost of the cpu and ram requested",
+                    "type": "number"
+                },
+                "podCount": {
+                    "description": "PodCount means the average pod count in this period",
+                    "type": "number"
+                },
+                "ramEfficiency": {
+                    "type": "number"
+                },
+                "ramGiBRequest": {
+                    "type": "number"
+                },
+                "ramGiBUsage": {
+                    "type": "number"
+                },
+                "timestamp": {
+                    "type": "integer"
+                },
+                "totalCost": {
+                    "type": "number"
+                },
+                "wastedCost": {
+                    "description": "WastedCost is the cost of the cpu and ram requested but not used in this period",
+                    "type": "number"
+                }
+            }
+        },
+        "github_com_kubefin_kubefin_pkg_api.ClusterWorkloadCostList": {
+            "type": "object",
+            "properties": {
+                "clusterId": {
+                    "type": "string"
+                },
+                "continue": {
+                    "description": "Continue is the token to query the next page with, it's empty on the last page",
+                    "type": "string"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.ClusterWorkloadCost"
+                    }
+                },
+                "totalItems": {
+                    "description": "TotalItems is the number of the items matched before paginating",
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_kubefin_kubefin_pkg_api.ContainerRecommendation": {
+            "type": "object",
+            "properties": {
+                "containerName": {
+                    "type": "string"
+                },
+                "cpu": {
+                    "description": "CPU is in cores, Memory is in GiB, both are per pod",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.ResourceRecommendation"
                         }
-                    }
+                    ]
+                },
+                "memory": {
+                    "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.ResourceRecommendation"
+                },
+                "monthlySavings": {
+                    "type": "number"
                 }
             }
         },
-        "/metrics/summary": {
-            "get": {
-                "description": "Get all clusters metrics summary in current two month",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Metrics"
-                ],
-                "summary": "Get all clusters metrics summary",
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.ClusterMetricsSummaryList"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.StatusError"
-                        }
-                    }
-                }
-            }
-        }
-    },
-    "definitions": {
-        "github_com_kubefin_kubefin_pkg_api.ClusterCostsSummary": {
+        "github_com_kubefin_kubefin_pkg_api.CostComparison": {
             "type": "object",
             "properties": {
-                "ClusterAvgHourlyCoreCost": {
-                    "description": "ClusterAvgDailyCost means average core/hour costs in current month",
+                "baseEndTime": {
+                    "type": "integer"
+                },
+                "baseStartTime": {
+                    "description": "BaseStartTime and BaseEndTime are the period compared with",
+                    "type": "integer"
+                },
+                "baseTotalCost": {
                     "type": "number"
                 },
-                "cloudProvider": {
+                "clusterId": {
                     "type": "string"
                 },
-                "clusterActiveTime": {
-                    "description": "ClusterActiveTime shows the cluster active time in seconds",
+                "costDelta": {
                     "type": "number"
                 },
-                "clusterAvgDailyCost": {
-                    "description": "ClusterAvgDailyCost means average daily costs in current month",
+                "costDeltaPercent": {
+                    "description": "CostDeltaPercent is 0 if the base total cost is 0",
                     "type": "number"
                 },
-                "clusterConnectionSate": {
-                    "description": "ClusterConnectionSate can be running/connect_failed",
+                "dimension": {
+                    "description": "Dimension could be namespace/workload/label/instanceType",
                     "type": "string"
                 },
-                "clusterId": {
+                "endTime": {
+                    "type": "integer"
+                },
+                "items": {
+                    "description": "Items are sorted by the absolute cost delta from the highest",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.CostComparisonItem"
+                    }
+                },
+                "labelKey": {
                     "type": "string"
                 },
-                "clusterMonthCostCurrent": {
-                    "description": "ClusterMonthCostCurrent means the total cost in current month",
+                "startTime": {
+                    "type": "integer"
+                },
+                "totalCost": {
+                    "type": "number"
+                }
+            }
+        },
+        "github_com_kubefin_kubefin_pkg_api.CostComparisonItem": {
+            "type": "object",
+            "properties": {
+                "baseCost": {
                     "type": "number"
                 },
-                "clusterMonthEstimateCost": {
-                    "description": "ClusterMonthEstimateCost means the estimating cost with previous 7 days costs",
+                "cost": {
                     "type": "number"
                 },
-                "clusterName": {
-                    "type": "string"
+                "costDelta": {
+                    "type": "number"
                 },
-                "clusterRegion": {
+                "costDeltaPercent": {
+                    "description": "CostDeltaPercent is 0 for the new items",
+                    "type": "number"
+                },
+                "name": {
+                    "description": "Name is the namespace, the workload as type/name, the label value or the instance type,\nthe pods without the label are in the item with an empty name",
                     "type": "string"
                 },
-                "connectionTime": {
-                    "description": "ConnectionTime shows the time the cluster connected",
-                    "type": "integer"
+                "namespace": {
+                    "description": "Namespace is only set for the workloads",
+                    "type": "string"
                 },
-                "lastActiveTime": {
-                    "type": "integer"
+                "status": {
+                    "description": "Status could be new/removed/existing",
+                    "type": "string"
                 }
             }
         },
-        "github_com_kubefin_kubefin_pkg_api.ClusterCostsSummaryList": {
+        "github_com_kubefin_kubefin_pkg_api.CostForecast": {
             "type": "object",
             "properties": {
-                "items": {
+                "dailyForecast": {
+                    "description": "DailyForecast is the cost forecast of the next 30 days, it's only set for the cluster",
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.ClusterCostsSummary"
+                        "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.CostForecastPoint"
                     }
+                },
+                "model": {
+                    "description": "Model could be average/trend/trend+weekly/trend+weekly+nodes, depending on the history length",
+                    "type": "string"
+                },
+                "monthCostCurrent": {
+                    "description": "MonthCostCurrent means the total cost in current month",
+                    "type": "number"
+                },
+                "monthEnd": {
+                    "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.CostForecastValue"
+                },
+                "namespace": {
+                    "type": "string"
+                },
+                "next30Days": {
+                    "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.CostForecastValue"
+                },
+                "next90Days": {
+                    "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.CostForecastValue"
                 }
             }
         },
-        "github_com_kubefin_kubefin_pkg_api.ClusterMetricsSummary": {
+        "github_com_kubefin_kubefin_pkg_api.CostForecastPoint": {
             "type": "object",
             "properties": {
-                "cloudProvider": {
+                "cost": {
+                    "type": "number"
+                },
+                "lower": {
+                    "type": "number"
+                },
+                "timestamp": {
+                    "description": "Timestamp is the start of the day",
+                    "type": "integer"
+                },
+                "upper": {
+                    "type": "number"
+                }
+            }
+        },
+        "github_com_kubefin_kubefin_pkg_api.CostForecastValue": {
+            "type": "object",
+            "properties": {
+                "cost": {
+                    "type": "number"
+                },
+                "lower": {
+                    "type": "number"
+                },
+                "upper": {
+                    "type": "number"
+                }
+            }
+        },
+        "github_com_kubefin_kubefin_pkg_api.CostLedgerRecord": {
+            "type": "object",
+            "properties": {
+                "clusterId": {
                     "type": "string"
                 },
-                "clusterActiveTime": {
-                    "description": "ClusterActiveTime shows the cluster active time in seconds",
+                "cost": {
                     "type": "number"
                 },
-                "clusterConnectionSate": {
-                    "description": "ClusterConnectionSate can be running/connect_failed",
+                "granularity": {
+                    "description": "Granularity could be hourly/daily",
                     "type": "string"
                 },
-                "clusterId": {
+                "kind": {
+                    "description": "Kind could be cluster/node/namespace/workload/label",
                     "type": "string"
                 },
-                "clusterName": {
+                "name": {
+                    "description": "Name is the node name, the workload as type/name, or the pod labels in json",
                     "type": "string"
                 },
-                "clusterRegion": {
+                "namespace": {
                     "type": "string"
                 },
-                "connectionTime": {
-                    "description": "ConnectionTime shows the time the cluster connected",
+                "startTime": {
+                    "description": "StartTime is the start of the hour or the UTC day",
                     "type": "integer"
                 },
-                "cpuCoreCapacity": {
-                    "description": "CPUCoreCapacity means all nodes' cpu core capacity",
+                "tenantId": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_kubefin_kubefin_pkg_api.CostLedgerRecordList": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.CostLedgerRecord"
+                    }
+                }
+            }
+        },
+        "github_com_kubefin_kubefin_pkg_api.InstanceTypeSpec": {
+            "type": "object",
+            "properties": {
+                "cpuCores": {
+                    "description": "CPUCores/RAMGiB are the allocatable resource of one node",
                     "type": "number"
                 },
-                "cpuCoreRequest": {
-                    "description": "CPUCoreRequest means all pods' cpu core request",
+                "hourlyPrice": {
                     "type": "number"
                 },
-                "cpuCoreTotal": {
-                    "description": "CPUCoreTotal means all nodes' cpu core",
-                    "type": "number"
+                "instanceType": {
+                    "type": "string"
                 },
-                "cpuCoreUsage": {
-                    "description": "CPUCoreUsage means all pods' cpu core usage",
+                "ramGiB": {
                     "type": "number"
+                }
+            }
+        },
+        "github_com_kubefin_kubefin_pkg_api.MultiClustersCostBreakdown": {
+            "type": "object",
+            "properties": {
+                "clusterIds": {
+                    "description": "ClusterIds are the clusters selected and accessible by the caller",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 },
-                "fallbackBillingNodeNumbersCurrent": {
-                    "type": "integer"
+                "dimension": {
+                    "description": "Dimension could be namespace/label/resourceType",
+                    "type": "string"
                 },
-                "lastActiveTime": {
+                "endTime": {
                     "type": "integer"
                 },
-                "nodeNumbersCurrent": {
-                    "type": "integer"
+                "items": {
+                    "description": "Items are sorted by the total cost from the highest",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.MultiClustersCostBreakdownItem"
+                    }
                 },
-                "onDemandBillingNodeNumbersCurrent": {
-                    "type": "integer"
+                "labelKey": {
+                    "type": "string"
                 },
-                "periodBillingNodeNumbersCurrent": {
+                "startTime": {
                     "type": "integer"
                 },
-                "podScheduledCurrent": {
-                    "type": "integer"
+                "totalCost": {
+                    "type": "number"
+                }
+            }
+        },
+        "github_com_kubefin_kubefin_pkg_api.MultiClustersCostBreakdownItem": {
+            "type": "object",
+            "properties": {
+                "clusterCosts": {
+                    "description": "ClusterCosts is the cost of the item in every cluster keyed by the cluster id",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "number"
+                    }
                 },
-                "podTotalCurrent": {
-                    "type": "integer"
+                "name": {
+                    "description": "Name is the namespace, the label value or the resource type,\nthe pods without the label are in the item with an empty name",
+                    "type": "string"
                 },
-                "podUnscheduledCurrent": {
+                "totalCost": {
+                    "type": "number"
+                }
+            }
+        },
+        "github_com_kubefin_kubefin_pkg_api.NamespaceSpotSavings": {
+            "type": "object",
+            "properties": {
+                "blockedMonthlySavings": {
+                    "description": "BlockedMonthlySavings is the savings of the unsuitable workloads, if their blockers were fixed",
+                    "type": "number"
+                },
+                "monthlySavings": {
+                    "type": "number"
+                },
+                "namespace": {
+                    "type": "string"
+                },
+                "suitableWorkloads": {
                     "type": "integer"
                 },
-                "ramGBCapacity": {
-                    "description": "RAMGBCapacity means all nodes' ram gb capacity",
+                "workloads": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_kubefin_kubefin_pkg_api.NodePoolPlacement": {
+            "type": "object",
+            "properties": {
+                "cpuRequestRatio": {
+                    "description": "CPURequestRatio/RAMRequestRatio are the requests of the pods against the allocatable resource",
                     "type": "number"
                 },
-                "ramGBRequest": {
-                    "description": "RAMGBRequest all pods' ram gb request",
+                "hourlyCost": {
                     "type": "number"
                 },
-                "ramGBTotal": {
-                    "description": "RAMGBTotal means all nodes' ram gb",
+                "instanceTypes": {
+                    "description": "InstanceTypes maps the instance type to the node count",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "monthlyCost": {
                     "type": "number"
                 },
-                "ramGBUsage": {
-                    "description": "RAMGBUsage means all pods' ram gb usage",
+                "monthlySavings": {
+                    "description": "MonthlySavings is against the current nodes, it's zero for the current placement",
                     "type": "number"
                 },
-                "spotBillingNodeNumbersCurrent": {
+                "nodeCount": {
                     "type": "integer"
+                },
+                "ramRequestRatio": {
+                    "type": "number"
                 }
             }
         },
-        "github_com_kubefin_kubefin_pkg_api.ClusterMetricsSummaryList": {
+        "github_com_kubefin_kubefin_pkg_api.NodePoolSimulation": {
             "type": "object",
             "properties": {
-                "items": {
+                "billingMode": {
+                    "type": "string"
+                },
+                "current": {
+                    "description": "Current is how the pods are placed now",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.NodePoolPlacement"
+                        }
+                    ]
+                },
+                "instanceType": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "podCount": {
+                    "type": "integer"
+                },
+                "proposals": {
+                    "description": "Proposals are the instance type mixes cheaper than the current nodes, the cheapest first",
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.ClusterMetricsSummary"
+                        "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.NodePoolPlacement"
                     }
+                },
+                "region": {
+                    "type": "string"
                 }
             }
         },
-        "github_com_kubefin_kubefin_pkg_api.ClusterNamespaceCost": {
+        "github_com_kubefin_kubefin_pkg_api.NodePoolSnapshot": {
             "type": "object",
             "properties": {
-                "costList": {
+                "billingMode": {
+                    "type": "string"
+                },
+                "instanceType": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "nodes": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.ClusterNamespaceCostDetail"
+                        "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.SimulationNode"
                     }
                 },
-                "namespace": {
+                "pods": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.SimulationPod"
+                    }
+                },
+                "region": {
                     "type": "string"
                 }
             }
         },
-        "github_com_kubefin_kubefin_pkg_api.ClusterNamespaceCostDetail": {
+        "github_com_kubefin_kubefin_pkg_api.NodeSimulationResult": {
             "type": "object",
             "properties": {
-                "cpuCoreUsage": {
-                    "type": "number"
+                "clusterId": {
+                    "type": "string"
                 },
-                "cpuRequest": {
+                "currentMonthlyCost": {
+                    "description": "CurrentMonthlyCost/ProposedMonthlyCost sum the current and the cheapest proposal of every node pool",
                     "type": "number"
                 },
-                "podCount": {
-                    "description": "PodCount means the average pod count in this period",
+                "monthlySavings": {
                     "type": "number"
                 },
-                "ramGBRequest": {
-                    "type": "number"
+                "nodePools": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.NodePoolSimulation"
+                    }
                 },
-                "ramGBUsage": {
+                "proposedMonthlyCost": {
                     "type": "number"
                 },
                 "timestamp": {
                     "type": "integer"
                 },
-                "totalCost": {
-                    "type": "number"
+                "unscheduledPods": {
+                    "type": "integer"
                 }
             }
         },
-        "github_com_kubefin_kubefin_pkg_api.ClusterNamespaceCostList": {
+        "github_com_kubefin_kubefin_pkg_api.NodeSimulationSnapshot": {
             "type": "object",
             "properties": {
+                "catalog": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.InstanceTypeSpec"
+                    }
+                },
                 "clusterId": {
                     "type": "string"
                 },
-                "items": {
+                "nodePools": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.ClusterNamespaceCost"
+                        "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.NodePoolSnapshot"
+                    }
+                },
+                "timestamp": {
+                    "description": "Timestamp is when the pods and nodes were taken from the query backend",
+                    "type": "integer"
+                },
+                "unscheduledPods": {
+                    "description": "UnscheduledPods are not packed in any node pool",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.SimulationPod"
                     }
                 }
             }
         },
-        "github_com_kubefin_kubefin_pkg_api.ClusterResourceCost": {
+        "github_com_kubefin_kubefin_pkg_api.ResourceRecommendation": {
             "type": "object",
             "properties": {
-                "costFallbackBillingMode": {
-                    "description": "TODO: implement this type",
+                "currentRequest": {
                     "type": "number"
                 },
-                "costOnDemandBillingMode": {
+                "recommendedLimit": {
+                    "description": "RecommendedLimit is not set if no limit is recommended",
                     "type": "number"
                 },
-                "costPeriodBillingMode": {
-                    "type": "number"
+                "recommendedLimitQuantity": {
+                    "type": "string"
                 },
-                "costSpotBillingMode": {
+                "recommendedRequest": {
                     "type": "number"
                 },
-                "cpuCoreCount": {
-                    "description": "CPUCoreCount means the average core hour count in this period",
-                    "type": "number"
+                "recommendedRequestQuantity": {
+                    "description": "RecommendedRequestQuantity/RecommendedLimitQuantity are the kubernetes quantities, such as 250m/512Mi",
+                    "type": "string"
                 },
-                "cpuCoreUsage": {
-                    "description": "CPUCoreUsage means the average core hour usage in this period",
+                "usageP50": {
                     "type": "number"
                 },
-                "cpuCost": {
+                "usageP95": {
                     "type": "number"
                 },
-                "ramCost": {
+                "usageP99": {
                     "type": "number"
                 },
-                "ramGBCount": {
-                    "description": "RAMGBCount means the average ram hour count in this period",
+                "usagePeak": {
+                    "type": "number"
+                }
+            }
+        },
+        "github_com_kubefin_kubefin_pkg_api.SimulationNode": {
+            "type": "object",
+            "properties": {
+                "cpuCores": {
+                    "description": "CPUCores/RAMGiB are the allocatable resource of the node",
                     "type": "number"
                 },
-                "ramGBUsage": {
-                    "description": "RAMGBUsage means the average ram hour usage in this period",
+                "hourlyPrice": {
                     "type": "number"
                 },
-                "timestamp": {
-                    "description": "Timestamp is in unix timestamp format, you can transform it to any you want",
-                    "type": "integer"
+                "nodeName": {
+                    "type": "string"
                 },
-                "totalCost": {
+                "ramGiB": {
                     "type": "number"
                 }
             }
         },
-        "github_com_kubefin_kubefin_pkg_api.ClusterResourceCostList": {
+        "github_com_kubefin_kubefin_pkg_api.SimulationPod": {
             "type": "object",
             "properties": {
-                "clusterId": {
+                "cpuCores": {
+                    "description": "CPUCores/RAMGiB are the requests of the pod",
+                    "type": "number"
+                },
+                "namespace": {
                     "type": "string"
                 },
-                "items": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.ClusterResourceCost"
-                    }
+                "podName": {
+                    "type": "string"
+                },
+                "ramGiB": {
+                    "type": "number"
                 }
             }
         },
-        "github_com_kubefin_kubefin_pkg_api.ClusterResourceMetrics": {
+        "github_com_kubefin_kubefin_pkg_api.SpotRates": {
+            "type": "object",
+            "properties": {
+                "observed": {
+                    "description": "Observed is false if the spot prices are discounted from the on-demand ones",
+                    "type": "boolean"
+                },
+                "onDemandCPUCoreHourlyPrice": {
+                    "type": "number"
+                },
+                "onDemandRAMGiBHourlyPrice": {
+                    "type": "number"
+                },
+                "spotCPUCoreHourlyPrice": {
+                    "type": "number"
+                },
+                "spotRAMGiBHourlyPrice": {
+                    "type": "number"
+                }
+            }
+        },
+        "github_com_kubefin_kubefin_pkg_api.SpotSavingsAnalysis": {
             "type": "object",
             "properties": {
+                "blockedMonthlySavings": {
+                    "description": "BlockedMonthlySavings is the sum of the unsuitable workloads savings, if their blockers were fixed",
+                    "type": "number"
+                },
                 "clusterId": {
                     "type": "string"
                 },
-                "resourceAllocatableValues": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/model.SamplePair"
-                    }
+                "monthlySavings": {
+                    "description": "MonthlySavings is the sum of the suitable workloads savings",
+                    "type": "number"
                 },
-                "resourceCapacityValues": {
+                "namespaces": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/model.SamplePair"
+                        "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.NamespaceSpotSavings"
                     }
                 },
-                "resourceRequestValues": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/model.SamplePair"
-                    }
+                "onDemandMonthlyCost": {
+                    "description": "OnDemandMonthlyCost is the cost of all the analyzed workloads' requests on on-demand nodes,\nSpotMonthlyCost is the cost once the suitable ones are moved to spot nodes",
+                    "type": "number"
                 },
-                "resourceTotalValues": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/model.SamplePair"
-                    }
+                "rates": {
+                    "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.SpotRates"
                 },
-                "resourceType": {
-                    "type": "string"
+                "spotMonthlyCost": {
+                    "type": "number"
                 },
-                "resourceUsageValues": {
+                "windowSeconds": {
+                    "description": "WindowSeconds is how far back the requests and unit prices are averaged",
+                    "type": "integer"
+                },
+                "workloads": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/model.SamplePair"
+                        "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.WorkloadSpotSavings"
                     }
-                },
-                "unit": {
-                    "type": "string"
                 }
             }
         },
-        "github_com_kubefin_kubefin_pkg_api.ClusterWorkloadCost": {
+        "github_com_kubefin_kubefin_pkg_api.StatusError": {
             "type": "object",
             "properties": {
-                "costList": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.ClusterWorkloadCostDetail"
-                    }
+                "apiVersion": {
+                    "type": "string"
                 },
-                "namespace": {
+                "code": {
+                    "type": "integer"
+                },
+                "kind": {
                     "type": "string"
                 },
-                "workloadName": {
+                "message": {
                     "type": "string"
                 },
-                "workloadType": {
-                    "description": "WorkloadType could be pod/daemonset/statefulset/deployment",
+                "reason": {
+                    "type": "string"
+                },
+                "status": {
                     "type": "string"
                 }
             }
         },
-        "github_com_kubefin_kubefin_pkg_api.ClusterWorkloadCostDetail": {
+        "github_com_kubefin_kubefin_pkg_api.WorkloadEfficiency": {
             "type": "object",
             "properties": {
+                "costEfficiency": {
+                    "type": "number"
+                },
                 "cpuCoreRequest": {
                     "type": "number"
                 },
                 "cpuCoreUsage": {
                     "type": "number"
                 },
+                "cpuEfficiency": {
+                    "description": "CPUEfficiency and RAMEfficiency are the usage/request ratios, CostEfficiency is the ratio\nof the cost of the cpu and ram used to the cost of the cpu and ram requested",
+                    "type": "number"
+                },
+                "namespace": {
+                    "type": "string"
+                },
                 "podCount": {
                     "description": "PodCount means the average pod count in this period",
                     "type": "number"
                 },
-                "ramGBRequest": {
+                "ramEfficiency": {
+                    "type": "number"
+                },
+                "ramGiBRequest": {
                     "type": "number"
                 },
-                "ramGBUsage": {
+                "ramGiBUsage": {
                     "type": "number"
                 },
                 "timestamp": {
@@ -779,42 +2715,137 @@ const docTemplate = `{
                 },
                 "totalCost": {
                     "type": "number"
+                },
+                "wastedCost": {
+                    "description": "WastedCost is the cost of the cpu and ram requested but not used in this period",
+                    "type": "number"
+                },
+                "workloadName": {
+                    "type": "string"
+                },
+                "workloadType": {
+                    "type": "string"
                 }
             }
         },
-        "github_com_kubefin_kubefin_pkg_api.ClusterWorkloadCostList": {
+        "github_com_kubefin_kubefin_pkg_api.WorkloadEfficiencyList": {
             "type": "object",
             "properties": {
                 "clusterId": {
                     "type": "string"
                 },
+                "endTime": {
+                    "type": "integer"
+                },
                 "items": {
+                    "description": "Items are sorted by the wasted cost from the highest",
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.ClusterWorkloadCost"
+                        "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.WorkloadEfficiency"
+                    }
+                },
+                "startTime": {
+                    "type": "integer"
+                },
+                "totalWastedCost": {
+                    "description": "TotalWastedCost is the wasted cost of all the workloads matched, not only the listed ones",
+                    "type": "number"
+                }
+            }
+        },
+        "github_com_kubefin_kubefin_pkg_api.WorkloadRecommendation": {
+            "type": "object",
+            "properties": {
+                "containers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.ContainerRecommendation"
                     }
+                },
+                "monthlySavings": {
+                    "description": "MonthlySavings is negative if the workload needs more resource",
+                    "type": "number"
+                },
+                "namespace": {
+                    "type": "string"
+                },
+                "podCount": {
+                    "description": "PodCount is the average pod count in the window",
+                    "type": "number"
+                },
+                "workloadName": {
+                    "type": "string"
+                },
+                "workloadType": {
+                    "description": "WorkloadType could be daemonset/statefulset/deployment",
+                    "type": "string"
                 }
             }
         },
-        "github_com_kubefin_kubefin_pkg_api.StatusError": {
+        "github_com_kubefin_kubefin_pkg_api.WorkloadRecommendationList": {
             "type": "object",
             "properties": {
-                "apiVersion": {
+                "clusterId": {
                     "type": "string"
                 },
-                "code": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_kubefin_kubefin_pkg_api.WorkloadRecommendation"
+                    }
+                },
+                "monthlySavings": {
+                    "description": "MonthlySavings is the sum of the workloads savings",
+                    "type": "number"
+                },
+                "windowSeconds": {
+                    "description": "WindowSeconds is how far back the usage is looked at",
                     "type": "integer"
+                }
+            }
+        },
+        "github_com_kubefin_kubefin_pkg_api.WorkloadSpotSavings": {
+            "type": "object",
+            "properties": {
+                "blockers": {
+                    "description": "Blockers could be single_replica/stateful/local_volume/no_pdb",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 },
-                "kind": {
-                    "type": "string"
+                "cpuRequest": {
+                    "description": "CPURequest is in cores, RAMRequest is in GiB, both are the sum of all the pods",
+                    "type": "number"
                 },
-                "message": {
+                "monthlySavings": {
+                    "description": "MonthlySavings is what the workload saves on spot nodes, it's set even if the workload is unsuitable",
+                    "type": "number"
+                },
+                "namespace": {
                     "type": "string"
                 },
-                "reason": {
+                "onDemandMonthlyCost": {
+                    "type": "number"
+                },
+                "podCount": {
+                    "description": "PodCount is the average pod count in the window",
+                    "type": "number"
+                },
+                "ramRequest": {
+                    "type": "number"
+                },
+                "spotMonthlyCost": {
+                    "type": "number"
+                },
+                "suitable": {
+                    "type": "boolean"
+                },
+                "workloadName": {
                     "type": "string"
                 },
-                "status": {
+                "workloadType": {
+                    "description": "WorkloadType could be statefulset/deployment",
                     "type": "string"
                 }
             }
@@ -39,6 +39,7 @@ import (
 	"github.com/kubefin/kubefin/pkg/api"
 	"github.com/kubefin/kubefin/pkg/cloudprice"
 	"github.com/kubefin/kubefin/pkg/metrics"
+	"github.com/kubefin/kubefin/pkg/rightsizing"
 )
 
 // NewAgentCommand creates a *cobra.Command object with defaultcloud parameters
@@ -49,7 +50,7 @@ func NewAgentCommand(ctx context.Context) *cobra.Command {
 		Use:  "kubefin-agent",
 		Long: `kubefin-agent used to scrap metrics to storage store such as thanos`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if err := opts.Complete(); err != nil {
+			if err := opts.Complete(cmd.Flags()); err != nil {
 				return err
 			}
 			if err := opts.Validate(); err != nil {
@@ -102,6 +103,11 @@ func Run(ctx context.Context, opts *options.AgentOptions) error {
 	factory := informers.NewSharedInformerFactory(clientSet, 0)
 	coreResourceInformerLister := getAllCoreResourceLister(factory)
 	metricsCollector := metrics.NewAgentMetricsCollector(ctx, opts, coreResourceInformerLister, provider, metricsClientSet)
+	var rightSizingController *rightsizing.Controller
+	if opts.RightSizing.Enabled {
+		rightSizingController = rightsizing.NewController(&opts.RightSizing, opts.ClusterName, opts.ClusterId,
+			clientSet, metricsClientSet, coreResourceInformerLister)
+	}
 
 	stopCh := ctx.Done()
 	factory.Start(stopCh)
@@ -110,7 +116,13 @@ func Run(ctx context.Context, opts *options.AgentOptions) error {
 	if ok := cache.WaitForCacheSync(stopCh,
 		coreResourceInformerLister.NamespaceInformer.HasSynced,
 		coreResourceInformerLister.NodeInformer.HasSynced,
-		coreResourceInformerLister.PodInformer.HasSynced); !ok {
+		coreResourceInformerLister.PodInformer.HasSynced,
+		coreResourceInformerLister.DeploymentInformer.HasSynced,
+		coreResourceInformerLister.StatefulSetInformer.HasSynced,
+		coreResourceInformerLister.DaemonSetInformer.HasSynced,
+		coreResourceInformerLister.PersistentVolumeClaimInformer.HasSynced,
+		coreResourceInformerLister.PersistentVolumeInformer.HasSynced,
+		coreResourceInformerLister.PodDisruptionBudgetInformer.HasSynced); !ok {
 		return fmt.Errorf("wait core resource cache sync failed")
 	}
 
@@ -118,16 +130,25 @@ func Run(ctx context.Context, opts *options.AgentOptions) error {
 		return err
 	}
 
-	klog.Infof("Start metrics http server")
+	klog.Infof("Start metrics http server on %s", opts.MetricsBindAddress)
 	http.Handle("/metrics", promhttp.Handler())
 	go func() {
-		if err := http.ListenAndServe(":8080", nil); err != nil {
+		if err := http.ListenAndServe(opts.MetricsBindAddress, nil); err != nil {
 			klog.Fatalf("Start http server error:%v", err)
 		}
 	}()
 
 	runFunc := func(runCtx context.Context) {
 		metricsCollector.StartAgentMetricsCollector()
+		if rightSizingController != nil {
+			go rightSizingController.Run(runCtx)
+		}
+	}
+	if !opts.LeaderElection.LeaderElect {
+		klog.Infof("Leader election is disabled, start collecting metrics directly")
+		runFunc(ctx)
+		<-stopCh
+		return nil
 	}
 	if err := runLeaderElection(ctx, clientSet, opts, runFunc); err != nil {
 		return fmt.Errorf("run leader election error:%v", err)
@@ -173,17 +194,24 @@ func runLeaderElection(ctx context.Context, clientset kubernetes.Interface,
 func getAllCoreResourceLister(factory informers.SharedInformerFactory) *api.CoreResourceInformerLister {
 	coreResource := factory.Core().V1()
 	appsResource := factory.Apps().V1()
+	policyResource := factory.Policy().V1()
 	return &api.CoreResourceInformerLister{
-		NodeInformer:        coreResource.Nodes().Informer(),
-		NamespaceInformer:   coreResource.Namespaces().Informer(),
-		PodInformer:         coreResource.Pods().Informer(),
-		DeploymentInformer:  appsResource.Deployments().Informer(),
-		StatefulSetInformer: appsResource.StatefulSets().Informer(),
-		DaemonSetInformer:   appsResource.DaemonSets().Informer(),
-		NodeLister:          coreResource.Nodes().Lister(),
-		PodLister:           coreResource.Pods().Lister(),
-		DeploymentLister:    appsResource.Deployments().Lister(),
-		StatefulSetLister:   appsResource.StatefulSets().Lister(),
-		DaemonSetLister:     appsResource.DaemonSets().Lister(),
+		NodeInformer:                  coreResource.Nodes().Informer(),
+		NamespaceInformer:             coreResource.Namespaces().Informer(),
+		PodInformer:                   coreResource.Pods().Informer(),
+		DeploymentInformer:            appsResource.Deployments().Informer(),
+		StatefulSetInformer:           appsResource.StatefulSets().Informer(),
+		DaemonSetInformer:             appsResource.DaemonSets().Informer(),
+		PersistentVolumeClaimInformer: coreResource.PersistentVolumeClaims().Informer(),
+		PersistentVolumeInformer:      coreResource.PersistentVolumes().Informer(),
+		PodDisruptionBudgetInformer:   policyResource.PodDisruptionBudgets().Informer(),
+		NodeLister:                    coreResource.Nodes().Lister(),
+		PodLister:                     coreResource.Pods().Lister(),
+		DeploymentLister:              appsResource.Deployments().Lister(),
+		StatefulSetLister:             appsResource.StatefulSets().Lister(),
+		DaemonSetLister:               appsResource.DaemonSets().Lister(),
+		PersistentVolumeClaimLister:   coreResource.PersistentVolumeClaims().Lister(),
+		PersistentVolumeLister:        coreResource.PersistentVolumes().Lister(),
+		PodDisruptionBudgetLister:     policyResource.PodDisruptionBudgets().Lister(),
 	}
 }
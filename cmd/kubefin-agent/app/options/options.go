@@ -17,37 +17,60 @@ limitations under the License.
 package options
 
 import (
+	"fmt"
+	"math"
+	"net"
 	"os"
 	"time"
 
 	"github.com/spf13/pflag"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/client-go/tools/leaderelection/resourcelock"
 	baseconfig "k8s.io/component-base/config"
+	baseconfigoptions "k8s.io/component-base/config/options"
+	baseconfigvalidation "k8s.io/component-base/config/validation"
 
+	"github.com/kubefin/kubefin/pkg/api"
+	cloudpriceapis "github.com/kubefin/kubefin/pkg/cloudprice/apis"
+	"github.com/kubefin/kubefin/pkg/config"
+	"github.com/kubefin/kubefin/pkg/rightsizing"
 	"github.com/kubefin/kubefin/pkg/values"
 )
 
+// AgentOptions holds the agent configuration. Values are resolved in the order
+// defaults < config file < environment variables < command-line flags.
 type AgentOptions struct {
-	LeaderElection       baseconfig.LeaderElectionConfiguration
-	ScrapMetricsInterval time.Duration
-	LeaderElectionID     string
-
-	CloudProvider string
-	ClusterName   string
-	ClusterId     string
-
-	NodeCPUCoreDeviation   string
-	NodeRAMGBDeviation     string
-	CPUMemoryCostRatio     string
-	CustomCPUCoreHourPrice string
-	CustomRAMGBHourPrice   string
+	// ConfigFile is the path of an optional YAML file whose keys match the json tags below
+	ConfigFile string `json:"-"`
+
+	LeaderElection       baseconfig.LeaderElectionConfiguration `json:"leaderElection"`
+	LeaderElectionID     string                                 `json:"leaderElectionID,omitempty"`
+	ScrapMetricsInterval metav1.Duration                        `json:"scrapMetricsInterval,omitempty"`
+	MetricsBindAddress   string                                 `json:"metricsBindAddress,omitempty"`
+
+	CloudProvider string `json:"cloudProvider,omitempty"`
+	ClusterName   string `json:"clusterName,omitempty"`
+	ClusterId     string `json:"clusterId,omitempty"`
+
+	// NodeCPUCoreDeviation/NodeRAMGBDeviation are added to the capacity reported by node.status
+	NodeCPUCoreDeviation float64 `json:"nodeCPUCoreDeviation,omitempty"`
+	NodeRAMGBDeviation   float64 `json:"nodeRAMGBDeviation,omitempty"`
+	CPUMemoryCostRatio   float64 `json:"cpuMemoryCostRatio,omitempty"`
+	// CustomCPUCoreHourPrice/CustomRAMGBHourPrice override the built-in prices when they are not zero
+	CustomCPUCoreHourPrice float64 `json:"customCPUCoreHourPrice,omitempty"`
+	CustomRAMGBHourPrice   float64 `json:"customRAMGBHourPrice,omitempty"`
+
+	// RightSizing holds the settings of the opt-in request right-sizing controller
+	RightSizing rightsizing.Config `json:"rightSizing"`
 }
 
-// NewAgentOptions builds an empty options.
+// NewAgentOptions builds an options with default values.
 func NewAgentOptions() *AgentOptions {
 	return &AgentOptions{
 		LeaderElection: baseconfig.LeaderElectionConfiguration{
+			LeaderElect:       true,
 			ResourceLock:      resourcelock.LeasesResourceLock,
 			ResourceNamespace: values.KubeFinNamespace,
 			ResourceName:      values.KubeFinAgentName,
@@ -55,29 +78,205 @@ func NewAgentOptions() *AgentOptions {
 			RenewDeadline:     metav1.Duration{Duration: values.DefaultRenewDeadline},
 			RetryPeriod:       metav1.Duration{Duration: values.DefaultRetryPeriod},
 		},
-		ScrapMetricsInterval:   time.Second,
-		LeaderElectionID:       os.Getenv(values.LeaderElectionIDEnv),
-		CloudProvider:          os.Getenv(values.CloudProviderEnv),
-		ClusterName:            os.Getenv(values.ClusterNameEnv),
-		ClusterId:              os.Getenv(values.ClusterIdEnv),
-		CPUMemoryCostRatio:     os.Getenv(values.CPUMemoryCostRatioEnv),
-		CustomCPUCoreHourPrice: os.Getenv(values.CustomCPUCoreHourPriceEnv),
-		CustomRAMGBHourPrice:   os.Getenv(values.CustomRAMGBHourPriceEnv),
-		NodeCPUCoreDeviation:   os.Getenv(values.NodeCPUDeviationEnv),
-		NodeRAMGBDeviation:     os.Getenv(values.NodeRAMDeviationEnv),
+		ScrapMetricsInterval: metav1.Duration{Duration: time.Second},
+		MetricsBindAddress:   values.DefaultMetricsBindAddress,
+		CPUMemoryCostRatio:   cloudpriceapis.DefaultCPUMemoryCostRatio,
+		RightSizing:          *rightsizing.NewDefaultConfig(),
 	}
 }
 
-func (o *AgentOptions) Complete() error {
+// Complete loads the config file and environment variables, the flags set explicitly
+// in the command line always take precedence over them.
+func (o *AgentOptions) Complete(flags *pflag.FlagSet) error {
+	if err := config.Load(flags, o.ConfigFile, o, o.loadEnv); err != nil {
+		return err
+	}
+
+	if o.LeaderElectionID == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return fmt.Errorf("get hostname as leader election id error:%v", err)
+		}
+		o.LeaderElectionID = hostname
+	}
 	return nil
 }
 
-func (o *AgentOptions) Validate() error {
+func (o *AgentOptions) loadEnv() error {
+	config.SetStringFromEnv(values.LeaderElectionIDEnv, &o.LeaderElectionID)
+	config.SetStringFromEnv(values.CloudProviderEnv, &o.CloudProvider)
+	config.SetStringFromEnv(values.ClusterNameEnv, &o.ClusterName)
+	config.SetStringFromEnv(values.ClusterIdEnv, &o.ClusterId)
+
+	floatEnvs := map[string]*float64{
+		values.NodeCPUDeviationEnv:       &o.NodeCPUCoreDeviation,
+		values.NodeRAMDeviationEnv:       &o.NodeRAMGBDeviation,
+		values.CPUMemoryCostRatioEnv:     &o.CPUMemoryCostRatio,
+		values.CustomCPUCoreHourPriceEnv: &o.CustomCPUCoreHourPrice,
+		values.CustomRAMGBHourPriceEnv:   &o.CustomRAMGBHourPrice,
+	}
+	for env, value := range floatEnvs {
+		if err := config.SetFloatFromEnv(env, value); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+func (o *AgentOptions) Validate() error {
+	allErrs := field.ErrorList{}
+
+	if o.ClusterName == "" {
+		allErrs = append(allErrs, field.Required(field.NewPath("clusterName"),
+			"set it via --cluster-name, the config file or env "+values.ClusterNameEnv))
+	}
+	// The analyzer only accepts the cluster ids of DNS-1123 subdomains
+	if o.ClusterId != "" {
+		for _, msg := range validation.IsDNS1123Subdomain(o.ClusterId) {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("clusterId"), o.ClusterId, msg))
+		}
+	}
+	switch o.CloudProvider {
+	case "", api.CloudProviderAck, api.CloudProviderEks, api.CloudProviderDefault:
+	default:
+		allErrs = append(allErrs, field.NotSupported(field.NewPath("cloudProvider"), o.CloudProvider,
+			[]string{api.CloudProviderAck, api.CloudProviderEks, api.CloudProviderDefault}))
+	}
+	if o.ScrapMetricsInterval.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("scrapMetricsInterval"),
+			o.ScrapMetricsInterval.Duration.String(), "must be greater than zero"))
+	}
+	if _, _, err := net.SplitHostPort(o.MetricsBindAddress); err != nil {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("metricsBindAddress"), o.MetricsBindAddress, err.Error()))
+	}
+
+	allErrs = append(allErrs, validateFloat(field.NewPath("nodeCPUCoreDeviation"), o.NodeCPUCoreDeviation, false)...)
+	allErrs = append(allErrs, validateFloat(field.NewPath("nodeRAMGBDeviation"), o.NodeRAMGBDeviation, false)...)
+	allErrs = append(allErrs, validateFloat(field.NewPath("cpuMemoryCostRatio"), o.CPUMemoryCostRatio, true)...)
+	allErrs = append(allErrs, validateFloat(field.NewPath("customCPUCoreHourPrice"), o.CustomCPUCoreHourPrice, false)...)
+	allErrs = append(allErrs, validateFloat(field.NewPath("customRAMGBHourPrice"), o.CustomRAMGBHourPrice, false)...)
+
+	allErrs = append(allErrs, baseconfigvalidation.ValidateLeaderElectionConfiguration(
+		&o.LeaderElection, field.NewPath("leaderElection"))...)
+	allErrs = append(allErrs, validateRightSizing(&o.RightSizing)...)
+
+	return allErrs.ToAggregate()
+}
+
+func validateRightSizing(config *rightsizing.Config) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if !config.Enabled {
+		return allErrs
+	}
+
+	rightSizingPath := field.NewPath("rightSizing")
+	if config.SampleInterval.Duration < 15*time.Second {
+		allErrs = append(allErrs, field.Invalid(rightSizingPath.Child("sampleInterval"),
+			config.SampleInterval.Duration.String(), "must be at least 15s"))
+	}
+	if config.Interval.Duration < config.SampleInterval.Duration {
+		allErrs = append(allErrs, field.Invalid(rightSizingPath.Child("interval"),
+			config.Interval.Duration.String(), "must be at least the sample interval"))
+	}
+	if config.Window.Duration < config.Interval.Duration {
+		allErrs = append(allErrs, field.Invalid(rightSizingPath.Child("window"),
+			config.Window.Duration.String(), "must be at least the interval"))
+	}
+	if config.Cooldown.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(rightSizingPath.Child("cooldown"),
+			config.Cooldown.Duration.String(), "must be greater than or equal to zero"))
+	}
+	if config.MinSamples < 1 {
+		allErrs = append(allErrs, field.Invalid(rightSizingPath.Child("minSamples"), config.MinSamples,
+			"must be greater than zero"))
+	}
+	for name, percentile := range map[string]float64{
+		"cpuPercentile":    config.CPUPercentile,
+		"memoryPercentile": config.MemoryPercentile,
+	} {
+		if percentile != 50 && percentile != 95 && percentile != 99 && percentile != 100 {
+			allErrs = append(allErrs, field.NotSupported(rightSizingPath.Child(name), percentile,
+				[]string{"50", "95", "99", "100"}))
+		}
+	}
+	allErrs = append(allErrs, validateFloat(rightSizingPath.Child("headroom"), config.Headroom, false)...)
+	allErrs = append(allErrs, validateFloat(rightSizingPath.Child("minCPUCores"), config.MinCPUCores, false)...)
+	allErrs = append(allErrs, validateFloat(rightSizingPath.Child("maxCPUCores"), config.MaxCPUCores, false)...)
+	allErrs = append(allErrs, validateFloat(rightSizingPath.Child("minMemoryGiB"), config.MinMemoryGiB, false)...)
+	allErrs = append(allErrs, validateFloat(rightSizingPath.Child("maxMemoryGiB"), config.MaxMemoryGiB, false)...)
+	if config.MaxCPUCores > 0 && config.MaxCPUCores < config.MinCPUCores {
+		allErrs = append(allErrs, field.Invalid(rightSizingPath.Child("maxCPUCores"), config.MaxCPUCores,
+			"must be zero or at least minCPUCores"))
+	}
+	if config.MaxMemoryGiB > 0 && config.MaxMemoryGiB < config.MinMemoryGiB {
+		allErrs = append(allErrs, field.Invalid(rightSizingPath.Child("maxMemoryGiB"), config.MaxMemoryGiB,
+			"must be zero or at least minMemoryGiB"))
+	}
+	if config.MaxChangeRatio <= 0 || config.MaxChangeRatio >= 1 {
+		allErrs = append(allErrs, field.Invalid(rightSizingPath.Child("maxChangeRatio"), config.MaxChangeRatio,
+			"must be greater than zero and less than 1"))
+	}
+	if config.MinChangeRatio < 0 || config.MinChangeRatio > config.MaxChangeRatio {
+		allErrs = append(allErrs, field.Invalid(rightSizingPath.Child("minChangeRatio"), config.MinChangeRatio,
+			"must be between zero and maxChangeRatio"))
+	}
+	return allErrs
+}
+
+// validateFloat checks the value is a finite, non-negative number, and positive if required
+func validateFloat(fldPath *field.Path, value float64, positive bool) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return append(allErrs, field.Invalid(fldPath, value, "must be a finite number"))
+	}
+	if value < 0 || (positive && value == 0) {
+		msg := "must be greater than or equal to zero"
+		if positive {
+			msg = "must be greater than zero"
+		}
+		allErrs = append(allErrs, field.Invalid(fldPath, value, msg))
+	}
+	return allErrs
+}
+
 func (o *AgentOptions) ApplyTo() {
 }
 
 func (o *AgentOptions) AddFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&o.ConfigFile, "config", o.ConfigFile,
+		"The path of the YAML config file, environment variables and flags override the values in it.")
+	flags.StringVar(&o.MetricsBindAddress, "metrics-bind-address", o.MetricsBindAddress,
+		"The address the metrics endpoint binds to.")
+	flags.DurationVar(&o.ScrapMetricsInterval.Duration, "scrap-metrics-interval", o.ScrapMetricsInterval.Duration,
+		"The interval to refresh the cost and resource metrics.")
+	flags.StringVar(&o.LeaderElectionID, "leader-elect-id", o.LeaderElectionID,
+		"The identity of this instance in leader election, defaults to the hostname. Env: "+values.LeaderElectionIDEnv)
+	baseconfigoptions.BindLeaderElectionFlags(&o.LeaderElection, flags)
+
+	flags.StringVar(&o.CloudProvider, "cloud-provider", o.CloudProvider,
+		"The cloud provider of the cluster(ack/eks/default), detected automatically if empty. Env: "+values.CloudProviderEnv)
+	flags.StringVar(&o.ClusterName, "cluster-name", o.ClusterName,
+		"The name of the cluster, required. Env: "+values.ClusterNameEnv)
+	flags.StringVar(&o.ClusterId, "cluster-id", o.ClusterId,
+		"The id of the cluster, detected from the cloud provider if empty. Env: "+values.ClusterIdEnv)
+
+	flags.Float64Var(&o.NodeCPUCoreDeviation, "node-cpu-deviation", o.NodeCPUCoreDeviation,
+		"The cpu cores added to the node capacity to get the real node cpu cores. Env: "+values.NodeCPUDeviationEnv)
+	flags.Float64Var(&o.NodeRAMGBDeviation, "node-ram-deviation", o.NodeRAMGBDeviation,
+		"The ram GiB added to the node capacity to get the real node ram. Env: "+values.NodeRAMDeviationEnv)
+	flags.Float64Var(&o.CPUMemoryCostRatio, "cpu-ram-price-ratio", o.CPUMemoryCostRatio,
+		"The price ratio of one cpu core to one GiB ram. Env: "+values.CPUMemoryCostRatioEnv)
+	flags.Float64Var(&o.CustomCPUCoreHourPrice, "custom-cpu-core-hour-price", o.CustomCPUCoreHourPrice,
+		"The cpu core hourly price used by the default cloud provider, 0 means the built-in price. Env: "+values.CustomCPUCoreHourPriceEnv)
+	flags.Float64Var(&o.CustomRAMGBHourPrice, "custom-ram-gb-hour-price", o.CustomRAMGBHourPrice,
+		"The ram GiB hourly price used by the default cloud provider, 0 means the built-in price. Env: "+values.CustomRAMGBHourPriceEnv)
+
+	flags.BoolVar(&o.RightSizing.Enabled, "rightsizing-enabled", o.RightSizing.Enabled,
+		"Adjust the requests of the workloads annotated with "+values.RightSizingAnnotationKey+"=enabled toward their usage.")
+	flags.BoolVar(&o.RightSizing.DryRun, "rightsizing-dry-run", o.RightSizing.DryRun,
+		"Only record the request changes as events and metrics without patching the workloads.")
+	flags.DurationVar(&o.RightSizing.Cooldown.Duration, "rightsizing-cooldown", o.RightSizing.Cooldown.Duration,
+		"The least time between two request changes of the same workload.")
+	flags.Float64Var(&o.RightSizing.MaxChangeRatio, "rightsizing-max-change-ratio", o.RightSizing.MaxChangeRatio,
+		"The largest fraction a request is changed by in one step.")
 }
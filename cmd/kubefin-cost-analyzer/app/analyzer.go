@@ -18,15 +18,28 @@ package app
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"flag"
+	"fmt"
+	"net/http"
+	"time"
 
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/wait"
 	cliflag "k8s.io/component-base/cli/flag"
 	"k8s.io/klog/v2"
 
 	"github.com/kubefin/kubefin/cmd/kubefin-cost-analyzer/app/options"
+	"github.com/kubefin/kubefin/pkg/anomaly"
+	"github.com/kubefin/kubefin/pkg/auth"
+	"github.com/kubefin/kubefin/pkg/budget"
+	"github.com/kubefin/kubefin/pkg/ledger"
+	"github.com/kubefin/kubefin/pkg/notification"
 	"github.com/kubefin/kubefin/pkg/query"
 	pkgrouter "github.com/kubefin/kubefin/pkg/router"
+	pkgserver "github.com/kubefin/kubefin/pkg/server"
+	"github.com/kubefin/kubefin/pkg/server/implementation"
 )
 
 // NewAnalyzerCommand creates a *cobra.Command object with parameters
@@ -37,12 +50,11 @@ func NewAnalyzerCommand(ctx context.Context) *cobra.Command {
 		Use:  "kubefin-cost-analyzer",
 		Long: `kubefin-cost-analyzer used to do cost analyzer with prometheus sql`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if err := opts.Complete(); err != nil {
+			if err := opts.Complete(cmd.Flags()); err != nil {
 				return err
 			}
 			if err := opts.Validate(); err != nil {
 				return err
-
 			}
 
 			if err := Run(ctx, opts); err != nil {
@@ -64,20 +76,169 @@ func NewAnalyzerCommand(ctx context.Context) *cobra.Command {
 	cmd.Flags().AddFlagSet(analyzerFlagSet)
 	cmd.Flags().AddFlagSet(logFlagSet)
 
+	cmd.AddCommand(NewSimulateCommand())
+	cmd.AddCommand(NewRollupRulesCommand(ctx))
+	cmd.AddCommand(NewLedgerBackfillCommand(ctx))
+
 	return cmd
 }
 
 func Run(ctx context.Context, opts *options.AnalyzerOptions) error {
 	klog.Infof("Start kubefin-cost-analyzer...")
-	stopCh := ctx.Done()
 
-	query.InitPromQueryClient(opts.QueryBackendEndpoint)
+	backend, err := query.NewQueryBackend(&opts.QueryBackend)
+	if err != nil {
+		klog.Errorf("Create query backend error:%v", err)
+		return err
+	}
+	// Check the backend before the circuit breaker is set, the open circuit would delay the check
+	if err := waitForQueryBackend(ctx, backend, opts.QueryBackendCheckTimeout.Duration); err != nil {
+		return err
+	}
+	backend = query.NewResilientBackend(backend, &opts.QueryBackend.Retry, &opts.QueryBackend.CircuitBreaker)
+	if opts.QueryCache.Enabled {
+		backend, err = query.NewCachedBackend(backend, &opts.QueryCache, opts.QueryBackend.DefaultTenantId)
+		if err != nil {
+			klog.Errorf("Create query cache error:%v", err)
+			return err
+		}
+	}
+
+	serverConfig := &pkgserver.Config{
+		QueryBackend:   backend,
+		QueryOptions:   &implementation.QueryOptions{MaxConcurrentQueries: opts.QueryConcurrency},
+		Recommendation: &opts.Recommendation,
+		Simulation:     &opts.Simulation,
+		Spot:           &opts.Spot,
+	}
+	if opts.QueryRollup.Enabled {
+		serverConfig.QueryOptions.RollupSelector = query.NewRollupSelector(&opts.QueryRollup)
+	}
+	// The background workers query with the same options as the handlers
+	queryCtx := implementation.WithQueryOptions(ctx, serverConfig.QueryOptions)
+
+	var budgetNotifier budget.Notifier = budget.LogNotifier{}
+	var anomalyNotifier anomaly.Notifier
+	if len(opts.Notification.Sinks) > 0 {
+		dispatcher, err := notification.NewDispatcher(&opts.Notification)
+		if err != nil {
+			klog.Errorf("Create notification dispatcher error:%v", err)
+			return err
+		}
+		budgetNotifier = notification.NewBudgetNotifier(dispatcher)
+		anomalyNotifier = notification.NewAnomalyNotifier(dispatcher)
+		if len(opts.Notification.Triggers) > 0 {
+			triggerEvaluator := notification.NewTriggerEvaluator(&opts.Notification,
+				opts.QueryBackend.DefaultTenantId, backend, dispatcher)
+			go triggerEvaluator.Run(queryCtx)
+		}
+	}
+	if len(opts.Budgets.Items) > 0 {
+		serverConfig.Evaluator = budget.NewEvaluator(&opts.Budgets, opts.QueryBackend.DefaultTenantId, backend, budgetNotifier)
+		go serverConfig.Evaluator.Run(queryCtx)
+	}
+	if opts.Anomaly.Enabled {
+		serverConfig.Detector = anomaly.NewDetector(&opts.Anomaly, opts.QueryBackend.DefaultTenantId, backend, anomalyNotifier)
+		go serverConfig.Detector.Run(queryCtx)
+	}
+	if opts.Ledger.Enabled {
+		costLedger, err := ledger.NewLedger(&opts.Ledger, opts.QueryBackend.DefaultTenantId, backend)
+		if err != nil {
+			klog.Errorf("Create cost ledger error:%v", err)
+			return err
+		}
+		defer costLedger.Close()
+		go costLedger.Run(ctx)
+		serverConfig.Ledger = costLedger
+	}
+
+	routerConfig := &pkgrouter.Config{
+		CORSAllowedOrigins: opts.CORSAllowedOrigins,
+		DefaultTenantId:    opts.QueryBackend.DefaultTenantId,
+		Server:             serverConfig,
+		RequestTimeout:     opts.RequestTimeout.Duration,
+	}
+	if opts.Authentication.Enabled() {
+		authn, err := auth.NewAuthenticator(&opts.Authentication)
+		if err != nil {
+			klog.Errorf("Create authenticator error:%v", err)
+			return err
+		}
+		authz, err := auth.NewAuthorizer(&opts.Authorization)
+		if err != nil {
+			klog.Errorf("Create authorizer error:%v", err)
+			return err
+		}
+		routerConfig.Authenticator = authn
+		routerConfig.Authorizer = authz
+	} else {
+		klog.Warningf("No authenticator is configured, the API is open to everyone who could reach it")
+	}
+	router := pkgrouter.NewServerRouter(routerConfig)
+	server := &http.Server{
+		Addr:              opts.BindAddress,
+		Handler:           router,
+		ReadHeaderTimeout: 30 * time.Second,
+	}
+	if opts.TLSCertFile != "" {
+		reloader, err := newCertificateReloader(opts.TLSCertFile, opts.TLSKeyFile)
+		if err != nil {
+			klog.Errorf("Load serving certificate error:%v", err)
+			return err
+		}
+		server.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: reloader.GetCertificate,
+		}
+	}
 
-	router := pkgrouter.NewServerRouter()
-	if err := router.Run(":8080"); err != nil {
-		klog.Errorf("run server failed:%v", err)
+	serveErr := make(chan error, 1)
+	go func() {
+		klog.Infof("Serving API on %s, tls:%v", opts.BindAddress, server.TLSConfig != nil)
+		var err error
+		if server.TLSConfig != nil {
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- err
+		}
+		close(serveErr)
+	}()
+
+	select {
+	case err, ok := <-serveErr:
+		if ok {
+			klog.Errorf("Run server failed:%v", err)
+			return err
+		}
+		return nil
+	case <-ctx.Done():
 	}
 
-	<-stopCh
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return server.Shutdown(shutdownCtx)
+}
+
+// waitForQueryBackend makes sure the query backend is reachable before serving,
+// a misconfigured endpoint or credential is reported at startup instead of on every request
+func waitForQueryBackend(ctx context.Context, backend query.QueryBackend, timeout time.Duration) error {
+	if timeout == 0 {
+		return nil
+	}
+
+	var lastErr error
+	err := wait.PollImmediateWithContext(ctx, 5*time.Second, timeout, func(ctx context.Context) (bool, error) {
+		if lastErr = backend.Ping(ctx); lastErr != nil {
+			klog.Warningf("Query backend is not reachable yet:%v", lastErr)
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil {
+		return fmt.Errorf("query backend is not reachable in %s, last error:%v", timeout, lastErr)
+	}
 	return nil
 }
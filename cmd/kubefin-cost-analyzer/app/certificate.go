@@ -0,0 +1,98 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// certificateReloader serves the certificate pair from disk and reloads it
+// once the files are modified, e.g. rotated by cert-manager
+type certificateReloader struct {
+	certFile string
+	keyFile  string
+
+	mu          sync.RWMutex
+	certificate *tls.Certificate
+	modTime     time.Time
+}
+
+func newCertificateReloader(certFile, keyFile string) (*certificateReloader, error) {
+	reloader := &certificateReloader{certFile: certFile, keyFile: keyFile}
+	if err := reloader.reload(); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+func (r *certificateReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, file := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return latest, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+func (r *certificateReloader) reload() error {
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return fmt.Errorf("stat certificate files error:%v", err)
+	}
+
+	r.mu.RLock()
+	unchanged := r.certificate != nil && modTime.Equal(r.modTime)
+	r.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load certificate pair error:%v", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.certificate = &certificate
+	r.modTime = modTime
+	klog.Infof("Loaded serving certificate %s", r.certFile)
+	return nil
+}
+
+// GetCertificate is used as tls.Config.GetCertificate, the previous certificate
+// keeps being served if the new one is broken
+func (r *certificateReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if err := r.reload(); err != nil {
+		klog.Errorf("Reload serving certificate failed, keep the previous one:%v", err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.certificate, nil
+}
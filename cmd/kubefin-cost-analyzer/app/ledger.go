@@ -0,0 +1,95 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/kubefin/kubefin/cmd/kubefin-cost-analyzer/app/options"
+	"github.com/kubefin/kubefin/pkg/ledger"
+	"github.com/kubefin/kubefin/pkg/query"
+)
+
+// NewLedgerBackfillCommand creates a *cobra.Command recording the cost ledger of a past range from the
+// query backend, such as the history before the ledger was enabled or the hours missed while the analyzer was down
+func NewLedgerBackfillCommand(ctx context.Context) *cobra.Command {
+	var configFile, startTime, endTime, tenantId string
+
+	cmd := &cobra.Command{
+		Use:   "ledger-backfill",
+		Short: "Record the hourly and daily cost of a past range into the cost ledger",
+		Long: `Record the hourly and daily cost from --start to --end into the cost ledger from the query backend,
+the records in the range are replaced. The time could be RFC3339 or a UTC date like 2023-06-01.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// The query backend connection and the ledger database are taken from the analyzer config file
+			// and environment variables, the ledger doesn't have to be enabled
+			opts := options.NewAnalyzerOptions()
+			opts.ConfigFile = configFile
+			if err := opts.Complete(pflag.NewFlagSet("ledger-backfill", pflag.ContinueOnError)); err != nil {
+				return err
+			}
+			if opts.QueryBackend.Endpoint == "" {
+				return fmt.Errorf("query backend endpoint is not set in the config file or the environment variables")
+			}
+			start, err := parseBackfillTime(startTime)
+			if err != nil {
+				return err
+			}
+			end := time.Now()
+			if endTime != "" {
+				if end, err = parseBackfillTime(endTime); err != nil {
+					return err
+				}
+			}
+
+			backend, err := query.NewQueryBackend(&opts.QueryBackend)
+			if err != nil {
+				return err
+			}
+			backend = query.NewResilientBackend(backend, &opts.QueryBackend.Retry, &opts.QueryBackend.CircuitBreaker)
+			costLedger, err := ledger.NewLedger(&opts.Ledger, opts.QueryBackend.DefaultTenantId, backend)
+			if err != nil {
+				return err
+			}
+			defer costLedger.Close()
+			return costLedger.Backfill(ctx, tenantId, start.Unix(), end.Unix())
+		},
+	}
+
+	cmd.Flags().StringVar(&configFile, "config", configFile,
+		"The path of the analyzer config file, the query backend connection and the ledger database are taken from it.")
+	cmd.Flags().StringVar(&startTime, "start", startTime, "The start of the range to backfill, required.")
+	cmd.Flags().StringVar(&endTime, "end", endTime, "The end of the range to backfill, the last finalized hour if it's empty.")
+	cmd.Flags().StringVar(&tenantId, "tenant-id", tenantId, "The tenant to backfill, the default tenant of the query backend if it's empty.")
+	_ = cmd.MarkFlagRequired("start")
+
+	return cmd
+}
+
+func parseBackfillTime(value string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("time %s is neither RFC3339 nor a date like 2023-06-01", value)
+}
@@ -17,29 +17,666 @@ limitations under the License.
 package options
 
 import (
-	"os"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
 
 	"github.com/spf13/pflag"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 
+	"github.com/kubefin/kubefin/pkg/anomaly"
+	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/auth"
+	"github.com/kubefin/kubefin/pkg/budget"
+	"github.com/kubefin/kubefin/pkg/config"
+	"github.com/kubefin/kubefin/pkg/ledger"
+	"github.com/kubefin/kubefin/pkg/notification"
+	"github.com/kubefin/kubefin/pkg/query"
+	"github.com/kubefin/kubefin/pkg/recommendation"
+	"github.com/kubefin/kubefin/pkg/simulation"
+	"github.com/kubefin/kubefin/pkg/spot"
 	"github.com/kubefin/kubefin/pkg/values"
 )
 
+// AnalyzerOptions holds the analyzer configuration. Values are resolved in the order
+// defaults < config file < environment variables < command-line flags.
 type AnalyzerOptions struct {
-	QueryBackendEndpoint string
+	// ConfigFile is the path of an optional YAML file whose keys match the json tags below
+	ConfigFile string `json:"-"`
+
+	BindAddress string `json:"bindAddress,omitempty"`
+	// TLSCertFile/TLSKeyFile enable https, they are reloaded once changed on disk
+	TLSCertFile string `json:"tlsCertFile,omitempty"`
+	TLSKeyFile  string `json:"tlsKeyFile,omitempty"`
+	// CORSAllowedOrigins is the list of origins the dashboard could be served from
+	CORSAllowedOrigins []string `json:"corsAllowedOrigins,omitempty"`
+	// RequestTimeout is the deadline of the queries sent for one API request, 0 means no deadline
+	RequestTimeout metav1.Duration `json:"requestTimeout,omitempty"`
+
+	// Authentication is disabled unless one of the authenticators is configured
+	Authentication auth.AuthenticationConfig `json:"authentication"`
+	// Authorization grants could be set in the config file or the policy file
+	Authorization auth.AuthorizationConfig `json:"authorization"`
+
+	QueryBackend query.BackendConfig `json:"queryBackend"`
+	// QueryBackendCheckTimeout is how long to wait for the backend to be reachable at startup, 0 disables the check
+	QueryBackendCheckTimeout metav1.Duration `json:"queryBackendCheckTimeout,omitempty"`
+	// QueryConcurrency is the number of queries one API request sends to the backend concurrently
+	QueryConcurrency int `json:"queryConcurrency,omitempty"`
+	// QueryCache caches the results of the queries on the historical data
+	QueryCache query.CacheConfig `json:"queryCache"`
+	// QueryRollup sums the cost of the long ranges from the rollups recorded by the rules of the rollup-rules command
+	QueryRollup query.RollupConfig `json:"queryRollup"`
+
+	// Budgets could only be set in the config file
+	Budgets budget.Config `json:"budgets"`
+	// Notification sinks and triggers could only be set in the config file
+	Notification notification.Config `json:"notification"`
+	Anomaly      anomaly.Config      `json:"anomaly"`
+	// Ledger records the finalized hourly and daily cost into a database kept beyond the query backend retention
+	Ledger ledger.Config `json:"ledger"`
+	// Recommendation holds the settings of the request right-sizing recommendations
+	Recommendation recommendation.Config `json:"recommendation"`
+	// Simulation holds the instance type catalog the node pools are simulated against
+	Simulation simulation.Config `json:"simulation"`
+	// Spot holds the settings of the spot adoption savings analysis
+	Spot spot.Config `json:"spot"`
 }
 
-// NewAnalyzerOptions builds an empty options.
+// NewAnalyzerOptions builds an options with default values.
 func NewAnalyzerOptions() *AnalyzerOptions {
 	return &AnalyzerOptions{
-		QueryBackendEndpoint: os.Getenv(values.QueryBackendEndpointEnv),
+		BindAddress:        values.DefaultAnalyzerBindAddress,
+		CORSAllowedOrigins: []string{"*"},
+		RequestTimeout:     metav1.Duration{Duration: 2 * time.Minute},
+		Authentication: auth.AuthenticationConfig{
+			CacheTTL: metav1.Duration{Duration: 2 * time.Minute},
+		},
+		QueryBackend: query.BackendConfig{
+			Timeout:        metav1.Duration{Duration: values.DefaultQueryBackendTimeout},
+			Retry:          query.NewDefaultRetryConfig(),
+			CircuitBreaker: query.NewDefaultCircuitBreakerConfig(),
+		},
+		QueryBackendCheckTimeout: metav1.Duration{Duration: time.Minute},
+		QueryConcurrency:         values.DefaultMaxConcurrentQueries,
+		QueryCache:               query.NewDefaultCacheConfig(),
+		QueryRollup:              query.NewDefaultRollupConfig(),
+		Budgets: budget.Config{
+			EvaluationInterval: metav1.Duration{Duration: 10 * time.Minute},
+		},
+		Notification: notification.Config{
+			EvaluationInterval: metav1.Duration{Duration: 5 * time.Minute},
+			RepeatInterval:     metav1.Duration{Duration: 4 * time.Hour},
+			Retry: notification.RetryConfig{
+				Attempts: 3,
+				Backoff:  metav1.Duration{Duration: 2 * time.Second},
+			},
+		},
+		Anomaly: anomaly.Config{
+			Enabled:  true,
+			Interval: metav1.Duration{Duration: time.Hour},
+			Granularities: []anomaly.Granularity{
+				{Name: "hourly", Step: metav1.Duration{Duration: time.Hour}, BaselinePoints: 7 * 24},
+				{Name: "daily", Step: metav1.Duration{Duration: 24 * time.Hour}, BaselinePoints: 28},
+			},
+			Sensitivity:     3.5,
+			MinCostDelta:    1,
+			MaxContributors: 5,
+			Retention:       metav1.Duration{Duration: 30 * 24 * time.Hour},
+		},
+		Ledger: ledger.Config{
+			Driver:        ledger.DriverSQLite,
+			DSN:           "kubefin-ledger.db",
+			Interval:      metav1.Duration{Duration: 10 * time.Minute},
+			FinalizeDelay: metav1.Duration{Duration: 15 * time.Minute},
+			MaxCatchUp:    metav1.Duration{Duration: 7 * 24 * time.Hour},
+		},
+		Recommendation: *recommendation.NewDefaultConfig(),
+		Simulation:     *simulation.NewDefaultConfig(),
+		Spot:           *spot.NewDefaultConfig(),
 	}
 }
 
-func (o *AnalyzerOptions) Complete() error {
-	return nil
+// Complete loads the config file and environment variables, the flags set explicitly
+// in the command line always take precedence over them.
+func (o *AnalyzerOptions) Complete(flags *pflag.FlagSet) error {
+	return config.Load(flags, o.ConfigFile, o, o.loadEnv)
+}
+
+func (o *AnalyzerOptions) loadEnv() error {
+	config.SetStringFromEnv(values.QueryBackendEndpointEnv, &o.QueryBackend.Endpoint)
+	config.SetStringFromEnv(values.QueryBackendDefaultTenantEnv, &o.QueryBackend.DefaultTenantId)
+	config.SetStringFromEnv(values.LedgerDSNEnv, &o.Ledger.DSN)
+	return config.SetDurationFromEnv(values.QueryBackendTimeoutEnv, &o.QueryBackend.Timeout.Duration)
 }
 
 func (o *AnalyzerOptions) Validate() error {
+	allErrs := field.ErrorList{}
+
+	if _, _, err := net.SplitHostPort(o.BindAddress); err != nil {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("bindAddress"), o.BindAddress, err.Error()))
+	}
+	if (o.TLSCertFile == "") != (o.TLSKeyFile == "") {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("tlsCertFile"), o.TLSCertFile,
+			"tlsCertFile and tlsKeyFile must be set together"))
+	}
+	if len(o.CORSAllowedOrigins) == 0 {
+		// The CORS middleware panics without any origin
+		allErrs = append(allErrs, field.Required(field.NewPath("corsAllowedOrigins"),
+			"set '*' to allow all origins"))
+	}
+	for i, origin := range o.CORSAllowedOrigins {
+		if origin == "*" {
+			continue
+		}
+		if u, err := url.Parse(origin); err != nil || u.Scheme == "" || u.Host == "" {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("corsAllowedOrigins").Index(i), origin,
+				"must be '*' or an origin such as https://kubefin.example.com"))
+		}
+	}
+
+	backendPath := field.NewPath("queryBackend")
+	switch o.QueryBackend.Type {
+	case "", query.BackendTypePrometheus, query.BackendTypeThanos, query.BackendTypeVictoriaMetrics:
+	default:
+		allErrs = append(allErrs, field.NotSupported(backendPath.Child("type"), o.QueryBackend.Type,
+			[]string{query.BackendTypePrometheus, query.BackendTypeThanos, query.BackendTypeVictoriaMetrics}))
+	}
+	if o.QueryBackend.Endpoint == "" {
+		allErrs = append(allErrs, field.Required(backendPath.Child("endpoint"),
+			"set it via --query-backend-endpoint, the config file or env "+values.QueryBackendEndpointEnv))
+	} else if u, err := url.Parse(o.QueryBackend.Endpoint); err != nil ||
+		(u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		allErrs = append(allErrs, field.Invalid(backendPath.Child("endpoint"), o.QueryBackend.Endpoint,
+			"must be an http(s) url"))
+	}
+	if o.QueryBackend.Timeout.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(backendPath.Child("timeout"),
+			o.QueryBackend.Timeout.Duration.String(), "must be greater than zero"))
+	}
+	if (o.QueryBackend.BasicAuthUsername == "") != (o.QueryBackend.BasicAuthPassword == "") {
+		allErrs = append(allErrs, field.Invalid(backendPath.Child("basicAuthUsername"), o.QueryBackend.BasicAuthUsername,
+			"basicAuthUsername and basicAuthPassword must be set together"))
+	}
+	if o.QueryBackend.BasicAuthUsername != "" &&
+		(o.QueryBackend.BearerToken != "" || o.QueryBackend.BearerTokenFile != "") {
+		allErrs = append(allErrs, field.Forbidden(backendPath.Child("bearerToken"),
+			"basic auth and bearer token auth are mutually exclusive"))
+	}
+	if (o.QueryBackend.CertFile == "") != (o.QueryBackend.KeyFile == "") {
+		allErrs = append(allErrs, field.Invalid(backendPath.Child("certFile"), o.QueryBackend.CertFile,
+			"certFile and keyFile must be set together"))
+	}
+	if o.RequestTimeout.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("requestTimeout"),
+			o.RequestTimeout.Duration.String(), "must be greater than or equal to zero"))
+	}
+	if o.QueryConcurrency < 1 {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("queryConcurrency"), o.QueryConcurrency,
+			"must be greater than zero"))
+	}
+	if o.QueryBackendCheckTimeout.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("queryBackendCheckTimeout"),
+			o.QueryBackendCheckTimeout.Duration.String(), "must be greater than or equal to zero"))
+	}
+
+	allErrs = append(allErrs, validateAuth(&o.Authentication, &o.Authorization)...)
+	allErrs = append(allErrs, validateBudgets(&o.Budgets)...)
+	allErrs = append(allErrs, validateNotification(&o.Notification)...)
+	allErrs = append(allErrs, validateAnomaly(&o.Anomaly)...)
+	allErrs = append(allErrs, validateLedger(&o.Ledger)...)
+	allErrs = append(allErrs, validateRecommendation(&o.Recommendation)...)
+	allErrs = append(allErrs, validateSimulation(&o.Simulation)...)
+	allErrs = append(allErrs, validateSpot(&o.Spot)...)
+	allErrs = append(allErrs, validateQueryRetry(&o.QueryBackend.Retry)...)
+	allErrs = append(allErrs, validateQueryCircuitBreaker(&o.QueryBackend.CircuitBreaker)...)
+	allErrs = append(allErrs, validateQueryCache(&o.QueryCache)...)
+	allErrs = append(allErrs, validateQueryRollup(&o.QueryRollup)...)
+
+	return allErrs.ToAggregate()
+}
+
+func validateAuth(authn *auth.AuthenticationConfig, authz *auth.AuthorizationConfig) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	authnPath := field.NewPath("authentication")
+	if authn.OIDC.IssuerURL != "" {
+		if u, err := url.Parse(authn.OIDC.IssuerURL); err != nil || u.Scheme != "https" {
+			allErrs = append(allErrs, field.Invalid(authnPath.Child("oidc", "issuerURL"), authn.OIDC.IssuerURL,
+				"must be an https url"))
+		}
+		if authn.OIDC.ClientID == "" {
+			allErrs = append(allErrs, field.Required(authnPath.Child("oidc", "clientID"),
+				"required when oidc is enabled"))
+		}
+	}
+	if authn.CacheTTL.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(authnPath.Child("cacheTTL"),
+			authn.CacheTTL.Duration.String(), "must be greater than or equal to zero"))
+	}
+
+	grantsPath := field.NewPath("authorization", "grants")
+	if authn.Enabled() && len(authz.Grants) == 0 && authz.PolicyFile == "" {
+		allErrs = append(allErrs, field.Required(grantsPath,
+			"no one could access the API when authentication is enabled without grants"))
+	}
+	for i, grant := range authz.Grants {
+		if len(grant.Users) == 0 && len(grant.Groups) == 0 {
+			allErrs = append(allErrs, field.Required(grantsPath.Index(i), "users or groups must be set"))
+		}
+	}
+
+	return allErrs
+}
+
+func validateBudgets(budgets *budget.Config) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	budgetsPath := field.NewPath("budgets")
+	if budgets.EvaluationInterval.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(budgetsPath.Child("evaluationInterval"),
+			budgets.EvaluationInterval.Duration.String(), "must be greater than zero"))
+	}
+	names := map[string]bool{}
+	for i, item := range budgets.Items {
+		itemPath := budgetsPath.Child("items").Index(i)
+		if item.Name == "" {
+			allErrs = append(allErrs, field.Required(itemPath.Child("name"), ""))
+		} else if names[item.Name] {
+			allErrs = append(allErrs, field.Duplicate(itemPath.Child("name"), item.Name))
+		}
+		names[item.Name] = true
+		if item.ClusterId == "" {
+			allErrs = append(allErrs, field.Required(itemPath.Child("clusterId"), ""))
+		}
+		if item.Period != api.BudgetPeriodMonthly && item.Period != api.BudgetPeriodWeekly {
+			allErrs = append(allErrs, field.NotSupported(itemPath.Child("period"), item.Period,
+				[]string{api.BudgetPeriodMonthly, api.BudgetPeriodWeekly}))
+		}
+		if item.Amount <= 0 {
+			allErrs = append(allErrs, field.Invalid(itemPath.Child("amount"), item.Amount, "must be greater than zero"))
+		}
+		if item.LabelKey == "" && item.LabelValue != "" {
+			allErrs = append(allErrs, field.Required(itemPath.Child("labelKey"), "required when labelValue is set"))
+		}
+		for j, threshold := range item.Thresholds {
+			if threshold <= 0 {
+				allErrs = append(allErrs, field.Invalid(itemPath.Child("thresholds").Index(j), threshold,
+					"must be greater than zero"))
+			}
+		}
+	}
+
+	return allErrs
+}
+
+func validateNotification(config *notification.Config) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	notificationPath := field.NewPath("notification")
+	if config.EvaluationInterval.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(notificationPath.Child("evaluationInterval"),
+			config.EvaluationInterval.Duration.String(), "must be greater than zero"))
+	}
+	if config.RepeatInterval.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(notificationPath.Child("repeatInterval"),
+			config.RepeatInterval.Duration.String(), "must be greater than or equal to zero"))
+	}
+	if config.Retry.Attempts <= 0 {
+		allErrs = append(allErrs, field.Invalid(notificationPath.Child("retry", "attempts"),
+			config.Retry.Attempts, "must be greater than zero"))
+	}
+
+	sinkNames := map[string]bool{}
+	for i, sink := range config.Sinks {
+		sinkPath := notificationPath.Child("sinks").Index(i)
+		if sink.Name == "" {
+			allErrs = append(allErrs, field.Required(sinkPath.Child("name"), ""))
+		} else if sinkNames[sink.Name] {
+			allErrs = append(allErrs, field.Duplicate(sinkPath.Child("name"), sink.Name))
+		}
+		sinkNames[sink.Name] = true
+
+		switch sink.Type {
+		case notification.SinkTypeWebhook:
+			if sink.Webhook == nil {
+				allErrs = append(allErrs, field.Required(sinkPath.Child("webhook"), ""))
+			} else {
+				allErrs = append(allErrs, validateHTTPURL(sinkPath.Child("webhook", "url"), sink.Webhook.URL)...)
+			}
+		case notification.SinkTypeSlack:
+			if sink.Slack == nil {
+				allErrs = append(allErrs, field.Required(sinkPath.Child("slack"), ""))
+			} else {
+				allErrs = append(allErrs, validateHTTPURL(sinkPath.Child("slack", "url"), sink.Slack.URL)...)
+			}
+		case notification.SinkTypeAlertmanager:
+			if sink.Alertmanager == nil {
+				allErrs = append(allErrs, field.Required(sinkPath.Child("alertmanager"), ""))
+			} else {
+				allErrs = append(allErrs, validateHTTPURL(sinkPath.Child("alertmanager", "url"), sink.Alertmanager.URL)...)
+			}
+		case notification.SinkTypeEmail:
+			if sink.Email == nil {
+				allErrs = append(allErrs, field.Required(sinkPath.Child("email"), ""))
+				break
+			}
+			if _, _, err := net.SplitHostPort(sink.Email.Address); err != nil {
+				allErrs = append(allErrs, field.Invalid(sinkPath.Child("email", "address"), sink.Email.Address, err.Error()))
+			}
+			if sink.Email.From == "" {
+				allErrs = append(allErrs, field.Required(sinkPath.Child("email", "from"), ""))
+			}
+			if len(sink.Email.To) == 0 {
+				allErrs = append(allErrs, field.Required(sinkPath.Child("email", "to"), ""))
+			}
+		default:
+			allErrs = append(allErrs, field.NotSupported(sinkPath.Child("type"), sink.Type,
+				[]string{notification.SinkTypeWebhook, notification.SinkTypeSlack,
+					notification.SinkTypeEmail, notification.SinkTypeAlertmanager}))
+		}
+	}
+
+	triggerNames := map[string]bool{}
+	for i, trigger := range config.Triggers {
+		triggerPath := notificationPath.Child("triggers").Index(i)
+		if trigger.Name == "" {
+			allErrs = append(allErrs, field.Required(triggerPath.Child("name"), ""))
+		} else if triggerNames[trigger.Name] {
+			allErrs = append(allErrs, field.Duplicate(triggerPath.Child("name"), trigger.Name))
+		}
+		triggerNames[trigger.Name] = true
+
+		switch trigger.Type {
+		case notification.TriggerTypeClusterCost, notification.TriggerTypeNamespaceCost:
+			if trigger.Type == notification.TriggerTypeNamespaceCost && trigger.ClusterId == "" {
+				allErrs = append(allErrs, field.Required(triggerPath.Child("clusterId"), "required by namespaceCost"))
+			}
+			if trigger.Window.Duration <= 0 {
+				allErrs = append(allErrs, field.Invalid(triggerPath.Child("window"),
+					trigger.Window.Duration.String(), "must be greater than zero"))
+			}
+			if trigger.Threshold <= 0 {
+				allErrs = append(allErrs, field.Invalid(triggerPath.Child("threshold"), trigger.Threshold,
+					"must be greater than zero"))
+			}
+		case notification.TriggerTypeLostConnection:
+		default:
+			allErrs = append(allErrs, field.NotSupported(triggerPath.Child("type"), trigger.Type,
+				[]string{notification.TriggerTypeClusterCost, notification.TriggerTypeNamespaceCost,
+					notification.TriggerTypeLostConnection}))
+		}
+		if trigger.Severity != "" && trigger.Severity != notification.SeverityInfo &&
+			trigger.Severity != notification.SeverityWarning && trigger.Severity != notification.SeverityCritical {
+			allErrs = append(allErrs, field.NotSupported(triggerPath.Child("severity"), trigger.Severity,
+				[]string{notification.SeverityInfo, notification.SeverityWarning, notification.SeverityCritical}))
+		}
+		for j, sinkName := range trigger.Sinks {
+			if !sinkNames[sinkName] {
+				allErrs = append(allErrs, field.NotFound(triggerPath.Child("sinks").Index(j), sinkName))
+			}
+		}
+	}
+	if len(config.Triggers) > 0 && len(config.Sinks) == 0 {
+		allErrs = append(allErrs, field.Required(notificationPath.Child("sinks"),
+			"the triggers could not fire without sinks"))
+	}
+
+	return allErrs
+}
+
+func validateAnomaly(config *anomaly.Config) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if !config.Enabled {
+		return allErrs
+	}
+
+	anomalyPath := field.NewPath("anomaly")
+	for name, duration := range map[string]time.Duration{
+		"interval":  config.Interval.Duration,
+		"retention": config.Retention.Duration,
+	} {
+		if duration <= 0 {
+			allErrs = append(allErrs, field.Invalid(anomalyPath.Child(name), duration.String(), "must be greater than zero"))
+		}
+	}
+	if config.Sensitivity <= 0 {
+		allErrs = append(allErrs, field.Invalid(anomalyPath.Child("sensitivity"), config.Sensitivity,
+			"must be greater than zero"))
+	}
+	if config.MinCostDelta < 0 {
+		allErrs = append(allErrs, field.Invalid(anomalyPath.Child("minCostDelta"), config.MinCostDelta,
+			"must be greater than or equal to zero"))
+	}
+	if config.MaxContributors < 0 {
+		allErrs = append(allErrs, field.Invalid(anomalyPath.Child("maxContributors"), config.MaxContributors,
+			"must be greater than or equal to zero"))
+	}
+	if len(config.Granularities) == 0 {
+		allErrs = append(allErrs, field.Required(anomalyPath.Child("granularities"), ""))
+	}
+	for i, granularity := range config.Granularities {
+		granularityPath := anomalyPath.Child("granularities").Index(i)
+		if granularity.Name == "" {
+			allErrs = append(allErrs, field.Required(granularityPath.Child("name"), ""))
+		}
+		if granularity.Step.Duration < time.Minute {
+			allErrs = append(allErrs, field.Invalid(granularityPath.Child("step"),
+				granularity.Step.Duration.String(), "must be at least 1m"))
+		}
+		if granularity.BaselinePoints < 3 {
+			allErrs = append(allErrs, field.Invalid(granularityPath.Child("baselinePoints"),
+				granularity.BaselinePoints, "must be at least 3"))
+		}
+	}
+
+	return allErrs
+}
+
+func validateRecommendation(config *recommendation.Config) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	recommendationPath := field.NewPath("recommendation")
+	if config.Window.Duration < time.Hour {
+		allErrs = append(allErrs, field.Invalid(recommendationPath.Child("window"),
+			config.Window.Duration.String(), "must be at least 1h"))
+	}
+	if config.Resolution.Duration < 15*time.Second || config.Resolution.Duration > config.Window.Duration {
+		allErrs = append(allErrs, field.Invalid(recommendationPath.Child("resolution"),
+			config.Resolution.Duration.String(), "must be between 15s and the window"))
+	}
+	for name, percentile := range map[string]float64{
+		"cpuPercentile":    config.CPUPercentile,
+		"memoryPercentile": config.MemoryPercentile,
+	} {
+		if percentile != 50 && percentile != 95 && percentile != 99 && percentile != 100 {
+			allErrs = append(allErrs, field.NotSupported(recommendationPath.Child(name), percentile,
+				[]string{"50", "95", "99", "100"}))
+		}
+	}
+	for name, value := range map[string]float64{
+		"headroom":            config.Headroom,
+		"memoryLimitHeadroom": config.MemoryLimitHeadroom,
+		"minCPUCores":         config.MinCPUCores,
+		"minMemoryGiB":        config.MinMemoryGiB,
+	} {
+		if value < 0 {
+			allErrs = append(allErrs, field.Invalid(recommendationPath.Child(name), value,
+				"must be greater than or equal to zero"))
+		}
+	}
+	if config.CPULimitRatio != 0 && config.CPULimitRatio < 1 {
+		allErrs = append(allErrs, field.Invalid(recommendationPath.Child("cpuLimitRatio"), config.CPULimitRatio,
+			"must be zero or at least 1"))
+	}
+
+	return allErrs
+}
+
+func validateSimulation(config *simulation.Config) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	simulationPath := field.NewPath("simulation")
+	for instanceType, spec := range config.NodeSpecs {
+		if spec.CPUCount <= 0 || spec.RAMGBCount <= 0 {
+			allErrs = append(allErrs, field.Invalid(simulationPath.Child("nodeSpecs").Key(instanceType), spec,
+				"cpuCount and ramGBCount must be greater than zero"))
+		}
+		if _, ok := config.NodePrices[instanceType]; !ok {
+			allErrs = append(allErrs, field.Required(simulationPath.Child("nodePrices").Key(instanceType),
+				"every instance type in nodeSpecs needs a price"))
+		}
+	}
+	for instanceType, price := range config.NodePrices {
+		if price <= 0 {
+			allErrs = append(allErrs, field.Invalid(simulationPath.Child("nodePrices").Key(instanceType), price,
+				"must be greater than zero"))
+		}
+	}
+	if config.SystemReservedRatio < 0 || config.SystemReservedRatio >= 1 {
+		allErrs = append(allErrs, field.Invalid(simulationPath.Child("systemReservedRatio"), config.SystemReservedRatio,
+			"must be greater than or equal to zero and less than 1"))
+	}
+	if config.MaxProposals < 1 {
+		allErrs = append(allErrs, field.Invalid(simulationPath.Child("maxProposals"), config.MaxProposals,
+			"must be greater than zero"))
+	}
+
+	return allErrs
+}
+
+func validateSpot(config *spot.Config) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	spotPath := field.NewPath("spot")
+	if config.Window.Duration < time.Hour {
+		allErrs = append(allErrs, field.Invalid(spotPath.Child("window"),
+			config.Window.Duration.String(), "must be at least 1h"))
+	}
+	if config.Discount < 0 || config.Discount >= 1 {
+		allErrs = append(allErrs, field.Invalid(spotPath.Child("discount"), config.Discount,
+			"must be greater than or equal to zero and less than 1"))
+	}
+
+	return allErrs
+}
+
+func validateQueryRetry(config *query.RetryConfig) field.ErrorList {
+	allErrs := field.ErrorList{}
+	retryPath := field.NewPath("queryBackend", "retry")
+	if config.MaxRetries < 0 {
+		allErrs = append(allErrs, field.Invalid(retryPath.Child("maxRetries"), config.MaxRetries,
+			"must be greater than or equal to zero"))
+	}
+	if config.MaxRetries == 0 {
+		return allErrs
+	}
+
+	if config.InitialBackoff.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(retryPath.Child("initialBackoff"),
+			config.InitialBackoff.Duration.String(), "must be greater than zero"))
+	}
+	if config.MaxBackoff.Duration < config.InitialBackoff.Duration {
+		allErrs = append(allErrs, field.Invalid(retryPath.Child("maxBackoff"),
+			config.MaxBackoff.Duration.String(), "must not be less than initialBackoff"))
+	}
+	return allErrs
+}
+
+func validateQueryCircuitBreaker(config *query.CircuitBreakerConfig) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if !config.Enabled {
+		return allErrs
+	}
+
+	breakerPath := field.NewPath("queryBackend", "circuitBreaker")
+	if config.FailureThreshold < 1 {
+		allErrs = append(allErrs, field.Invalid(breakerPath.Child("failureThreshold"), config.FailureThreshold,
+			"must be greater than zero"))
+	}
+	if config.OpenDuration.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(breakerPath.Child("openDuration"),
+			config.OpenDuration.Duration.String(), "must be greater than zero"))
+	}
+	return allErrs
+}
+
+func validateQueryCache(config *query.CacheConfig) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if !config.Enabled {
+		return allErrs
+	}
+
+	cachePath := field.NewPath("queryCache")
+	if config.MaxEntries < 1 {
+		allErrs = append(allErrs, field.Invalid(cachePath.Child("maxEntries"), config.MaxEntries,
+			"must be greater than zero"))
+	}
+	if config.SplitInterval.Duration < time.Minute {
+		allErrs = append(allErrs, field.Invalid(cachePath.Child("splitInterval"),
+			config.SplitInterval.Duration.String(), "must be at least 1m"))
+	}
+	if config.MaxFreshness.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(cachePath.Child("maxFreshness"),
+			config.MaxFreshness.Duration.String(), "must be greater than or equal to zero"))
+	}
+
+	return allErrs
+}
+
+func validateQueryRollup(config *query.RollupConfig) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if !config.Enabled {
+		return allErrs
+	}
+
+	rollupPath := field.NewPath("queryRollup")
+	if config.HourlyMinRange.Duration < time.Hour {
+		allErrs = append(allErrs, field.Invalid(rollupPath.Child("hourlyMinRange"),
+			config.HourlyMinRange.Duration.String(), "must be at least 1h"))
+	}
+	if config.DailyMinRange.Duration < 24*time.Hour {
+		allErrs = append(allErrs, field.Invalid(rollupPath.Child("dailyMinRange"),
+			config.DailyMinRange.Duration.String(), "must be at least 24h"))
+	}
+	return allErrs
+}
+
+func validateLedger(config *ledger.Config) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if !config.Enabled {
+		return allErrs
+	}
+
+	ledgerPath := field.NewPath("ledger")
+	supportedDrivers := []string{ledger.DriverSQLite, ledger.DriverPostgres}
+	if config.Driver != ledger.DriverSQLite && config.Driver != ledger.DriverPostgres {
+		allErrs = append(allErrs, field.NotSupported(ledgerPath.Child("driver"), config.Driver, supportedDrivers))
+	}
+	if config.DSN == "" {
+		allErrs = append(allErrs, field.Required(ledgerPath.Child("dsn"), ""))
+	}
+	for name, duration := range map[string]time.Duration{
+		"interval":   config.Interval.Duration,
+		"maxCatchUp": config.MaxCatchUp.Duration,
+	} {
+		if duration <= 0 {
+			allErrs = append(allErrs, field.Invalid(ledgerPath.Child(name), duration.String(), "must be greater than zero"))
+		}
+	}
+	if config.FinalizeDelay.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(ledgerPath.Child("finalizeDelay"),
+			config.FinalizeDelay.Duration.String(), "must be greater than or equal to zero"))
+	}
+	return allErrs
+}
+
+func validateHTTPURL(path *field.Path, rawURL string) field.ErrorList {
+	if u, err := url.Parse(rawURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return field.ErrorList{field.Invalid(path, rawURL, "must be an http(s) url")}
+	}
 	return nil
 }
 
@@ -47,4 +684,98 @@ func (o *AnalyzerOptions) ApplyTo() {
 }
 
 func (o *AnalyzerOptions) AddFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&o.ConfigFile, "config", o.ConfigFile,
+		"The path of the YAML config file, environment variables and flags override the values in it.")
+	flags.StringVar(&o.BindAddress, "bind-address", o.BindAddress,
+		"The address the API server binds to.")
+	flags.StringVar(&o.TLSCertFile, "tls-cert-file", o.TLSCertFile,
+		"The serving certificate file, the API is served over https if it's set. It's reloaded once changed.")
+	flags.StringVar(&o.TLSKeyFile, "tls-private-key-file", o.TLSKeyFile,
+		"The serving private key file matching --tls-cert-file.")
+	flags.StringSliceVar(&o.CORSAllowedOrigins, "cors-allowed-origins", o.CORSAllowedOrigins,
+		"The origins allowed to call the API from a browser, '*' allows all.")
+
+	flags.StringVar(&o.Authentication.TokenFile, "token-auth-file", o.Authentication.TokenFile,
+		"The csv file of static API tokens, each line is: token,user,uid,\"group1,group2\".")
+	flags.StringVar(&o.Authentication.OIDC.IssuerURL, "oidc-issuer-url", o.Authentication.OIDC.IssuerURL,
+		"The URL of the OIDC issuer, bearer tokens are validated as its id tokens if it's set.")
+	flags.StringVar(&o.Authentication.OIDC.ClientID, "oidc-client-id", o.Authentication.OIDC.ClientID,
+		"The client id of the OIDC tokens.")
+	flags.StringVar(&o.Authentication.OIDC.CAFile, "oidc-ca-file", o.Authentication.OIDC.CAFile,
+		"The CA file to verify the OIDC issuer serving certificate.")
+	flags.StringVar(&o.Authentication.OIDC.UsernameClaim, "oidc-username-claim", o.Authentication.OIDC.UsernameClaim,
+		"The OIDC claim used as the user name, 'sub' if it's empty.")
+	flags.StringVar(&o.Authentication.OIDC.GroupsClaim, "oidc-groups-claim", o.Authentication.OIDC.GroupsClaim,
+		"The OIDC claim used as the user groups.")
+	flags.BoolVar(&o.Authentication.TokenReview, "token-review", o.Authentication.TokenReview,
+		"Validate bearer tokens with the kubernetes TokenReview API.")
+	flags.StringSliceVar(&o.Authentication.TokenReviewAudiences, "token-review-audiences", o.Authentication.TokenReviewAudiences,
+		"The audiences the token must be issued for when validated by TokenReview.")
+	flags.DurationVar(&o.Authentication.CacheTTL.Duration, "authentication-cache-ttl", o.Authentication.CacheTTL.Duration,
+		"How long the authentication result of a token is cached, 0 disables the cache.")
+	flags.StringVar(&o.Authorization.PolicyFile, "authorization-policy-file", o.Authorization.PolicyFile,
+		"The YAML file of grants binding users and groups to tenants, clusters and namespaces.")
+
+	flags.StringVar(&o.QueryBackend.Type, "query-backend-type", o.QueryBackend.Type,
+		"The type of the query backend, prometheus(also for Mimir and Cortex)/thanos/victoriametrics, prometheus is used if it's empty.")
+	flags.StringVar(&o.QueryBackend.Endpoint, "query-backend-endpoint", o.QueryBackend.Endpoint,
+		"The prometheus compatible query backend endpoint. Env: "+values.QueryBackendEndpointEnv)
+	flags.DurationVar(&o.QueryBackend.Timeout.Duration, "query-backend-timeout", o.QueryBackend.Timeout.Duration,
+		"The timeout of each request sent to the query backend. Env: "+values.QueryBackendTimeoutEnv)
+	flags.StringVar(&o.QueryBackend.DefaultTenantId, "default-tenant-id", o.QueryBackend.DefaultTenantId,
+		fmt.Sprintf("The tenant id used when the request has no %s header. Env: %s",
+			values.MultiTenantHeader, values.QueryBackendDefaultTenantEnv))
+	flags.StringVar(&o.QueryBackend.BasicAuthUsername, "query-backend-basic-auth-username", o.QueryBackend.BasicAuthUsername,
+		"The basic auth username to access the query backend.")
+	flags.StringVar(&o.QueryBackend.BasicAuthPassword, "query-backend-basic-auth-password", o.QueryBackend.BasicAuthPassword,
+		"The basic auth password to access the query backend.")
+	flags.StringVar(&o.QueryBackend.BearerToken, "query-backend-bearer-token", o.QueryBackend.BearerToken,
+		"The bearer token to access the query backend.")
+	flags.StringVar(&o.QueryBackend.BearerTokenFile, "query-backend-bearer-token-file", o.QueryBackend.BearerTokenFile,
+		"The file containing the bearer token to access the query backend, it's reloaded periodically.")
+	flags.StringVar(&o.QueryBackend.CAFile, "query-backend-ca-file", o.QueryBackend.CAFile,
+		"The CA file to verify the query backend serving certificate.")
+	flags.StringVar(&o.QueryBackend.CertFile, "query-backend-cert-file", o.QueryBackend.CertFile,
+		"The client certificate file for mTLS with the query backend.")
+	flags.StringVar(&o.QueryBackend.KeyFile, "query-backend-key-file", o.QueryBackend.KeyFile,
+		"The client private key file for mTLS with the query backend.")
+	flags.BoolVar(&o.QueryBackend.InsecureSkipVerify, "query-backend-insecure-skip-verify", o.QueryBackend.InsecureSkipVerify,
+		"Skip verifying the query backend serving certificate, for testing only.")
+	flags.IntVar(&o.QueryBackend.Retry.MaxRetries, "query-backend-max-retries", o.QueryBackend.Retry.MaxRetries,
+		"The number of retries of the queries failed as the query backend is unavailable, 0 disables retrying.")
+	flags.BoolVar(&o.QueryBackend.CircuitBreaker.Enabled, "query-backend-circuit-breaker-enabled",
+		o.QueryBackend.CircuitBreaker.Enabled,
+		"Fast-fail the queries while the query backend keeps failing, one query is sent to probe it periodically.")
+	flags.DurationVar(&o.QueryBackendCheckTimeout.Duration, "query-backend-check-timeout", o.QueryBackendCheckTimeout.Duration,
+		"How long to wait for the query backend to be reachable at startup, 0 disables the check.")
+	flags.DurationVar(&o.RequestTimeout.Duration, "request-timeout", o.RequestTimeout.Duration,
+		"The deadline of the queries sent for one API request, the queries are canceled once it's exceeded, 0 means no deadline.")
+	flags.IntVar(&o.QueryConcurrency, "query-concurrency", o.QueryConcurrency,
+		"The number of queries one API request sends to the query backend concurrently.")
+	flags.BoolVar(&o.QueryCache.Enabled, "query-cache-enabled", o.QueryCache.Enabled,
+		"Cache the results of the queries on the historical data.")
+	flags.StringVar(&o.QueryCache.Directory, "query-cache-directory", o.QueryCache.Directory,
+		"The directory the query results are cached in, they are cached in memory if it's empty.")
+	flags.BoolVar(&o.QueryRollup.Enabled, "query-rollup-enabled", o.QueryRollup.Enabled,
+		"Sum the cost of the long ranges from the rollups once they are recorded, see the rollup-rules command.")
+	flags.DurationVar(&o.Budgets.EvaluationInterval.Duration, "budget-evaluation-interval", o.Budgets.EvaluationInterval.Duration,
+		"How often the budgets in the config file are evaluated.")
+	flags.DurationVar(&o.Notification.EvaluationInterval.Duration, "notification-evaluation-interval", o.Notification.EvaluationInterval.Duration,
+		"How often the notification triggers in the config file are evaluated.")
+	flags.DurationVar(&o.Notification.RepeatInterval.Duration, "notification-repeat-interval", o.Notification.RepeatInterval.Duration,
+		"How long to wait before sending a still firing notification again.")
+	flags.BoolVar(&o.Anomaly.Enabled, "anomaly-detection", o.Anomaly.Enabled,
+		"Detect the cost spikes of clusters, namespaces and workloads periodically.")
+	flags.DurationVar(&o.Anomaly.Interval.Duration, "anomaly-detection-interval", o.Anomaly.Interval.Duration,
+		"How often the cost anomalies are detected.")
+	flags.BoolVar(&o.Ledger.Enabled, "ledger-enabled", o.Ledger.Enabled,
+		"Record the finalized hourly and daily cost into the ledger database, see the ledger-backfill command.")
+	flags.StringVar(&o.Ledger.Driver, "ledger-driver", o.Ledger.Driver,
+		"The database of the cost ledger, sqlite or postgres.")
+	flags.StringVar(&o.Ledger.DSN, "ledger-dsn", o.Ledger.DSN,
+		"The database file of sqlite, or the connection string of postgres. Env: "+values.LedgerDSNEnv)
+	flags.DurationVar(&o.Recommendation.Window.Duration, "recommendation-window", o.Recommendation.Window.Duration,
+		"How far back the usage is looked at to recommend the requests.")
+	flags.Float64Var(&o.Recommendation.Headroom, "recommendation-headroom", o.Recommendation.Headroom,
+		"The fraction added on top of the usage percentile for the recommended requests.")
 }
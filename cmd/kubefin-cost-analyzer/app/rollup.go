@@ -0,0 +1,81 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+
+	"github.com/kubefin/kubefin/cmd/kubefin-cost-analyzer/app/options"
+	"github.com/kubefin/kubefin/pkg/query"
+)
+
+// NewRollupRulesCommand creates a *cobra.Command rendering the recording rules of the cost rollups,
+// the analyzer sums the cost of the long ranges from the rollups once they are recorded
+func NewRollupRulesCommand(ctx context.Context) *cobra.Command {
+	var outputFile, configFile, namespace string
+	var apply bool
+	namespace = "kubefin"
+
+	cmd := &cobra.Command{
+		Use:   "rollup-rules",
+		Short: "Render the recording rules of the hourly and daily cost rollups",
+		Long: `Render the recording rules pre-aggregating the node, namespace, workload and label cost hourly and daily
+as a Prometheus rule file, or upload them to the ruler of Mimir/Cortex with --apply`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if apply {
+				// The query backend connection is taken from the analyzer config file and environment variables
+				opts := options.NewAnalyzerOptions()
+				opts.ConfigFile = configFile
+				if err := opts.Complete(pflag.NewFlagSet("rollup-rules", pflag.ContinueOnError)); err != nil {
+					return err
+				}
+				if opts.QueryBackend.Endpoint == "" {
+					return fmt.Errorf("query backend endpoint is not set in the config file or the environment variables")
+				}
+				return query.ApplyRollupRules(ctx, &opts.QueryBackend, namespace)
+			}
+
+			data, err := yaml.Marshal(query.NewRollupRuleGroups())
+			if err != nil {
+				return fmt.Errorf("encode rollup rules error:%v", err)
+			}
+			if outputFile == "" {
+				_, err = cmd.OutOrStdout().Write(data)
+				return err
+			}
+			if err := os.WriteFile(outputFile, data, 0o644); err != nil {
+				return fmt.Errorf("write rule file(%s) error:%v", outputFile, err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&outputFile, "output", outputFile, "The path of the rule file written, the rules are printed if it's empty.")
+	cmd.Flags().BoolVar(&apply, "apply", apply,
+		"Upload the rules to the ruler api of Mimir/Cortex served under the query backend endpoint instead of printing them.")
+	cmd.Flags().StringVar(&configFile, "config", configFile,
+		"The path of the analyzer config file, the query backend connection is taken from it with --apply.")
+	cmd.Flags().StringVar(&namespace, "rule-namespace", namespace, "The ruler namespace the rule groups are uploaded to with --apply.")
+
+	return cmd
+}
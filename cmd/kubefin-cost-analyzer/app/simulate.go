@@ -0,0 +1,78 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/kubefin/kubefin/cmd/kubefin-cost-analyzer/app/options"
+	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/config"
+	"github.com/kubefin/kubefin/pkg/simulation"
+)
+
+// NewSimulateCommand creates a *cobra.Command simulating the node instance types offline
+// against a snapshot saved from /recommendations/clusters/{cluster_id}/nodes/snapshot
+func NewSimulateCommand() *cobra.Command {
+	var snapshotFile, configFile string
+	maxProposals := simulation.NewDefaultConfig().MaxProposals
+
+	cmd := &cobra.Command{
+		Use:   "simulate",
+		Short: "Simulate the node instance types of a snapshot offline",
+		Long: `Simulate packing the pods of every node pool in the snapshot on the instance types of the catalog,
+and print the instance type mixes cheaper than the current nodes as json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := os.ReadFile(snapshotFile)
+			if err != nil {
+				return fmt.Errorf("read snapshot file(%s) error:%v", snapshotFile, err)
+			}
+			snapshot := &api.NodeSimulationSnapshot{}
+			if err := json.Unmarshal(data, snapshot); err != nil {
+				return fmt.Errorf("parse snapshot file(%s) error:%v", snapshotFile, err)
+			}
+
+			// The catalog in the config file replaces the one in the snapshot, so other
+			// instance types and prices could be tried
+			if configFile != "" {
+				opts := options.NewAnalyzerOptions()
+				if err := config.Load(pflag.NewFlagSet("simulate", pflag.ContinueOnError), configFile, opts, nil); err != nil {
+					return err
+				}
+				snapshot.Catalog = simulation.BuildCatalog(&opts.Simulation, snapshot.NodePools)
+			}
+
+			result := simulation.Simulate(snapshot, maxProposals)
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(result)
+		},
+	}
+
+	cmd.Flags().StringVar(&snapshotFile, "snapshot", snapshotFile, "The path of the snapshot json file, required.")
+	cmd.Flags().StringVar(&configFile, "config", configFile,
+		"The path of the analyzer config file, its simulation catalog replaces the one in the snapshot.")
+	cmd.Flags().IntVar(&maxProposals, "max-proposals", maxProposals, "The most proposals of every node pool.")
+	_ = cmd.MarkFlagRequired("snapshot")
+
+	return cmd
+}
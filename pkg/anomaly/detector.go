@@ -0,0 +1,285 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package anomaly
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+
+	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/query"
+	"github.com/kubefin/kubefin/pkg/server/implementation"
+)
+
+// Notifier delivers the newly detected anomalies
+type Notifier interface {
+	Notify(ctx context.Context, anomaly *api.Anomaly) error
+}
+
+// Filter selects the anomalies, the empty fields match all
+type Filter struct {
+	ClusterId string
+	Namespace string
+	Scope     string
+	Severity  string
+	Start     int64
+	End       int64
+}
+
+// Detector evaluates the cost series periodically and records the anomalies
+type Detector struct {
+	config          Config
+	defaultTenantId string
+	notifier        Notifier
+	backend         query.QueryBackend
+
+	mutex     sync.RWMutex
+	anomalies map[string]*api.Anomaly
+}
+
+// NewDetector creates the detector of the cost anomalies, notifier could be nil
+func NewDetector(config *Config, defaultTenantId string, backend query.QueryBackend, notifier Notifier) *Detector {
+	detector := &Detector{
+		config:          *config,
+		defaultTenantId: defaultTenantId,
+		notifier:        notifier,
+		backend:         backend,
+		anomalies:       make(map[string]*api.Anomaly),
+	}
+	if len(detector.config.TenantIds) == 0 {
+		detector.config.TenantIds = []string{defaultTenantId}
+	}
+	return detector
+}
+
+func (d *Detector) Run(ctx context.Context) {
+	klog.Infof("Start detecting cost anomalies every %s", d.config.Interval.Duration)
+	wait.UntilWithContext(ctx, d.evaluateAll, d.config.Interval.Duration)
+}
+
+func (d *Detector) evaluateAll(ctx context.Context) {
+	now := time.Now()
+	for _, tenantId := range d.config.TenantIds {
+		// Only the clusters reporting in the last day are evaluated
+		clusters, err := implementation.QueryAllClustersBasicProperty(ctx, d.backend.WithTenantId(tenantId), now.Add(-24*time.Hour).Unix(), now.Unix())
+		if err != nil {
+			klog.Errorf("Query clusters of tenant %s error:%v", tenantId, err)
+			continue
+		}
+		for clusterId := range clusters {
+			for i := range d.config.Granularities {
+				if err := d.evaluate(ctx, tenantId, clusterId, &d.config.Granularities[i], now); err != nil {
+					klog.Errorf("Detect cluster(%s) %s cost anomalies error:%v",
+						clusterId, d.config.Granularities[i].Name, err)
+				}
+			}
+		}
+	}
+	d.prune(now)
+}
+
+// evaluate checks the last complete step of the cluster, namespaces and workloads cost series
+func (d *Detector) evaluate(ctx context.Context, tenantId, clusterId string, granularity *Granularity, now time.Time) error {
+	stepSeconds := int64(granularity.Step.Seconds())
+	end := now.Unix() / stepSeconds * stepSeconds
+	start := end - int64(granularity.BaselinePoints)*stepSeconds
+	series, err := implementation.QueryCostSeries(ctx, d.backend.WithTenantId(tenantId), clusterId, start, end, stepSeconds)
+	if err != nil {
+		return err
+	}
+
+	newAnomaly := func(scope string, cost float64, b *baseline) *api.Anomaly {
+		return &api.Anomaly{
+			TenantId:     tenantId,
+			ClusterId:    clusterId,
+			Scope:        scope,
+			Granularity:  granularity.Name,
+			Timestamp:    end,
+			StepSeconds:  stepSeconds,
+			Cost:         cost,
+			BaselineCost: b.median,
+			Score:        b.score(cost),
+			DetectedTime: now.Unix(),
+		}
+	}
+
+	namespaceDeltas := d.contributors(series.Namespaces, end, func(key string) *api.AnomalyContributor {
+		return &api.AnomalyContributor{Scope: api.AnomalyScopeNamespace, Namespace: key}
+	})
+	workloadDeltas := d.contributors(series.Workloads, end, func(key string) *api.AnomalyContributor {
+		namespace, name, workloadType := implementation.ParseWorkloadKey(key)
+		return &api.AnomalyContributor{Scope: api.AnomalyScopeWorkload, Namespace: namespace,
+			WorkloadName: name, WorkloadType: workloadType}
+	})
+
+	if cost, b, ok := d.detect(series.Cluster, end); ok {
+		anomaly := newAnomaly(api.AnomalyScopeCluster, cost, b)
+		anomaly.Contributors = d.topContributors(namespaceDeltas, func(*api.AnomalyContributor) bool { return true })
+		d.record(ctx, anomaly)
+	}
+	for namespace, nsSeries := range series.Namespaces {
+		if cost, b, ok := d.detect(nsSeries, end); ok {
+			anomaly := newAnomaly(api.AnomalyScopeNamespace, cost, b)
+			anomaly.Namespace = namespace
+			anomaly.Contributors = d.topContributors(workloadDeltas, func(c *api.AnomalyContributor) bool {
+				return c.Namespace == namespace
+			})
+			d.record(ctx, anomaly)
+		}
+	}
+	for key, workloadSeries := range series.Workloads {
+		if cost, b, ok := d.detect(workloadSeries, end); ok {
+			anomaly := newAnomaly(api.AnomalyScopeWorkload, cost, b)
+			anomaly.Namespace, anomaly.WorkloadName, anomaly.WorkloadType = implementation.ParseWorkloadKey(key)
+			d.record(ctx, anomaly)
+		}
+	}
+	return nil
+}
+
+// detect returns the cost at end and its baseline if the cost is a spike
+func (d *Detector) detect(series map[int64]float64, end int64) (float64, *baseline, bool) {
+	cost, points, ok := splitSeries(series, end)
+	if !ok {
+		return 0, nil, false
+	}
+	b, ok := newBaseline(points, d.config.MinCostDelta)
+	if !ok {
+		return 0, nil, false
+	}
+	if cost-b.median < d.config.MinCostDelta || b.score(cost) < d.config.Sensitivity {
+		return 0, nil, false
+	}
+	return cost, b, true
+}
+
+// contributors computes the cost and baseline at end of every series
+func (d *Detector) contributors(series map[string]map[int64]float64, end int64,
+	newContributor func(key string) *api.AnomalyContributor) []*api.AnomalyContributor {
+	ret := make([]*api.AnomalyContributor, 0, len(series))
+	for key, s := range series {
+		cost, points, ok := splitSeries(s, end)
+		if !ok {
+			continue
+		}
+		contributor := newContributor(key)
+		contributor.Cost = cost
+		contributor.BaselineCost = median(points)
+		ret = append(ret, contributor)
+	}
+	return ret
+}
+
+// topContributors returns the contributors whose cost increased most
+func (d *Detector) topContributors(contributors []*api.AnomalyContributor,
+	match func(*api.AnomalyContributor) bool) []*api.AnomalyContributor {
+	var ret []*api.AnomalyContributor
+	for _, contributor := range contributors {
+		if match(contributor) && contributor.Cost > contributor.BaselineCost {
+			ret = append(ret, contributor)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Cost-ret[i].BaselineCost > ret[j].Cost-ret[j].BaselineCost
+	})
+	if len(ret) > d.config.MaxContributors {
+		ret = ret[:d.config.MaxContributors]
+	}
+	return ret
+}
+
+// record saves the anomaly, the anomaly detected before is updated without notifying again
+func (d *Detector) record(ctx context.Context, anomaly *api.Anomaly) {
+	anomaly.Severity = api.AnomalySeverityWarning
+	if anomaly.Score >= 2*d.config.Sensitivity {
+		anomaly.Severity = api.AnomalySeverityCritical
+	}
+	key := fmt.Sprintf("%s/%s/%s/%s/%s/%s/%s/%d", anomaly.TenantId, anomaly.ClusterId, anomaly.Granularity,
+		anomaly.Scope, anomaly.Namespace, anomaly.WorkloadType, anomaly.WorkloadName, anomaly.Timestamp)
+	sum := sha1.Sum([]byte(key))
+	anomaly.Id = hex.EncodeToString(sum[:8])
+
+	d.mutex.Lock()
+	existing, ok := d.anomalies[anomaly.Id]
+	if ok {
+		anomaly.DetectedTime = existing.DetectedTime
+	}
+	d.anomalies[anomaly.Id] = anomaly
+	d.mutex.Unlock()
+	if ok {
+		return
+	}
+
+	klog.Infof("Detected %s cost anomaly %s in cluster %s, namespace:%s, workload:%s, cost:%.2f, baseline:%.2f",
+		anomaly.Granularity, anomaly.Scope, anomaly.ClusterId, anomaly.Namespace, anomaly.WorkloadName,
+		anomaly.Cost, anomaly.BaselineCost)
+	if d.notifier != nil {
+		if err := d.notifier.Notify(ctx, anomaly); err != nil {
+			klog.Errorf("Notify anomaly %s error:%v", anomaly.Id, err)
+		}
+	}
+}
+
+func (d *Detector) prune(now time.Time) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	expired := now.Add(-d.config.Retention.Duration).Unix()
+	for id, anomaly := range d.anomalies {
+		if anomaly.Timestamp < expired {
+			delete(d.anomalies, id)
+		}
+	}
+}
+
+// Anomalies returns the anomalies of the tenant matching the filter, the latest ones first,
+// an empty tenant means the default tenant
+func (d *Detector) Anomalies(tenantId string, filter *Filter) []*api.Anomaly {
+	if tenantId == "" {
+		tenantId = d.defaultTenantId
+	}
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	ret := make([]*api.Anomaly, 0)
+	for _, anomaly := range d.anomalies {
+		if anomaly.TenantId != tenantId ||
+			filter.ClusterId != "" && anomaly.ClusterId != filter.ClusterId ||
+			filter.Namespace != "" && anomaly.Namespace != filter.Namespace ||
+			filter.Scope != "" && anomaly.Scope != filter.Scope ||
+			filter.Severity != "" && anomaly.Severity != filter.Severity ||
+			filter.Start != 0 && anomaly.Timestamp < filter.Start ||
+			filter.End != 0 && anomaly.Timestamp > filter.End {
+			continue
+		}
+		ret = append(ret, anomaly)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Timestamp != ret[j].Timestamp {
+			return ret[i].Timestamp > ret[j].Timestamp
+		}
+		return ret[i].Score > ret[j].Score
+	})
+	return ret
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package anomaly
+
+import (
+	"math"
+	"sort"
+)
+
+// madScale makes the MAD a consistent estimator of the standard deviation of normal data
+const madScale = 1.4826
+
+// minBaselinePoints is the least number of points to compute a baseline from
+const minBaselinePoints = 3
+
+// baseline is the robust center and spread of the points before the evaluated one
+type baseline struct {
+	median float64
+	// scale is the MAD scaled as a standard deviation, it's never zero
+	scale float64
+}
+
+// newBaseline returns false if there are not enough points to compute the baseline
+func newBaseline(points []float64, minCostDelta float64) (*baseline, bool) {
+	if len(points) < minBaselinePoints {
+		return nil, false
+	}
+	center := median(points)
+	deviations := make([]float64, 0, len(points))
+	for _, point := range points {
+		deviations = append(deviations, math.Abs(point-center))
+	}
+
+	// A flat series has a zero MAD, a small part of the median is used instead, so a
+	// tiny change is not reported with an infinite score
+	scale := madScale * median(deviations)
+	scale = math.Max(scale, 0.05*center)
+	scale = math.Max(scale, minCostDelta/10)
+	if scale == 0 {
+		scale = 1e-6
+	}
+	return &baseline{median: center, scale: scale}, true
+}
+
+func (b *baseline) score(value float64) float64 {
+	return (value - b.median) / b.scale
+}
+
+func median(points []float64) float64 {
+	sorted := append([]float64(nil), points...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n == 0 {
+		return 0
+	}
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// splitSeries returns the value at end and the values of the steps before it,
+// missing steps are skipped since the cluster may not be reporting then
+func splitSeries(series map[int64]float64, end int64) (float64, []float64, bool) {
+	value, ok := series[end]
+	if !ok {
+		return 0, nil, false
+	}
+	points := make([]float64, 0, len(series))
+	for timestamp, v := range series {
+		if timestamp < end {
+			points = append(points, v)
+		}
+	}
+	return value, points, true
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package anomaly
+
+import (
+	"math"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestMedian(t *testing.T) {
+	tests := []struct {
+		name   string
+		points []float64
+		want   float64
+	}{
+		{name: "empty", points: nil, want: 0},
+		{name: "odd", points: []float64{3, 1, 2}, want: 2},
+		{name: "even", points: []float64{4, 1, 3, 2}, want: 2.5},
+		{name: "outlier", points: []float64{1, 2, 3, 100}, want: 2.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := median(tt.points); got != tt.want {
+				t.Errorf("median(%v) = %v, want %v", tt.points, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewBaseline(t *testing.T) {
+	tests := []struct {
+		name         string
+		points       []float64
+		minCostDelta float64
+		wantOK       bool
+		wantMedian   float64
+		wantScale    float64
+	}{
+		{
+			name:   "not enough points",
+			points: []float64{10, 12},
+		},
+		{
+			name:       "scaled MAD",
+			points:     []float64{10, 12, 14, 16, 100},
+			wantOK:     true,
+			wantMedian: 14,
+			wantScale:  madScale * 2,
+		},
+		{
+			name:       "flat series uses a part of the median",
+			points:     []float64{10, 10, 10},
+			wantOK:     true,
+			wantMedian: 10,
+			wantScale:  0.5,
+		},
+		{
+			name:         "flat series uses a part of the min cost delta",
+			points:       []float64{10, 10, 10},
+			minCostDelta: 20,
+			wantOK:       true,
+			wantMedian:   10,
+			wantScale:    2,
+		},
+		{
+			name:       "zero series never has a zero scale",
+			points:     []float64{0, 0, 0},
+			wantOK:     true,
+			wantMedian: 0,
+			wantScale:  1e-6,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, ok := newBaseline(tt.points, tt.minCostDelta)
+			if ok != tt.wantOK {
+				t.Fatalf("newBaseline() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if b.median != tt.wantMedian || math.Abs(b.scale-tt.wantScale) > 1e-9 {
+				t.Errorf("newBaseline() = {%v, %v}, want {%v, %v}", b.median, b.scale, tt.wantMedian, tt.wantScale)
+			}
+		})
+	}
+}
+
+func TestBaselineScore(t *testing.T) {
+	b := &baseline{median: 10, scale: 2}
+	tests := []struct {
+		value float64
+		want  float64
+	}{
+		{value: 10, want: 0},
+		{value: 16, want: 3},
+		{value: 4, want: -3},
+	}
+	for _, tt := range tests {
+		if got := b.score(tt.value); got != tt.want {
+			t.Errorf("score(%v) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestSplitSeries(t *testing.T) {
+	tests := []struct {
+		name       string
+		series     map[int64]float64
+		end        int64
+		wantOK     bool
+		wantValue  float64
+		wantPoints []float64
+	}{
+		{
+			name:   "no value at end",
+			series: map[int64]float64{100: 1, 200: 2},
+			end:    300,
+		},
+		{
+			name:       "points before end",
+			series:     map[int64]float64{100: 1, 200: 2, 300: 3},
+			end:        300,
+			wantOK:     true,
+			wantValue:  3,
+			wantPoints: []float64{1, 2},
+		},
+		{
+			name:       "missing steps are skipped and later points ignored",
+			series:     map[int64]float64{100: 1, 300: 3, 400: 4, 500: 5},
+			end:        400,
+			wantOK:     true,
+			wantValue:  4,
+			wantPoints: []float64{1, 3},
+		},
+		{
+			name:       "only the value at end",
+			series:     map[int64]float64{100: 1},
+			end:        100,
+			wantOK:     true,
+			wantValue:  1,
+			wantPoints: []float64{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, points, ok := splitSeries(tt.series, tt.end)
+			if ok != tt.wantOK {
+				t.Fatalf("splitSeries() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			sort.Float64s(points)
+			if value != tt.wantValue || !reflect.DeepEqual(points, tt.wantPoints) {
+				t.Errorf("splitSeries() = %v, %v, want %v, %v", value, points, tt.wantValue, tt.wantPoints)
+			}
+		})
+	}
+}
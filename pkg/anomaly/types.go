@@ -0,0 +1,50 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package anomaly
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Config holds the anomaly detection settings
+type Config struct {
+	Enabled bool `json:"enabled"`
+	// Interval is how often the cost series are evaluated
+	Interval metav1.Duration `json:"interval,omitempty"`
+	// TenantIds are the tenants to evaluate, only the default tenant is evaluated if it's empty
+	TenantIds     []string      `json:"tenantIds,omitempty"`
+	Granularities []Granularity `json:"granularities,omitempty"`
+	// Sensitivity is the robust z-score, (cost - median) / (1.4826 * MAD), to record an anomaly at,
+	// an anomaly whose score is twice of it is critical
+	Sensitivity float64 `json:"sensitivity,omitempty"`
+	// MinCostDelta ignores the spikes whose cost increased less than it
+	MinCostDelta float64 `json:"minCostDelta,omitempty"`
+	// MaxContributors is the max number of contributing namespaces or workloads of an anomaly
+	MaxContributors int `json:"maxContributors,omitempty"`
+	// Retention is how long the anomalies are kept
+	Retention metav1.Duration `json:"retention,omitempty"`
+}
+
+// Granularity is a cost series to evaluate
+type Granularity struct {
+	// Name is the name of the series, such as hourly/daily
+	Name string `json:"name"`
+	// Step is the period every point of the series is summed over
+	Step metav1.Duration `json:"step"`
+	// BaselinePoints is the number of points before the latest one the baseline is computed from
+	BaselinePoints int `json:"baselinePoints"`
+}
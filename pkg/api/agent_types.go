@@ -19,6 +19,7 @@ package api
 import (
 	appv1 "k8s.io/client-go/listers/apps/v1"
 	v1 "k8s.io/client-go/listers/core/v1"
+	policyv1 "k8s.io/client-go/listers/policy/v1"
 	"k8s.io/client-go/tools/cache"
 )
 
@@ -38,15 +39,21 @@ type InstancePriceInfo struct {
 }
 
 type CoreResourceInformerLister struct {
-	NodeInformer        cache.SharedIndexInformer
-	NamespaceInformer   cache.SharedIndexInformer
-	PodInformer         cache.SharedIndexInformer
-	DeploymentInformer  cache.SharedIndexInformer
-	StatefulSetInformer cache.SharedIndexInformer
-	DaemonSetInformer   cache.SharedIndexInformer
-	NodeLister          v1.NodeLister
-	PodLister           v1.PodLister
-	DeploymentLister    appv1.DeploymentLister
-	StatefulSetLister   appv1.StatefulSetLister
-	DaemonSetLister     appv1.DaemonSetLister
+	NodeInformer                  cache.SharedIndexInformer
+	NamespaceInformer             cache.SharedIndexInformer
+	PodInformer                   cache.SharedIndexInformer
+	DeploymentInformer            cache.SharedIndexInformer
+	StatefulSetInformer           cache.SharedIndexInformer
+	DaemonSetInformer             cache.SharedIndexInformer
+	PersistentVolumeClaimInformer cache.SharedIndexInformer
+	PersistentVolumeInformer      cache.SharedIndexInformer
+	PodDisruptionBudgetInformer   cache.SharedIndexInformer
+	NodeLister                    v1.NodeLister
+	PodLister                     v1.PodLister
+	DeploymentLister              appv1.DeploymentLister
+	StatefulSetLister             appv1.StatefulSetLister
+	DaemonSetLister               appv1.DaemonSetLister
+	PersistentVolumeClaimLister   v1.PersistentVolumeClaimLister
+	PersistentVolumeLister        v1.PersistentVolumeLister
+	PodDisruptionBudgetLister     policyv1.PodDisruptionBudgetLister
 }
@@ -34,11 +34,35 @@ var (
 	QueryFailedStatus = "QueryFailed"
 	QueryFailedReason = "Query backend with promql error"
 
+	QueryBadQueryStatus = "QueryBadQuery"
+	QueryBadQueryReason = "Query backend rejects the promql"
+
+	QueryTimeoutStatus = "QueryTimeout"
+	QueryTimeoutReason = "Query backend does not answer in time"
+
+	QueryBackendUnavailableStatus = "QueryBackendUnavailable"
+	QueryBackendUnavailableReason = "Query backend is not reachable"
+
+	QueryPartialDataStatus = "QueryPartialData"
+	QueryPartialDataReason = "Query backend returns incomplete data"
+
 	QueryNotFoundStatus = "QueryNotFound"
 	QueryNotFoundReason = "Query parameter not found"
 
 	QueryParaErrorStatus = "BadParameters"
 	QueryParaErrorReason = "Query parameters are wrong"
+
+	UnauthorizedStatus = "Unauthorized"
+	UnauthorizedReason = "Request is not authenticated"
+
+	ForbiddenStatus = "Forbidden"
+	ForbiddenReason = "Request is not allowed to access the resource"
+
+	LedgerDisabledStatus = "LedgerDisabled"
+	LedgerDisabledReason = "Cost ledger is not enabled"
+
+	LedgerFailedStatus = "LedgerFailed"
+	LedgerFailedReason = "Query cost ledger database error"
 )
 
 const (
@@ -53,6 +77,49 @@ const (
 	QueryEndTimePara     = "endTime"
 	QueryStepSecondsPara = "stepSeconds"
 	QueryAggregateBy     = "aggregateBy"
+	// QueryFormatPara could be json/csv/xlsx, the Accept header is used if it's not set
+	QueryFormatPara       = "format"
+	QueryInstanceTypePara = "instanceType"
+	QueryBillingModePara  = "billingMode"
+	QuerySortByPara       = "sortBy"
+	QuerySortOrderPara    = "sortOrder"
+	QueryClusterIdPara    = "clusterId"
+	QueryNamespacePara    = "namespace"
+	QueryScopePara        = "scope"
+	QuerySeverityPara     = "severity"
+	QueryHistoryDaysPara  = "historyDays"
+	QueryKindPara         = "kind"
+	QueryGranularityPara  = "granularity"
+	QueryLabelKeyPara     = "labelKey"
+	QueryLabelValuePara   = "labelValue"
+	QueryWorkloadTypePara = "workloadType"
+	// QueryNameRegexPara is fully matched by the item names like the promql regex matchers
+	QueryNameRegexPara = "nameRegex"
+	QueryTopNPara      = "topN"
+	QueryLimitPara     = "limit"
+	QueryContinuePara  = "continue"
+	// QueryBaseStartTimePara/QueryBaseEndTimePara are the period the cost is compared with
+	QueryBaseStartTimePara = "baseStartTime"
+	QueryBaseEndTimePara   = "baseEndTime"
+	QueryDimensionPara     = "dimension"
+	// QueryClusterIdsPara is the comma separated cluster ids, QueryClusterSelectorPara is the label
+	// selector on the cluster_name/cloud_provider/region labels of the clusters
+	QueryClusterIdsPara      = "clusterIds"
+	QueryClusterSelectorPara = "clusterSelector"
+	// QueryForecastPara enables the month end forecast in the costs summary, it's off by default
+	// as the forecast queries the daily history
+	QueryForecastPara = "forecast"
+
+	SortByName        = "name"
+	SortByTotalCost   = "totalCost"
+	SortByIdleCost    = "idleCost"
+	SortByHourlyPrice = "hourlyPrice"
+	SortByCPURequest  = "cpuCoreRequest"
+	SortByRAMRequest  = "ramGiBRequest"
+	SortByEfficiency  = "efficiency"
+	SortByWastedCost  = "wastedCost"
+	SortOrderAsc      = "asc"
+	SortOrderDesc     = "desc"
 )
 
 type StatusError struct {
@@ -86,6 +153,44 @@ type ClusterCostsSummary struct {
 	ClusterAvgDailyCost float64 `json:"clusterAvgDailyCost,omitempty"`
 	// ClusterAvgDailyCost means average core/hour costs in current month
 	ClusterAvgHourlyCoreCost float64 `json:"ClusterAvgHourlyCoreCost,omitempty"`
+	// ClusterMonthForecast means the month end cost forecast with trend and weekly seasonality,
+	// it's not set if the history is not available
+	ClusterMonthForecast *CostForecastValue `json:"clusterMonthForecast,omitempty"`
+}
+
+type ClusterCostForecast struct {
+	ClusterId   string `json:"clusterId"`
+	HistoryDays int64  `json:"historyDays"`
+	// ConfidenceLevel is the confidence level of the lower and upper bounds
+	ConfidenceLevel float64 `json:"confidenceLevel"`
+	// Cluster is not set if only some namespaces of the cluster could be accessed
+	Cluster    *CostForecast   `json:"cluster,omitempty"`
+	Namespaces []*CostForecast `json:"namespaces"`
+}
+
+type CostForecast struct {
+	Namespace string `json:"namespace,omitempty"`
+	// Model could be average/trend/trend+weekly/trend+weekly+nodes, depending on the history length
+	Model string `json:"model"`
+	// MonthCostCurrent means the total cost in current month
+	MonthCostCurrent float64           `json:"monthCostCurrent"`
+	MonthEnd         CostForecastValue `json:"monthEnd"`
+	Next30Days       CostForecastValue `json:"next30Days"`
+	Next90Days       CostForecastValue `json:"next90Days"`
+	// DailyForecast is the cost forecast of the next 30 days, it's only set for the cluster
+	DailyForecast []*CostForecastPoint `json:"dailyForecast,omitempty"`
+}
+
+type CostForecastValue struct {
+	Cost  float64 `json:"cost"`
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+}
+
+type CostForecastPoint struct {
+	// Timestamp is the start of the day
+	Timestamp int64 `json:"timestamp"`
+	CostForecastValue
 }
 
 type ClusterResourceCostList struct {
@@ -114,11 +219,24 @@ type ClusterResourceCost struct {
 	// RAMGiBUsage means the average ram hour usage in this period
 	RAMGiBUsage float64 `json:"ramGiBUsage,omitempty"`
 	RAMCost     float64 `json:"ramCost,omitempty"`
+
+	// CPUCoreRequest/RAMGiBRequest mean the average resources requested by all pods in this period,
+	// the efficiency of the cluster is the usage of the nodes against them
+	CPUCoreRequest float64 `json:"cpuCoreRequest,omitempty"`
+	RAMGiBRequest  float64 `json:"ramGiBRequest,omitempty"`
+	CPUEfficiency  float64 `json:"cpuEfficiency,omitempty"`
+	RAMEfficiency  float64 `json:"ramEfficiency,omitempty"`
+	CostEfficiency float64 `json:"costEfficiency,omitempty"`
+	WastedCost     float64 `json:"wastedCost,omitempty"`
 }
 
 type ClusterWorkloadCostList struct {
 	ClusterId string                 `json:"clusterId"`
 	Items     []*ClusterWorkloadCost `json:"items"`
+	// TotalItems is the number of the items matched before paginating
+	TotalItems int `json:"totalItems,omitempty"`
+	// Continue is the token to query the next page with, it's empty on the last page
+	Continue string `json:"continue,omitempty"`
 }
 
 type ClusterWorkloadCost struct {
@@ -138,11 +256,22 @@ type ClusterWorkloadCostDetail struct {
 	RAMGiBRequest  float64 `json:"ramGiBRequest,omitempty"`
 	RAMGiBUsage    float64 `json:"ramGiBUsage,omitempty"`
 	TotalCost      float64 `json:"totalCost,omitempty"`
+	// CPUEfficiency and RAMEfficiency are the usage/request ratios, CostEfficiency is the ratio
+	// of the cost of the cpu and ram used to the cost of the cpu and ram requested
+	CPUEfficiency  float64 `json:"cpuEfficiency,omitempty"`
+	RAMEfficiency  float64 `json:"ramEfficiency,omitempty"`
+	CostEfficiency float64 `json:"costEfficiency,omitempty"`
+	// WastedCost is the cost of the cpu and ram requested but not used in this period
+	WastedCost float64 `json:"wastedCost,omitempty"`
 }
 
 type ClusterNamespaceCostList struct {
 	ClusterId string                  `json:"clusterId,omitempty"`
 	Items     []*ClusterNamespaceCost `json:"items,omitempty"`
+	// TotalItems is the number of the items matched before paginating
+	TotalItems int `json:"totalItems,omitempty"`
+	// Continue is the token to query the next page with, it's empty on the last page
+	Continue string `json:"continue,omitempty"`
 }
 
 type ClusterNamespaceCost struct {
@@ -159,6 +288,444 @@ type ClusterNamespaceCostDetail struct {
 	RAMGiBRequest  float64 `json:"ramGiBRequest,omitempty"`
 	RAMGiBUsage    float64 `json:"ramGiBUsage,omitempty"`
 	TotalCost      float64 `json:"totalCost,omitempty"`
+	CPUEfficiency  float64 `json:"cpuEfficiency,omitempty"`
+	RAMEfficiency  float64 `json:"ramEfficiency,omitempty"`
+	CostEfficiency float64 `json:"costEfficiency,omitempty"`
+	WastedCost     float64 `json:"wastedCost,omitempty"`
+}
+
+type ClusterNodeCostList struct {
+	ClusterId string             `json:"clusterId"`
+	Items     []*ClusterNodeCost `json:"items"`
+}
+
+type ClusterNodeCost struct {
+	NodeName     string                   `json:"nodeName"`
+	InstanceType string                   `json:"instanceType"`
+	BillingMode  string                   `json:"billingMode"`
+	Region       string                   `json:"region,omitempty"`
+	CostList     []*ClusterNodeCostDetail `json:"costList"`
+}
+
+type ClusterNodeCostDetail struct {
+	Timestamp int64 `json:"timestamp"`
+	// HourlyPrice means the average node hourly price in this period
+	HourlyPrice float64 `json:"hourlyPrice,omitempty"`
+	TotalCost   float64 `json:"totalCost,omitempty"`
+	// CPURequestRatio means the average ratio of the allocatable cpu requested by pods in this period
+	CPURequestRatio float64 `json:"cpuRequestRatio,omitempty"`
+	// CPUUsageRatio means the average ratio of the total cpu used in this period
+	CPUUsageRatio   float64 `json:"cpuUsageRatio,omitempty"`
+	RAMRequestRatio float64 `json:"ramRequestRatio,omitempty"`
+	RAMUsageRatio   float64 `json:"ramUsageRatio,omitempty"`
+	// IdleCost means the cost of the cpu and ram not requested by any pod in this period
+	IdleCost float64 `json:"idleCost,omitempty"`
+}
+
+const (
+	BudgetPeriodMonthly = "monthly"
+	BudgetPeriodWeekly  = "weekly"
+
+	BudgetStateOK = "ok"
+	// BudgetStateAtRisk means the forecast cost of the period exceeds the amount
+	BudgetStateAtRisk   = "atRisk"
+	BudgetStateExceeded = "exceeded"
+)
+
+// Budget is the spending limit of a cluster, or a namespace/pods with some label in the cluster
+type Budget struct {
+	Name string `json:"name"`
+	// TenantId is the tenant the cluster belongs to, the default tenant is used if it's empty
+	TenantId  string `json:"tenantId,omitempty"`
+	ClusterId string `json:"clusterId"`
+	// Namespace limits the budget to the namespace
+	Namespace string `json:"namespace,omitempty"`
+	// LabelKey and LabelValue limit the budget to the pods with the label
+	LabelKey   string `json:"labelKey,omitempty"`
+	LabelValue string `json:"labelValue,omitempty"`
+	// Period could be monthly/weekly
+	Period string  `json:"period"`
+	Amount float64 `json:"amount"`
+	// Thresholds are the percentages of the amount to notify at once the actual or forecast cost crosses them
+	Thresholds []float64 `json:"thresholds,omitempty"`
+}
+
+type BudgetStatusList struct {
+	Items []*BudgetStatus `json:"items"`
+}
+
+type BudgetStatus struct {
+	Budget
+	PeriodStart int64 `json:"periodStart"`
+	PeriodEnd   int64 `json:"periodEnd"`
+	// ActualCost means the cost from the period start to the last evaluation
+	ActualCost float64 `json:"actualCost"`
+	// ForecastCost means the estimated cost of the whole period
+	ForecastCost    float64 `json:"forecastCost"`
+	ActualPercent   float64 `json:"actualPercent"`
+	ForecastPercent float64 `json:"forecastPercent"`
+	// State could be ok/atRisk/exceeded
+	State              string `json:"state"`
+	LastEvaluationTime int64  `json:"lastEvaluationTime,omitempty"`
+	// Error means the last evaluation failed, the costs are from the evaluation before it
+	Error string `json:"error,omitempty"`
+}
+
+const (
+	AnomalyScopeCluster   = "cluster"
+	AnomalyScopeNamespace = "namespace"
+	AnomalyScopeWorkload  = "workload"
+
+	AnomalySeverityWarning  = "warning"
+	AnomalySeverityCritical = "critical"
+)
+
+type AnomalyList struct {
+	Items []*Anomaly `json:"items"`
+}
+
+// Anomaly is a cost spike of a cluster, namespace or workload in one step of the cost series
+type Anomaly struct {
+	Id        string `json:"id"`
+	TenantId  string `json:"tenantId,omitempty"`
+	ClusterId string `json:"clusterId"`
+	// Scope could be cluster/namespace/workload
+	Scope        string `json:"scope"`
+	Namespace    string `json:"namespace,omitempty"`
+	WorkloadName string `json:"workloadName,omitempty"`
+	WorkloadType string `json:"workloadType,omitempty"`
+	// Granularity is the name of the cost series, such as hourly/daily
+	Granularity string `json:"granularity"`
+	// Timestamp is the end of the step the cost is summed over
+	Timestamp   int64   `json:"timestamp"`
+	StepSeconds int64   `json:"stepSeconds"`
+	Cost        float64 `json:"cost"`
+	// BaselineCost is the median cost of the steps before
+	BaselineCost float64 `json:"baselineCost"`
+	// Score is how many robust standard deviations the cost is above the baseline
+	Score float64 `json:"score"`
+	// Severity could be warning/critical
+	Severity     string                `json:"severity"`
+	Contributors []*AnomalyContributor `json:"contributors,omitempty"`
+	DetectedTime int64                 `json:"detectedTime"`
+}
+
+// AnomalyContributor is a namespace or workload whose cost increased most in the anomaly
+type AnomalyContributor struct {
+	// Scope could be namespace/workload
+	Scope        string  `json:"scope"`
+	Namespace    string  `json:"namespace"`
+	WorkloadName string  `json:"workloadName,omitempty"`
+	WorkloadType string  `json:"workloadType,omitempty"`
+	Cost         float64 `json:"cost"`
+	BaselineCost float64 `json:"baselineCost"`
+}
+
+const (
+	LedgerGranularityHourly = "hourly"
+	LedgerGranularityDaily  = "daily"
+
+	LedgerKindCluster   = "cluster"
+	LedgerKindNode      = "node"
+	LedgerKindNamespace = "namespace"
+	LedgerKindWorkload  = "workload"
+	LedgerKindLabel     = "label"
+)
+
+type CostLedgerRecordList struct {
+	Items []*CostLedgerRecord `json:"items"`
+}
+
+// CostLedgerRecord is the finalized cost of a cluster, node, namespace, workload or pod labels in an hour or a day
+type CostLedgerRecord struct {
+	TenantId  string `json:"tenantId,omitempty"`
+	ClusterId string `json:"clusterId"`
+	// Kind could be cluster/node/namespace/workload/label
+	Kind string `json:"kind"`
+	// Granularity could be hourly/daily
+	Granularity string `json:"granularity"`
+	Namespace   string `json:"namespace,omitempty"`
+	// Name is the node name, the workload as type/name, or the pod labels in json
+	Name string `json:"name,omitempty"`
+	// StartTime is the start of the hour or the UTC day
+	StartTime int64   `json:"startTime"`
+	Cost      float64 `json:"cost"`
+}
+
+const (
+	CompareDimensionNamespace    = "namespace"
+	CompareDimensionWorkload     = "workload"
+	CompareDimensionLabel        = "label"
+	CompareDimensionInstanceType = "instanceType"
+
+	CompareStatusNew      = "new"
+	CompareStatusRemoved  = "removed"
+	CompareStatusExisting = "existing"
+)
+
+// CostComparison compares the cost of a cluster in two periods by a dimension
+type CostComparison struct {
+	ClusterId string `json:"clusterId"`
+	// Dimension could be namespace/workload/label/instanceType
+	Dimension string `json:"dimension"`
+	LabelKey  string `json:"labelKey,omitempty"`
+	// BaseStartTime and BaseEndTime are the period compared with
+	BaseStartTime int64   `json:"baseStartTime"`
+	BaseEndTime   int64   `json:"baseEndTime"`
+	StartTime     int64   `json:"startTime"`
+	EndTime       int64   `json:"endTime"`
+	BaseTotalCost float64 `json:"baseTotalCost"`
+	TotalCost     float64 `json:"totalCost"`
+	CostDelta     float64 `json:"costDelta"`
+	// CostDeltaPercent is 0 if the base total cost is 0
+	CostDeltaPercent float64 `json:"costDeltaPercent"`
+	// Items are sorted by the absolute cost delta from the highest
+	Items []*CostComparisonItem `json:"items"`
+}
+
+type CostComparisonItem struct {
+	// Name is the namespace, the workload as type/name, the label value or the instance type,
+	// the pods without the label are in the item with an empty name
+	Name string `json:"name"`
+	// Namespace is only set for the workloads
+	Namespace string  `json:"namespace,omitempty"`
+	BaseCost  float64 `json:"baseCost"`
+	Cost      float64 `json:"cost"`
+	CostDelta float64 `json:"costDelta"`
+	// CostDeltaPercent is 0 for the new items
+	CostDeltaPercent float64 `json:"costDeltaPercent"`
+	// Status could be new/removed/existing
+	Status string `json:"status"`
+}
+
+const (
+	BreakdownDimensionNamespace    = "namespace"
+	BreakdownDimensionLabel        = "label"
+	BreakdownDimensionResourceType = "resourceType"
+)
+
+// MultiClustersCostBreakdown is the cost of the selected clusters aggregated by a dimension
+type MultiClustersCostBreakdown struct {
+	// Dimension could be namespace/label/resourceType
+	Dimension string `json:"dimension"`
+	LabelKey  string `json:"labelKey,omitempty"`
+	StartTime int64  `json:"startTime"`
+	EndTime   int64  `json:"endTime"`
+	// ClusterIds are the clusters selected and accessible by the caller
+	ClusterIds []string `json:"clusterIds"`
+	TotalCost  float64  `json:"totalCost"`
+	// Items are sorted by the total cost from the highest
+	Items []*MultiClustersCostBreakdownItem `json:"items"`
+}
+
+type MultiClustersCostBreakdownItem struct {
+	// Name is the namespace, the label value or the resource type,
+	// the pods without the label are in the item with an empty name
+	Name      string  `json:"name"`
+	TotalCost float64 `json:"totalCost"`
+	// ClusterCosts is the cost of the item in every cluster keyed by the cluster id
+	ClusterCosts map[string]float64 `json:"clusterCosts"`
+}
+
+// WorkloadEfficiencyList lists the workloads wasting the most cost in the period
+type WorkloadEfficiencyList struct {
+	ClusterId string `json:"clusterId"`
+	StartTime int64  `json:"startTime"`
+	EndTime   int64  `json:"endTime"`
+	// TotalWastedCost is the wasted cost of all the workloads matched, not only the listed ones
+	TotalWastedCost float64 `json:"totalWastedCost"`
+	// Items are sorted by the wasted cost from the highest
+	Items []*WorkloadEfficiency `json:"items"`
+}
+
+type WorkloadEfficiency struct {
+	Namespace    string `json:"namespace"`
+	WorkloadName string `json:"workloadName"`
+	WorkloadType string `json:"workloadType"`
+	// ClusterWorkloadCostDetail is the cost of the whole period, its timestamp is the end time
+	ClusterWorkloadCostDetail
+}
+
+type WorkloadRecommendationList struct {
+	ClusterId string `json:"clusterId"`
+	// WindowSeconds is how far back the usage is looked at
+	WindowSeconds int64 `json:"windowSeconds"`
+	// MonthlySavings is the sum of the workloads savings
+	MonthlySavings float64                   `json:"monthlySavings"`
+	Items          []*WorkloadRecommendation `json:"items"`
+}
+
+type WorkloadRecommendation struct {
+	Namespace    string `json:"namespace"`
+	WorkloadName string `json:"workloadName"`
+	// WorkloadType could be daemonset/statefulset/deployment
+	WorkloadType string `json:"workloadType"`
+	// PodCount is the average pod count in the window
+	PodCount   float64                    `json:"podCount"`
+	Containers []*ContainerRecommendation `json:"containers"`
+	// MonthlySavings is negative if the workload needs more resource
+	MonthlySavings float64 `json:"monthlySavings"`
+}
+
+type ContainerRecommendation struct {
+	ContainerName string `json:"containerName"`
+	// CPU is in cores, Memory is in GiB, both are per pod
+	CPU            ResourceRecommendation `json:"cpu"`
+	Memory         ResourceRecommendation `json:"memory"`
+	MonthlySavings float64                `json:"monthlySavings"`
+}
+
+type ResourceRecommendation struct {
+	CurrentRequest     float64 `json:"currentRequest"`
+	UsageP50           float64 `json:"usageP50"`
+	UsageP95           float64 `json:"usageP95"`
+	UsageP99           float64 `json:"usageP99"`
+	UsagePeak          float64 `json:"usagePeak"`
+	RecommendedRequest float64 `json:"recommendedRequest"`
+	// RecommendedLimit is not set if no limit is recommended
+	RecommendedLimit float64 `json:"recommendedLimit,omitempty"`
+	// RecommendedRequestQuantity/RecommendedLimitQuantity are the kubernetes quantities, such as 250m/512Mi
+	RecommendedRequestQuantity string `json:"recommendedRequestQuantity"`
+	RecommendedLimitQuantity   string `json:"recommendedLimitQuantity,omitempty"`
+}
+
+type SpotSavingsAnalysis struct {
+	ClusterId string `json:"clusterId"`
+	// WindowSeconds is how far back the requests and unit prices are averaged
+	WindowSeconds int64     `json:"windowSeconds"`
+	Rates         SpotRates `json:"rates"`
+	// OnDemandMonthlyCost is the cost of all the analyzed workloads' requests on on-demand nodes,
+	// SpotMonthlyCost is the cost once the suitable ones are moved to spot nodes
+	OnDemandMonthlyCost float64 `json:"onDemandMonthlyCost"`
+	SpotMonthlyCost     float64 `json:"spotMonthlyCost"`
+	// MonthlySavings is the sum of the suitable workloads savings
+	MonthlySavings float64 `json:"monthlySavings"`
+	// BlockedMonthlySavings is the sum of the unsuitable workloads savings, if their blockers were fixed
+	BlockedMonthlySavings float64                 `json:"blockedMonthlySavings"`
+	Namespaces            []*NamespaceSpotSavings `json:"namespaces"`
+	Workloads             []*WorkloadSpotSavings  `json:"workloads"`
+}
+
+// SpotRates are the unit prices the requests are priced with, in cores and GiB
+type SpotRates struct {
+	OnDemandCPUCoreHourlyPrice float64 `json:"onDemandCPUCoreHourlyPrice"`
+	OnDemandRAMGiBHourlyPrice  float64 `json:"onDemandRAMGiBHourlyPrice"`
+	SpotCPUCoreHourlyPrice     float64 `json:"spotCPUCoreHourlyPrice"`
+	SpotRAMGiBHourlyPrice      float64 `json:"spotRAMGiBHourlyPrice"`
+	// Observed is false if the spot prices are discounted from the on-demand ones
+	Observed bool `json:"observed"`
+}
+
+type NamespaceSpotSavings struct {
+	Namespace         string  `json:"namespace"`
+	Workloads         int     `json:"workloads"`
+	SuitableWorkloads int     `json:"suitableWorkloads"`
+	MonthlySavings    float64 `json:"monthlySavings"`
+	// BlockedMonthlySavings is the savings of the unsuitable workloads, if their blockers were fixed
+	BlockedMonthlySavings float64 `json:"blockedMonthlySavings"`
+}
+
+type WorkloadSpotSavings struct {
+	Namespace    string `json:"namespace"`
+	WorkloadName string `json:"workloadName"`
+	// WorkloadType could be statefulset/deployment
+	WorkloadType string `json:"workloadType"`
+	Suitable     bool   `json:"suitable"`
+	// Blockers could be single_replica/stateful/local_volume/no_pdb
+	Blockers []string `json:"blockers"`
+	// PodCount is the average pod count in the window
+	PodCount float64 `json:"podCount"`
+	// CPURequest is in cores, RAMRequest is in GiB, both are the sum of all the pods
+	CPURequest          float64 `json:"cpuRequest"`
+	RAMRequest          float64 `json:"ramRequest"`
+	OnDemandMonthlyCost float64 `json:"onDemandMonthlyCost"`
+	SpotMonthlyCost     float64 `json:"spotMonthlyCost"`
+	// MonthlySavings is what the workload saves on spot nodes, it's set even if the workload is unsuitable
+	MonthlySavings float64 `json:"monthlySavings"`
+}
+
+// NodeSimulationSnapshot is the input of the node instance type simulation, it could be
+// saved from the analyzer and simulated offline
+type NodeSimulationSnapshot struct {
+	ClusterId string `json:"clusterId"`
+	// Timestamp is when the pods and nodes were taken from the query backend
+	Timestamp int64               `json:"timestamp"`
+	NodePools []*NodePoolSnapshot `json:"nodePools"`
+	Catalog   []*InstanceTypeSpec `json:"catalog"`
+	// UnscheduledPods are not packed in any node pool
+	UnscheduledPods []*SimulationPod `json:"unscheduledPods,omitempty"`
+}
+
+// NodePoolSnapshot is the nodes with the same instance type and billing mode, and the pods on them
+type NodePoolSnapshot struct {
+	Name         string            `json:"name"`
+	InstanceType string            `json:"instanceType"`
+	BillingMode  string            `json:"billingMode"`
+	Region       string            `json:"region,omitempty"`
+	Nodes        []*SimulationNode `json:"nodes"`
+	Pods         []*SimulationPod  `json:"pods"`
+}
+
+type SimulationNode struct {
+	NodeName string `json:"nodeName"`
+	// CPUCores/RAMGiB are the allocatable resource of the node
+	CPUCores    float64 `json:"cpuCores"`
+	RAMGiB      float64 `json:"ramGiB"`
+	HourlyPrice float64 `json:"hourlyPrice"`
+}
+
+type SimulationPod struct {
+	Namespace string `json:"namespace"`
+	PodName   string `json:"podName"`
+	// CPUCores/RAMGiB are the requests of the pod
+	CPUCores float64 `json:"cpuCores"`
+	RAMGiB   float64 `json:"ramGiB"`
+}
+
+// InstanceTypeSpec is an instance type the pods could be packed on
+type InstanceTypeSpec struct {
+	InstanceType string `json:"instanceType"`
+	// CPUCores/RAMGiB are the allocatable resource of one node
+	CPUCores    float64 `json:"cpuCores"`
+	RAMGiB      float64 `json:"ramGiB"`
+	HourlyPrice float64 `json:"hourlyPrice"`
+}
+
+type NodeSimulationResult struct {
+	ClusterId string `json:"clusterId"`
+	Timestamp int64  `json:"timestamp"`
+	// CurrentMonthlyCost/ProposedMonthlyCost sum the current and the cheapest proposal of every node pool
+	CurrentMonthlyCost  float64               `json:"currentMonthlyCost"`
+	ProposedMonthlyCost float64               `json:"proposedMonthlyCost"`
+	MonthlySavings      float64               `json:"monthlySavings"`
+	NodePools           []*NodePoolSimulation `json:"nodePools"`
+	UnscheduledPods     int                   `json:"unscheduledPods,omitempty"`
+}
+
+type NodePoolSimulation struct {
+	Name         string `json:"name"`
+	InstanceType string `json:"instanceType"`
+	BillingMode  string `json:"billingMode"`
+	Region       string `json:"region,omitempty"`
+	PodCount     int    `json:"podCount"`
+	// Current is how the pods are placed now
+	Current NodePoolPlacement `json:"current"`
+	// Proposals are the instance type mixes cheaper than the current nodes, the cheapest first
+	Proposals []*NodePoolPlacement `json:"proposals"`
+}
+
+// NodePoolPlacement is a set of nodes holding all the pods of the node pool
+type NodePoolPlacement struct {
+	// InstanceTypes maps the instance type to the node count
+	InstanceTypes map[string]int `json:"instanceTypes"`
+	NodeCount     int            `json:"nodeCount"`
+	HourlyCost    float64        `json:"hourlyCost"`
+	MonthlyCost   float64        `json:"monthlyCost"`
+	// MonthlySavings is against the current nodes, it's zero for the current placement
+	MonthlySavings float64 `json:"monthlySavings,omitempty"`
+	// CPURequestRatio/RAMRequestRatio are the requests of the pods against the allocatable resource
+	CPURequestRatio float64 `json:"cpuRequestRatio"`
+	RAMRequestRatio float64 `json:"ramRequestRatio"`
 }
 
 type ClusterMetricsSummary struct {
@@ -0,0 +1,96 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"fmt"
+
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/request/bearertoken"
+	"k8s.io/apiserver/pkg/authentication/token/cache"
+	"k8s.io/apiserver/pkg/authentication/token/tokenfile"
+	"k8s.io/apiserver/pkg/authentication/token/union"
+	"k8s.io/apiserver/pkg/server/dynamiccertificates"
+	"k8s.io/apiserver/plugin/pkg/authenticator/token/oidc"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+)
+
+// NewAuthenticator builds the bearer token authenticator from the config, the token
+// is tried against static tokens, OIDC and TokenReview in order
+func NewAuthenticator(config *AuthenticationConfig) (authenticator.Request, error) {
+	var tokenAuthenticators []authenticator.Token
+
+	if config.TokenFile != "" {
+		tokenAuthenticator, err := tokenfile.NewCSV(config.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("load token file error:%v", err)
+		}
+		tokenAuthenticators = append(tokenAuthenticators, tokenAuthenticator)
+		klog.Infof("Static token authentication is enabled")
+	}
+
+	if config.OIDC.IssuerURL != "" {
+		oidcOpts := oidc.Options{
+			IssuerURL:      config.OIDC.IssuerURL,
+			ClientID:       config.OIDC.ClientID,
+			UsernameClaim:  config.OIDC.UsernameClaim,
+			UsernamePrefix: config.OIDC.UsernamePrefix,
+			GroupsClaim:    config.OIDC.GroupsClaim,
+			GroupsPrefix:   config.OIDC.GroupsPrefix,
+		}
+		if config.OIDC.CAFile != "" {
+			caContent, err := dynamiccertificates.NewDynamicCAContentFromFile("oidc-ca", config.OIDC.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("load oidc ca file error:%v", err)
+			}
+			oidcOpts.CAContentProvider = caContent
+		}
+		tokenAuthenticator, err := oidc.New(oidcOpts)
+		if err != nil {
+			return nil, fmt.Errorf("create oidc authenticator error:%v", err)
+		}
+		tokenAuthenticators = append(tokenAuthenticators, tokenAuthenticator)
+		klog.Infof("OIDC authentication is enabled, issuer:%s", config.OIDC.IssuerURL)
+	}
+
+	if config.TokenReview {
+		restConfig, err := rest.InClusterConfig()
+		if err != nil {
+			return nil, fmt.Errorf("get in-cluster config for token review error:%v", err)
+		}
+		clientSet, err := kubernetes.NewForConfig(restConfig)
+		if err != nil {
+			return nil, err
+		}
+		tokenAuthenticators = append(tokenAuthenticators,
+			newTokenReviewAuthenticator(clientSet, config.TokenReviewAudiences))
+		klog.Infof("Kubernetes TokenReview authentication is enabled")
+	}
+
+	if len(tokenAuthenticators) == 0 {
+		return nil, fmt.Errorf("no authenticator is configured")
+	}
+
+	tokenAuthenticator := union.New(tokenAuthenticators...)
+	// TokenReview is a remote call, cache the result to not hit the apiserver on every dashboard request
+	if ttl := config.CacheTTL.Duration; ttl > 0 {
+		tokenAuthenticator = cache.New(tokenAuthenticator, false, ttl, ttl/2)
+	}
+	return bearertoken.New(tokenAuthenticator), nil
+}
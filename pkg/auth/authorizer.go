@@ -0,0 +1,254 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"sigs.k8s.io/yaml"
+)
+
+const accessContextKey = "kubefin.io/access"
+
+// Authorizer resolves what an authenticated identity could access from the grants
+type Authorizer struct {
+	grants []*compiledGrant
+}
+
+// compiledGrant keeps the patterns of the grant compiled once, a nil regexp matches nothing
+type compiledGrant struct {
+	Grant
+	tenants    *regexp.Regexp
+	clusterIds *regexp.Regexp
+	namespaces *regexp.Regexp
+}
+
+func NewAuthorizer(config *AuthorizationConfig) (*Authorizer, error) {
+	grants := append([]Grant{}, config.Grants...)
+	if config.PolicyFile != "" {
+		policy, err := LoadPolicy(config.PolicyFile)
+		if err != nil {
+			return nil, err
+		}
+		grants = append(grants, policy.Grants...)
+	}
+
+	authorizer := &Authorizer{}
+	for i, grant := range grants {
+		compiled := &compiledGrant{Grant: grant}
+		var err error
+		if compiled.tenants, err = compilePatterns(grant.Tenants); err != nil {
+			return nil, fmt.Errorf("grant %d tenants error:%v", i, err)
+		}
+		if compiled.clusterIds, err = compilePatterns(grant.ClusterIds); err != nil {
+			return nil, fmt.Errorf("grant %d clusterIds error:%v", i, err)
+		}
+		if compiled.namespaces, err = compilePatterns(grant.Namespaces); err != nil {
+			return nil, fmt.Errorf("grant %d namespaces error:%v", i, err)
+		}
+		authorizer.grants = append(authorizer.grants, compiled)
+	}
+	return authorizer, nil
+}
+
+func LoadPolicy(policyFile string) (*Policy, error) {
+	data, err := os.ReadFile(policyFile)
+	if err != nil {
+		return nil, fmt.Errorf("read policy file error:%v", err)
+	}
+	policy := &Policy{}
+	if err := yaml.UnmarshalStrict(data, policy); err != nil {
+		return nil, fmt.Errorf("parse policy file %s error:%v", policyFile, err)
+	}
+	return policy, nil
+}
+
+// Access is the grants matching one identity
+type Access struct {
+	User   user.Info
+	grants []*compiledGrant
+}
+
+// AccessFor collects all the grants matching the user or one of its groups
+func (a *Authorizer) AccessFor(u user.Info) *Access {
+	access := &Access{User: u}
+	groups := sets.NewString(u.GetGroups()...)
+	for _, grant := range a.grants {
+		if sets.NewString(grant.Users...).Has(u.GetName()) || groups.HasAny(grant.Groups...) {
+			access.grants = append(access.grants, grant)
+		}
+	}
+	return access
+}
+
+func (a *Access) TenantAllowed(tenantId string) bool {
+	for _, grant := range a.grants {
+		if matches(grant.tenants, tenantId) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClusterAllowed reports whether any data of the cluster, maybe only some namespaces, could be accessed
+func (a *Access) ClusterAllowed(clusterId string) bool {
+	for _, grant := range a.grants {
+		if matches(grant.clusterIds, clusterId) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClusterScopeAllowed reports whether the cluster level data could be accessed,
+// which requires all namespaces of the cluster are granted
+func (a *Access) ClusterScopeAllowed(clusterId string) bool {
+	_, all := a.NamespacePatterns(clusterId)
+	return all
+}
+
+// NamespacePatterns returns the namespace patterns granted in the cluster, all is true
+// if there's no restriction on the namespaces
+func (a *Access) NamespacePatterns(clusterId string) (patterns []string, all bool) {
+	for _, grant := range a.grants {
+		if !matches(grant.clusterIds, clusterId) {
+			continue
+		}
+		if len(grant.Namespaces) == 0 {
+			return nil, true
+		}
+		patterns = append(patterns, grant.Namespaces...)
+	}
+	return patterns, false
+}
+
+func (a *Access) NamespaceAllowed(clusterId, namespace string) bool {
+	for _, grant := range a.grants {
+		if !matches(grant.clusterIds, clusterId) {
+			continue
+		}
+		if len(grant.Namespaces) == 0 || matches(grant.namespaces, namespace) {
+			return true
+		}
+	}
+	return false
+}
+
+// SingleTenant returns the tenant if exactly one tenant without wildcards is granted,
+// it's used as the tenant of requests without the tenant header
+func (a *Access) SingleTenant() (string, bool) {
+	tenants := sets.NewString()
+	for _, grant := range a.grants {
+		tenants.Insert(grant.Tenants...)
+	}
+	if tenants.Len() != 1 {
+		return "", false
+	}
+	tenant := tenants.List()[0]
+	if strings.ContainsAny(tenant, "*?") {
+		return "", false
+	}
+	return tenant, true
+}
+
+func WithAccess(ctx *gin.Context, access *Access) {
+	ctx.Set(accessContextKey, access)
+}
+
+// AccessFromContext returns nil if authentication is disabled
+func AccessFromContext(ctx *gin.Context) *Access {
+	value, ok := ctx.Get(accessContextKey)
+	if !ok {
+		return nil
+	}
+	return value.(*Access)
+}
+
+// FilterClusters removes the clusters whose cluster level data the caller has no access
+// to from the map keyed by cluster id
+func FilterClusters[T any](ctx *gin.Context, clusters map[string]T) {
+	access := AccessFromContext(ctx)
+	if access == nil {
+		return
+	}
+	for clusterId := range clusters {
+		if !access.ClusterScopeAllowed(clusterId) {
+			delete(clusters, clusterId)
+		}
+	}
+}
+
+// NamespaceRegexFromContext returns the PromQL regex matching the namespaces the caller
+// could access in the cluster, it's empty if all namespaces could be accessed
+func NamespaceRegexFromContext(ctx *gin.Context, clusterId string) string {
+	access := AccessFromContext(ctx)
+	if access == nil {
+		return ""
+	}
+	patterns, all := access.NamespacePatterns(clusterId)
+	if all {
+		return ""
+	}
+	return patternsToRegex(patterns)
+}
+
+// FilterNamespaces keeps the items in the namespaces the caller could access, the
+// query is already restricted by NamespaceRegexFromContext, this is a second guard
+func FilterNamespaces[T any](ctx *gin.Context, clusterId string, items []T, namespaceOf func(T) string) []T {
+	access := AccessFromContext(ctx)
+	if access == nil {
+		return items
+	}
+	ret := make([]T, 0, len(items))
+	for _, item := range items {
+		if access.NamespaceAllowed(clusterId, namespaceOf(item)) {
+			ret = append(ret, item)
+		}
+	}
+	return ret
+}
+
+// compilePatterns compiles the patterns to one fully anchored regexp, it's nil if there's no pattern
+func compilePatterns(patterns []string) (*regexp.Regexp, error) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	return regexp.Compile("^(?:" + patternsToRegex(patterns) + ")$")
+}
+
+func matches(re *regexp.Regexp, value string) bool {
+	return re != nil && re.MatchString(value)
+}
+
+// patternsToRegex converts the wildcard patterns to one regex, PromQL regex is
+// always fully anchored so the anchors are added by the caller for go regexp
+func patternsToRegex(patterns []string) string {
+	res := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		re := regexp.QuoteMeta(pattern)
+		re = strings.ReplaceAll(re, `\*`, ".*")
+		re = strings.ReplaceAll(re, `\?`, ".")
+		res = append(res, re)
+	}
+	return strings.Join(res, "|")
+}
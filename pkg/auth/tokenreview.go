@@ -0,0 +1,72 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"context"
+	"fmt"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/client-go/kubernetes"
+)
+
+// tokenReviewAuthenticator validates the token by the kubernetes TokenReview API,
+// the analyzer service account needs the permission to create tokenreviews
+type tokenReviewAuthenticator struct {
+	clientSet kubernetes.Interface
+	audiences []string
+}
+
+func newTokenReviewAuthenticator(clientSet kubernetes.Interface, audiences []string) authenticator.Token {
+	return &tokenReviewAuthenticator{
+		clientSet: clientSet,
+		audiences: audiences,
+	}
+}
+
+func (t *tokenReviewAuthenticator) AuthenticateToken(ctx context.Context, token string) (*authenticator.Response, bool, error) {
+	review := &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{
+			Token:     token,
+			Audiences: t.audiences,
+		},
+	}
+	result, err := t.clientSet.AuthenticationV1().TokenReviews().Create(ctx, review, metav1.CreateOptions{})
+	if err != nil {
+		return nil, false, fmt.Errorf("create token review error:%v", err)
+	}
+	if !result.Status.Authenticated {
+		return nil, false, nil
+	}
+
+	extra := map[string][]string{}
+	for k, v := range result.Status.User.Extra {
+		extra[k] = v
+	}
+	return &authenticator.Response{
+		Audiences: result.Status.Audiences,
+		User: &user.DefaultInfo{
+			Name:   result.Status.User.Username,
+			UID:    result.Status.User.UID,
+			Groups: result.Status.User.Groups,
+			Extra:  extra,
+		},
+	}, true, nil
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AuthenticationConfig configures how the callers of the analyzer API are authenticated,
+// the API is open to everyone if none of the authenticators is enabled
+type AuthenticationConfig struct {
+	// TokenFile is a csv file of static API tokens, each line is: token,user,uid,"group1,group2"
+	TokenFile string `json:"tokenFile,omitempty"`
+	// OIDC validates the bearer token as an id token issued by the OIDC provider
+	OIDC OIDCConfig `json:"oidc,omitempty"`
+	// TokenReview validates the bearer token against the kubernetes apiserver, e.g. service account tokens
+	TokenReview bool `json:"tokenReview,omitempty"`
+	// TokenReviewAudiences are the audiences the token must be issued for, the apiserver audience is used if it's empty
+	TokenReviewAudiences []string `json:"tokenReviewAudiences,omitempty"`
+	// CacheTTL is how long the authentication result of a token is cached
+	CacheTTL metav1.Duration `json:"cacheTTL,omitempty"`
+}
+
+type OIDCConfig struct {
+	IssuerURL string `json:"issuerURL,omitempty"`
+	ClientID  string `json:"clientID,omitempty"`
+	// CAFile is used to verify the OIDC provider serving certificate, the host's root CA set is used if it's empty
+	CAFile         string `json:"caFile,omitempty"`
+	UsernameClaim  string `json:"usernameClaim,omitempty"`
+	UsernamePrefix string `json:"usernamePrefix,omitempty"`
+	GroupsClaim    string `json:"groupsClaim,omitempty"`
+	GroupsPrefix   string `json:"groupsPrefix,omitempty"`
+}
+
+// Enabled reports whether any authenticator is configured
+func (c *AuthenticationConfig) Enabled() bool {
+	return c.TokenFile != "" || c.OIDC.IssuerURL != "" || c.TokenReview
+}
+
+// AuthorizationConfig maps the authenticated identities to the data they could read
+type AuthorizationConfig struct {
+	// PolicyFile is a YAML file holding more grants, so the policy could be managed
+	// separately from the analyzer config, e.g. in a ConfigMap owned by the platform team
+	PolicyFile string  `json:"policyFile,omitempty"`
+	Grants     []Grant `json:"grants,omitempty"`
+}
+
+// Policy is the content of the policy file
+type Policy struct {
+	Grants []Grant `json:"grants,omitempty"`
+}
+
+// Grant gives the users and groups access to the tenants, clusters and namespaces.
+// Tenants, ClusterIds and Namespaces are patterns where "*" matches any characters
+// and "?" matches one character.
+type Grant struct {
+	Users      []string `json:"users,omitempty"`
+	Groups     []string `json:"groups,omitempty"`
+	Tenants    []string `json:"tenants,omitempty"`
+	ClusterIds []string `json:"clusterIds,omitempty"`
+	// Namespaces restricts the grant to these namespaces of the clusters, all namespaces
+	// are granted if it's empty. Cluster level data is hidden from namespace restricted grants.
+	Namespaces []string `json:"namespaces,omitempty"`
+}
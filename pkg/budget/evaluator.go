@@ -0,0 +1,233 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package budget
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+
+	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/query"
+	"github.com/kubefin/kubefin/pkg/server/implementation"
+	"github.com/kubefin/kubefin/pkg/values"
+)
+
+// DefaultThresholds are used if the budget has no thresholds
+var DefaultThresholds = []float64{80, 100}
+
+// Alert is fired once the actual or forecast cost of a budget crosses a threshold
+type Alert struct {
+	Status    api.BudgetStatus
+	Threshold float64
+	// Forecast means the forecast cost crossed the threshold, otherwise the actual cost did
+	Forecast bool
+}
+
+// Notifier delivers the budget alerts
+type Notifier interface {
+	Notify(ctx context.Context, alert *Alert) error
+}
+
+// Evaluator evaluates all the budgets periodically and keeps the latest status
+type Evaluator struct {
+	budgets         []api.Budget
+	defaultTenantId string
+	interval        time.Duration
+	notifier        Notifier
+	backend         query.QueryBackend
+
+	mutex    sync.RWMutex
+	statuses map[string]*api.BudgetStatus
+	// fired remembers the alerts sent in current period of each budget, so every
+	// threshold is only notified once per period
+	fired map[string]int64
+}
+
+// NewEvaluator creates the evaluator of the budgets, the budgets without tenant are set to defaultTenantId
+func NewEvaluator(config *Config, defaultTenantId string, backend query.QueryBackend, notifier Notifier) *Evaluator {
+	evaluator := &Evaluator{
+		defaultTenantId: defaultTenantId,
+		interval:        config.EvaluationInterval.Duration,
+		notifier:        notifier,
+		backend:         backend,
+		statuses:        make(map[string]*api.BudgetStatus),
+		fired:           make(map[string]int64),
+	}
+	for _, budget := range config.Items {
+		if budget.TenantId == "" {
+			budget.TenantId = defaultTenantId
+		}
+		thresholds := budget.Thresholds
+		if len(thresholds) == 0 {
+			thresholds = DefaultThresholds
+		}
+		// Sort a copy, the thresholds may be shared with the caller or the defaults
+		budget.Thresholds = append([]float64(nil), thresholds...)
+		sort.Float64s(budget.Thresholds)
+		evaluator.budgets = append(evaluator.budgets, budget)
+		evaluator.statuses[budget.Name] = &api.BudgetStatus{Budget: budget, State: api.BudgetStateOK}
+	}
+	return evaluator
+}
+
+func (e *Evaluator) Run(ctx context.Context) {
+	klog.Infof("Start evaluating %d budgets every %s", len(e.budgets), e.interval)
+	wait.UntilWithContext(ctx, e.evaluateAll, e.interval)
+}
+
+func (e *Evaluator) evaluateAll(ctx context.Context) {
+	now := time.Now()
+	for i := range e.budgets {
+		e.evaluate(ctx, &e.budgets[i], now)
+	}
+}
+
+func (e *Evaluator) evaluate(ctx context.Context, budget *api.Budget, now time.Time) {
+	periodStart, periodEnd := periodRange(budget.Period, now)
+
+	e.mutex.RLock()
+	status := *e.statuses[budget.Name]
+	e.mutex.RUnlock()
+	if status.PeriodStart != periodStart {
+		status = api.BudgetStatus{Budget: *budget, State: api.BudgetStateOK}
+	}
+	status.PeriodStart, status.PeriodEnd = periodStart, periodEnd
+	status.LastEvaluationTime = now.Unix()
+
+	cost, activeSeconds, err := implementation.QueryBudgetCost(ctx, e.backend, budget, periodStart, now.Unix())
+	if err != nil {
+		klog.Errorf("Evaluate budget %s error:%v", budget.Name, err)
+		status.Error = err.Error()
+		e.setStatus(&status)
+		return
+	}
+	status.Error = ""
+	status.ActualCost = cost
+	status.ForecastCost = cost
+	if activeSeconds > 0 {
+		periodHours := float64(periodEnd-periodStart) / values.HourInSeconds
+		status.ForecastCost = implementation.EstimateCost(cost, activeSeconds, periodHours)
+	}
+	status.ActualPercent = 100 * status.ActualCost / budget.Amount
+	status.ForecastPercent = 100 * status.ForecastCost / budget.Amount
+	switch {
+	case status.ActualPercent >= 100:
+		status.State = api.BudgetStateExceeded
+	case status.ForecastPercent >= 100:
+		status.State = api.BudgetStateAtRisk
+	default:
+		status.State = api.BudgetStateOK
+	}
+	e.setStatus(&status)
+
+	for _, threshold := range budget.Thresholds {
+		if status.ActualPercent >= threshold {
+			e.fire(ctx, &Alert{Status: status, Threshold: threshold})
+		} else if status.ForecastPercent >= threshold {
+			e.fire(ctx, &Alert{Status: status, Threshold: threshold, Forecast: true})
+		}
+	}
+}
+
+func (e *Evaluator) setStatus(status *api.BudgetStatus) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.statuses[status.Name] = status
+}
+
+// fire notifies the alert if it has not been sent in current period, the alert is
+// retried in the next evaluation if it failed
+func (e *Evaluator) fire(ctx context.Context, alert *Alert) {
+	key := fmt.Sprintf("%s/%v/%v", alert.Status.Name, alert.Threshold, alert.Forecast)
+	e.mutex.RLock()
+	firedPeriod, ok := e.fired[key]
+	e.mutex.RUnlock()
+	if ok && firedPeriod == alert.Status.PeriodStart {
+		return
+	}
+
+	if err := e.notifier.Notify(ctx, alert); err != nil {
+		klog.Errorf("Notify budget %s alert error:%v", alert.Status.Name, err)
+		return
+	}
+	e.mutex.Lock()
+	e.fired[key] = alert.Status.PeriodStart
+	e.mutex.Unlock()
+}
+
+// Statuses returns the status of the budgets in the tenant, an empty tenant means the default tenant
+func (e *Evaluator) Statuses(tenantId string) []*api.BudgetStatus {
+	if tenantId == "" {
+		tenantId = e.defaultTenantId
+	}
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	ret := make([]*api.BudgetStatus, 0, len(e.budgets))
+	for _, budget := range e.budgets {
+		if budget.TenantId != tenantId {
+			continue
+		}
+		status := *e.statuses[budget.Name]
+		ret = append(ret, &status)
+	}
+	return ret
+}
+
+// periodRange returns the start and end of the period containing now, weeks start on Monday
+func periodRange(period string, now time.Time) (int64, int64) {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if period == api.BudgetPeriodWeekly {
+		start := day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+		return start.Unix(), start.AddDate(0, 0, 7).Unix()
+	}
+	start := day.AddDate(0, 0, -day.Day()+1)
+	return start.Unix(), start.AddDate(0, 1, 0).Unix()
+}
+
+// LogNotifier writes the alerts to the log
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(ctx context.Context, alert *Alert) error {
+	kind := "actual"
+	if alert.Forecast {
+		kind = "forecast"
+	}
+	klog.Warningf("Budget %s %s cost %.2f is %.1f%% of the amount %.2f, crossed the threshold %v%%",
+		alert.Status.Name, kind, alertCost(alert), alertPercent(alert), alert.Status.Amount, alert.Threshold)
+	return nil
+}
+
+func alertCost(alert *Alert) float64 {
+	if alert.Forecast {
+		return alert.Status.ForecastCost
+	}
+	return alert.Status.ActualCost
+}
+
+func alertPercent(alert *Alert) float64 {
+	if alert.Forecast {
+		return alert.Status.ForecastPercent
+	}
+	return alert.Status.ActualPercent
+}
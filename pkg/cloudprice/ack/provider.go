@@ -62,18 +62,9 @@ type AckCloudProvider struct {
 }
 
 func NewAckCloudProvider(client kubernetes.Interface, agentOptions *options.AgentOptions) (*AckCloudProvider, error) {
-	var err error
-
-	cpuMemoryCostRatio := apis.DefaultCPUMemoryCostRatio
-	if agentOptions.CPUMemoryCostRatio != "" {
-		cpuMemoryCostRatio, err = strconv.ParseFloat(agentOptions.CPUMemoryCostRatio, 64)
-		if err != nil {
-			return nil, err
-		}
-	}
 	ackCloud := AckCloudProvider{
 		client:             client,
-		cpuMemoryCostRatio: cpuMemoryCostRatio,
+		cpuMemoryCostRatio: agentOptions.CPUMemoryCostRatio,
 		nodePriceMap:       map[string]map[string]float64{},
 		nodeSpecMap:        map[string]apis.NodeSpec{},
 	}
@@ -82,11 +73,6 @@ func NewAckCloudProvider(client kubernetes.Interface, agentOptions *options.Agen
 }
 
 func (c *AckCloudProvider) ParseClusterInfo(agentOptions *options.AgentOptions) error {
-	// We have no way to get the cluster name currently
-	if agentOptions.ClusterName == "" {
-		return fmt.Errorf("please set the cluster name via env CLUSTER_NAME in agent manifest")
-	}
-
 	if agentOptions.ClusterId != "" {
 		return nil
 	}
@@ -21,6 +21,6 @@ const (
 )
 
 type NodeSpec struct {
-	CPUCount   float64
-	RAMGBCount float64
+	CPUCount   float64 `json:"cpuCount"`
+	RAMGBCount float64 `json:"ramGBCount"`
 }
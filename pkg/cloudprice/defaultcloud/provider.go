@@ -18,13 +18,10 @@ package defaultcloud
 
 import (
 	"context"
-	"fmt"
-	"strconv"
 
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/klog/v2"
 
 	"github.com/kubefin/kubefin/cmd/kubefin-agent/app/options"
 	"github.com/kubefin/kubefin/pkg/api"
@@ -40,30 +37,17 @@ type DefaultCloudProvider struct {
 	client               kubernetes.Interface
 	CpuCoreHourlyPrice   float64
 	RamGBHourlyPrice     float64
-	NodeCPUCoreDeviation string
-	NodeRAMGBDeviation   string
+	NodeCPUCoreDeviation float64
+	NodeRAMGBDeviation   float64
 }
 
 func NewDefaultCloudProvider(client kubernetes.Interface, agentOptions *options.AgentOptions) (*DefaultCloudProvider, error) {
-	var err error
-
 	cpuCoreHourlyPrice, ramGBHourlyPrice := defaultCpuCoreHourlyPrice, defaultRamGBHourlyPrice
-	if agentOptions.CustomCPUCoreHourPrice != "" {
-		cpuCoreHourlyPrice, err = strconv.ParseFloat(agentOptions.CustomCPUCoreHourPrice, 64)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		cpuCoreHourlyPrice = defaultCpuCoreHourlyPrice
+	if agentOptions.CustomCPUCoreHourPrice != 0 {
+		cpuCoreHourlyPrice = agentOptions.CustomCPUCoreHourPrice
 	}
-
-	if agentOptions.CustomRAMGBHourPrice != "" {
-		ramGBHourlyPrice, err = strconv.ParseFloat(agentOptions.CustomRAMGBHourPrice, 64)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		ramGBHourlyPrice = defaultRamGBHourlyPrice
+	if agentOptions.CustomRAMGBHourPrice != 0 {
+		ramGBHourlyPrice = agentOptions.CustomRAMGBHourPrice
 	}
 
 	defaultCloud := DefaultCloudProvider{
@@ -78,10 +62,6 @@ func NewDefaultCloudProvider(client kubernetes.Interface, agentOptions *options.
 }
 
 func (c *DefaultCloudProvider) ParseClusterInfo(agentOptions *options.AgentOptions) error {
-	if agentOptions.ClusterName == "" {
-		return fmt.Errorf("please set the cluster name via env CLUSTER_NAME in agent manifest")
-	}
-
 	if agentOptions.ClusterId != "" {
 		return nil
 	}
@@ -101,31 +81,12 @@ func (c *DefaultCloudProvider) GetNodeHourlyPrice(node *v1.Node) (*api.InstanceP
 	cpuCores := cpuCoresQuantity.AsApproximateFloat64()
 	ramBytes := ramBytesQuantity.AsApproximateFloat64()
 
-	// we can't get real cpu/ram from node.status, take env and add it
-	var err error
-	CPUCoreDeviation := 0.0
-	if c.NodeCPUCoreDeviation != "" {
-		CPUCoreDeviation, err = strconv.ParseFloat(c.NodeCPUCoreDeviation, 64)
-		if err != nil {
-			klog.Errorf("Parse %s error:%v", c.NodeCPUCoreDeviation, err)
-			return nil, err
-		}
-	}
-
-	RAMGBDeviation := 0.0
-	if c.NodeRAMGBDeviation != "" {
-		RAMGBDeviation, err = strconv.ParseFloat(c.NodeRAMGBDeviation, 64)
-		if err != nil {
-			klog.Errorf("Parse %s error:%v", c.NodeRAMGBDeviation, err)
-			return nil, err
-		}
-	}
-
+	// we can't get real cpu/ram from node.status, take the configured deviation and add it
 	return &api.InstancePriceInfo{
 		NodeTotalHourlyPrice: c.CpuCoreHourlyPrice*cpuCores + c.RamGBHourlyPrice*(ramBytes/values.GBInBytes),
-		CPUCore:              cpuCores + CPUCoreDeviation,
+		CPUCore:              cpuCores + c.NodeCPUCoreDeviation,
 		CPUCoreHourlyPrice:   c.CpuCoreHourlyPrice,
-		RamGiB:               (ramBytes / values.GBInBytes) + RAMGBDeviation,
+		RamGiB:               (ramBytes / values.GBInBytes) + c.NodeRAMGBDeviation,
 		RAMGBHourlyPrice:     c.RamGBHourlyPrice,
 		InstanceType:         "default_instance_type",
 		BillingMode:          values.BillingModeOnDemand,
@@ -17,7 +17,6 @@ limitations under the License.
 package eks
 
 import (
-	"strconv"
 	"sync"
 
 	v1 "k8s.io/api/core/v1"
@@ -43,18 +42,9 @@ type EksCloudProvider struct {
 }
 
 func NewEksCloudProvider(client kubernetes.Interface, agentOptions *options.AgentOptions) (*EksCloudProvider, error) {
-	var err error
-
-	cpuMemoryCostRatio := cloudpriceapis.DefaultCPUMemoryCostRatio
-	if agentOptions.CPUMemoryCostRatio != "" {
-		cpuMemoryCostRatio, err = strconv.ParseFloat(agentOptions.CPUMemoryCostRatio, 64)
-		if err != nil {
-			return nil, err
-		}
-	}
 	eksCloud := EksCloudProvider{
 		client:             client,
-		cpuMemoryCostRatio: cpuMemoryCostRatio,
+		cpuMemoryCostRatio: agentOptions.CPUMemoryCostRatio,
 		nodePriceMap:       map[string]map[string]float64{},
 		nodeSpecMap:        map[string]cloudpriceapis.NodeSpec{},
 	}
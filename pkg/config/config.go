@@ -0,0 +1,102 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+)
+
+// Load fills obj from the YAML config file and the environment variables, then
+// re-applies the flags set explicitly in the command line, so the precedence is
+// defaults < config file < environment variables < command-line flags.
+func Load(flags *pflag.FlagSet, configFile string, obj interface{}, loadEnv func() error) error {
+	// Remember the explicitly set flags before they are overwritten
+	restoreFlags := map[string]func() error{}
+	flags.Visit(func(f *pflag.Flag) {
+		name := f.Name
+		if sliceValue, ok := f.Value.(pflag.SliceValue); ok {
+			value := append([]string{}, sliceValue.GetSlice()...)
+			restoreFlags[name] = func() error { return sliceValue.Replace(value) }
+			return
+		}
+		value := f.Value.String()
+		restoreFlags[name] = func() error { return flags.Set(name, value) }
+	})
+
+	if configFile != "" {
+		data, err := os.ReadFile(configFile)
+		if err != nil {
+			return fmt.Errorf("read config file(%s) error:%v", configFile, err)
+		}
+		if err := yaml.UnmarshalStrict(data, obj); err != nil {
+			return fmt.Errorf("parse config file(%s) error:%v", configFile, err)
+		}
+	}
+	if loadEnv != nil {
+		if err := loadEnv(); err != nil {
+			return err
+		}
+	}
+
+	for name, restore := range restoreFlags {
+		if err := restore(); err != nil {
+			return fmt.Errorf("reapply flag --%s error:%v", name, err)
+		}
+	}
+	return nil
+}
+
+// SetStringFromEnv overrides value with the environment variable if it's not empty
+func SetStringFromEnv(env string, value *string) {
+	if v := os.Getenv(env); v != "" {
+		*value = v
+	}
+}
+
+// SetFloatFromEnv overrides value with the environment variable if it's not empty
+func SetFloatFromEnv(env string, value *float64) error {
+	v := os.Getenv(env)
+	if v == "" {
+		return nil
+	}
+	parsed, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return fmt.Errorf("parse env %s(%s) error:%v", env, v, err)
+	}
+	*value = parsed
+	return nil
+}
+
+// SetDurationFromEnv overrides value with the environment variable if it's not empty
+func SetDurationFromEnv(env string, value *time.Duration) error {
+	v := os.Getenv(env)
+	if v == "" {
+		return nil
+	}
+	parsed, err := time.ParseDuration(v)
+	if err != nil {
+		return fmt.Errorf("parse env %s(%s) error:%v", env, v, err)
+	}
+	*value = parsed
+	return nil
+}
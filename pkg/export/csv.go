@@ -0,0 +1,53 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package export
+
+import (
+	"encoding/csv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func writeCSV(w gin.ResponseWriter, table *Table) error {
+	csvWriter := csv.NewWriter(w)
+	if err := csvWriter.Write(table.Header); err != nil {
+		return err
+	}
+
+	rows := 0
+	record := make([]string, len(table.Header))
+	err := table.Rows(func(row []interface{}) error {
+		for i, cell := range row {
+			record[i] = formatCell(cell)
+		}
+		if err := csvWriter.Write(record); err != nil {
+			return err
+		}
+		rows++
+		if rows%flushRows == 0 {
+			csvWriter.Flush()
+			w.Flush()
+		}
+		return csvWriter.Error()
+	})
+	if err != nil {
+		return err
+	}
+
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package export
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/klog/v2"
+
+	"github.com/kubefin/kubefin/pkg/api"
+)
+
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+
+	ContentTypeCSV  = "text/csv"
+	ContentTypeXLSX = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+	// flushRows is how many rows are buffered before flushing to the client
+	flushRows = 1000
+)
+
+// Table is a flat view of the cost data, Rows streams the rows in order
+// and stops once write returns an error
+type Table struct {
+	Header []string
+	Rows   func(write func(row []interface{}) error) error
+}
+
+// FormatFromCtx returns the response format, the format query parameter takes
+// precedence over the Accept header, json is returned by default
+func FormatFromCtx(ctx *gin.Context) (string, error) {
+	switch format := ctx.Query(api.QueryFormatPara); format {
+	case FormatJSON, FormatCSV, FormatXLSX:
+		return format, nil
+	case "":
+	default:
+		return "", fmt.Errorf("format %s is not supported, should be one of json/csv/xlsx", format)
+	}
+
+	for _, accept := range strings.Split(ctx.GetHeader("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case ContentTypeCSV:
+			return FormatCSV, nil
+		case ContentTypeXLSX:
+			return FormatXLSX, nil
+		}
+	}
+	return FormatJSON, nil
+}
+
+// Forward streams the table as an attachment named fileName in the format
+func Forward(ctx *gin.Context, format, fileName string, table *Table) {
+	var contentType string
+	var write func(w gin.ResponseWriter, table *Table) error
+	switch format {
+	case FormatCSV:
+		contentType, write = ContentTypeCSV, writeCSV
+	case FormatXLSX:
+		contentType, write = ContentTypeXLSX, writeXLSX
+	default:
+		klog.Errorf("Export format %s is not supported", format)
+		ctx.Status(http.StatusNotAcceptable)
+		return
+	}
+
+	ctx.Header("Content-Type", contentType)
+	ctx.Header("Content-Disposition",
+		mime.FormatMediaType("attachment", map[string]string{"filename": fileName + "." + format}))
+	ctx.Status(http.StatusOK)
+	// The header has been sent once the first row is written, so the error could only be logged
+	if err := write(ctx.Writer, table); err != nil {
+		klog.Errorf("Export %s as %s error:%v", fileName, format, err)
+	}
+}
+
+// formatCell renders the cell as text, timestamps are in ISO 8601 format in UTC
+func formatCell(cell interface{}) string {
+	switch v := cell.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
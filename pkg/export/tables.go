@@ -0,0 +1,146 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package export
+
+import (
+	"sort"
+	"time"
+
+	"github.com/kubefin/kubefin/pkg/api"
+)
+
+// ClustersCostsSummaryTable has one row per cluster
+func ClustersCostsSummaryTable(list *api.ClusterCostsSummaryList) *Table {
+	items := append([]*api.ClusterCostsSummary{}, list.Items...)
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].ClusterId < items[j].ClusterId
+	})
+
+	return &Table{
+		Header: []string{"cluster_id", "cluster_name", "cloud_provider", "cluster_region", "connection_state",
+			"last_active_time", "month_cost_current", "month_estimate_cost", "avg_daily_cost", "avg_hourly_core_cost",
+			"month_forecast_cost", "month_forecast_lower", "month_forecast_upper"},
+		Rows: func(write func(row []interface{}) error) error {
+			for _, item := range items {
+				var monthForecast api.CostForecastValue
+				if item.ClusterMonthForecast != nil {
+					monthForecast = *item.ClusterMonthForecast
+				}
+				if err := write([]interface{}{item.ClusterId, item.ClusterName, item.CloudProvider, item.ClusterRegion,
+					item.ClusterConnectionSate, unixTime(item.LastActiveTime), item.ClusterMonthCostCurrent,
+					item.ClusterMonthEstimateCost, item.ClusterAvgDailyCost, item.ClusterAvgHourlyCoreCost,
+					monthForecast.Cost, monthForecast.Lower, monthForecast.Upper}); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// ResourceCostsTable has one row per timestamp
+func ResourceCostsTable(list *api.ClusterResourceCostList) *Table {
+	return &Table{
+		Header: []string{"cluster_id", "timestamp", "total_cost", "cost_on_demand_billing_mode",
+			"cost_spot_billing_mode", "cost_period_billing_mode", "cost_fallback_billing_mode",
+			"cpu_core_count", "cpu_core_usage", "cpu_cost", "ram_gib_count", "ram_gib_usage", "ram_cost",
+			"cpu_core_request", "ram_gib_request", "cpu_efficiency", "ram_efficiency", "cost_efficiency", "wasted_cost"},
+		Rows: func(write func(row []interface{}) error) error {
+			for _, item := range list.Items {
+				if err := write([]interface{}{list.ClusterId, unixTime(item.Timestamp), item.TotalCost,
+					item.CostOnDemandBillingMode, item.CostSpotBillingMode, item.CostPeriodBillingMode,
+					item.CostFallbackBillingMode, item.CPUCoreCount, item.CPUCoreUsage, item.CPUCost,
+					item.RAMGiBCount, item.RAMGiBUsage, item.RAMCost, item.CPUCoreRequest, item.RAMGiBRequest,
+					item.CPUEfficiency, item.RAMEfficiency, item.CostEfficiency, item.WastedCost}); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// WorkloadCostsTable has one row per workload and timestamp, the workloads keep the order of the list
+func WorkloadCostsTable(list *api.ClusterWorkloadCostList) *Table {
+	items := list.Items
+	return &Table{
+		Header: []string{"cluster_id", "namespace", "workload_type", "workload_name", "timestamp", "pod_count",
+			"cpu_core_request", "cpu_core_usage", "ram_gib_request", "ram_gib_usage", "total_cost",
+			"cpu_efficiency", "ram_efficiency", "cost_efficiency", "wasted_cost"},
+		Rows: func(write func(row []interface{}) error) error {
+			for _, item := range items {
+				for _, cost := range item.CostList {
+					if err := write([]interface{}{list.ClusterId, item.Namespace, item.WorkloadType, item.WorkloadName,
+						unixTime(cost.Timestamp), cost.PodCount, cost.CPUCoreRequest, cost.CPUCoreUsage,
+						cost.RAMGiBRequest, cost.RAMGiBUsage, cost.TotalCost, cost.CPUEfficiency,
+						cost.RAMEfficiency, cost.CostEfficiency, cost.WastedCost}); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// NamespaceCostsTable has one row per namespace and timestamp, the namespaces keep the order of the list
+func NamespaceCostsTable(list *api.ClusterNamespaceCostList) *Table {
+	items := list.Items
+	return &Table{
+		Header: []string{"cluster_id", "namespace", "timestamp", "pod_count",
+			"cpu_core_request", "cpu_core_usage", "ram_gib_request", "ram_gib_usage", "total_cost",
+			"cpu_efficiency", "ram_efficiency", "cost_efficiency", "wasted_cost"},
+		Rows: func(write func(row []interface{}) error) error {
+			for _, item := range items {
+				for _, cost := range item.CostList {
+					if err := write([]interface{}{list.ClusterId, item.Namespace, unixTime(cost.Timestamp),
+						cost.PodCount, cost.CPUCoreRequest, cost.CPUCoreUsage,
+						cost.RAMGiBRequest, cost.RAMGiBUsage, cost.TotalCost, cost.CPUEfficiency,
+						cost.RAMEfficiency, cost.CostEfficiency, cost.WastedCost}); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// NodeCostsTable has one row per node and timestamp, the nodes keep the order of the list
+func NodeCostsTable(list *api.ClusterNodeCostList) *Table {
+	return &Table{
+		Header: []string{"cluster_id", "node_name", "instance_type", "billing_mode", "region", "timestamp",
+			"hourly_price", "total_cost", "cpu_request_ratio", "cpu_usage_ratio", "ram_request_ratio",
+			"ram_usage_ratio", "idle_cost"},
+		Rows: func(write func(row []interface{}) error) error {
+			for _, item := range list.Items {
+				for _, cost := range item.CostList {
+					if err := write([]interface{}{list.ClusterId, item.NodeName, item.InstanceType, item.BillingMode,
+						item.Region, unixTime(cost.Timestamp), cost.HourlyPrice, cost.TotalCost, cost.CPURequestRatio,
+						cost.CPUUsageRatio, cost.RAMRequestRatio, cost.RAMUsageRatio, cost.IdleCost}); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func unixTime(timestamp int64) time.Time {
+	return time.Unix(timestamp, 0)
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The minimal parts of an Office Open XML workbook with one sheet, the sheet
+// uses inline strings so no shared string table needs to be held in memory
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+</Types>`
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="costs" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+</Relationships>`
+	xlsxSheetHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetFooter = `</sheetData></worksheet>`
+)
+
+func writeXLSX(w gin.ResponseWriter, table *Table) error {
+	zipWriter := zip.NewWriter(w)
+	for _, file := range []struct{ name, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	} {
+		part, err := zipWriter.Create(file.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(part, file.content); err != nil {
+			return err
+		}
+	}
+
+	part, err := zipWriter.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	sheet := bufio.NewWriter(part)
+	if _, err := sheet.WriteString(xlsxSheetHeader); err != nil {
+		return err
+	}
+
+	rowIndex := 1
+	header := make([]interface{}, len(table.Header))
+	for i, h := range table.Header {
+		header[i] = h
+	}
+	if err := writeXLSXRow(sheet, rowIndex, header); err != nil {
+		return err
+	}
+	err = table.Rows(func(row []interface{}) error {
+		rowIndex++
+		if err := writeXLSXRow(sheet, rowIndex, row); err != nil {
+			return err
+		}
+		if rowIndex%flushRows == 0 {
+			if err := sheet.Flush(); err != nil {
+				return err
+			}
+			if err := zipWriter.Flush(); err != nil {
+				return err
+			}
+			w.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := sheet.WriteString(xlsxSheetFooter); err != nil {
+		return err
+	}
+	if err := sheet.Flush(); err != nil {
+		return err
+	}
+	return zipWriter.Close()
+}
+
+// writeXLSXRow writes numbers as numeric cells so they could be summed in the
+// spreadsheet, the others including timestamps are written as text
+func writeXLSXRow(w *bufio.Writer, rowIndex int, row []interface{}) error {
+	if _, err := fmt.Fprintf(w, `<row r="%d">`, rowIndex); err != nil {
+		return err
+	}
+	for i, cell := range row {
+		ref := xlsxColumnName(i) + strconv.Itoa(rowIndex)
+		switch v := cell.(type) {
+		case float64:
+			// NaN and Inf are not valid numeric cells, leave them empty
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, `<c r="%s"><v>%s</v></c>`, ref, formatCell(v)); err != nil {
+				return err
+			}
+		case int64:
+			if _, err := fmt.Fprintf(w, `<c r="%s"><v>%s</v></c>`, ref, formatCell(v)); err != nil {
+				return err
+			}
+		default:
+			if _, err := fmt.Fprintf(w, `<c r="%s" t="inlineStr"><is><t>`, ref); err != nil {
+				return err
+			}
+			if err := xml.EscapeText(w, []byte(formatCell(v))); err != nil {
+				return err
+			}
+			if _, err := w.WriteString(`</t></is></c>`); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := w.WriteString(`</row>`)
+	return err
+}
+
+// xlsxColumnName converts the zero based column index to A, B, ..., Z, AA, AB...
+func xlsxColumnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
@@ -0,0 +1,292 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package forecast
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	ModelAverage         = "average"
+	ModelTrend           = "trend"
+	ModelTrendWeekly     = "trend+weekly"
+	ModelTrendWeeklyNode = "trend+weekly+nodes"
+
+	// ConfidenceLevel is the confidence level of the forecast intervals
+	ConfidenceLevel = 0.95
+	// zScore is the two sided z score of ConfidenceLevel
+	zScore = 1.96
+
+	// The least number of days to fit the trend, the weekly seasonality and the node count with
+	minTrendDays   = 7
+	minWeeklyDays  = 14
+	minRegressDays = 21
+)
+
+// Point is the cost of the day starting at Time
+type Point struct {
+	Time time.Time
+	Cost float64
+	// Nodes is the average node count of the day, it's only used by the cluster forecast
+	Nodes float64
+}
+
+// Model is the linear model of the daily cost fitted on the history:
+// cost = intercept + trend * day + weekday offset + nodes coefficient * nodes
+type Model struct {
+	Name string
+	// start is the first day of the history, the days are counted from it
+	start time.Time
+	// coefficients are the intercept, the trend, the weekday offsets from Monday to
+	// Saturday with Sunday as the baseline, then the node count
+	coefficients []float64
+	weekly       bool
+	nodes        bool
+	// sigma is the standard deviation of the residuals
+	sigma float64
+	// trendSE is the standard error of the trend, it widens the intervals over the horizon
+	trendSE float64
+	meanDay float64
+}
+
+// Value is a forecast cost with its interval at ConfidenceLevel
+type Value struct {
+	Cost  float64
+	Lower float64
+	Upper float64
+}
+
+// Fit fits the model on the daily points ordered by time, the node count is only used if
+// useNodes is true and it changed in the history. Fit returns nil if there is no point.
+func Fit(points []Point, useNodes bool) *Model {
+	n := len(points)
+	if n == 0 {
+		return nil
+	}
+	model := &Model{start: points[0].Time}
+
+	if n < minTrendDays {
+		mean, variance := meanVariance(costs(points))
+		model.Name = ModelAverage
+		model.coefficients = []float64{mean, 0}
+		model.sigma = math.Sqrt(variance)
+		return model
+	}
+
+	model.weekly = n >= minWeeklyDays
+	model.nodes = useNodes && n >= minRegressDays && nodesChanged(points)
+	switch {
+	case model.nodes:
+		model.Name = ModelTrendWeeklyNode
+	case model.weekly:
+		model.Name = ModelTrendWeekly
+	default:
+		model.Name = ModelTrend
+	}
+
+	x := make([][]float64, 0, n)
+	y := make([]float64, 0, n)
+	var sumDay float64
+	for _, point := range points {
+		day := model.day(point.Time)
+		sumDay += day
+		x = append(x, model.features(point.Time, point.Nodes))
+		y = append(y, point.Cost)
+	}
+	model.coefficients = leastSquares(x, y)
+	model.meanDay = sumDay / float64(n)
+
+	var sse, sxx float64
+	for i, point := range points {
+		residual := y[i] - dot(model.coefficients, x[i])
+		sse += residual * residual
+		day := model.day(point.Time) - model.meanDay
+		sxx += day * day
+	}
+	if dof := n - len(model.coefficients); dof > 0 {
+		model.sigma = math.Sqrt(sse / float64(dof))
+	}
+	if sxx > 0 {
+		model.trendSE = model.sigma / math.Sqrt(sxx)
+	}
+	return model
+}
+
+// Predict returns the cost of the day starting at t with the node count
+func (m *Model) Predict(t time.Time, nodes float64) float64 {
+	if m.Name == ModelAverage {
+		return m.coefficients[0]
+	}
+	return math.Max(0, dot(m.coefficients, m.features(t, nodes)))
+}
+
+// Sum forecasts the total cost between start and end, the node count is assumed to stay
+// the same. The partial days are prorated.
+func (m *Model) Sum(start, end time.Time, nodes float64) Value {
+	var total, days, dayOffsets float64
+	for dayStart := StartOfDay(start); dayStart.Before(end); dayStart = dayStart.AddDate(0, 0, 1) {
+		dayEnd := dayStart.AddDate(0, 0, 1)
+		from, to := maxTime(dayStart, start), minTime(dayEnd, end)
+		fraction := to.Sub(from).Seconds() / dayEnd.Sub(dayStart).Seconds()
+		total += fraction * m.Predict(dayStart, nodes)
+		days += fraction
+		dayOffsets += fraction * (m.day(dayStart) - m.meanDay)
+	}
+
+	// The daily residuals are assumed independent, the error of the trend grows with the
+	// distance from the middle of the history
+	variance := days*m.sigma*m.sigma + dayOffsets*dayOffsets*m.trendSE*m.trendSE
+	margin := zScore * math.Sqrt(variance)
+	return Value{Cost: total, Lower: math.Max(0, total-margin), Upper: total + margin}
+}
+
+func (m *Model) day(t time.Time) float64 {
+	return t.Sub(m.start).Hours() / 24
+}
+
+func (m *Model) features(t time.Time, nodes float64) []float64 {
+	features := []float64{1, m.day(t)}
+	if m.weekly {
+		weekday := t.Weekday()
+		for d := time.Monday; d <= time.Saturday; d++ {
+			if weekday == d {
+				features = append(features, 1)
+			} else {
+				features = append(features, 0)
+			}
+		}
+	}
+	if m.nodes {
+		features = append(features, nodes)
+	}
+	return features
+}
+
+// leastSquares solves the normal equations with a tiny ridge, so the collinear features
+// do not make the system singular
+func leastSquares(x [][]float64, y []float64) []float64 {
+	p := len(x[0])
+	a := make([][]float64, p)
+	for i := range a {
+		a[i] = make([]float64, p+1)
+	}
+	for row := range x {
+		for i := 0; i < p; i++ {
+			for j := 0; j < p; j++ {
+				a[i][j] += x[row][i] * x[row][j]
+			}
+			a[i][p] += x[row][i] * y[row]
+		}
+	}
+	for i := 0; i < p; i++ {
+		a[i][i] += 1e-9 * (1 + a[i][i])
+	}
+
+	// Gaussian elimination with partial pivoting
+	for col := 0; col < p; col++ {
+		pivot := col
+		for row := col + 1; row < p; row++ {
+			if math.Abs(a[row][col]) > math.Abs(a[pivot][col]) {
+				pivot = row
+			}
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		if a[col][col] == 0 {
+			continue
+		}
+		for row := col + 1; row < p; row++ {
+			factor := a[row][col] / a[col][col]
+			for k := col; k <= p; k++ {
+				a[row][k] -= factor * a[col][k]
+			}
+		}
+	}
+	coefficients := make([]float64, p)
+	for i := p - 1; i >= 0; i-- {
+		if a[i][i] == 0 {
+			continue
+		}
+		sum := a[i][p]
+		for j := i + 1; j < p; j++ {
+			sum -= a[i][j] * coefficients[j]
+		}
+		coefficients[i] = sum / a[i][i]
+	}
+	return coefficients
+}
+
+func nodesChanged(points []Point) bool {
+	for _, point := range points[1:] {
+		if math.Abs(point.Nodes-points[0].Nodes) >= 0.5 {
+			return true
+		}
+	}
+	return false
+}
+
+func costs(points []Point) []float64 {
+	ret := make([]float64, 0, len(points))
+	for _, point := range points {
+		ret = append(ret, point.Cost)
+	}
+	return ret
+}
+
+func meanVariance(values []float64) (float64, float64) {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	if len(values) < 2 {
+		return mean, 0
+	}
+	var sse float64
+	for _, v := range values {
+		sse += (v - mean) * (v - mean)
+	}
+	return mean, sse / float64(len(values)-1)
+}
+
+func dot(a, b []float64) float64 {
+	var sum float64
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+// StartOfDay returns the midnight of t in its time zone, the days are in the same time zone
+// as the month windows of the costs
+func StartOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package forecast
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestLeastSquares(t *testing.T) {
+	tests := []struct {
+		name string
+		x    [][]float64
+		y    []float64
+		// want is compared with the predictions, the coefficients of collinear features are not unique
+		want []float64
+	}{
+		{
+			name: "exact line",
+			x:    [][]float64{{1, 0}, {1, 1}, {1, 2}, {1, 3}},
+			y:    []float64{2, 5, 8, 11},
+			want: []float64{2, 5, 8, 11},
+		},
+		{
+			name: "noisy line",
+			x:    [][]float64{{1, 0}, {1, 1}, {1, 2}, {1, 3}},
+			y:    []float64{1, 3, 2, 4},
+			want: []float64{1.3, 2.1, 2.9, 3.7},
+		},
+		{
+			name: "two features",
+			x:    [][]float64{{1, 0, 0}, {1, 1, 0}, {1, 0, 1}, {1, 1, 1}},
+			y:    []float64{1, 3, 4, 6},
+			want: []float64{1, 3, 4, 6},
+		},
+		{
+			name: "collinear features",
+			x:    [][]float64{{1, 1, 2}, {1, 2, 4}, {1, 3, 6}},
+			y:    []float64{3, 5, 7},
+			want: []float64{3, 5, 7},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			coefficients := leastSquares(tt.x, tt.y)
+			for i := range tt.x {
+				if got := dot(coefficients, tt.x[i]); math.Abs(got-tt.want[i]) > 1e-6 {
+					t.Errorf("prediction of row %d = %v, want %v", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFit(t *testing.T) {
+	start := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+	dailyPoints := func(days int, cost func(day int) float64, nodes func(day int) float64) []Point {
+		points := make([]Point, 0, days)
+		for day := 0; day < days; day++ {
+			points = append(points, Point{Time: start.AddDate(0, 0, day), Cost: cost(day), Nodes: nodes(day)})
+		}
+		return points
+	}
+	constantNodes := func(int) float64 { return 3 }
+
+	tests := []struct {
+		name      string
+		points    []Point
+		useNodes  bool
+		wantModel string
+		// wantNext is the prediction of the day following the history
+		wantNext float64
+	}{
+		{
+			name:      "average of a short history",
+			points:    dailyPoints(3, func(day int) float64 { return float64(10 + day) }, constantNodes),
+			wantModel: ModelAverage,
+			wantNext:  11,
+		},
+		{
+			name:      "linear trend",
+			points:    dailyPoints(10, func(day int) float64 { return float64(10 + 2*day) }, constantNodes),
+			wantModel: ModelTrend,
+			wantNext:  30,
+		},
+		{
+			name: "weekly seasonality",
+			points: dailyPoints(28, func(day int) float64 {
+				// The history starts on Monday, the weekends cost less
+				if day%7 >= 5 {
+					return 5
+				}
+				return 20
+			}, constantNodes),
+			wantModel: ModelTrendWeekly,
+			wantNext:  20,
+		},
+		{
+			name:      "node count is not used if it never changed",
+			points:    dailyPoints(28, func(int) float64 { return 20 }, constantNodes),
+			useNodes:  true,
+			wantModel: ModelTrendWeekly,
+			wantNext:  20,
+		},
+		{
+			name: "node count",
+			points: dailyPoints(28, func(day int) float64 { return float64(5 * (3 + day%2)) },
+				func(day int) float64 { return float64(3 + day%2) }),
+			useNodes:  true,
+			wantModel: ModelTrendWeeklyNode,
+			// The next day has 3 nodes
+			wantNext: 15,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := Fit(tt.points, tt.useNodes)
+			if m.Name != tt.wantModel {
+				t.Fatalf("model = %s, want %s", m.Name, tt.wantModel)
+			}
+			next := tt.points[len(tt.points)-1].Time.AddDate(0, 0, 1)
+			nodes := tt.points[0].Nodes
+			if got := m.Predict(next, nodes); math.Abs(got-tt.wantNext) > 1e-3 {
+				t.Errorf("Predict() = %v, want %v", got, tt.wantNext)
+			}
+		})
+	}
+
+	if m := Fit(nil, false); m != nil {
+		t.Errorf("Fit(nil) = %v, want nil", m)
+	}
+}
+
+func TestSum(t *testing.T) {
+	start := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+	m := Fit([]Point{{Time: start, Cost: 10}, {Time: start.AddDate(0, 0, 1), Cost: 10}}, false)
+
+	tests := []struct {
+		name  string
+		start time.Time
+		end   time.Time
+		want  float64
+	}{
+		{name: "whole days", start: start.AddDate(0, 0, 2), end: start.AddDate(0, 0, 5), want: 30},
+		{name: "partial days are prorated", start: start.Add(12 * time.Hour), end: start.AddDate(0, 0, 2), want: 15},
+		{name: "empty range", start: start, end: start, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value := m.Sum(tt.start, tt.end, 0)
+			if math.Abs(value.Cost-tt.want) > 1e-9 {
+				t.Errorf("Sum() = %v, want %v", value.Cost, tt.want)
+			}
+			if value.Lower > value.Cost || value.Upper < value.Cost {
+				t.Errorf("Sum() interval [%v, %v] does not contain %v", value.Lower, value.Upper, value.Cost)
+			}
+		})
+	}
+}
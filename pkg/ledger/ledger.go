@@ -0,0 +1,292 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ledger
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+
+	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/query"
+	"github.com/kubefin/kubefin/pkg/values"
+)
+
+const (
+	hourSeconds = int64(3600)
+	daySeconds  = 24 * hourSeconds
+)
+
+// Filter selects the records, Kind, Granularity and the time range are required
+type Filter struct {
+	ClusterId   string
+	Kind        string
+	Granularity string
+	Namespace   string
+	// LabelKey and LabelValue select the label records of the pods with the label
+	LabelKey   string
+	LabelValue string
+	Start      int64
+	End        int64
+}
+
+// recordQuery sums the cost of a kind over an hour, the labels identify a record
+type recordQuery struct {
+	kind   string
+	metric string
+	by     []string
+	name   func(metric model.Metric) string
+}
+
+var recordQueries = []recordQuery{
+	{kind: api.LedgerKindCluster, metric: values.NodeTotalHourlyCostMetricsName,
+		by: []string{values.ClusterIdLabelKey}},
+	{kind: api.LedgerKindNode, metric: values.NodeTotalHourlyCostMetricsName,
+		by: []string{values.ClusterIdLabelKey, values.NodeNameLabelKey},
+		name: func(metric model.Metric) string {
+			return string(metric[model.LabelName(values.NodeNameLabelKey)])
+		}},
+	{kind: api.LedgerKindNamespace, metric: values.PodResoueceCostMetricsName,
+		by: []string{values.ClusterIdLabelKey, values.NamespaceLabelKey}},
+	{kind: api.LedgerKindWorkload, metric: values.WorkloadResourceCostMetricsName,
+		by: []string{values.ClusterIdLabelKey, values.NamespaceLabelKey, values.WorkloadTypeLabelKey, values.WorkloadNameLabelKey},
+		name: func(metric model.Metric) string {
+			return string(metric[model.LabelName(values.WorkloadTypeLabelKey)]) + "/" + string(metric[model.LabelName(values.WorkloadNameLabelKey)])
+		}},
+	{kind: api.LedgerKindLabel, metric: values.PodResoueceCostMetricsName,
+		by: []string{values.ClusterIdLabelKey, values.NamespaceLabelKey, values.LabelsLabelKey},
+		name: func(metric model.Metric) string {
+			return string(metric[model.LabelName(values.LabelsLabelKey)])
+		}},
+}
+
+// Ledger records the cost of every finalized hour into the database, and the cost of every UTC day
+// once its last hour is recorded. The records are not changed by the retention of the query backend.
+type Ledger struct {
+	config          Config
+	defaultTenantId string
+	backend         query.QueryBackend
+	store           *store
+}
+
+// NewLedger opens the ledger database and creates the tables if they don't exist
+func NewLedger(config *Config, defaultTenantId string, backend query.QueryBackend) (*Ledger, error) {
+	store, err := newStore(config.Driver, config.DSN)
+	if err != nil {
+		return nil, err
+	}
+	l := &Ledger{
+		config:          *config,
+		defaultTenantId: defaultTenantId,
+		backend:         backend,
+		store:           store,
+	}
+	if len(l.config.TenantIds) == 0 {
+		l.config.TenantIds = []string{defaultTenantId}
+	}
+	return l, nil
+}
+
+func (l *Ledger) Close() error {
+	return l.store.Close()
+}
+
+func (l *Ledger) Run(ctx context.Context) {
+	klog.Infof("Start recording the finalized cost into the ledger every %s", l.config.Interval.Duration)
+	wait.UntilWithContext(ctx, l.recordAll, l.config.Interval.Duration)
+}
+
+func (l *Ledger) recordAll(ctx context.Context) {
+	for _, tenantId := range l.config.TenantIds {
+		if err := l.catchUp(ctx, tenantId, time.Now()); err != nil {
+			klog.Errorf("Record the cost ledger of tenant %s error:%v", tenantId, err)
+		}
+	}
+}
+
+// finalizedUntil returns the end of the last hour finalized at now
+func (l *Ledger) finalizedUntil(now time.Time) int64 {
+	return now.Add(-l.config.FinalizeDelay.Duration).Unix() / hourSeconds * hourSeconds
+}
+
+// catchUp records the hours finalized since the last record, the progress is kept after every hour
+// so the recording resumes where it stopped
+func (l *Ledger) catchUp(ctx context.Context, tenantId string, now time.Time) error {
+	until := l.finalizedUntil(now)
+	_, from, err := l.store.progress(ctx, tenantId)
+	if err != nil {
+		return err
+	}
+	if from == 0 {
+		// A new ledger starts from the last finalized hour, the hours before are left to the backfill
+		from = until - hourSeconds
+		if err := l.store.setRecordedFrom(ctx, tenantId, from); err != nil {
+			return err
+		}
+	}
+	if oldest := until - int64(l.config.MaxCatchUp.Seconds())/hourSeconds*hourSeconds; from < oldest {
+		klog.Warningf("Cost ledger of tenant %s is not recorded from %s to %s, backfill it with ledger-backfill",
+			tenantId, time.Unix(from, 0).UTC(), time.Unix(oldest, 0).UTC())
+		// The records before the gap are not counted as recorded until it's backfilled
+		from = oldest
+		if err := l.store.setRecordedFrom(ctx, tenantId, from); err != nil {
+			return err
+		}
+	}
+
+	return l.record(ctx, tenantId, from, until, func(hourEnd int64) error {
+		return l.store.setFinalizedUntil(ctx, tenantId, hourEnd)
+	})
+}
+
+// Backfill records the finalized hours from start to end again from the query backend, the days
+// with an hour in the range are summed again. The range is aligned to the hours. The ledger is
+// counted as recorded from start if the range reaches the hours recorded already.
+func (l *Ledger) Backfill(ctx context.Context, tenantId string, start, end int64) error {
+	if tenantId == "" {
+		tenantId = l.defaultTenantId
+	}
+	start = start / hourSeconds * hourSeconds
+	if until := l.finalizedUntil(time.Now()); end > until {
+		end = until
+	}
+	end = end / hourSeconds * hourSeconds
+	if start >= end {
+		return fmt.Errorf("no finalized hour to backfill between %s and %s",
+			time.Unix(start, 0).UTC(), time.Unix(end, 0).UTC())
+	}
+
+	klog.Infof("Backfill the cost ledger of tenant %s from %s to %s", tenantId,
+		time.Unix(start, 0).UTC(), time.Unix(end, 0).UTC())
+	if err := l.record(ctx, tenantId, start, end, nil); err != nil {
+		return err
+	}
+
+	recordedFrom, finalizedUntil, err := l.store.progress(ctx, tenantId)
+	if err != nil {
+		return err
+	}
+	// The day of the last hour is summed again if it's finalized, the hours after the range are recorded already
+	if dayStart := end / daySeconds * daySeconds; end%daySeconds != 0 && dayStart+daySeconds <= finalizedUntil {
+		if err := l.store.sumDay(ctx, tenantId, dayStart); err != nil {
+			return err
+		}
+	}
+	if finalizedUntil != 0 && start < recordedFrom && end >= recordedFrom {
+		return l.store.setRecordedFrom(ctx, tenantId, start)
+	}
+	return nil
+}
+
+// record queries and writes every hour from start to end, afterHour is called once an hour is written
+func (l *Ledger) record(ctx context.Context, tenantId string, start, end int64, afterHour func(hourEnd int64) error) error {
+	backend := l.backend.WithTenantId(tenantId)
+	for hourStart := start; hourStart < end; hourStart += hourSeconds {
+		records, err := l.queryHour(ctx, backend, tenantId, hourStart)
+		if err != nil {
+			return err
+		}
+		if err := l.store.upsert(ctx, records); err != nil {
+			return err
+		}
+
+		hourEnd := hourStart + hourSeconds
+		if hourEnd%daySeconds == 0 {
+			if err := l.store.sumDay(ctx, tenantId, hourEnd-daySeconds); err != nil {
+				return err
+			}
+		}
+		if afterHour != nil {
+			if err := afterHour(hourEnd); err != nil {
+				return err
+			}
+		}
+		klog.V(4).Infof("Recorded %d cost ledger records of tenant %s at %s", len(records), tenantId, time.Unix(hourStart, 0).UTC())
+	}
+	return nil
+}
+
+// queryHour sums the cost of every kind in the hour, the samples at the end of the hour are counted
+// and the ones at its start are not, as the other cost queries do
+func (l *Ledger) queryHour(ctx context.Context, backend query.QueryBackend, tenantId string, hourStart int64) ([]*api.CostLedgerRecord, error) {
+	var records []*api.CostLedgerRecord
+	for i := range recordQueries {
+		recordQuery := &recordQueries[i]
+		promql := fmt.Sprintf(query.QlCostByWithTimeRange, recordQuery.metric, hourSeconds, strings.Join(recordQuery.by, ","))
+		samples, err := backend.QueryInstantWithTime(ctx, promql, hourStart+hourSeconds)
+		if err != nil {
+			return nil, fmt.Errorf("query %s cost at %d error:%v", recordQuery.kind, hourStart, err)
+		}
+		for _, sample := range samples {
+			record := &api.CostLedgerRecord{
+				TenantId:    tenantId,
+				ClusterId:   string(sample.Metric[model.LabelName(values.ClusterIdLabelKey)]),
+				Kind:        recordQuery.kind,
+				Granularity: api.LedgerGranularityHourly,
+				Namespace:   string(sample.Metric[model.LabelName(values.NamespaceLabelKey)]),
+				StartTime:   hourStart,
+				Cost:        float64(sample.Value),
+			}
+			if recordQuery.name != nil {
+				record.Name = recordQuery.name(sample.Metric)
+			}
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+// Records returns the records of the tenant matching the filter, ordered by time
+func (l *Ledger) Records(ctx context.Context, tenantId string, filter *Filter) ([]*api.CostLedgerRecord, error) {
+	if tenantId == "" {
+		tenantId = l.defaultTenantId
+	}
+	return l.store.records(ctx, tenantId, filter)
+}
+
+// Coverage returns the start of the first hour and the end of the last hour recorded without gap for
+// the tenant, the days partly recorded are summed from the hours recorded. Both are 0 if nothing is recorded yet.
+func (l *Ledger) Coverage(ctx context.Context, tenantId string) (int64, int64, error) {
+	if tenantId == "" {
+		tenantId = l.defaultTenantId
+	}
+	return l.store.progress(ctx, tenantId)
+}
+
+// ForTenant returns the ledger reading the records of the tenant only
+func (l *Ledger) ForTenant(tenantId string) *TenantLedger {
+	return &TenantLedger{ledger: l, tenantId: tenantId}
+}
+
+// TenantLedger reads the records of a tenant, the cost queries of a request read the ledger through it
+type TenantLedger struct {
+	ledger   *Ledger
+	tenantId string
+}
+
+func (t *TenantLedger) Coverage(ctx context.Context) (int64, int64, error) {
+	return t.ledger.Coverage(ctx, t.tenantId)
+}
+
+func (t *TenantLedger) Records(ctx context.Context, filter *Filter) ([]*api.CostLedgerRecord, error) {
+	return t.ledger.Records(ctx, t.tenantId, filter)
+}
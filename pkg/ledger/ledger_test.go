@@ -0,0 +1,190 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ledger
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/query"
+	"github.com/kubefin/kubefin/pkg/query/querytest"
+	"github.com/kubefin/kubefin/pkg/values"
+)
+
+func TestTenantLedger(t *testing.T) {
+	ctx := context.Background()
+	l, err := NewLedger(&Config{Driver: DriverSQLite, DSN: filepath.Join(t.TempDir(), "ledger.db")},
+		"tenant-1", querytest.NewBackend())
+	if err != nil {
+		t.Fatalf("NewLedger() error:%v", err)
+	}
+	defer l.Close()
+
+	record := func(tenantId string, cost float64) *api.CostLedgerRecord {
+		return &api.CostLedgerRecord{TenantId: tenantId, ClusterId: "cluster-1", Kind: api.LedgerKindCluster,
+			Granularity: api.LedgerGranularityHourly, StartTime: 0, Cost: cost}
+	}
+	if err := l.store.upsert(ctx, []*api.CostLedgerRecord{record("tenant-1", 1), record("tenant-2", 2)}); err != nil {
+		t.Fatalf("upsert() error:%v", err)
+	}
+	if err := l.store.setRecordedFrom(ctx, "tenant-1", 0); err != nil {
+		t.Fatalf("setRecordedFrom() error:%v", err)
+	}
+	if err := l.store.setFinalizedUntil(ctx, "tenant-1", hourSeconds); err != nil {
+		t.Fatalf("setFinalizedUntil() error:%v", err)
+	}
+
+	tests := []struct {
+		name         string
+		tenantId     string
+		wantFinal    int64
+		wantCost     float64
+		wantRecorded bool
+	}{
+		{name: "default tenant", wantFinal: hourSeconds, wantCost: 1, wantRecorded: true},
+		{name: "tenant recorded", tenantId: "tenant-1", wantFinal: hourSeconds, wantCost: 1, wantRecorded: true},
+		{name: "tenant without progress", tenantId: "tenant-2", wantFinal: 0, wantCost: 2, wantRecorded: true},
+		{name: "tenant not recorded", tenantId: "tenant-3", wantFinal: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tenantLedger := l.ForTenant(tt.tenantId)
+			_, finalizedUntil, err := tenantLedger.Coverage(ctx)
+			if err != nil {
+				t.Fatalf("Coverage() error:%v", err)
+			}
+			if finalizedUntil != tt.wantFinal {
+				t.Errorf("finalizedUntil = %d, want %d", finalizedUntil, tt.wantFinal)
+			}
+
+			records, err := tenantLedger.Records(ctx, &Filter{Kind: api.LedgerKindCluster,
+				Granularity: api.LedgerGranularityHourly, Start: 0, End: hourSeconds})
+			if err != nil {
+				t.Fatalf("Records() error:%v", err)
+			}
+			if recorded := len(records) > 0; recorded != tt.wantRecorded {
+				t.Fatalf("recorded = %v, want %v", recorded, tt.wantRecorded)
+			}
+			if tt.wantRecorded && (len(records) != 1 || records[0].Cost != tt.wantCost) {
+				t.Errorf("records = %+v, want one record of cost %v", records, tt.wantCost)
+			}
+		})
+	}
+}
+
+func TestLedgerStartedMidDay(t *testing.T) {
+	ctx := context.Background()
+	backend := querytest.NewBackend()
+	// Every hour of the cluster costs 1
+	backend.SetInstant(fmt.Sprintf(query.QlCostByWithTimeRange, values.NodeTotalHourlyCostMetricsName, hourSeconds, values.ClusterIdLabelKey),
+		&model.Sample{Metric: model.Metric{model.LabelName(values.ClusterIdLabelKey): "cluster-1"}, Value: 1})
+	l, err := NewLedger(&Config{Driver: DriverSQLite, DSN: filepath.Join(t.TempDir(), "ledger.db"),
+		MaxCatchUp: metav1.Duration{Duration: 24 * time.Hour}}, "tenant-1", backend)
+	if err != nil {
+		t.Fatalf("NewLedger() error:%v", err)
+	}
+	defer l.Close()
+
+	dailyCost := func() float64 {
+		records, err := l.Records(ctx, "", &Filter{Kind: api.LedgerKindCluster,
+			Granularity: api.LedgerGranularityDaily, Start: 0, End: daySeconds})
+		if err != nil {
+			t.Fatalf("Records() error:%v", err)
+		}
+		if len(records) != 1 {
+			t.Fatalf("records = %+v, want one daily record", records)
+		}
+		return records[0].Cost
+	}
+	checkCoverage := func(wantFrom, wantUntil int64) {
+		from, until, err := l.Coverage(ctx, "")
+		if err != nil {
+			t.Fatalf("Coverage() error:%v", err)
+		}
+		if from != wantFrom || until != wantUntil {
+			t.Errorf("Coverage() = %d, %d, want %d, %d", from, until, wantFrom, wantUntil)
+		}
+	}
+
+	// The ledger starts at 12:30 of the first day, from the hour finalized last
+	if err := l.catchUp(ctx, "tenant-1", time.Unix(12*hourSeconds+1800, 0)); err != nil {
+		t.Fatalf("catchUp() error:%v", err)
+	}
+	checkCoverage(11*hourSeconds, 12*hourSeconds)
+	if err := l.catchUp(ctx, "tenant-1", time.Unix(25*hourSeconds, 0)); err != nil {
+		t.Fatalf("catchUp() error:%v", err)
+	}
+	checkCoverage(11*hourSeconds, 25*hourSeconds)
+	if cost := dailyCost(); cost != 13 {
+		t.Errorf("daily cost of the day partly recorded = %v, want 13", cost)
+	}
+
+	// The backfill reaching the recorded hours completes the first day
+	if err := l.Backfill(ctx, "", 0, 11*hourSeconds); err != nil {
+		t.Fatalf("Backfill() error:%v", err)
+	}
+	checkCoverage(0, 25*hourSeconds)
+	if cost := dailyCost(); cost != 24 {
+		t.Errorf("daily cost of the day backfilled = %v, want 24", cost)
+	}
+}
+
+func TestNewLedgerMigratesRecordedFrom(t *testing.T) {
+	ctx := context.Background()
+	dsn := filepath.Join(t.TempDir(), "ledger.db")
+	// The ledger database created before recorded_from was kept
+	s, err := newStore(DriverSQLite, dsn)
+	if err != nil {
+		t.Fatalf("newStore() error:%v", err)
+	}
+	for _, statement := range []string{
+		`DROP TABLE ledger_progress`,
+		`CREATE TABLE ledger_progress (tenant_id VARCHAR(255) PRIMARY KEY, finalized_until BIGINT NOT NULL)`,
+		`INSERT INTO ledger_progress (tenant_id, finalized_until) VALUES ('tenant-1', 30 * 3600), ('tenant-2', 30 * 3600)`,
+	} {
+		if _, err := s.db.Exec(statement); err != nil {
+			t.Fatalf("Exec(%s) error:%v", statement, err)
+		}
+	}
+	if err := s.upsert(ctx, []*api.CostLedgerRecord{{TenantId: "tenant-1", ClusterId: "cluster-1", Kind: api.LedgerKindCluster,
+		Granularity: api.LedgerGranularityHourly, StartTime: 10 * hourSeconds, Cost: 1}}); err != nil {
+		t.Fatalf("upsert() error:%v", err)
+	}
+	s.Close()
+
+	l, err := NewLedger(&Config{Driver: DriverSQLite, DSN: dsn}, "tenant-1", querytest.NewBackend())
+	if err != nil {
+		t.Fatalf("NewLedger() error:%v", err)
+	}
+	defer l.Close()
+	for tenantId, wantFrom := range map[string]int64{"tenant-1": 10 * hourSeconds, "tenant-2": 30 * hourSeconds} {
+		from, until, err := l.Coverage(ctx, tenantId)
+		if err != nil {
+			t.Fatalf("Coverage() error:%v", err)
+		}
+		if from != wantFrom || until != 30*hourSeconds {
+			t.Errorf("Coverage(%s) = %d, %d, want %d, %d", tenantId, from, until, wantFrom, 30*hourSeconds)
+		}
+	}
+}
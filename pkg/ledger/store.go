@@ -0,0 +1,247 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ledger
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	// The drivers of the ledger databases, sqlite is pure go so the analyzer needs no cgo
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+
+	"github.com/kubefin/kubefin/pkg/api"
+)
+
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS cost_records (
+		granularity VARCHAR(16) NOT NULL,
+		kind VARCHAR(16) NOT NULL,
+		tenant_id VARCHAR(255) NOT NULL,
+		cluster_id VARCHAR(255) NOT NULL,
+		namespace VARCHAR(255) NOT NULL,
+		name TEXT NOT NULL,
+		start_time BIGINT NOT NULL,
+		cost DOUBLE PRECISION NOT NULL,
+		PRIMARY KEY (granularity, kind, tenant_id, cluster_id, namespace, name, start_time)
+	)`,
+	`CREATE INDEX IF NOT EXISTS cost_records_time ON cost_records (tenant_id, granularity, kind, start_time)`,
+	`CREATE TABLE IF NOT EXISTS ledger_progress (
+		tenant_id VARCHAR(255) PRIMARY KEY,
+		recorded_from BIGINT NOT NULL DEFAULT 0,
+		finalized_until BIGINT NOT NULL
+	)`,
+}
+
+const (
+	upsertRecordSql = `INSERT INTO cost_records (granularity, kind, tenant_id, cluster_id, namespace, name, start_time, cost)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (granularity, kind, tenant_id, cluster_id, namespace, name, start_time) DO UPDATE SET cost = excluded.cost`
+	// sumDaySql sums the hourly records of a day into the daily ones, the WHERE clause keeps sqlite
+	// from parsing the ON CONFLICT as a join constraint
+	sumDaySql = `INSERT INTO cost_records (granularity, kind, tenant_id, cluster_id, namespace, name, start_time, cost)
+		SELECT '` + api.LedgerGranularityDaily + `', kind, tenant_id, cluster_id, namespace, name, CAST(? AS BIGINT), SUM(cost)
+		FROM cost_records WHERE granularity = '` + api.LedgerGranularityHourly + `' AND tenant_id = ? AND start_time >= ? AND start_time < ?
+		GROUP BY kind, tenant_id, cluster_id, namespace, name
+		ON CONFLICT (granularity, kind, tenant_id, cluster_id, namespace, name, start_time) DO UPDATE SET cost = excluded.cost`
+	selectProgressSql = `SELECT recorded_from, finalized_until FROM ledger_progress WHERE tenant_id = ?`
+	// upsertRecordedFromSql starts the records of the tenant from an hour, nothing after it is recorded yet
+	upsertRecordedFromSql = `INSERT INTO ledger_progress (tenant_id, recorded_from, finalized_until) VALUES (?, ?, ?)
+		ON CONFLICT (tenant_id) DO UPDATE SET recorded_from = excluded.recorded_from`
+	updateFinalizedUntilSql = `UPDATE ledger_progress SET finalized_until = ? WHERE tenant_id = ?`
+	// The ledgers created before recorded_from was kept are taken as recorded from their oldest hourly record
+	probeRecordedFromSql = `SELECT recorded_from FROM ledger_progress WHERE 1 = 0`
+	addRecordedFromSql   = `ALTER TABLE ledger_progress ADD COLUMN recorded_from BIGINT NOT NULL DEFAULT 0`
+	fillRecordedFromSql  = `UPDATE ledger_progress SET recorded_from = COALESCE((SELECT MIN(start_time) FROM cost_records
+		WHERE cost_records.tenant_id = ledger_progress.tenant_id AND granularity = '` + api.LedgerGranularityHourly + `'), finalized_until)`
+	selectRecordsSql = `SELECT granularity, kind, tenant_id, cluster_id, namespace, name, start_time, cost
+		FROM cost_records WHERE tenant_id = ? AND granularity = ? AND kind = ? AND start_time >= ? AND start_time < ?`
+)
+
+// store keeps the cost records in sqlite or postgres, the statements are written with ? placeholders
+type store struct {
+	db     *sql.DB
+	driver string
+}
+
+func newStore(driver, dsn string) (*store, error) {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open ledger database error:%v", err)
+	}
+	s := &store{db: db, driver: driver}
+	if driver == DriverSQLite {
+		// sqlite allows one writer, the single connection serializes the writes of the analyzer
+		db.SetMaxOpenConns(1)
+		for _, pragma := range []string{"PRAGMA journal_mode=WAL", "PRAGMA busy_timeout=10000"} {
+			if _, err := db.Exec(pragma); err != nil {
+				db.Close()
+				return nil, fmt.Errorf("set ledger database %s error:%v", pragma, err)
+			}
+		}
+	}
+	for _, statement := range schema {
+		if _, err := db.Exec(statement); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("create ledger schema error:%v", err)
+		}
+	}
+	if _, err := db.Exec(probeRecordedFromSql); err != nil {
+		for _, statement := range []string{addRecordedFromSql, fillRecordedFromSql} {
+			if _, err := db.Exec(statement); err != nil {
+				db.Close()
+				return nil, fmt.Errorf("migrate ledger schema error:%v", err)
+			}
+		}
+	}
+	return s, nil
+}
+
+func (s *store) Close() error {
+	return s.db.Close()
+}
+
+// rebind replaces the ? placeholders with $n for postgres
+func (s *store) rebind(statement string) string {
+	if s.driver != DriverPostgres {
+		return statement
+	}
+	var builder strings.Builder
+	n := 0
+	for _, c := range statement {
+		if c == '?' {
+			n++
+			builder.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		builder.WriteRune(c)
+	}
+	return builder.String()
+}
+
+// upsert writes the records in a transaction, the records recorded before are replaced
+func (s *store) upsert(ctx context.Context, records []*api.CostLedgerRecord) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin ledger transaction error:%v", err)
+	}
+	defer tx.Rollback()
+
+	statement, err := tx.PrepareContext(ctx, s.rebind(upsertRecordSql))
+	if err != nil {
+		return fmt.Errorf("prepare ledger statement error:%v", err)
+	}
+	defer statement.Close()
+	for _, record := range records {
+		if _, err := statement.ExecContext(ctx, record.Granularity, record.Kind, record.TenantId,
+			record.ClusterId, record.Namespace, record.Name, record.StartTime, record.Cost); err != nil {
+			return fmt.Errorf("write ledger record error:%v", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit ledger transaction error:%v", err)
+	}
+	return nil
+}
+
+// sumDay records the daily records of the day from its hourly records
+func (s *store) sumDay(ctx context.Context, tenantId string, dayStart int64) error {
+	if _, err := s.db.ExecContext(ctx, s.rebind(sumDaySql), dayStart, tenantId, dayStart, dayStart+daySeconds); err != nil {
+		return fmt.Errorf("sum ledger records of day %d error:%v", dayStart, err)
+	}
+	return nil
+}
+
+// progress returns the start of the first hour and the end of the last hour recorded without gap
+// for the tenant, both are 0 if none is recorded
+func (s *store) progress(ctx context.Context, tenantId string) (int64, int64, error) {
+	var from, until int64
+	err := s.db.QueryRowContext(ctx, s.rebind(selectProgressSql), tenantId).Scan(&from, &until)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("read ledger progress error:%v", err)
+	}
+	return from, until, nil
+}
+
+func (s *store) setRecordedFrom(ctx context.Context, tenantId string, from int64) error {
+	if _, err := s.db.ExecContext(ctx, s.rebind(upsertRecordedFromSql), tenantId, from, from); err != nil {
+		return fmt.Errorf("write ledger progress error:%v", err)
+	}
+	return nil
+}
+
+// setFinalizedUntil moves the end of the records, the records of the tenant must be started by setRecordedFrom
+func (s *store) setFinalizedUntil(ctx context.Context, tenantId string, until int64) error {
+	if _, err := s.db.ExecContext(ctx, s.rebind(updateFinalizedUntilSql), until, tenantId); err != nil {
+		return fmt.Errorf("write ledger progress error:%v", err)
+	}
+	return nil
+}
+
+// records selects the records of the tenant matching the filter, ordered by time
+func (s *store) records(ctx context.Context, tenantId string, filter *Filter) ([]*api.CostLedgerRecord, error) {
+	statement := selectRecordsSql
+	args := []interface{}{tenantId, filter.Granularity, filter.Kind, filter.Start, filter.End}
+	if filter.ClusterId != "" {
+		statement += " AND cluster_id = ?"
+		args = append(args, filter.ClusterId)
+	}
+	if filter.Namespace != "" {
+		statement += " AND namespace = ?"
+		args = append(args, filter.Namespace)
+	}
+	if filter.LabelKey != "" {
+		statement += ` AND name LIKE ? ESCAPE '\'`
+		args = append(args, "%"+podLabelPattern(filter.LabelKey, filter.LabelValue)+"%")
+	}
+	statement += " ORDER BY start_time, cluster_id, namespace, name"
+
+	rows, err := s.db.QueryContext(ctx, s.rebind(statement), args...)
+	if err != nil {
+		return nil, fmt.Errorf("query ledger records error:%v", err)
+	}
+	defer rows.Close()
+
+	records := []*api.CostLedgerRecord{}
+	for rows.Next() {
+		record := &api.CostLedgerRecord{}
+		if err := rows.Scan(&record.Granularity, &record.Kind, &record.TenantId, &record.ClusterId,
+			&record.Namespace, &record.Name, &record.StartTime, &record.Cost); err != nil {
+			return nil, fmt.Errorf("read ledger record error:%v", err)
+		}
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read ledger records error:%v", err)
+	}
+	return records, nil
+}
+
+// podLabelPattern matches the label in the pod labels json with LIKE, as query.PodLabelMatcher does with regex
+func podLabelPattern(key, value string) string {
+	keyJson, _ := json.Marshal(key)
+	valueJson, _ := json.Marshal(value)
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(string(keyJson) + ":" + string(valueJson))
+}
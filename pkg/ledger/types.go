@@ -0,0 +1,43 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ledger
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// Config holds the cost ledger settings
+type Config struct {
+	Enabled bool `json:"enabled"`
+	// Driver could be sqlite/postgres
+	Driver string `json:"driver,omitempty"`
+	// DSN is the database file of sqlite, or the connection string of postgres
+	DSN string `json:"dsn,omitempty"`
+	// Interval is how often the finalized hours are recorded
+	Interval metav1.Duration `json:"interval,omitempty"`
+	// FinalizeDelay is how long after its end an hour is finalized, the late samples are counted before it
+	FinalizeDelay metav1.Duration `json:"finalizeDelay,omitempty"`
+	// MaxCatchUp is the longest range recorded after the analyzer was down, the hours before it are left to the backfill
+	MaxCatchUp metav1.Duration `json:"maxCatchUp,omitempty"`
+	// TenantIds are the tenants to record, only the default tenant is recorded if it's empty
+	TenantIds []string `json:"tenantIds,omitempty"`
+}
@@ -36,6 +36,7 @@ type AgentMetricsCollector struct {
 	nodeLevelMetricsCollector     *core.NodeLevelMetricsCollector
 	podLevelMetricsCollector      *core.PodLevelMetricsCollector
 	workloadLevelMetricsCollector *core.WorkloadLevelMetricsCollector
+	spotMetricsCollector          *core.SpotMetricsCollector
 }
 
 func NewAgentMetricsCollector(ctx context.Context,
@@ -46,7 +47,7 @@ func NewAgentMetricsCollector(ctx context.Context,
 	return &AgentMetricsCollector{
 		ctx:                       ctx,
 		agentOptions:              options,
-		interval:                  options.ScrapMetricsInterval,
+		interval:                  options.ScrapMetricsInterval.Duration,
 		clusterMetricsCollector:   core.NewClusterLevelMetricsCollector(provider, coreResourceInformerLister.NodeLister),
 		nodeLevelMetricsCollector: core.NewNodeLevelMetricsCollector(metricsClientSet, provider, coreResourceInformerLister),
 		podLevelMetricsCollector: core.NewPodLevelMetricsCollector(
@@ -60,6 +61,7 @@ func NewAgentMetricsCollector(ctx context.Context,
 			coreResourceInformerLister.DaemonSetLister,
 			coreResourceInformerLister.DeploymentLister,
 			coreResourceInformerLister.StatefulSetLister),
+		spotMetricsCollector: core.NewSpotMetricsCollector(coreResourceInformerLister),
 	}
 }
 
@@ -68,4 +70,5 @@ func (a *AgentMetricsCollector) StartAgentMetricsCollector() {
 	go a.podLevelMetricsCollector.StartCollectPodLevelMetrics(a.ctx, a.interval, a.agentOptions)
 	go a.workloadLevelMetricsCollector.StartCollectWorkloadLevelMetrics(a.ctx, a.interval, a.agentOptions)
 	go a.clusterMetricsCollector.StartCollectClusterLevelMetrics(a.ctx, a.interval, a.agentOptions)
+	go a.spotMetricsCollector.StartCollectSpotMetrics(a.ctx, a.interval, a.agentOptions)
 }
@@ -74,9 +74,10 @@ func NewPodLevelMetricsCollector(client *versioned.Clientset, provider cloudpric
 		// For multiple container pod, this metrics is needed for cpu/memory size recommendation
 		values.ContainerNameLabelKey,
 	}
+	// The node is needed to simulate packing the pods on other instance types
 	podResourceRequestGV := prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Name: values.PodResourceRequestMetricsName,
-		Help: "The pod container level resource requested"}, containerCareLabelKey)
+		Help: "The pod container level resource requested"}, append(containerCareLabelKey, values.NodeNameLabelKey))
 	podResourceUsageGV := prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Name: values.PodResourceUsageMetricsName,
 		Help: "The pod container level resource usage"}, containerCareLabelKey)
@@ -161,6 +162,7 @@ func (p *PodLevelMetricsCollector) collectPodResourceRequest(agentOptions *optio
 			values.ClusterNameLabelKey: agentOptions.ClusterName,
 			values.ClusterIdLabelKey:   agentOptions.ClusterId,
 			values.LabelsLabelKey:      string(podLabels),
+			values.NodeNameLabelKey:    pod.Spec.NodeName,
 		}
 		cpuRequest, memoryRequest := utils.ParsePodResourceRequest(pod.Spec.Containers)
 
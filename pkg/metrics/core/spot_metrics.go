@@ -0,0 +1,234 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	"github.com/kubefin/kubefin/cmd/kubefin-agent/app/options"
+	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/values"
+)
+
+// SpotMetricsCollector classifies whether the deployments/statefulsets could run on spot nodes,
+// a workload is suitable if it has more than one replica, keeps no state in persistent volumes,
+// uses no local volumes and is protected by a PodDisruptionBudget
+type SpotMetricsCollector struct {
+	coreResourceInformerLister *api.CoreResourceInformerLister
+
+	workloadSpotSuitableGV *prometheus.GaugeVec
+}
+
+// spotWorkload is the part of deployment/statefulset the suitability depends on
+type spotWorkload struct {
+	workloadType string
+	namespace    string
+	name         string
+	replicas     int32
+	stateful     bool
+	selector     *metav1.LabelSelector
+	template     *corev1.PodTemplateSpec
+}
+
+func NewSpotMetricsCollector(coreResourceInformerLister *api.CoreResourceInformerLister) *SpotMetricsCollector {
+	labelKey := []string{
+		values.WorkloadTypeLabelKey,
+		values.WorkloadNameLabelKey,
+		values.NamespaceLabelKey,
+		values.ClusterNameLabelKey,
+		values.ClusterIdLabelKey,
+		values.SpotBlockersLabelKey,
+	}
+	workloadSpotSuitableGV := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: values.WorkloadSpotSuitableMetricsName,
+		Help: "Whether the workload could run on spot nodes"}, labelKey)
+
+	prometheus.MustRegister(workloadSpotSuitableGV)
+	return &SpotMetricsCollector{
+		coreResourceInformerLister: coreResourceInformerLister,
+		workloadSpotSuitableGV:     workloadSpotSuitableGV,
+	}
+}
+
+func (s *SpotMetricsCollector) StartCollectSpotMetrics(ctx context.Context,
+	interval time.Duration, agentOptions *options.AgentOptions) {
+	ticker := time.NewTicker(interval)
+
+	klog.Infof("Start collecting spot suitability metrics")
+	stopCh := ctx.Done()
+	for {
+		select {
+		case <-stopCh:
+			klog.Infof("Stop collecting spot suitability metrics")
+			return
+		case <-ticker.C:
+			s.collectSpotSuitabilityMetrics(agentOptions)
+		}
+	}
+}
+
+func (s *SpotMetricsCollector) collectSpotSuitabilityMetrics(agentOptions *options.AgentOptions) {
+	var workloads []*spotWorkload
+	deployments, err := s.coreResourceInformerLister.DeploymentLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("List all deployment error:%v", err)
+		return
+	}
+	for _, deployment := range deployments {
+		workloads = append(workloads, &spotWorkload{
+			workloadType: "deployment",
+			namespace:    deployment.Namespace,
+			name:         deployment.Name,
+			replicas:     replicasOrDefault(deployment.Spec.Replicas),
+			selector:     deployment.Spec.Selector,
+			template:     &deployment.Spec.Template,
+		})
+	}
+	statefulSets, err := s.coreResourceInformerLister.StatefulSetLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("List all statefulSet error:%v", err)
+		return
+	}
+	for _, statefulSet := range statefulSets {
+		workloads = append(workloads, &spotWorkload{
+			workloadType: "statefulset",
+			namespace:    statefulSet.Namespace,
+			name:         statefulSet.Name,
+			replicas:     replicasOrDefault(statefulSet.Spec.Replicas),
+			stateful:     true,
+			selector:     statefulSet.Spec.Selector,
+			template:     &statefulSet.Spec.Template,
+		})
+	}
+
+	// The blockers of a workload could change, reset to drop the stale series
+	s.workloadSpotSuitableGV.Reset()
+	for _, workload := range workloads {
+		blockers := s.spotBlockers(workload)
+		suitable := 0.0
+		if len(blockers) == 0 {
+			suitable = 1
+		}
+		s.workloadSpotSuitableGV.With(prometheus.Labels{
+			values.WorkloadTypeLabelKey: workload.workloadType,
+			values.WorkloadNameLabelKey: workload.name,
+			values.NamespaceLabelKey:    workload.namespace,
+			values.ClusterNameLabelKey:  agentOptions.ClusterName,
+			values.ClusterIdLabelKey:    agentOptions.ClusterId,
+			values.SpotBlockersLabelKey: strings.Join(blockers, ","),
+		}).Set(suitable)
+	}
+}
+
+func (s *SpotMetricsCollector) spotBlockers(workload *spotWorkload) []string {
+	var blockers []string
+	if workload.replicas <= 1 {
+		blockers = append(blockers, values.SpotBlockerSingleReplica)
+	}
+
+	stateful, localVolume := workload.stateful, false
+	claimNames := map[string]bool{}
+	for _, volume := range workload.template.Spec.Volumes {
+		if volume.HostPath != nil {
+			localVolume = true
+		}
+		if volume.PersistentVolumeClaim != nil {
+			stateful = true
+			claimNames[volume.PersistentVolumeClaim.ClaimName] = true
+		}
+	}
+	// The claims of statefulsets are only known from the pods
+	if selector, err := metav1.LabelSelectorAsSelector(workload.selector); err != nil {
+		klog.Errorf("Parse %s(%s/%s) selector error:%v", workload.workloadType, workload.namespace, workload.name, err)
+	} else if pods, err := s.coreResourceInformerLister.PodLister.Pods(workload.namespace).List(selector); err != nil {
+		klog.Errorf("List all pods error:%v", err)
+	} else {
+		for _, pod := range pods {
+			for _, volume := range pod.Spec.Volumes {
+				if volume.PersistentVolumeClaim != nil {
+					claimNames[volume.PersistentVolumeClaim.ClaimName] = true
+				}
+			}
+		}
+	}
+	for claimName := range claimNames {
+		if s.isLocalClaim(workload.namespace, claimName) {
+			localVolume = true
+			break
+		}
+	}
+	if stateful {
+		blockers = append(blockers, values.SpotBlockerStateful)
+	}
+	if localVolume {
+		blockers = append(blockers, values.SpotBlockerLocalVolume)
+	}
+
+	if !s.hasPodDisruptionBudget(workload.namespace, workload.template.Labels) {
+		blockers = append(blockers, values.SpotBlockerNoPDB)
+	}
+	return blockers
+}
+
+// isLocalClaim checks whether the claim is bound to a local or hostPath persistent volume
+func (s *SpotMetricsCollector) isLocalClaim(namespace, claimName string) bool {
+	claim, err := s.coreResourceInformerLister.PersistentVolumeClaimLister.
+		PersistentVolumeClaims(namespace).Get(claimName)
+	if err != nil || claim.Spec.VolumeName == "" {
+		return false
+	}
+	volume, err := s.coreResourceInformerLister.PersistentVolumeLister.Get(claim.Spec.VolumeName)
+	if err != nil {
+		return false
+	}
+	return volume.Spec.Local != nil || volume.Spec.HostPath != nil
+}
+
+func (s *SpotMetricsCollector) hasPodDisruptionBudget(namespace string, podLabels map[string]string) bool {
+	pdbs, err := s.coreResourceInformerLister.PodDisruptionBudgetLister.PodDisruptionBudgets(namespace).
+		List(labels.Everything())
+	if err != nil {
+		klog.Errorf("List all podDisruptionBudgets error:%v", err)
+		return false
+	}
+	for _, pdb := range pdbs {
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil {
+			continue
+		}
+		// An empty selector of policy/v1 matches all the pods in the namespace
+		if selector.Matches(labels.Set(podLabels)) {
+			return true
+		}
+	}
+	return false
+}
+
+func replicasOrDefault(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// alertmanagerSink posts the events as alerts to the Alertmanager v2 API, Alertmanager
+// resolves them after its resolve_timeout unless they're sent again
+type alertmanagerSink struct {
+	url    string
+	client *http.Client
+	labels map[string]string
+}
+
+type alertmanagerAlert struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+	StartsAt    time.Time         `json:"startsAt"`
+}
+
+func newAlertmanagerSink(config *AlertmanagerConfig) (*alertmanagerSink, error) {
+	return &alertmanagerSink{
+		url:    strings.TrimSuffix(config.URL, "/") + "/api/v2/alerts",
+		client: newHTTPClient(&config.HTTPConfig),
+		labels: config.Labels,
+	}, nil
+}
+
+func (a *alertmanagerSink) Send(ctx context.Context, event *Event) error {
+	alert := alertmanagerAlert{
+		Labels: map[string]string{
+			"alertname": event.Name,
+			"type":      event.Type,
+			"severity":  event.Severity,
+		},
+		Annotations: map[string]string{
+			"summary": event.Summary,
+		},
+		StartsAt: event.Time,
+	}
+	for key, value := range a.labels {
+		alert.Labels[key] = value
+	}
+	for key, value := range map[string]string{
+		"tenant_id":  event.TenantId,
+		"cluster_id": event.ClusterId,
+		"namespace":  event.Namespace,
+	} {
+		if value != "" {
+			alert.Labels[key] = value
+		}
+	}
+
+	body, err := json.Marshal([]alertmanagerAlert{alert})
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, a.client, a.url, nil, body)
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestAlertmanagerSinkSend(t *testing.T) {
+	server, requests := newRecordingServer(t, http.StatusOK)
+	sink, err := newAlertmanagerSink(&AlertmanagerConfig{
+		HTTPConfig: HTTPConfig{URL: server.URL + "/"},
+		Labels:     map[string]string{"team": "platform"},
+	})
+	if err != nil {
+		t.Fatalf("create sink error:%v", err)
+	}
+	event := newTestEvent()
+	if err := sink.Send(context.Background(), event); err != nil {
+		t.Fatalf("send error:%v", err)
+	}
+
+	request := <-requests
+	if request.path != "/api/v2/alerts" {
+		t.Errorf("path = %s, want /api/v2/alerts", request.path)
+	}
+	var alerts []alertmanagerAlert
+	if err := json.Unmarshal([]byte(request.body), &alerts); err != nil {
+		t.Fatalf("decode body %s error:%v", request.body, err)
+	}
+	if len(alerts) != 1 {
+		t.Fatalf("alerts = %s, want one alert", request.body)
+	}
+	wantLabels := map[string]string{
+		"alertname":  "cost-high",
+		"type":       TriggerTypeClusterCost,
+		"severity":   SeverityWarning,
+		"cluster_id": "prod-1",
+		"namespace":  "default",
+		"team":       "platform",
+	}
+	if !reflect.DeepEqual(alerts[0].Labels, wantLabels) {
+		t.Errorf("labels = %v, want %v", alerts[0].Labels, wantLabels)
+	}
+	if alerts[0].Annotations["summary"] != event.Summary || !alerts[0].StartsAt.Equal(event.Time) {
+		t.Errorf("alert = %+v, want the summary and start time of the event", alerts[0])
+	}
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notification
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kubefin/kubefin/pkg/anomaly"
+	"github.com/kubefin/kubefin/pkg/api"
+)
+
+type anomalyNotifier struct {
+	dispatcher *Dispatcher
+}
+
+// NewAnomalyNotifier sends the cost anomalies to all the sinks
+func NewAnomalyNotifier(dispatcher *Dispatcher) anomaly.Notifier {
+	return &anomalyNotifier{dispatcher: dispatcher}
+}
+
+func (a *anomalyNotifier) Notify(ctx context.Context, item *api.Anomaly) error {
+	target := "cluster " + item.ClusterId
+	switch item.Scope {
+	case api.AnomalyScopeNamespace:
+		target = fmt.Sprintf("namespace %s of cluster %s", item.Namespace, item.ClusterId)
+	case api.AnomalyScopeWorkload:
+		target = fmt.Sprintf("%s %s/%s of cluster %s", item.WorkloadType, item.Namespace, item.WorkloadName, item.ClusterId)
+	}
+
+	event := &Event{
+		Fingerprint: "anomaly/" + item.Id,
+		Name:        fmt.Sprintf("%s-cost-anomaly", item.Scope),
+		Type:        EventTypeAnomaly,
+		Severity:    item.Severity,
+		TenantId:    item.TenantId,
+		ClusterId:   item.ClusterId,
+		Namespace:   item.Namespace,
+		Summary: fmt.Sprintf("The %s cost of %s is %.2f at %s, the baseline is %.2f",
+			item.Granularity, target, item.Cost, time.Unix(item.Timestamp, 0).Format(time.RFC3339), item.BaselineCost),
+		Value:     item.Cost,
+		Threshold: item.BaselineCost,
+		Time:      time.Now(),
+	}
+	return a.dispatcher.Dispatch(ctx, event)
+}
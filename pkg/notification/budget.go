@@ -0,0 +1,64 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notification
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/budget"
+)
+
+type budgetNotifier struct {
+	dispatcher *Dispatcher
+}
+
+// NewBudgetNotifier sends the budget alerts to all the sinks
+func NewBudgetNotifier(dispatcher *Dispatcher) budget.Notifier {
+	return &budgetNotifier{dispatcher: dispatcher}
+}
+
+func (b *budgetNotifier) Notify(ctx context.Context, alert *budget.Alert) error {
+	status := &alert.Status
+	kind, cost, percent := "actual", status.ActualCost, status.ActualPercent
+	if alert.Forecast {
+		kind, cost, percent = "forecast", status.ForecastCost, status.ForecastPercent
+	}
+	severity := SeverityWarning
+	if status.State == api.BudgetStateExceeded {
+		severity = SeverityCritical
+	}
+
+	event := &Event{
+		// The budget evaluator makes sure the alert is only notified once per period
+		Fingerprint: fmt.Sprintf("budget/%s/%d/%s/%v", status.Name, status.PeriodStart, kind, alert.Threshold),
+		Name:        status.Name,
+		Type:        EventTypeBudget,
+		Severity:    severity,
+		TenantId:    status.TenantId,
+		ClusterId:   status.ClusterId,
+		Namespace:   status.Namespace,
+		Summary: fmt.Sprintf("Budget %s %s cost %.2f is %.1f%% of the %s amount %.2f, crossed the threshold %v%%",
+			status.Name, kind, cost, percent, status.Period, status.Amount, alert.Threshold),
+		Value:     cost,
+		Threshold: alert.Threshold * status.Amount / 100,
+		Time:      time.Now(),
+	}
+	return b.dispatcher.Dispatch(ctx, event)
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notification
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+// Sink delivers the events to an outbound integration
+type Sink interface {
+	Send(ctx context.Context, event *Event) error
+}
+
+// Dispatcher sends the events to the sinks with retry, the same event is not sent to
+// a sink again until RepeatInterval passed or the event is resolved
+type Dispatcher struct {
+	sinks          map[string]Sink
+	sinkNames      []string
+	repeatInterval time.Duration
+	backoff        wait.Backoff
+
+	mutex sync.Mutex
+	// sent is the last time an event is sent to a sink, keyed by sink name and fingerprint
+	sent map[string]time.Time
+}
+
+func NewDispatcher(config *Config) (*Dispatcher, error) {
+	dispatcher := &Dispatcher{
+		sinks:          make(map[string]Sink),
+		repeatInterval: config.RepeatInterval.Duration,
+		backoff: wait.Backoff{
+			Duration: config.Retry.Backoff.Duration,
+			Factor:   2,
+			Jitter:   0.1,
+			Steps:    config.Retry.Attempts,
+		},
+		sent: make(map[string]time.Time),
+	}
+	for i := range config.Sinks {
+		sinkConfig := &config.Sinks[i]
+		sink, err := newSink(sinkConfig)
+		if err != nil {
+			return nil, fmt.Errorf("create sink %s error:%v", sinkConfig.Name, err)
+		}
+		dispatcher.sinks[sinkConfig.Name] = sink
+		dispatcher.sinkNames = append(dispatcher.sinkNames, sinkConfig.Name)
+	}
+	return dispatcher, nil
+}
+
+func newSink(config *SinkConfig) (Sink, error) {
+	switch config.Type {
+	case SinkTypeWebhook:
+		return newWebhookSink(config.Webhook)
+	case SinkTypeSlack:
+		return newSlackSink(config.Slack)
+	case SinkTypeEmail:
+		return newEmailSink(config.Email), nil
+	case SinkTypeAlertmanager:
+		return newAlertmanagerSink(config.Alertmanager)
+	}
+	return nil, fmt.Errorf("unknown sink type:%s", config.Type)
+}
+
+// Dispatch sends the event to its sinks, the sinks failed are retried on the next dispatch
+func (d *Dispatcher) Dispatch(ctx context.Context, event *Event) error {
+	sinkNames := event.sinks
+	if len(sinkNames) == 0 {
+		sinkNames = d.sinkNames
+	}
+
+	var errs []error
+	for _, name := range sinkNames {
+		sink, ok := d.sinks[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("no such sink:%s", name))
+			continue
+		}
+		key := name + "/" + event.Fingerprint
+		if !d.shouldSend(key, event.Time) {
+			klog.V(4).Infof("Skip sending duplicated event %s to sink %s", event.Fingerprint, name)
+			continue
+		}
+		if err := d.send(ctx, name, sink, event); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		d.mutex.Lock()
+		d.sent[key] = event.Time
+		d.mutex.Unlock()
+	}
+	return errors.NewAggregate(errs)
+}
+
+// Resolve forgets the event, so it's sent immediately once it fires again
+func (d *Dispatcher) Resolve(fingerprint string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	for _, name := range d.sinkNames {
+		delete(d.sent, name+"/"+fingerprint)
+	}
+}
+
+func (d *Dispatcher) shouldSend(key string, now time.Time) bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	// The events sent before RepeatInterval would be sent again anyway
+	for sentKey, sentTime := range d.sent {
+		if now.Sub(sentTime) >= d.repeatInterval {
+			delete(d.sent, sentKey)
+		}
+	}
+	lastSent, ok := d.sent[key]
+	return !ok || now.Sub(lastSent) >= d.repeatInterval
+}
+
+func (d *Dispatcher) send(ctx context.Context, name string, sink Sink, event *Event) error {
+	var lastErr error
+	err := wait.ExponentialBackoffWithContext(ctx, d.backoff, func() (bool, error) {
+		if lastErr = sink.Send(ctx, event); lastErr != nil {
+			klog.Warningf("Send event %s to sink %s error:%v", event.Fingerprint, name, lastErr)
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil {
+		if lastErr == nil {
+			lastErr = err
+		}
+		klog.Errorf("Send event %s to sink %s error:%v", event.Fingerprint, name, lastErr)
+		return fmt.Errorf("send event to sink %s error:%v", name, lastErr)
+	}
+	klog.V(4).Infof("Sent event %s to sink %s", event.Fingerprint, name)
+	return nil
+}
@@ -0,0 +1,191 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notification
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// countingSink fails the first failures sends
+type countingSink struct {
+	failures int
+	sent     int
+}
+
+func (c *countingSink) Send(context.Context, *Event) error {
+	c.sent++
+	if c.sent <= c.failures {
+		return errors.New("unavailable")
+	}
+	return nil
+}
+
+func newTestDispatcher(attempts int, sinks map[string]Sink) *Dispatcher {
+	dispatcher, _ := NewDispatcher(&Config{
+		RepeatInterval: metav1.Duration{Duration: time.Hour},
+		Retry:          RetryConfig{Attempts: attempts, Backoff: metav1.Duration{Duration: time.Millisecond}},
+	})
+	for name, sink := range sinks {
+		dispatcher.sinks[name] = sink
+		dispatcher.sinkNames = append(dispatcher.sinkNames, name)
+	}
+	return dispatcher
+}
+
+func TestDispatcherRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		attempts  int
+		failures  int
+		wantErr   bool
+		wantSends int
+	}{
+		{name: "sent at the first attempt", attempts: 3, failures: 0, wantSends: 1},
+		{name: "sent after retries", attempts: 3, failures: 2, wantSends: 3},
+		{name: "attempts run out", attempts: 3, failures: 5, wantErr: true, wantSends: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &countingSink{failures: tt.failures}
+			dispatcher := newTestDispatcher(tt.attempts, map[string]Sink{"test": sink})
+			err := dispatcher.Dispatch(context.Background(), newTestEvent())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Dispatch() error = %v, want error %v", err, tt.wantErr)
+			}
+			if sink.sent != tt.wantSends {
+				t.Errorf("sends = %d, want %d", sink.sent, tt.wantSends)
+			}
+		})
+	}
+}
+
+func TestDispatcherRetryWebhook(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	dispatcher, err := NewDispatcher(&Config{
+		Sinks: []SinkConfig{{
+			Name:    "hook",
+			Type:    SinkTypeWebhook,
+			Webhook: &WebhookConfig{HTTPConfig: HTTPConfig{URL: server.URL}},
+		}},
+		RepeatInterval: metav1.Duration{Duration: time.Hour},
+		Retry:          RetryConfig{Attempts: 3, Backoff: metav1.Duration{Duration: time.Millisecond}},
+	})
+	if err != nil {
+		t.Fatalf("create dispatcher error:%v", err)
+	}
+	if err := dispatcher.Dispatch(context.Background(), newTestEvent()); err != nil {
+		t.Fatalf("Dispatch() error:%v", err)
+	}
+	if got := atomic.LoadInt32(&requests); got != 3 {
+		t.Errorf("requests = %d, want 3", got)
+	}
+}
+
+func TestDispatcherDedup(t *testing.T) {
+	start := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	type dispatch struct {
+		fingerprint string
+		after       time.Duration
+		resolve     bool
+	}
+	tests := []struct {
+		name       string
+		dispatches []dispatch
+		wantSends  int
+	}{
+		{
+			name:       "duplicated event in the repeat interval",
+			dispatches: []dispatch{{fingerprint: "a"}, {fingerprint: "a", after: 30 * time.Minute}},
+			wantSends:  1,
+		},
+		{
+			name:       "event repeated after the interval",
+			dispatches: []dispatch{{fingerprint: "a"}, {fingerprint: "a", after: time.Hour}},
+			wantSends:  2,
+		},
+		{
+			name:       "different events",
+			dispatches: []dispatch{{fingerprint: "a"}, {fingerprint: "b"}},
+			wantSends:  2,
+		},
+		{
+			name:       "resolved event fires again",
+			dispatches: []dispatch{{fingerprint: "a"}, {fingerprint: "a", after: time.Minute, resolve: true}},
+			wantSends:  2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &countingSink{}
+			dispatcher := newTestDispatcher(1, map[string]Sink{"test": sink})
+			for _, d := range tt.dispatches {
+				if d.resolve {
+					dispatcher.Resolve(d.fingerprint)
+				}
+				event := newTestEvent()
+				event.Fingerprint = d.fingerprint
+				event.Time = start.Add(d.after)
+				if err := dispatcher.Dispatch(context.Background(), event); err != nil {
+					t.Fatalf("Dispatch() error:%v", err)
+				}
+			}
+			if sink.sent != tt.wantSends {
+				t.Errorf("sends = %d, want %d", sink.sent, tt.wantSends)
+			}
+		})
+	}
+}
+
+func TestDispatcherFailedSinkRetriedOnNextDispatch(t *testing.T) {
+	sink := &countingSink{failures: 1}
+	dispatcher := newTestDispatcher(1, map[string]Sink{"test": sink})
+	if err := dispatcher.Dispatch(context.Background(), newTestEvent()); err == nil {
+		t.Fatal("Dispatch() to a failing sink, want error")
+	}
+	if err := dispatcher.Dispatch(context.Background(), newTestEvent()); err != nil {
+		t.Fatalf("Dispatch() error:%v", err)
+	}
+	if sink.sent != 2 {
+		t.Errorf("sends = %d, want the failed event sent again", sink.sent)
+	}
+}
+
+func TestDispatcherUnknownSink(t *testing.T) {
+	dispatcher := newTestDispatcher(1, nil)
+	event := newTestEvent()
+	event.sinks = []string{"missing"}
+	if err := dispatcher.Dispatch(context.Background(), event); err == nil {
+		t.Fatal("Dispatch() to an unknown sink, want error")
+	}
+}
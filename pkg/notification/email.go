@@ -0,0 +1,66 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notification
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+type emailSink struct {
+	config *EmailConfig
+}
+
+func newEmailSink(config *EmailConfig) *emailSink {
+	return &emailSink{config: config}
+}
+
+// Send sends the event by SMTP, the plain auth is only used over TLS or to localhost
+func (e *emailSink) Send(ctx context.Context, event *Event) error {
+	var auth smtp.Auth
+	if e.config.Username != "" {
+		host, _, err := net.SplitHostPort(e.config.Address)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", e.config.Username, e.config.Password, host)
+	}
+
+	subject := fmt.Sprintf("[KubeFin][%s] %s", event.Severity, event.Name)
+	message := strings.Join([]string{
+		"From: " + e.config.From,
+		"To: " + strings.Join(e.config.To, ", "),
+		"Subject: " + subject,
+		"Date: " + event.Time.Format(time.RFC1123Z),
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		event.Summary,
+		"",
+		"Type: " + event.Type,
+		"Tenant: " + event.TenantId,
+		"Cluster: " + event.ClusterId,
+		"Namespace: " + event.Namespace,
+		fmt.Sprintf("Value: %.2f", event.Value),
+		fmt.Sprintf("Threshold: %.2f", event.Threshold),
+	}, "\r\n")
+
+	return smtp.SendMail(e.config.Address, auth, e.config.From, e.config.To, []byte(message))
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notification
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+)
+
+// smtpMessage is a mail received by the test SMTP server
+type smtpMessage struct {
+	from string
+	to   []string
+	data string
+}
+
+// newSMTPServer serves the minimal SMTP dialog smtp.SendMail needs without STARTTLS and AUTH
+func newSMTPServer(t *testing.T) (string, chan smtpMessage) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error:%v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	messages := make(chan smtpMessage, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		reply := func(line string) {
+			_, _ = conn.Write([]byte(line + "\r\n"))
+		}
+
+		message := smtpMessage{}
+		reply("220 localhost ESMTP")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			command := strings.ToUpper(line)
+			switch {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(command, "MAIL FROM:"):
+				message.from = strings.Trim(line[len("MAIL FROM:"):], "<>")
+				reply("250 OK")
+			case strings.HasPrefix(command, "RCPT TO:"):
+				message.to = append(message.to, strings.Trim(line[len("RCPT TO:"):], "<>"))
+				reply("250 OK")
+			case command == "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				var data []string
+				for {
+					dataLine, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					dataLine = strings.TrimRight(dataLine, "\r\n")
+					if dataLine == "." {
+						break
+					}
+					data = append(data, dataLine)
+				}
+				message.data = strings.Join(data, "\n")
+				reply("250 OK")
+			case command == "QUIT":
+				reply("221 Bye")
+				messages <- message
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+	return listener.Addr().String(), messages
+}
+
+func TestEmailSinkSend(t *testing.T) {
+	address, messages := newSMTPServer(t)
+	sink := newEmailSink(&EmailConfig{
+		Address: address,
+		From:    "kubefin@example.com",
+		To:      []string{"ops@example.com", "finance@example.com"},
+	})
+	if err := sink.Send(context.Background(), newTestEvent()); err != nil {
+		t.Fatalf("send error:%v", err)
+	}
+
+	message := <-messages
+	if message.from != "kubefin@example.com" {
+		t.Errorf("from = %s, want kubefin@example.com", message.from)
+	}
+	if strings.Join(message.to, ",") != "ops@example.com,finance@example.com" {
+		t.Errorf("to = %v, want both recipients", message.to)
+	}
+	for _, want := range []string{
+		"Subject: [KubeFin][warning] cost-high",
+		"To: ops@example.com, finance@example.com",
+		"cluster prod-1 cost 120.00 exceeds 100.00",
+		"Cluster: prod-1",
+		"Value: 120.00",
+		"Threshold: 100.00",
+	} {
+		if !strings.Contains(message.data, want) {
+			t.Errorf("mail does not contain %q:\n%s", want, message.data)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notification
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+
+	"github.com/kubefin/kubefin/pkg/query"
+	"github.com/kubefin/kubefin/pkg/server/implementation"
+	"github.com/kubefin/kubefin/pkg/values"
+)
+
+// TriggerEvaluator evaluates the triggers periodically and dispatches the events fired
+type TriggerEvaluator struct {
+	triggers   []TriggerConfig
+	interval   time.Duration
+	dispatcher *Dispatcher
+	backend    query.QueryBackend
+
+	// firing are the fingerprints of the events fired in the last evaluation of each trigger
+	firing map[string]map[string]bool
+}
+
+// NewTriggerEvaluator creates the evaluator, the triggers without tenant are set to defaultTenantId
+func NewTriggerEvaluator(config *Config, defaultTenantId string, backend query.QueryBackend,
+	dispatcher *Dispatcher) *TriggerEvaluator {
+	evaluator := &TriggerEvaluator{
+		interval:   config.EvaluationInterval.Duration,
+		dispatcher: dispatcher,
+		backend:    backend,
+		firing:     make(map[string]map[string]bool),
+	}
+	for _, trigger := range config.Triggers {
+		if trigger.TenantId == "" {
+			trigger.TenantId = defaultTenantId
+		}
+		if trigger.Severity == "" {
+			trigger.Severity = SeverityWarning
+		}
+		evaluator.triggers = append(evaluator.triggers, trigger)
+	}
+	return evaluator
+}
+
+func (t *TriggerEvaluator) Run(ctx context.Context) {
+	klog.Infof("Start evaluating %d notification triggers every %s", len(t.triggers), t.interval)
+	wait.UntilWithContext(ctx, t.evaluateAll, t.interval)
+}
+
+func (t *TriggerEvaluator) evaluateAll(ctx context.Context) {
+	now := time.Now()
+	for i := range t.triggers {
+		trigger := &t.triggers[i]
+		events, err := t.evaluateTrigger(ctx, trigger, now)
+		if err != nil {
+			klog.Errorf("Evaluate trigger %s error:%v", trigger.Name, err)
+			continue
+		}
+
+		firing := make(map[string]bool)
+		for _, event := range events {
+			firing[event.Fingerprint] = true
+			if err := t.dispatcher.Dispatch(ctx, event); err != nil {
+				klog.Errorf("Dispatch event of trigger %s error:%v", trigger.Name, err)
+			}
+		}
+		for fingerprint := range t.firing[trigger.Name] {
+			if !firing[fingerprint] {
+				klog.Infof("Event %s of trigger %s is resolved", fingerprint, trigger.Name)
+				t.dispatcher.Resolve(fingerprint)
+			}
+		}
+		t.firing[trigger.Name] = firing
+	}
+}
+
+func (t *TriggerEvaluator) evaluateTrigger(ctx context.Context, trigger *TriggerConfig, now time.Time) ([]*Event, error) {
+	end := now.Unix()
+	start := now.Add(-trigger.Window.Duration).Unix()
+
+	var events []*Event
+	switch trigger.Type {
+	case TriggerTypeClusterCost:
+		clustersCost, err := implementation.QueryAllClustersCostWithTimeRange(ctx, t.backend.WithTenantId(trigger.TenantId), start, end)
+		if err != nil {
+			return nil, err
+		}
+		for clusterId, cost := range clustersCost {
+			if trigger.ClusterId != "" && trigger.ClusterId != clusterId || cost < trigger.Threshold {
+				continue
+			}
+			event := newTriggerEvent(trigger, clusterId, "", now)
+			event.Value = cost
+			event.Summary = fmt.Sprintf("Cluster %s cost %.2f in the last %s is over %.2f",
+				clusterId, cost, trigger.Window.Duration, trigger.Threshold)
+			events = append(events, event)
+		}
+	case TriggerTypeNamespaceCost:
+		namespacesCost, err := implementation.QueryNamespacesTotalCost(ctx, t.backend.WithTenantId(trigger.TenantId), trigger.ClusterId,
+			trigger.Namespace, start, end)
+		if err != nil {
+			return nil, err
+		}
+		for namespace, cost := range namespacesCost {
+			if cost < trigger.Threshold {
+				continue
+			}
+			event := newTriggerEvent(trigger, trigger.ClusterId, namespace, now)
+			event.Value = cost
+			event.Summary = fmt.Sprintf("Namespace %s of cluster %s cost %.2f in the last %s is over %.2f",
+				namespace, trigger.ClusterId, cost, trigger.Window.Duration, trigger.Threshold)
+			events = append(events, event)
+		}
+	case TriggerTypeLostConnection:
+		// Only the clusters reported in the last hour are checked
+		clustersProperty, err := implementation.QueryAllClustersBasicProperty(ctx, t.backend.WithTenantId(trigger.TenantId),
+			now.Add(-time.Hour).Unix(), end)
+		if err != nil {
+			return nil, err
+		}
+		for clusterId, property := range clustersProperty {
+			if trigger.ClusterId != "" && trigger.ClusterId != clusterId ||
+				property.ClusterConnectionSate != values.ClusterStateLostConnection {
+				continue
+			}
+			event := newTriggerEvent(trigger, clusterId, "", now)
+			event.Summary = fmt.Sprintf("Cluster %s lost connection, last active at %s",
+				clusterId, time.Unix(property.LastActiveTime, 0).Format(time.RFC3339))
+			events = append(events, event)
+		}
+	default:
+		return nil, fmt.Errorf("unknown trigger type:%s", trigger.Type)
+	}
+	return events, nil
+}
+
+func newTriggerEvent(trigger *TriggerConfig, clusterId, namespace string, now time.Time) *Event {
+	return &Event{
+		Fingerprint: fmt.Sprintf("%s/%s/%s/%s", trigger.Name, trigger.TenantId, clusterId, namespace),
+		Name:        trigger.Name,
+		Type:        trigger.Type,
+		Severity:    trigger.Severity,
+		TenantId:    trigger.TenantId,
+		ClusterId:   clusterId,
+		Namespace:   namespace,
+		Threshold:   trigger.Threshold,
+		Time:        now,
+		sinks:       trigger.Sinks,
+	}
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notification
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	SinkTypeWebhook      = "webhook"
+	SinkTypeSlack        = "slack"
+	SinkTypeEmail        = "email"
+	SinkTypeAlertmanager = "alertmanager"
+
+	TriggerTypeClusterCost    = "clusterCost"
+	TriggerTypeNamespaceCost  = "namespaceCost"
+	TriggerTypeLostConnection = "lostConnection"
+	// EventTypeBudget is the type of the events fired by the budgets
+	EventTypeBudget = "budget"
+	// EventTypeAnomaly is the type of the events fired by the cost anomalies
+	EventTypeAnomaly = "anomaly"
+
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Config holds the notification sinks and triggers, nothing is sent if there is no sink
+type Config struct {
+	Sinks    []SinkConfig    `json:"sinks,omitempty"`
+	Triggers []TriggerConfig `json:"triggers,omitempty"`
+	// EvaluationInterval is how often the triggers are evaluated
+	EvaluationInterval metav1.Duration `json:"evaluationInterval,omitempty"`
+	// RepeatInterval is how long to wait before sending a still firing event again
+	RepeatInterval metav1.Duration `json:"repeatInterval,omitempty"`
+	Retry          RetryConfig     `json:"retry"`
+}
+
+type RetryConfig struct {
+	// Attempts is the max number of times to send an event to a sink
+	Attempts int `json:"attempts,omitempty"`
+	// Backoff is the wait before the first retry, it's doubled after every retry
+	Backoff metav1.Duration `json:"backoff,omitempty"`
+}
+
+// SinkConfig holds one sink, only the config matching Type is used
+type SinkConfig struct {
+	Name string `json:"name"`
+	// Type could be webhook/slack/email/alertmanager
+	Type         string              `json:"type"`
+	Webhook      *WebhookConfig      `json:"webhook,omitempty"`
+	Slack        *SlackConfig        `json:"slack,omitempty"`
+	Email        *EmailConfig        `json:"email,omitempty"`
+	Alertmanager *AlertmanagerConfig `json:"alertmanager,omitempty"`
+}
+
+type HTTPConfig struct {
+	URL     string          `json:"url"`
+	Timeout metav1.Duration `json:"timeout,omitempty"`
+	// InsecureSkipVerify skips verifying the serving certificate, for testing only
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+}
+
+type WebhookConfig struct {
+	HTTPConfig `json:",inline"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	// Template is the go template of the request body rendered with the Event, the
+	// json function quotes a value as json. The Event is sent as json if it's empty.
+	Template string `json:"template,omitempty"`
+}
+
+// SlackConfig works with the Slack compatible incoming webhooks
+type SlackConfig struct {
+	HTTPConfig `json:",inline"`
+	Channel    string `json:"channel,omitempty"`
+	Username   string `json:"username,omitempty"`
+}
+
+type EmailConfig struct {
+	// Address is the host:port of the SMTP server, STARTTLS is used if the server supports it
+	Address  string   `json:"address"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+}
+
+type AlertmanagerConfig struct {
+	// URL is the Alertmanager base url, the events are posted to /api/v2/alerts
+	HTTPConfig `json:",inline"`
+	// Labels are added to all the alerts
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// TriggerConfig fires an event once the condition is met
+type TriggerConfig struct {
+	Name string `json:"name"`
+	// Type could be clusterCost/namespaceCost/lostConnection
+	Type     string `json:"type"`
+	TenantId string `json:"tenantId,omitempty"`
+	// ClusterId is required by namespaceCost, all clusters are checked if it's empty
+	ClusterId string `json:"clusterId,omitempty"`
+	// Namespace is only used by namespaceCost, all namespaces are checked if it's empty
+	Namespace string `json:"namespace,omitempty"`
+	// Window is the period the cost is summed over, e.g. 24h
+	Window metav1.Duration `json:"window,omitempty"`
+	// Threshold is the cost to fire the event at
+	Threshold float64 `json:"threshold,omitempty"`
+	Severity  string  `json:"severity,omitempty"`
+	// Sinks are the names of the sinks to send to, all sinks are used if it's empty
+	Sinks []string `json:"sinks,omitempty"`
+}
+
+// Event is a cost event sent to the sinks
+type Event struct {
+	// Fingerprint identifies the event, a firing event is only sent once per RepeatInterval
+	Fingerprint string    `json:"fingerprint"`
+	Name        string    `json:"name"`
+	Type        string    `json:"type"`
+	Severity    string    `json:"severity"`
+	TenantId    string    `json:"tenantId,omitempty"`
+	ClusterId   string    `json:"clusterId,omitempty"`
+	Namespace   string    `json:"namespace,omitempty"`
+	Summary     string    `json:"summary"`
+	Value       float64   `json:"value,omitempty"`
+	Threshold   float64   `json:"threshold,omitempty"`
+	Time        time.Time `json:"time"`
+
+	// sinks are the names of the sinks to send to, all sinks are used if it's empty
+	sinks []string
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"text/template"
+	"time"
+)
+
+const defaultHTTPTimeout = 10 * time.Second
+
+type webhookSink struct {
+	url      string
+	client   *http.Client
+	headers  map[string]string
+	template *template.Template
+}
+
+func newWebhookSink(config *WebhookConfig) (*webhookSink, error) {
+	sink := &webhookSink{
+		url:     config.URL,
+		client:  newHTTPClient(&config.HTTPConfig),
+		headers: config.Headers,
+	}
+	if config.Template != "" {
+		tmpl, err := template.New("webhook").Funcs(template.FuncMap{"json": toJSON}).Parse(config.Template)
+		if err != nil {
+			return nil, fmt.Errorf("parse webhook template error:%v", err)
+		}
+		sink.template = tmpl
+	}
+	return sink, nil
+}
+
+func (w *webhookSink) Send(ctx context.Context, event *Event) error {
+	var body []byte
+	if w.template == nil {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		body = data
+	} else {
+		buf := &bytes.Buffer{}
+		if err := w.template.Execute(buf, event); err != nil {
+			return fmt.Errorf("render webhook template error:%v", err)
+		}
+		body = buf.Bytes()
+	}
+	return postJSON(ctx, w.client, w.url, w.headers, body)
+}
+
+type slackSink struct {
+	url      string
+	client   *http.Client
+	channel  string
+	username string
+}
+
+func newSlackSink(config *SlackConfig) (*slackSink, error) {
+	return &slackSink{
+		url:      config.URL,
+		client:   newHTTPClient(&config.HTTPConfig),
+		channel:  config.Channel,
+		username: config.Username,
+	}, nil
+}
+
+func (s *slackSink) Send(ctx context.Context, event *Event) error {
+	message := struct {
+		Text     string `json:"text"`
+		Channel  string `json:"channel,omitempty"`
+		Username string `json:"username,omitempty"`
+	}{
+		Text:     fmt.Sprintf("[%s] %s", event.Severity, event.Summary),
+		Channel:  s.channel,
+		Username: s.username,
+	}
+	body, err := json.Marshal(&message)
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, s.client, s.url, nil, body)
+}
+
+func newHTTPClient(config *HTTPConfig) *http.Client {
+	timeout := config.Timeout.Duration
+	if timeout == 0 {
+		timeout = defaultHTTPTimeout
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if config.InsecureSkipVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("post %s got status %d:%s", url, resp.StatusCode, respBody)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+func toJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
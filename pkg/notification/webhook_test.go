@@ -0,0 +1,170 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordedRequest is a request received by the test server
+type recordedRequest struct {
+	path    string
+	headers http.Header
+	body    string
+}
+
+// newRecordingServer records the requests and answers them with status
+func newRecordingServer(t *testing.T, status int) (*httptest.Server, chan recordedRequest) {
+	requests := make(chan recordedRequest, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- recordedRequest{path: r.URL.Path, headers: r.Header, body: string(body)}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+func newTestEvent() *Event {
+	return &Event{
+		Fingerprint: "fp",
+		Name:        "cost-high",
+		Type:        TriggerTypeClusterCost,
+		Severity:    SeverityWarning,
+		ClusterId:   "prod-1",
+		Namespace:   "default",
+		Summary:     "cluster prod-1 cost 120.00 exceeds 100.00",
+		Value:       120,
+		Threshold:   100,
+		Time:        time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestWebhookSinkSend(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		headers  map[string]string
+		wantBody func(t *testing.T, body string)
+	}{
+		{
+			name: "event as json",
+			wantBody: func(t *testing.T, body string) {
+				event := &Event{}
+				if err := json.Unmarshal([]byte(body), event); err != nil {
+					t.Fatalf("decode body %s error:%v", body, err)
+				}
+				if event.Fingerprint != "fp" || event.ClusterId != "prod-1" || event.Value != 120 {
+					t.Errorf("body = %s, want the event", body)
+				}
+			},
+		},
+		{
+			name:     "template",
+			template: `{"text":{{json .Summary}},"cluster":"{{.ClusterId}}"}`,
+			headers:  map[string]string{"Authorization": "Bearer token"},
+			wantBody: func(t *testing.T, body string) {
+				want := `{"text":"cluster prod-1 cost 120.00 exceeds 100.00","cluster":"prod-1"}`
+				if body != want {
+					t.Errorf("body = %s, want %s", body, want)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := newRecordingServer(t, http.StatusOK)
+			sink, err := newWebhookSink(&WebhookConfig{
+				HTTPConfig: HTTPConfig{URL: server.URL + "/hook"},
+				Headers:    tt.headers,
+				Template:   tt.template,
+			})
+			if err != nil {
+				t.Fatalf("create sink error:%v", err)
+			}
+			if err := sink.Send(context.Background(), newTestEvent()); err != nil {
+				t.Fatalf("send error:%v", err)
+			}
+
+			request := <-requests
+			if request.path != "/hook" || request.headers.Get("Content-Type") != "application/json" {
+				t.Errorf("request = %s %s, want /hook with json", request.path, request.headers.Get("Content-Type"))
+			}
+			for key, value := range tt.headers {
+				if got := request.headers.Get(key); got != value {
+					t.Errorf("header %s = %s, want %s", key, got, value)
+				}
+			}
+			tt.wantBody(t, request.body)
+		})
+	}
+}
+
+func TestWebhookSinkInvalidTemplate(t *testing.T) {
+	if _, err := newWebhookSink(&WebhookConfig{Template: "{{.Summary"}); err == nil {
+		t.Fatal("create sink with an invalid template, want error")
+	}
+}
+
+func TestWebhookSinkErrorStatus(t *testing.T) {
+	server, _ := newRecordingServer(t, http.StatusInternalServerError)
+	sink, err := newWebhookSink(&WebhookConfig{HTTPConfig: HTTPConfig{URL: server.URL}})
+	if err != nil {
+		t.Fatalf("create sink error:%v", err)
+	}
+	if err := sink.Send(context.Background(), newTestEvent()); err == nil || !strings.Contains(err.Error(), "500") {
+		t.Fatalf("send error = %v, want the status", err)
+	}
+}
+
+func TestSlackSinkSend(t *testing.T) {
+	server, requests := newRecordingServer(t, http.StatusOK)
+	sink, err := newSlackSink(&SlackConfig{
+		HTTPConfig: HTTPConfig{URL: server.URL},
+		Channel:    "#cost",
+		Username:   "kubefin",
+	})
+	if err != nil {
+		t.Fatalf("create sink error:%v", err)
+	}
+	if err := sink.Send(context.Background(), newTestEvent()); err != nil {
+		t.Fatalf("send error:%v", err)
+	}
+
+	message := map[string]string{}
+	request := <-requests
+	if err := json.Unmarshal([]byte(request.body), &message); err != nil {
+		t.Fatalf("decode body %s error:%v", request.body, err)
+	}
+	want := map[string]string{
+		"text":     "[warning] cluster prod-1 cost 120.00 exceeds 100.00",
+		"channel":  "#cost",
+		"username": "kubefin",
+	}
+	for key, value := range want {
+		if message[key] != value {
+			t.Errorf("message %s = %s, want %s", key, message[key], value)
+		}
+	}
+}
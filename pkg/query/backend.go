@@ -0,0 +1,69 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package query
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/kubefin/kubefin/pkg/values"
+)
+
+const (
+	// BackendTypePrometheus also covers the storages serving the Prometheus HTTP API with
+	// the tenant id in the X-Scope-OrgID header, such as Mimir and Cortex
+	BackendTypePrometheus = "prometheus"
+	// BackendTypeThanos sends the tenant id in the THANOS-TENANT header
+	BackendTypeThanos = "thanos"
+	// BackendTypeVictoriaMetrics sends the tenant id in the url path of the cluster version
+	BackendTypeVictoriaMetrics = "victoriametrics"
+)
+
+// WarningHandler receives the warnings of a successful query, such as the partial response of Thanos
+type WarningHandler func(warnings []string)
+
+// QueryBackend runs promql against the storage the agent metrics are written to
+type QueryBackend interface {
+	// WithTenantId returns a backend querying with the tenant id, the default tenant is kept if id is empty
+	WithTenantId(id string) QueryBackend
+	// WithWarningHandler returns a backend passing the warnings of the queries to the handler
+	WithWarningHandler(handler WarningHandler) QueryBackend
+	// Ping checks the backend is reachable and answers promql
+	Ping(ctx context.Context) error
+	// The query methods return *Error if the query fails, use ErrorTypeOf to classify it.
+	// The request to the backend is canceled once ctx is done.
+	QueryInstant(ctx context.Context, promql string) ([]*model.Sample, error)
+	QueryInstantWithTime(ctx context.Context, promql string, time int64) ([]*model.Sample, error)
+	QueryRange(ctx context.Context, promql string, start, end int64) ([]*model.SampleStream, error)
+	QueryRangeWithStep(ctx context.Context, promql string, start, end, stepSeconds int64) ([]*model.SampleStream, error)
+}
+
+// NewQueryBackend creates the backend of the configured type
+func NewQueryBackend(config *BackendConfig) (QueryBackend, error) {
+	switch config.Type {
+	case "", BackendTypePrometheus:
+		return NewPromQueryClient(config, values.MultiTenantHeader)
+	case BackendTypeThanos:
+		return NewPromQueryClient(config, values.ThanosTenantHeader)
+	case BackendTypeVictoriaMetrics:
+		return NewVictoriaMetricsQueryClient(config)
+	default:
+		return nil, fmt.Errorf("unsupported query backend type:%s", config.Type)
+	}
+}
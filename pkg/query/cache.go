@@ -0,0 +1,325 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package query
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/kubefin/kubefin/pkg/values"
+)
+
+const (
+	cacheQueryTypeInstant = "instant"
+	cacheQueryTypeRange   = "range"
+	cacheResultHit        = "hit"
+	cacheResultMiss       = "miss"
+)
+
+// CacheConfig holds the settings of the query result cache
+type CacheConfig struct {
+	Enabled bool `json:"enabled"`
+	// MaxEntries is the number of results kept, the least recently used ones are evicted
+	MaxEntries int `json:"maxEntries,omitempty"`
+	// SplitInterval splits the range queries into windows aligned to it, every window is cached separately
+	SplitInterval metav1.Duration `json:"splitInterval,omitempty"`
+	// MaxFreshness is how long the data may still change after written, the results
+	// newer than it are always queried from the backend
+	MaxFreshness metav1.Duration `json:"maxFreshness,omitempty"`
+	// Directory stores the results on disk to survive restarts, the results are kept in memory if it's empty
+	Directory string `json:"directory,omitempty"`
+}
+
+// NewDefaultCacheConfig caches the results in memory
+func NewDefaultCacheConfig() CacheConfig {
+	return CacheConfig{
+		Enabled:       true,
+		MaxEntries:    10000,
+		SplitInterval: metav1.Duration{Duration: 24 * time.Hour},
+		MaxFreshness:  metav1.Duration{Duration: 10 * time.Minute},
+	}
+}
+
+var queryCacheRequestsCV = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: values.QueryCacheRequestsMetricsName,
+	Help: "The lookups of the query result cache, every window of the range queries is counted",
+}, []string{values.QueryTypeLabelKey, values.ResultLabelKey})
+
+func init() {
+	prometheus.MustRegister(queryCacheRequestsCV)
+}
+
+// cachedBackend caches the results of the queries whose data could not change anymore.
+// The range queries are split into windows, the windows ending before MaxFreshness are
+// cached and the others are queried every time. The instant queries are cached only
+// if they are evaluated at a time before MaxFreshness.
+type cachedBackend struct {
+	backend  QueryBackend
+	tenantId string
+	store    cacheStore
+	config   *CacheConfig
+
+	requestsCV     *prometheus.CounterVec
+	warningHandler WarningHandler
+}
+
+// NewCachedBackend wraps the backend with the result cache, defaultTenantId is the tenant
+// of the queries without tenant id
+func NewCachedBackend(backend QueryBackend, config *CacheConfig, defaultTenantId string) (QueryBackend, error) {
+	var store cacheStore = newMemoryStore(config.MaxEntries)
+	if config.Directory != "" {
+		diskStore, err := newDiskStore(config.Directory, config.MaxEntries)
+		if err != nil {
+			return nil, err
+		}
+		store = diskStore
+	}
+
+	return &cachedBackend{
+		backend:    backend,
+		tenantId:   defaultTenantId,
+		store:      store,
+		config:     config,
+		requestsCV: queryCacheRequestsCV,
+	}, nil
+}
+
+func (c *cachedBackend) WithTenantId(id string) QueryBackend {
+	backendCopy := *c
+	backendCopy.backend = c.backend.WithTenantId(id)
+	if id != "" {
+		backendCopy.tenantId = id
+	}
+	return &backendCopy
+}
+
+func (c *cachedBackend) WithWarningHandler(handler WarningHandler) QueryBackend {
+	backendCopy := *c
+	backendCopy.warningHandler = handler
+	return &backendCopy
+}
+
+func (c *cachedBackend) Ping(ctx context.Context) error {
+	return c.backend.Ping(ctx)
+}
+
+// QueryInstant is never cached as it's evaluated at the current time, the sums over long ranges
+// should be split into a range query of the completed windows and an instant query of the live tail
+func (c *cachedBackend) QueryInstant(ctx context.Context, promql string) ([]*model.Sample, error) {
+	return c.backend.WithWarningHandler(c.warningHandler).QueryInstant(ctx, promql)
+}
+
+func (c *cachedBackend) QueryInstantWithTime(ctx context.Context, promql string, time int64) ([]*model.Sample, error) {
+	if time > c.immutableBefore() {
+		return c.backend.WithWarningHandler(c.warningHandler).QueryInstantWithTime(ctx, promql, time)
+	}
+
+	key := c.key(cacheQueryTypeInstant, promql, time)
+	var samples []*model.Sample
+	if c.get(cacheQueryTypeInstant, key, &samples) {
+		return samples, nil
+	}
+	backend, warned := c.uncachedBackend()
+	samples, err := backend.QueryInstantWithTime(ctx, promql, time)
+	if err != nil {
+		return nil, err
+	}
+	if !warned() {
+		c.add(key, samples)
+	}
+	return samples, nil
+}
+
+func (c *cachedBackend) QueryRange(ctx context.Context, promql string, start, end int64) ([]*model.SampleStream, error) {
+	return c.QueryRangeWithStep(ctx, promql, start, end, rangeStepSeconds(start, end))
+}
+
+// QueryRangeWithStep splits the range into windows aligned to SplitInterval, the points in every
+// window are start+k*step so the windows are reused by the queries with the same step and offset.
+// The consecutive windows not cached are queried from the backend together.
+func (c *cachedBackend) QueryRangeWithStep(ctx context.Context, promql string, start, end, stepSeconds int64) ([]*model.SampleStream, error) {
+	if stepSeconds <= 0 || start > end {
+		return c.backend.WithWarningHandler(c.warningHandler).QueryRangeWithStep(ctx, promql, start, end, stepSeconds)
+	}
+
+	windows := c.splitRange(start, end, stepSeconds)
+	results := make([][]*model.SampleStream, len(windows))
+	immutableBefore := c.immutableBefore()
+	for i := 0; i < len(windows); {
+		if windows[i].last <= immutableBefore {
+			var streams []*model.SampleStream
+			if c.get(cacheQueryTypeRange, c.key(cacheQueryTypeRange, promql, windows[i].first, windows[i].last, stepSeconds), &streams) {
+				results[i] = streams
+				i++
+				continue
+			}
+		} else {
+			c.requestsCV.WithLabelValues(cacheQueryTypeRange, cacheResultMiss).Inc()
+		}
+
+		// Extend the query to the following windows not cached
+		j := i + 1
+		for ; j < len(windows); j++ {
+			if windows[j].last <= immutableBefore &&
+				c.contains(c.key(cacheQueryTypeRange, promql, windows[j].first, windows[j].last, stepSeconds)) {
+				break
+			}
+			c.requestsCV.WithLabelValues(cacheQueryTypeRange, cacheResultMiss).Inc()
+		}
+		backend, warned := c.uncachedBackend()
+		streams, err := backend.QueryRangeWithStep(ctx, promql, windows[i].first, windows[j-1].last, stepSeconds)
+		if err != nil {
+			return nil, err
+		}
+		for k := i; k < j; k++ {
+			results[k] = windows[k].slice(streams)
+			if windows[k].last <= immutableBefore && !warned() {
+				c.add(c.key(cacheQueryTypeRange, promql, windows[k].first, windows[k].last, stepSeconds), results[k])
+			}
+		}
+		i = j
+	}
+	return mergeSampleStreams(results), nil
+}
+
+// uncachedBackend returns the backend to query the results not cached, and whether it returns
+// warnings, the results with warnings may be partial and are not cached
+func (c *cachedBackend) uncachedBackend() (QueryBackend, func() bool) {
+	var warned int32
+	backend := c.backend.WithWarningHandler(func(warnings []string) {
+		atomic.StoreInt32(&warned, 1)
+		if c.warningHandler != nil {
+			c.warningHandler(warnings)
+		}
+	})
+	return backend, func() bool {
+		return atomic.LoadInt32(&warned) == 1
+	}
+}
+
+func (c *cachedBackend) immutableBefore() int64 {
+	return time.Now().Add(-c.config.MaxFreshness.Duration).Unix()
+}
+
+func (c *cachedBackend) key(queryType, promql string, times ...int64) string {
+	hash := sha256.New()
+	hash.Write([]byte(fmt.Sprintf("%s\n%s\n%s\n%v", queryType, c.tenantId, promql, times)))
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func (c *cachedBackend) contains(key string) bool {
+	_, ok := c.store.Get(key)
+	return ok
+}
+
+// get decodes the cached result into value and counts the lookup
+func (c *cachedBackend) get(queryType, key string, value interface{}) bool {
+	data, ok := c.store.Get(key)
+	if ok {
+		if err := json.Unmarshal(data, value); err != nil {
+			klog.Warningf("Decode cached query result error:%v", err)
+			ok = false
+		}
+	}
+	if ok {
+		c.requestsCV.WithLabelValues(queryType, cacheResultHit).Inc()
+	} else {
+		c.requestsCV.WithLabelValues(queryType, cacheResultMiss).Inc()
+	}
+	return ok
+}
+
+func (c *cachedBackend) add(key string, value interface{}) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		klog.Warningf("Encode query result error:%v", err)
+		return
+	}
+	c.store.Add(key, data)
+}
+
+// rangeWindow is the points from first to last of a range query
+type rangeWindow struct {
+	first int64
+	last  int64
+}
+
+// splitRange splits the points start+k*step before end by SplitInterval
+func (c *cachedBackend) splitRange(start, end, stepSeconds int64) []rangeWindow {
+	splitSeconds := int64(c.config.SplitInterval.Seconds())
+	if splitSeconds < stepSeconds {
+		splitSeconds = stepSeconds
+	}
+
+	var windows []rangeWindow
+	for first := start; first <= end; {
+		boundary := (first/splitSeconds + 1) * splitSeconds
+		if boundary > end+1 {
+			boundary = end + 1
+		}
+		last := first + (boundary-1-first)/stepSeconds*stepSeconds
+		windows = append(windows, rangeWindow{first: first, last: last})
+		first = last + stepSeconds
+	}
+	return windows
+}
+
+// slice returns the points of the streams in the window
+func (w rangeWindow) slice(streams []*model.SampleStream) []*model.SampleStream {
+	ret := []*model.SampleStream{}
+	for _, stream := range streams {
+		var points []model.SamplePair
+		for _, point := range stream.Values {
+			if seconds := point.Timestamp.Unix(); seconds >= w.first && seconds <= w.last {
+				points = append(points, point)
+			}
+		}
+		if len(points) > 0 {
+			ret = append(ret, &model.SampleStream{Metric: stream.Metric, Values: points})
+		}
+	}
+	return ret
+}
+
+// mergeSampleStreams joins the windows of the same series in order
+func mergeSampleStreams(windows [][]*model.SampleStream) []*model.SampleStream {
+	ret := []*model.SampleStream{}
+	series := make(map[model.Fingerprint]*model.SampleStream)
+	for _, streams := range windows {
+		for _, stream := range streams {
+			fingerprint := stream.Metric.Fingerprint()
+			merged, ok := series[fingerprint]
+			if !ok {
+				merged = &model.SampleStream{Metric: stream.Metric}
+				series[fingerprint] = merged
+				ret = append(ret, merged)
+			}
+			merged.Values = append(merged.Values, stream.Values...)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package query
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang/groupcache/lru"
+	"k8s.io/klog/v2"
+)
+
+const tmpFileSuffix = ".tmp"
+
+// cacheStore keeps the encoded query results, the keys are hex digests
+type cacheStore interface {
+	Get(key string) ([]byte, bool)
+	Add(key string, value []byte)
+}
+
+// memoryStore is an LRU of the results
+type memoryStore struct {
+	mutex sync.Mutex
+	cache *lru.Cache
+}
+
+func newMemoryStore(maxEntries int) *memoryStore {
+	return &memoryStore{cache: lru.New(maxEntries)}
+}
+
+func (m *memoryStore) Get(key string) ([]byte, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	value, ok := m.cache.Get(key)
+	if !ok {
+		return nil, false
+	}
+	return value.([]byte), true
+}
+
+func (m *memoryStore) Add(key string, value []byte) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.cache.Add(key, value)
+}
+
+// diskStore writes every result to a file in directory, the files are kept in an LRU of
+// their names and the least recently used ones are removed once maxEntries is exceeded
+type diskStore struct {
+	directory string
+
+	mutex sync.Mutex
+	files *lru.Cache
+}
+
+func newDiskStore(directory string, maxEntries int) (*diskStore, error) {
+	if err := os.MkdirAll(directory, 0o750); err != nil {
+		return nil, fmt.Errorf("create query cache directory error:%v", err)
+	}
+	store := &diskStore{directory: directory, files: lru.New(maxEntries)}
+	store.files.OnEvicted = func(key lru.Key, _ interface{}) {
+		if err := os.Remove(store.path(key.(string))); err != nil && !os.IsNotExist(err) {
+			klog.Warningf("Remove query cache file error:%v", err)
+		}
+	}
+
+	// Load the files written before restart, the recently modified ones are used recently
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return nil, fmt.Errorf("read query cache directory error:%v", err)
+	}
+	var infos []os.FileInfo
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if strings.HasSuffix(entry.Name(), tmpFileSuffix) {
+			_ = os.Remove(store.path(entry.Name()))
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ModTime().Before(infos[j].ModTime())
+	})
+	for _, info := range infos {
+		store.files.Add(info.Name(), nil)
+	}
+	klog.Infof("Loaded %d query cache entries from %s", store.files.Len(), directory)
+	return store, nil
+}
+
+func (d *diskStore) path(key string) string {
+	return filepath.Join(d.directory, key)
+}
+
+func (d *diskStore) Get(key string) ([]byte, bool) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if _, ok := d.files.Get(key); !ok {
+		return nil, false
+	}
+	value, err := os.ReadFile(d.path(key))
+	if err != nil {
+		klog.Warningf("Read query cache file error:%v", err)
+		d.files.Remove(key)
+		return nil, false
+	}
+	return value, true
+}
+
+func (d *diskStore) Add(key string, value []byte) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	// Write to a temporary file first, the half written file is never read after crash
+	tmpFile := d.path(key) + tmpFileSuffix
+	if err := os.WriteFile(tmpFile, value, 0o640); err != nil {
+		klog.Warningf("Write query cache file error:%v", err)
+		return
+	}
+	if err := os.Rename(tmpFile, d.path(key)); err != nil {
+		klog.Warningf("Rename query cache file error:%v", err)
+		return
+	}
+	d.files.Add(key, nil)
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package query
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSplitRange(t *testing.T) {
+	const day = 24 * 60 * 60
+	tests := []struct {
+		name        string
+		split       time.Duration
+		start       int64
+		end         int64
+		stepSeconds int64
+		want        []rangeWindow
+	}{
+		{
+			name:  "aligned days",
+			split: 24 * time.Hour,
+			start: 0, end: 2*day + 3600, stepSeconds: 3600,
+			want: []rangeWindow{{first: 0, last: day - 3600}, {first: day, last: 2*day - 3600}, {first: 2 * day, last: 2*day + 3600}},
+		},
+		{
+			name:  "offset start keeps the points of the step",
+			split: 24 * time.Hour,
+			start: 1800, end: day + 1800, stepSeconds: 3600,
+			want: []rangeWindow{{first: 1800, last: day - 1800}, {first: day + 1800, last: day + 1800}},
+		},
+		{
+			name:  "split shorter than the step",
+			split: time.Hour,
+			start: 0, end: 2 * day, stepSeconds: day,
+			want: []rangeWindow{{first: 0, last: 0}, {first: day, last: day}, {first: 2 * day, last: 2 * day}},
+		},
+		{
+			name:  "single point",
+			split: 24 * time.Hour,
+			start: 100, end: 100, stepSeconds: 60,
+			want: []rangeWindow{{first: 100, last: 100}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &cachedBackend{config: &CacheConfig{SplitInterval: metav1.Duration{Duration: tt.split}}}
+			if got := c.splitRange(tt.start, tt.end, tt.stepSeconds); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitRange(%d, %d, %d) = %v, want %v", tt.start, tt.end, tt.stepSeconds, got, tt.want)
+			}
+		})
+	}
+}
+
+// rangeRecorder answers every range query with one point per step, its value is the timestamp
+type rangeRecorder struct {
+	QueryBackend
+	ranges [][2]int64
+}
+
+func (r *rangeRecorder) WithWarningHandler(WarningHandler) QueryBackend {
+	return r
+}
+
+func (r *rangeRecorder) QueryRangeWithStep(_ context.Context, _ string, start, end, stepSeconds int64) ([]*model.SampleStream, error) {
+	r.ranges = append(r.ranges, [2]int64{start, end})
+	stream := &model.SampleStream{Metric: model.Metric{"cluster_id": "test"}}
+	for t := start; t <= end; t += stepSeconds {
+		stream.Values = append(stream.Values, model.SamplePair{Timestamp: model.TimeFromUnix(t), Value: model.SampleValue(t)})
+	}
+	return []*model.SampleStream{stream}, nil
+}
+
+func TestCachedBackendQueryRangeWithStep(t *testing.T) {
+	const day = 24 * 60 * 60
+	// The points of three completed days are cached, and the point at now is not
+	end := time.Now().Unix()
+	start := end - 3*day
+
+	recorder := &rangeRecorder{}
+	config := NewDefaultCacheConfig()
+	c := &cachedBackend{
+		backend:    recorder,
+		store:      newMemoryStore(config.MaxEntries),
+		config:     &config,
+		requestsCV: queryCacheRequestsCV,
+	}
+
+	first, err := c.QueryRangeWithStep(context.Background(), "up", start, end, day)
+	if err != nil {
+		t.Fatalf("query error:%v", err)
+	}
+	if want := [][2]int64{{start, end}}; !reflect.DeepEqual(recorder.ranges, want) {
+		t.Fatalf("first query ranges = %v, want %v", recorder.ranges, want)
+	}
+
+	recorder.ranges = nil
+	second, err := c.QueryRangeWithStep(context.Background(), "up", start, end, day)
+	if err != nil {
+		t.Fatalf("query error:%v", err)
+	}
+	if want := [][2]int64{{end, end}}; !reflect.DeepEqual(recorder.ranges, want) {
+		t.Fatalf("second query ranges = %v, want only the live window %v", recorder.ranges, want)
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Fatalf("cached result %v differs from the first one %v", second, first)
+	}
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package query
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// ErrorType classifies the errors of the query backend
+type ErrorType string
+
+const (
+	// ErrorTypeBadQuery means the promql or its parameters are rejected by the backend
+	ErrorTypeBadQuery ErrorType = "bad_query"
+	// ErrorTypeTimeout means the query is not finished in time, or canceled as the caller has gone
+	ErrorTypeTimeout ErrorType = "timeout"
+	// ErrorTypeUnavailable means the backend could not be reached or is not ready
+	ErrorTypeUnavailable ErrorType = "unavailable"
+	// ErrorTypePartialData means the response is cut off before all the data is received
+	ErrorTypePartialData ErrorType = "partial_data"
+	// ErrorTypeInternal covers the other errors, such as the backend failing to execute the query
+	// or returning a response could not be decoded
+	ErrorTypeInternal ErrorType = "internal"
+)
+
+// errorTypePriority decides the type reported if several queries fail together,
+// the errors caused by the request come first as retrying won't fix them
+var errorTypePriority = []ErrorType{
+	ErrorTypeBadQuery,
+	ErrorTypeUnavailable,
+	ErrorTypeTimeout,
+	ErrorTypePartialData,
+	ErrorTypeInternal,
+}
+
+// Error is the error returned by the query backend
+type Error struct {
+	Type    ErrorType
+	Message string
+	// Err is the underlying error, it's nil if the backend answers with an error
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s:%v", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func newError(errorType ErrorType, message string, err error) *Error {
+	return &Error{Type: errorType, Message: message, Err: err}
+}
+
+// ErrorTypeOf returns the type of the error, the errors not returned by the query backend
+// are ErrorTypeInternal. The aggregated errors are reported by errorTypePriority, nil has no type.
+func ErrorTypeOf(err error) ErrorType {
+	if err == nil {
+		return ""
+	}
+	var aggregate utilerrors.Aggregate
+	if errors.As(err, &aggregate) {
+		types := make(map[ErrorType]bool)
+		for _, e := range aggregate.Errors() {
+			types[ErrorTypeOf(e)] = true
+		}
+		for _, errorType := range errorTypePriority {
+			if types[errorType] {
+				return errorType
+			}
+		}
+	}
+
+	var queryErr *Error
+	if errors.As(err, &queryErr) {
+		return queryErr.Type
+	}
+	return ErrorTypeInternal
+}
+
+// errorTypeFromAPI maps the errorType of the Prometheus HTTP API
+func errorTypeFromAPI(apiErrorType string) ErrorType {
+	switch apiErrorType {
+	case "bad_data", "execution", "not_found":
+		return ErrorTypeBadQuery
+	case "timeout", "canceled":
+		return ErrorTypeTimeout
+	case "unavailable":
+		return ErrorTypeUnavailable
+	default:
+		return ErrorTypeInternal
+	}
+}
+
+// errorTypeFromStatusCode maps the http status code if the response carries no errorType
+func errorTypeFromStatusCode(code int) ErrorType {
+	switch code {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return ErrorTypeBadQuery
+	case http.StatusGatewayTimeout, http.StatusRequestTimeout:
+		return ErrorTypeTimeout
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusTooManyRequests:
+		return ErrorTypeUnavailable
+	default:
+		return ErrorTypeInternal
+	}
+}
+
+// errorTypeFromTransport maps the errors of sending the request and reading the response
+func errorTypeFromTransport(err error) ErrorType {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorTypeTimeout
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return ErrorTypePartialData
+	default:
+		return ErrorTypeUnavailable
+	}
+}
@@ -0,0 +1,43 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package query
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const readyzPingTimeout = 5 * time.Second
+
+// NewReadyzHandler reports ready if the backend answers the ping, the ping fast-fails
+// without reaching the backend while the circuit breaker is open
+func NewReadyzHandler(backend QueryBackend) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), readyzPingTimeout)
+		defer cancel()
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if err := backend.Ping(ctx); err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintf(w, "query backend is not ready:%v\n", err)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+}
@@ -17,22 +17,26 @@ limitations under the License.
 package query
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"net/http"
-	"time"
+	"net/url"
+	"strings"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/transport"
 	"k8s.io/klog/v2"
 
-	"github.com/kubefin/kubefin/pkg/values"
 	"github.com/prometheus/common/model"
 )
 
 const (
 	instantQueryBaseUrl = "/api/v1/query"
 	rangeQueryBaseUrl   = "/api/v1/query_range"
+
+	statusSuccess = "success"
 )
 
 type PromqlInstantMessageType struct {
@@ -62,211 +66,244 @@ type PromqlRangeMessageType struct {
 }
 
 type PromqlStatusType struct {
+	Status    string `json:"status"`
 	Error     string `json:"error,omitempty"`
 	ErrorType string `json:"errorType,omitempty"`
 	// Extra field supported by Thanos Querier.
 	Warnings []string `json:"warnings"`
 }
 
+// BackendConfig describes how to connect to the query backend
+type BackendConfig struct {
+	// Type could be prometheus/thanos/victoriametrics, prometheus is used if it's empty
+	Type     string `json:"type,omitempty"`
+	Endpoint string `json:"endpoint,omitempty"`
+	// Timeout is the timeout of every request sent to the backend
+	Timeout metav1.Duration `json:"timeout,omitempty"`
+	// DefaultTenantId is used when the request carries no tenant id
+	DefaultTenantId string `json:"defaultTenantId,omitempty"`
+
+	BasicAuthUsername string `json:"basicAuthUsername,omitempty"`
+	BasicAuthPassword string `json:"basicAuthPassword,omitempty"`
+	BearerToken       string `json:"bearerToken,omitempty"`
+	// BearerTokenFile is reloaded periodically, it takes precedence over BearerToken
+	BearerTokenFile string `json:"bearerTokenFile,omitempty"`
+
+	CAFile             string `json:"caFile,omitempty"`
+	CertFile           string `json:"certFile,omitempty"`
+	KeyFile            string `json:"keyFile,omitempty"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty"`
+
+	// Retry retries the queries failed as the backend is unavailable
+	Retry RetryConfig `json:"retry"`
+	// CircuitBreaker fast-fails the queries while the backend is down
+	CircuitBreaker CircuitBreakerConfig `json:"circuitBreaker"`
+}
+
+// PromQueryClient queries the storages serving the Prometheus HTTP API, such as Prometheus,
+// Mimir, Cortex and Thanos
 type PromQueryClient struct {
 	endpoint   string
 	httpClient *http.Client
 	// This is used in multi-tenant storage system
 	tenantId string
+	// tenantHeader is the header carrying the tenant id, the tenant id is not sent if it's empty
+	tenantHeader string
+	// apiPath returns the path prefix of the query api for the tenant
+	apiPath func(tenantId string) string
+	// warningHandler receives the warnings of the responses, it's called concurrently
+	warningHandler WarningHandler
 }
 
-var promQueryClient *PromQueryClient
+// NewPromQueryClient creates a client sending the tenant id in the tenantHeader
+func NewPromQueryClient(config *BackendConfig, tenantHeader string) (*PromQueryClient, error) {
+	httpClient, err := newHTTPClient(config)
+	if err != nil {
+		return nil, err
+	}
+	return &PromQueryClient{
+		endpoint:     strings.TrimSuffix(config.Endpoint, "/"),
+		httpClient:   httpClient,
+		tenantId:     config.DefaultTenantId,
+		tenantHeader: tenantHeader,
+		apiPath: func(string) string {
+			return ""
+		},
+	}, nil
+}
 
-func InitPromQueryClient(endpoint string) {
-	promQueryClient = &PromQueryClient{
-		endpoint:   endpoint,
-		httpClient: &http.Client{Timeout: time.Second * 30},
-		tenantId:   "",
+func newHTTPClient(config *BackendConfig) (*http.Client, error) {
+	roundTripper, err := transport.New(&transport.Config{
+		Username:        config.BasicAuthUsername,
+		Password:        config.BasicAuthPassword,
+		BearerToken:     config.BearerToken,
+		BearerTokenFile: config.BearerTokenFile,
+		TLS: transport.TLSConfig{
+			CAFile:         config.CAFile,
+			CertFile:       config.CertFile,
+			KeyFile:        config.KeyFile,
+			ReloadTLSFiles: config.CertFile != "",
+			Insecure:       config.InsecureSkipVerify,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create query backend transport error:%v", err)
 	}
+	return &http.Client{Timeout: config.Timeout.Duration, Transport: roundTripper}, nil
 }
 
-func GetPromQueryClient() *PromQueryClient {
-	return promQueryClient
+// WithTenantId returns a client querying with the tenant id, the default tenant is kept if id is empty
+func (p *PromQueryClient) WithTenantId(id string) QueryBackend {
+	clientCopy := *p
+	if id != "" {
+		clientCopy.tenantId = id
+	}
+	return &clientCopy
 }
 
-func (p *PromQueryClient) WithTenantId(id string) *PromQueryClient {
+// WithWarningHandler returns a client passing the warnings of the responses to the handler
+func (p *PromQueryClient) WithWarningHandler(handler WarningHandler) QueryBackend {
 	clientCopy := *p
-	clientCopy.tenantId = id
+	clientCopy.warningHandler = handler
 	return &clientCopy
 }
 
-func (p *PromQueryClient) QueryInstant(promql string) ([]*model.Sample, error) {
-	respBody, err := p.queryInstant(promql, "")
-	if err != nil {
-		return nil, err
-	}
+// Ping checks the query backend is reachable and answers promql
+func (p *PromQueryClient) Ping(ctx context.Context) error {
+	_, err := p.QueryInstant(ctx, "vector(1)")
+	return err
+}
 
+func (p *PromQueryClient) QueryInstant(ctx context.Context, promql string) ([]*model.Sample, error) {
 	message := &PromqlInstantMessageType{}
-	if err := json.Unmarshal(respBody, message); err != nil {
-		klog.Errorf("Unmarshal error:%v", err)
+	if err := p.queryInstant(ctx, promql, "", message, &message.PromqlStatusType); err != nil {
+		return nil, err
 	}
-
 	return message.Data.Result, nil
 }
 
-func (p *PromQueryClient) QueryInstantWithTime(promql string, time int64) ([]*model.Sample, error) {
-	timeStr := fmt.Sprintf("%d", time)
-	respBody, err := p.queryInstant(promql, timeStr)
-
-	if err != nil {
-		return nil, err
-	}
-
+func (p *PromQueryClient) QueryInstantWithTime(ctx context.Context, promql string, time int64) ([]*model.Sample, error) {
 	message := &PromqlInstantMessageType{}
-	if err := json.Unmarshal(respBody, message); err != nil {
-		klog.Errorf("Unmarshal error:%v", err)
+	if err := p.queryInstant(ctx, promql, fmt.Sprintf("%d", time), message, &message.PromqlStatusType); err != nil {
 		return nil, err
 	}
-
 	return message.Data.Result, nil
 }
 
-func (p *PromQueryClient) QueryInstantRange(promql string) ([]*model.SampleStream, error) {
-	respBody, err := p.queryInstant(promql, "")
-	if err != nil {
-		return nil, err
-	}
-
+func (p *PromQueryClient) QueryInstantRange(ctx context.Context, promql string) ([]*model.SampleStream, error) {
 	message := &PromqlRangeMessageType{}
-	if err := json.Unmarshal(respBody, message); err != nil {
-		klog.Errorf("Unmarshal error:%v", err)
+	if err := p.queryInstant(ctx, promql, "", message, &message.PromqlStatusType); err != nil {
+		return nil, err
 	}
-
 	return message.Data.Result, nil
 }
 
-func (p *PromQueryClient) queryInstant(promql string, time string) ([]byte, error) {
-	req, err := http.NewRequest(http.MethodPost, p.endpoint+instantQueryBaseUrl, nil)
+// newRequest creates the request of the query api, the tenant id is set in the path or the header
+func (p *PromQueryClient) newRequest(ctx context.Context, method, api string, queryParameters url.Values) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, p.endpoint+p.apiPath(p.tenantId)+api, nil)
 	if err != nil {
 		klog.Errorf("Create http request error:%v", err)
-		return nil, err
+		return nil, newError(ErrorTypeInternal, "create query request error", err)
+	}
+	if p.tenantId != "" && p.tenantHeader != "" {
+		klog.V(4).Infof("Query data with tenant id:%s", p.tenantId)
+		req.Header.Add(p.tenantHeader, p.tenantId)
 	}
+	req.URL.RawQuery = queryParameters.Encode()
+	return req, nil
+}
 
-	queryParameters := req.URL.Query()
+func (p *PromQueryClient) queryInstant(ctx context.Context, promql string, time string, message interface{}, status *PromqlStatusType) error {
+	queryParameters := url.Values{}
 	queryParameters.Add("query", promql)
 	// If it's not set, query from current time
 	if time != "" {
 		queryParameters.Add("time", time)
 	}
-	if p.tenantId != "" {
-		klog.Infof("Query data with tenant id:%s", p.tenantId)
-		req.Header.Add(values.MultiTenantHeader, p.tenantId)
-	}
-	req.URL.RawQuery = queryParameters.Encode()
-
-	resp, err := p.httpClient.Do(req)
-	if err != nil {
-		klog.Errorf("Promql query instant error:%v", err)
-		return nil, err
-	}
-
-	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	req, err := p.newRequest(ctx, http.MethodPost, instantQueryBaseUrl, queryParameters)
 	if err != nil {
-		klog.Errorf("Read resp body error:%v", err)
-		return nil, err
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		err = fmt.Errorf("query failed:%s", string(bodyBytes))
-		klog.Errorf("%v", err)
-		return nil, err
+		return err
 	}
-
-	return bodyBytes, nil
+	return p.do(req, message, status)
 }
 
-func (p *PromQueryClient) QueryRange(promql string, start, end int64) ([]*model.SampleStream, error) {
-	req, err := http.NewRequest(http.MethodGet, p.endpoint+rangeQueryBaseUrl, nil)
-	if err != nil {
-		klog.Errorf("Create http request error:%v", err)
-		return nil, err
-	}
+func (p *PromQueryClient) QueryRange(ctx context.Context, promql string, start, end int64) ([]*model.SampleStream, error) {
+	return p.QueryRangeWithStep(ctx, promql, start, end, rangeStepSeconds(start, end))
+}
 
+func rangeStepSeconds(start, end int64) int64 {
 	// The returned max point's number is 11000, so we chould choose a right step step seconds
 	stepSeconds := (end - start) / 10000
+	// KubeFin collect metrics in 15s period
 	if stepSeconds < 15 {
 		stepSeconds = 15
 	}
+	return stepSeconds
+}
 
-	queryParameters := req.URL.Query()
+func (p *PromQueryClient) QueryRangeWithStep(ctx context.Context, promql string, start, end, stepSeconds int64) ([]*model.SampleStream, error) {
+	queryParameters := url.Values{}
 	queryParameters.Add("query", promql)
 	queryParameters.Add("start", fmt.Sprintf("%d", start))
 	queryParameters.Add("end", fmt.Sprintf("%d", end))
-	// KubeFin collect metrics in 15s period
 	queryParameters.Add("step", fmt.Sprintf("%ds", stepSeconds))
-	if p.tenantId != "" {
-		klog.V(4).Infof("Query data with tenant id:%s", p.tenantId)
-		req.Header.Add(values.MultiTenantHeader, p.tenantId)
-	}
-	req.URL.RawQuery = queryParameters.Encode()
-	resp, err := p.httpClient.Do(req)
-	if err != nil {
-		klog.Errorf("Promql query range error:%v", err)
-		return nil, err
-	}
-	bodyBytes, err := io.ReadAll(resp.Body)
+	req, err := p.newRequest(ctx, http.MethodGet, rangeQueryBaseUrl, queryParameters)
 	if err != nil {
-		klog.Errorf("Read resp body error:%v", err)
-		return nil, err
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		err := fmt.Errorf("query backend error:%s", string(bodyBytes))
-		klog.Errorf("%v", err)
 		return nil, err
 	}
 
 	message := &PromqlRangeMessageType{}
-	if err := json.Unmarshal(bodyBytes, message); err != nil {
-		klog.Errorf("Unmarshal error:%v", err)
+	if err := p.do(req, message, &message.PromqlStatusType); err != nil {
+		return nil, err
 	}
-
 	return message.Data.Result, nil
 }
 
-func (p *PromQueryClient) QueryRangeWithStep(promql string, start, end, stepSeconds int64) ([]*model.SampleStream, error) {
-	req, err := http.NewRequest(http.MethodGet, p.endpoint+rangeQueryBaseUrl, nil)
-	if err != nil {
-		klog.Errorf("Create http request error:%v", err)
-		return nil, err
-	}
-
-	queryParameters := req.URL.Query()
-	queryParameters.Add("query", promql)
-	queryParameters.Add("start", fmt.Sprintf("%d", start))
-	queryParameters.Add("end", fmt.Sprintf("%d", end))
-	queryParameters.Add("step", fmt.Sprintf("%ds", stepSeconds))
-	if p.tenantId != "" {
-		klog.Infof("Query data with tenant id:%s", p.tenantId)
-		req.Header.Add(values.MultiTenantHeader, p.tenantId)
-	}
-	req.URL.RawQuery = queryParameters.Encode()
+// do sends the request and decodes the response into message, status is the PromqlStatusType
+// embedded in message. The failures are returned as *Error classified by ErrorType.
+func (p *PromQueryClient) do(req *http.Request, message interface{}, status *PromqlStatusType) error {
 	resp, err := p.httpClient.Do(req)
 	if err != nil {
-		klog.Errorf("Promql query range error:%v", err)
-		return nil, err
+		klog.Errorf("Promql query error:%v", err)
+		return newError(errorTypeFromTransport(err), "send query request error", err)
 	}
+	defer resp.Body.Close()
 
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
 		klog.Errorf("Read resp body error:%v", err)
-		return nil, err
+		return newError(errorTypeFromTransport(err), "read query response error", err)
 	}
+
 	if resp.StatusCode != http.StatusOK {
-		err := fmt.Errorf("query backend error:%s", string(bodyBytes))
+		// The Prometheus HTTP API returns the error in json, but the proxies in front of it may not
+		if err := json.Unmarshal(bodyBytes, status); err != nil || status.ErrorType == "" {
+			err := newError(errorTypeFromStatusCode(resp.StatusCode),
+				fmt.Sprintf("query backend returns %d:%s", resp.StatusCode, string(bodyBytes)), nil)
+			klog.Errorf("%v", err)
+			return err
+		}
+		err := newError(errorTypeFromAPI(status.ErrorType),
+			fmt.Sprintf("query backend returns %s:%s", status.ErrorType, status.Error), nil)
 		klog.Errorf("%v", err)
-		return nil, err
+		return err
 	}
 
-	message := &PromqlRangeMessageType{}
 	if err := json.Unmarshal(bodyBytes, message); err != nil {
 		klog.Errorf("Unmarshal error:%v", err)
+		return newError(ErrorTypeInternal, "decode query response error", err)
 	}
-
-	return message.Data.Result, nil
+	if status.Status != statusSuccess {
+		err := newError(errorTypeFromAPI(status.ErrorType),
+			fmt.Sprintf("query backend returns %s:%s", status.ErrorType, status.Error), nil)
+		klog.Errorf("%v", err)
+		return err
+	}
+	if len(status.Warnings) > 0 {
+		klog.Warningf("Query backend returns warnings:%v", status.Warnings)
+		if p.warningHandler != nil {
+			p.warningHandler(status.Warnings)
+		}
+	}
+	return nil
 }
@@ -16,14 +16,21 @@ limitations under the License.
 
 package query
 
-import "github.com/kubefin/kubefin/pkg/values"
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/kubefin/kubefin/pkg/values"
+)
 
 var (
-	QlSumNodesResourceTotalFromCluster       = "sum(" + values.NodeResourceTotalMetricsName + "{cluster_id='%s',resource='%s'})"
-	QlSumNodesResourceAvailableFromCluster   = "sum(" + values.NodeResourceAvailableMetricsName + "{cluster_id='%s',resource='%s'})"
-	QlSumNodesResourceUsageFromCluster       = "sum(" + values.NodeResourceUsageMetricsName + "{cluster_id='%s',resource='%s'})"
-	QlSumNodesResourceSystemTakenFromCluster = "sum(" + values.NodeResourceSystemTakenName + "{cluster_id='%s',resource='%s'})"
-	QlSumPodResourceRequestFromCluster       = "sum(" + values.PodResourceRequestMetricsName + "{cluster_id='%s',resource='%s'})"
+	QlSumNodesResourceTotalFromCluster       = "sum(" + values.NodeResourceTotalMetricsName + "{%s,resource='%s'})"
+	QlSumNodesResourceAvailableFromCluster   = "sum(" + values.NodeResourceAvailableMetricsName + "{%s,resource='%s'})"
+	QlSumNodesResourceUsageFromCluster       = "sum(" + values.NodeResourceUsageMetricsName + "{%s,resource='%s'})"
+	QlSumNodesResourceSystemTakenFromCluster = "sum(" + values.NodeResourceSystemTakenName + "{%s,resource='%s'})"
+	QlSumPodResourceRequestFromCluster       = "sum(" + values.PodResourceRequestMetricsName + "{%s,resource='%s'})"
 
 	QlNodesNumber         = "count(" + values.NodeTotalHourlyCostMetricsName + ") by (cluster_id,billing_mode)"
 	QlPodsNumber          = "count(" + values.PodResoueceCostMetricsName + ") by (cluster_id,scheduled)"
@@ -33,50 +40,170 @@ var (
 	QlResourceAvailable   = "sum(" + values.NodeResourceAvailableMetricsName + ") by (cluster_id,resource)"
 	QlResoruceSystemTaken = "sum(" + values.NodeResourceSystemTakenName + ") by (cluster_id,resource)"
 
-	QlNodesNumberFromCluster         = "count(" + values.NodeTotalHourlyCostMetricsName + "{cluster_id='%s'}) by (billing_mode)"
-	QlPodsNumberFromCluster          = "count(" + values.PodResoueceCostMetricsName + "{cluster_id='%s'}) by (scheduled)"
-	QlResourceTotalFromCluster       = "sum(" + values.NodeResourceTotalMetricsName + "{cluster_id='%s'}) by (resource)"
-	QlResourceUsageFromCluster       = "sum(" + values.NodeResourceUsageMetricsName + "{cluster_id='%s'}) by (resource)"
-	QlResourceRequestFromCluster     = "sum(" + values.PodResourceRequestMetricsName + "{cluster_id='%s'}) by (resource)"
-	QlResourceAvailableFromCluster   = "sum(" + values.NodeResourceAvailableMetricsName + "{cluster_id='%s'}) by (resource)"
-	QlResourceSystemTakenFromCluster = "sum(" + values.NodeResourceSystemTakenName + "{cluster_id='%s'}) by (resource)"
+	QlNodesNumberFromCluster         = "count(" + values.NodeTotalHourlyCostMetricsName + "{%s}) by (billing_mode)"
+	QlPodsNumberFromCluster          = "count(" + values.PodResoueceCostMetricsName + "{%s}) by (scheduled)"
+	QlResourceTotalFromCluster       = "sum(" + values.NodeResourceTotalMetricsName + "{%s}) by (resource)"
+	QlResourceUsageFromCluster       = "sum(" + values.NodeResourceUsageMetricsName + "{%s}) by (resource)"
+	QlResourceRequestFromCluster     = "sum(" + values.PodResourceRequestMetricsName + "{%s}) by (resource)"
+	QlResourceAvailableFromCluster   = "sum(" + values.NodeResourceAvailableMetricsName + "{%s}) by (resource)"
+	QlResourceSystemTakenFromCluster = "sum(" + values.NodeResourceSystemTakenName + "{%s}) by (resource)"
 
-	QlTotalPodsNumberFromCluster            = "count(count(" + values.PodResourceRequestMetricsName + "{cluster_id='%s'}) by (pod))"
-	QlPodsNumberByScheduleStatusFromCluster = "count(count(" + values.PodResourceRequestMetricsName + "{cluster_id='%s',scheduled='%s'}) by (pod))"
+	QlTotalPodsNumberFromCluster            = "count(count(" + values.PodResourceRequestMetricsName + "{%s}) by (pod))"
+	QlPodsNumberByScheduleStatusFromCluster = "count(count(" + values.PodResourceRequestMetricsName + "{%s,scheduled='%s'}) by (pod))"
 
-	QlClusterActiveTimeWithTimeRange = "count_over_time(" + values.ClusterActiveMetricsName + "{cluster_id='%s'}[%ds])*15"
+	QlClusterActiveTimeWithTimeRange = "count_over_time(" + values.ClusterActiveMetricsName + "{%s}[%ds])*15"
 
-	QlNodesTotalHourlyCostFromClusterWithTimeRange            = "sum(sum_over_time(" + values.NodeTotalHourlyCostMetricsName + "{cluster_id='%s'}[%ds]))/240"
-	QlNodesTotalHourlyBillingModeCostFromClusterWithTimeRange = "sum(sum_over_time(" + values.NodeTotalHourlyCostMetricsName + "{cluster_id='%s'}[%ds])/240) by (billing_mode)"
+	QlNodesTotalHourlyCostFromClusterWithTimeRange            = "sum(sum_over_time(" + values.NodeTotalHourlyCostMetricsName + "{%s}[%ds]))/240"
+	QlNodesTotalHourlyBillingModeCostFromClusterWithTimeRange = "sum(sum_over_time(" + values.NodeTotalHourlyCostMetricsName + "{%s}[%ds])/240) by (billing_mode)"
 
 	// TODO: NodeCPUHourlyCostMetricsName/NodeRAMHourlyCostMetricsName could be merged as one
-	QlNodeCPUTotalCostFromClusterWithTimeRange       = "sum(sum_over_time(" + values.NodeResourceHourlyCostMetricsName + "{cluster_id='%s',resource='cpu'}[%ds]))/240"
-	QlNodeResourceTotalCostFromClusterWithTimeRange  = "sum(sum_over_time(" + values.NodeResourceHourlyCostMetricsName + "{cluster_id='%s'}[%ds])/240) by (resource)"
+	QlNodeCPUTotalCostFromClusterWithTimeRange       = "sum(sum_over_time(" + values.NodeResourceHourlyCostMetricsName + "{%s,resource='cpu'}[%ds]))/240"
+	QlNodeResourceTotalCostFromClusterWithTimeRange  = "sum(sum_over_time(" + values.NodeResourceHourlyCostMetricsName + "{%s}[%ds])/240) by (resource)"
 	QlNodeCPUTotalCostWithTimeRange                  = "sum(sum_over_time(" + values.NodeResourceHourlyCostMetricsName + "{resource='cpu'}[%ds])/240) by (cluster_id)"
-	QlNodeResourceTotalCountFromClusterWithTimeRange = "sum(sum_over_time(" + values.NodeResourceTotalMetricsName + "{cluster_id='%s',resource='%s'}[%ds]))/240"
+	QlNodeResourceTotalCountFromClusterWithTimeRange = "sum(sum_over_time(" + values.NodeResourceTotalMetricsName + "{%s,resource='%s'}[%ds]))/240"
 	QlNodeCPUTotalCountWithTimeRange                 = "sum(sum_over_time(" + values.NodeResourceTotalMetricsName + "{resource='%s'}[%ds])/240) by (cluster_id)"
-	QlNodeResourceUsageCountFromClusterWithTimeRange = "sum(sum_over_time(" + values.NodeResourceUsageMetricsName + "{cluster_id='%s',resource='%s'}[%ds]))/240"
-
-	QlPodTotalCostFromClusterWithTimeRange            = "sum(sum_over_time(" + values.PodResoueceCostMetricsName + "{cluster_id='%s'}[%ds])/240) by (pod,namespace)"
-	QlPodResourceRequestFromClusterWithTimeRange      = "sum(sum_over_time(" + values.PodResourceRequestMetricsName + "{cluster_id='%s'}[%ds])/240) by (pod,namespace,resource)"
-	QlPodResourceUsageFromClusterWithTimeRange        = "sum(sum_over_time(" + values.PodResourceUsageMetricsName + "{cluster_id='%s'}[%ds])/240) by (pod,namespace,resource)"
-	QlWorkloadTotalCostFromClusterWithTimeRange       = "sum(sum_over_time(" + values.WorkloadResourceCostMetricsName + "{cluster_id='%s',workload_type=~'%s'}[%ds])/240) by (namespace,workload_name,workload_type)"
-	QlWorkloadPodFromClusterWithTimeRange             = "sum(sum_over_time(" + values.WorkloadPodCountMetricsName + "{cluster_id='%s',workload_type=~'%s'}[%ds])) by (namespace,workload_name,workload_type)"
-	QlWorkloadResourceRequestFromClusterWithTimeRange = "sum(sum_over_time(" + values.WorkloadResourceRequestMetricsName + "{cluster_id='%s',workload_type=~'%s'}[%ds])/240) by (namespace,workload_name,workload_type,resource)"
-	QlWorkloadResourceUsageFromClusterWithTimeRange   = "sum(sum_over_time(" + values.WorkloadResourceUsageMetricsName + "{cluster_id='%s',workload_type=~'%s'}[%ds])/240) by (namespace,workload_name,workload_type,resource)"
-	QlNSTotalCostFromClusterWithTimeRange             = "sum(sum_over_time(" + values.PodResoueceCostMetricsName + "{cluster_id='%s'}[%ds])/240) by (namespace)"
+	QlNodeResourceUsageCountFromClusterWithTimeRange = "sum(sum_over_time(" + values.NodeResourceUsageMetricsName + "{%s,resource='%s'}[%ds]))/240"
+	// QlPodResourceRequestCountFromClusterWithTimeRange gets the resource hours requested by all pods
+	QlPodResourceRequestCountFromClusterWithTimeRange = "sum(sum_over_time(" + values.PodResourceRequestMetricsName + "{%s,resource='%s'}[%ds]))/240"
+
+	// The pod/workload/namespace queries below take the extra label matchers right after the other label matchers
+	QlPodTotalCostFromClusterWithTimeRange            = "sum(sum_over_time(" + values.PodResoueceCostMetricsName + "{%s%s}[%ds])/240) by (pod,namespace)"
+	QlPodResourceRequestFromClusterWithTimeRange      = "sum(sum_over_time(" + values.PodResourceRequestMetricsName + "{%s%s}[%ds])/240) by (pod,namespace,resource)"
+	QlPodResourceUsageFromClusterWithTimeRange        = "sum(sum_over_time(" + values.PodResourceUsageMetricsName + "{%s%s}[%ds])/240) by (pod,namespace,resource)"
+	QlWorkloadTotalCostFromClusterWithTimeRange       = "sum(sum_over_time(" + values.WorkloadResourceCostMetricsName + "{%s,workload_type=~'%s'%s}[%ds])/240) by (namespace,workload_name,workload_type)"
+	QlWorkloadPodFromClusterWithTimeRange             = "sum(sum_over_time(" + values.WorkloadPodCountMetricsName + "{%s,workload_type=~'%s'%s}[%ds])) by (namespace,workload_name,workload_type)"
+	QlWorkloadResourceRequestFromClusterWithTimeRange = "sum(sum_over_time(" + values.WorkloadResourceRequestMetricsName + "{%s,workload_type=~'%s'%s}[%ds])/240) by (namespace,workload_name,workload_type,resource)"
+	QlWorkloadResourceUsageFromClusterWithTimeRange   = "sum(sum_over_time(" + values.WorkloadResourceUsageMetricsName + "{%s,workload_type=~'%s'%s}[%ds])/240) by (namespace,workload_name,workload_type,resource)"
+	QlNSTotalCostFromClusterWithTimeRange             = "sum(sum_over_time(" + values.PodResoueceCostMetricsName + "{%s%s}[%ds])/240) by (namespace)"
+	QlPodLabelsTotalCostFromClusterWithTimeRange      = "sum(sum_over_time(" + values.PodResoueceCostMetricsName + "{%s%s}[%ds])/240) by (labels)"
 	// TODO: Check the scheduled label has effect on this
-	QlNSPodFromClusterWithTimeRange             = "sum(count_over_time(" + values.PodResoueceCostMetricsName + "{cluster_id='%s'}[%ds])) by (namespace)"
-	QlNSResourceRequestFromClusterWithTimeRange = "sum(sum_over_time(" + values.PodResourceRequestMetricsName + "{cluster_id='%s'}[%ds])/240) by (namespace,resource)"
-	QlNSResourceUsageFromClusterWithTimeRange   = "sum(sum_over_time(" + values.PodResourceUsageMetricsName + "{cluster_id='%s'}[%ds])/240) by (namespace,resource)"
+	QlNSPodFromClusterWithTimeRange             = "sum(count_over_time(" + values.PodResoueceCostMetricsName + "{%s%s}[%ds])) by (namespace)"
+	QlNSResourceRequestFromClusterWithTimeRange = "sum(sum_over_time(" + values.PodResourceRequestMetricsName + "{%s%s}[%ds])/240) by (namespace,resource)"
+	QlNSResourceUsageFromClusterWithTimeRange   = "sum(sum_over_time(" + values.PodResourceUsageMetricsName + "{%s%s}[%ds])/240) by (namespace,resource)"
+
+	// The multi-clusters queries below take the cluster id regex and the extra label matchers right after it
+	QlNSTotalCostFromClustersWithTimeRange           = "sum(sum_over_time(" + values.PodResoueceCostMetricsName + "{cluster_id=~'%s'%s}[%ds])/240) by (cluster_id,namespace)"
+	QlPodLabelsTotalCostFromClustersWithTimeRange    = "sum(sum_over_time(" + values.PodResoueceCostMetricsName + "{cluster_id=~'%s'%s}[%ds])/240) by (cluster_id,namespace,labels)"
+	QlNodeResourceTotalCostFromClustersWithTimeRange = "sum(sum_over_time(" + values.NodeResourceHourlyCostMetricsName + "{cluster_id=~'%s'%s}[%ds])/240) by (cluster_id,resource)"
 
 	// QlNodesTotalCostsFromClusterWithTimeRange get all nodes cost with time range, we sample metrics
 	// every 15 seconds, so 240 is used to transform it to one hour
-	QlNodesTotalCostsFromClusterWithTimeRange = "sum(sum_over_time(" + values.NodeTotalHourlyCostMetricsName + "{cluster_id='%s'}[%ds]))/240"
-	QlNodesTotalCostsWithTimeRange            = "sum(sum_over_time(" + values.NodeTotalHourlyCostMetricsName + "[%ds])/240) by (cluster_id)"
+	QlNodesTotalCostsFromClusterWithTimeRange = "sum(sum_over_time(" + values.NodeTotalHourlyCostMetricsName + "{%s}[%ds]))/240"
+	// QlPodsTotalCostFromClusterWithTimeRange takes the extra label matchers right after cluster_id
+	QlPodsTotalCostFromClusterWithTimeRange = "sum(sum_over_time(" + values.PodResoueceCostMetricsName + "{%s%s}[%ds]))/240"
+	QlNodesTotalCostsWithTimeRange          = "sum(sum_over_time(" + values.NodeTotalHourlyCostMetricsName + "[%ds])/240) by (cluster_id)"
+	// QlNodesAvgCountWithTimeRange gets the average node count of every cluster, it takes the range twice
+	QlNodesAvgCountWithTimeRange            = "sum(count_over_time(" + values.NodeTotalHourlyCostMetricsName + "[%ds])) by (cluster_id)*15/%d"
+	QlNodesAvgCountFromClusterWithTimeRange = "sum(count_over_time(" + values.NodeTotalHourlyCostMetricsName + "{%s}[%ds]))*15/%d"
+
+	// The node queries below take the extra label matchers right after cluster_id
+	QlNodeTotalCostFromClusterWithTimeRange    = "sum(sum_over_time(" + values.NodeTotalHourlyCostMetricsName + "{%s%s}[%ds])/240) by (node,instance_type,billing_mode,region)"
+	QlNodeHourlyPriceFromClusterWithTimeRange  = "max(avg_over_time(" + values.NodeTotalHourlyCostMetricsName + "{%s%s}[%ds])) by (node)"
+	QlNodeResourceCostFromClusterWithTimeRange = "sum(sum_over_time(" + values.NodeResourceHourlyCostMetricsName + "{%s%s}[%ds])/240) by (node,resource)"
+	// QlNodeResourceAvgFromClusterWithTimeRange takes the metric name of node total/system taken/available/usage resource
+	QlNodeResourceAvgFromClusterWithTimeRange = "sum(avg_over_time(%s{%s%s}[%ds])) by (node,resource)"
+
+	// The node simulation queries take the current nodes and the pod requests placed on them
+	QlNodeHourlyPriceFromCluster = "max(" + values.NodeTotalHourlyCostMetricsName + "{%s}) by (node,instance_type,billing_mode,region)"
+	// QlNodeResourceFromCluster takes the metric name of node total/system taken resource
+	QlNodeResourceFromCluster             = "sum(%s{%s}) by (node,resource)"
+	QlPodResourceRequestByNodeFromCluster = "sum(" + values.PodResourceRequestMetricsName + "{%s}) by (namespace,pod,node,resource)"
+
+	// QlWorkloadContainerPerPodResource gets the request/usage of every workload container divided by the pod count,
+	// it takes the metric name, the cluster matcher, the resource and the extra matchers, then the cluster matcher and matchers again
+	QlWorkloadContainerPerPodResource = "sum(%s{%s,resource='%s'%s}) by (namespace,workload_type,workload_name,container)" +
+		" / on(namespace,workload_type,workload_name) group_left sum(" + values.WorkloadPodCountMetricsName +
+		"{%s%s}) by (namespace,workload_type,workload_name)"
+	QlWorkloadAvgPodCountWithTimeRange = "sum(avg_over_time(" + values.WorkloadPodCountMetricsName + "{%s%s}[%ds])) by (namespace,workload_type,workload_name)"
+	// QlQuantileOverTime/QlMaxOverTime wrap a query as a subquery with the range and the resolution
+	QlQuantileOverTime = "quantile_over_time(%v, (%s)[%ds:%ds])"
+	QlMaxOverTime      = "max_over_time((%s)[%ds:%ds])"
+
+	QlClusterAvgCPUCoreHourlyCostWithTimeRange = "avg(avg_over_time(" + values.NodeCPUCoreHourlyCostMetricsName + "{%s}[%ds]))"
+	QlClusterAvgRAMGBHourlyCostWithTimeRange   = "avg(avg_over_time(" + values.NodeRAMGBHourlyCostMetricsName + "{%s}[%ds]))"
+
+	// The spot analysis queries take the suitability, the average requests and the unit prices by billing mode
+	QlWorkloadSpotSuitableFromCluster                       = "max(" + values.WorkloadSpotSuitableMetricsName + "{%s%s}) by (namespace,workload_type,workload_name,spot_blockers)"
+	QlWorkloadAvgResourceRequestFromClusterWithTimeRange    = "sum(avg_over_time(" + values.WorkloadResourceRequestMetricsName + "{%s,workload_type=~'deployment|statefulset'%s}[%ds])) by (namespace,workload_type,workload_name,resource)"
+	QlClusterAvgCPUCoreHourlyCostByBillingModeWithTimeRange = "avg(avg_over_time(" + values.NodeCPUCoreHourlyCostMetricsName + "{%s}[%ds])) by (billing_mode)"
+	QlClusterAvgRAMGBHourlyCostByBillingModeWithTimeRange   = "avg(avg_over_time(" + values.NodeRAMGBHourlyCostMetricsName + "{%s}[%ds])) by (billing_mode)"
+
+	// The rollup queries take the rollup series first and sum it over the range, the rollups are the cost already
+	QlRollupRecordedWithTimeRange                        = "count(last_over_time({__name__='%s'%s}[%ds]))"
+	QlNodesTotalCostsRollupWithTimeRange                 = "sum(sum_over_time(%s[%ds])) by (cluster_id)"
+	QlNodesBillingModeCostFromClusterRollupWithTimeRange = "sum(sum_over_time(%s{%s}[%ds])) by (billing_mode)"
+	QlNodeTotalCostFromClusterRollupWithTimeRange        = "sum(sum_over_time(%s{%s%s}[%ds])) by (node,instance_type,billing_mode,region)"
+	QlNSTotalCostFromClusterRollupWithTimeRange          = "sum(sum_over_time(%s{%s%s}[%ds])) by (namespace)"
+	QlWorkloadTotalCostFromClusterRollupWithTimeRange    = "sum(sum_over_time(%s{%s,workload_type=~'%s'%s}[%ds])) by (namespace,workload_name,workload_type)"
+	QlPodLabelsTotalCostFromClusterRollupWithTimeRange   = "sum(sum_over_time(%s{%s%s}[%ds])) by (labels)"
+	QlTotalCostFromClusterRollupWithTimeRange            = "sum(sum_over_time(%s{%s%s}[%ds]))"
+	QlNSTotalCostFromClustersRollupWithTimeRange         = "sum(sum_over_time(%s{cluster_id=~'%s'%s}[%ds])) by (cluster_id,namespace)"
+	QlPodLabelsTotalCostFromClustersRollupWithTimeRange  = "sum(sum_over_time(%s{cluster_id=~'%s'%s}[%ds])) by (cluster_id,namespace,labels)"
+
+	// QlCostByWithTimeRange sums the cost metric over the range by the labels, it takes the metric, the range and the labels
+	QlCostByWithTimeRange = "sum(sum_over_time(%s[%ds])/240) by (%s)"
 
 	QlAllClustersActivity   = "kubefin_cluster_active"
-	QlClusterActivity       = "kubefin_cluster_active{cluster_id='%s'}"
-	QlClusterActiveTime     = "count_over_time(" + values.ClusterActiveMetricsName + "{cluster_id='%s'}[%ds])*15"
+	QlClusterActivity       = "kubefin_cluster_active{%s}"
+	QlClusterActiveTime     = "count_over_time(" + values.ClusterActiveMetricsName + "{%s}[%ds])*15"
 	QlAllClustersActiveTime = "count_over_time(" + values.ClusterActiveMetricsName + "[%ds])*15"
 )
+
+// NamespaceMatcher renders the extra label matcher restricting the query to the namespaces
+// matching namespaceRe, it renders nothing if namespaceRe is empty
+func NamespaceMatcher(namespaceRe string) string {
+	if namespaceRe == "" {
+		return ""
+	}
+	namespaceRe = strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(namespaceRe)
+	return fmt.Sprintf(",%s=~'%s'", values.NamespaceLabelKey, namespaceRe)
+}
+
+// ClusterMatcher renders the label matcher selecting the cluster, the templates taking a cluster
+// always take it through this so the cluster id could not break out of the quotes
+func ClusterMatcher(clusterId string) string {
+	clusterId = strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(clusterId)
+	return fmt.Sprintf("%s='%s'", values.ClusterIdLabelKey, clusterId)
+}
+
+// ClusterIdsRegex renders the regex matching exactly the cluster ids to be put into the promql
+func ClusterIdsRegex(clusterIds []string) string {
+	res := make([]string, 0, len(clusterIds))
+	for _, clusterId := range clusterIds {
+		res = append(res, regexp.QuoteMeta(clusterId))
+	}
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(strings.Join(res, "|"))
+}
+
+// LabelMatcher renders the extra label matcher selecting the exact label value,
+// it renders nothing if value is empty
+func LabelMatcher(label, value string) string {
+	if value == "" {
+		return ""
+	}
+	value = strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value)
+	return fmt.Sprintf(",%s='%s'", label, value)
+}
+
+// RegexMatcher renders the extra label matcher selecting the label values fully matching re,
+// it renders nothing if re is empty
+func RegexMatcher(label, re string) string {
+	if re == "" {
+		return ""
+	}
+	re = strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(re)
+	return fmt.Sprintf(",%s=~'%s'", label, re)
+}
+
+// PodLabelMatcher renders the extra label matcher selecting the pods with the label,
+// the pod labels are kept in the labels label as a json object
+func PodLabelMatcher(key, value string) string {
+	if key == "" {
+		return ""
+	}
+	keyJson, _ := json.Marshal(key)
+	valueJson, _ := json.Marshal(value)
+	re := ".*" + regexp.QuoteMeta(string(keyJson)+":"+string(valueJson)) + ".*"
+	re = strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(re)
+	return fmt.Sprintf(",%s=~'%s'", values.LabelsLabelKey, re)
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package query_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/kubefin/kubefin/pkg/query"
+	"github.com/kubefin/kubefin/pkg/query/querytest"
+	"github.com/kubefin/kubefin/pkg/values"
+)
+
+func TestTemplatesRenderValidPromql(t *testing.T) {
+	const (
+		rangeSeconds = int64(3600)
+		rollup       = "kubefin_cost_rollup_hourly"
+		workloadRe   = "deployment|statefulset|daemonset"
+	)
+	cluster := query.ClusterMatcher("cluster-1")
+	matchers := query.NamespaceMatcher("default")
+	clustersRe := query.ClusterIdsRegex([]string{"cluster-1", "cluster-2"})
+	perPodUsage := fmt.Sprintf(query.QlWorkloadContainerPerPodResource, values.WorkloadResourceUsageMetricsName,
+		cluster, "cpu", matchers, cluster, matchers)
+
+	tests := []struct {
+		name     string
+		template string
+		args     []interface{}
+	}{
+		{"QlSumNodesResourceTotalFromCluster", query.QlSumNodesResourceTotalFromCluster, []interface{}{cluster, "cpu"}},
+		{"QlSumNodesResourceAvailableFromCluster", query.QlSumNodesResourceAvailableFromCluster, []interface{}{cluster, "cpu"}},
+		{"QlSumNodesResourceUsageFromCluster", query.QlSumNodesResourceUsageFromCluster, []interface{}{cluster, "cpu"}},
+		{"QlSumNodesResourceSystemTakenFromCluster", query.QlSumNodesResourceSystemTakenFromCluster, []interface{}{cluster, "cpu"}},
+		{"QlSumPodResourceRequestFromCluster", query.QlSumPodResourceRequestFromCluster, []interface{}{cluster, "cpu"}},
+		{"QlNodesNumber", query.QlNodesNumber, nil},
+		{"QlPodsNumber", query.QlPodsNumber, nil},
+		{"QlResourceTotal", query.QlResourceTotal, nil},
+		{"QlResourceUsage", query.QlResourceUsage, nil},
+		{"QlResourceRequest", query.QlResourceRequest, nil},
+		{"QlResourceAvailable", query.QlResourceAvailable, nil},
+		{"QlResoruceSystemTaken", query.QlResoruceSystemTaken, nil},
+		{"QlNodesNumberFromCluster", query.QlNodesNumberFromCluster, []interface{}{cluster}},
+		{"QlPodsNumberFromCluster", query.QlPodsNumberFromCluster, []interface{}{cluster}},
+		{"QlResourceTotalFromCluster", query.QlResourceTotalFromCluster, []interface{}{cluster}},
+		{"QlResourceUsageFromCluster", query.QlResourceUsageFromCluster, []interface{}{cluster}},
+		{"QlResourceRequestFromCluster", query.QlResourceRequestFromCluster, []interface{}{cluster}},
+		{"QlResourceAvailableFromCluster", query.QlResourceAvailableFromCluster, []interface{}{cluster}},
+		{"QlResourceSystemTakenFromCluster", query.QlResourceSystemTakenFromCluster, []interface{}{cluster}},
+		{"QlTotalPodsNumberFromCluster", query.QlTotalPodsNumberFromCluster, []interface{}{cluster}},
+		{"QlPodsNumberByScheduleStatusFromCluster", query.QlPodsNumberByScheduleStatusFromCluster, []interface{}{cluster, "true"}},
+		{"QlClusterActiveTimeWithTimeRange", query.QlClusterActiveTimeWithTimeRange, []interface{}{cluster, rangeSeconds}},
+		{"QlNodesTotalHourlyCostFromClusterWithTimeRange", query.QlNodesTotalHourlyCostFromClusterWithTimeRange, []interface{}{cluster, rangeSeconds}},
+		{"QlNodesTotalHourlyBillingModeCostFromClusterWithTimeRange", query.QlNodesTotalHourlyBillingModeCostFromClusterWithTimeRange, []interface{}{cluster, rangeSeconds}},
+		{"QlNodeCPUTotalCostFromClusterWithTimeRange", query.QlNodeCPUTotalCostFromClusterWithTimeRange, []interface{}{cluster, rangeSeconds}},
+		{"QlNodeResourceTotalCostFromClusterWithTimeRange", query.QlNodeResourceTotalCostFromClusterWithTimeRange, []interface{}{cluster, rangeSeconds}},
+		{"QlNodeCPUTotalCostWithTimeRange", query.QlNodeCPUTotalCostWithTimeRange, []interface{}{rangeSeconds}},
+		{"QlNodeResourceTotalCountFromClusterWithTimeRange", query.QlNodeResourceTotalCountFromClusterWithTimeRange, []interface{}{cluster, "cpu", rangeSeconds}},
+		{"QlNodeCPUTotalCountWithTimeRange", query.QlNodeCPUTotalCountWithTimeRange, []interface{}{"cpu", rangeSeconds}},
+		{"QlNodeResourceUsageCountFromClusterWithTimeRange", query.QlNodeResourceUsageCountFromClusterWithTimeRange, []interface{}{cluster, "cpu", rangeSeconds}},
+		{"QlPodResourceRequestCountFromClusterWithTimeRange", query.QlPodResourceRequestCountFromClusterWithTimeRange, []interface{}{cluster, "cpu", rangeSeconds}},
+		{"QlPodTotalCostFromClusterWithTimeRange", query.QlPodTotalCostFromClusterWithTimeRange, []interface{}{cluster, matchers, rangeSeconds}},
+		{"QlPodResourceRequestFromClusterWithTimeRange", query.QlPodResourceRequestFromClusterWithTimeRange, []interface{}{cluster, matchers, rangeSeconds}},
+		{"QlPodResourceUsageFromClusterWithTimeRange", query.QlPodResourceUsageFromClusterWithTimeRange, []interface{}{cluster, matchers, rangeSeconds}},
+		{"QlWorkloadTotalCostFromClusterWithTimeRange", query.QlWorkloadTotalCostFromClusterWithTimeRange, []interface{}{cluster, workloadRe, matchers, rangeSeconds}},
+		{"QlWorkloadPodFromClusterWithTimeRange", query.QlWorkloadPodFromClusterWithTimeRange, []interface{}{cluster, workloadRe, matchers, rangeSeconds}},
+		{"QlWorkloadResourceRequestFromClusterWithTimeRange", query.QlWorkloadResourceRequestFromClusterWithTimeRange, []interface{}{cluster, workloadRe, matchers, rangeSeconds}},
+		{"QlWorkloadResourceUsageFromClusterWithTimeRange", query.QlWorkloadResourceUsageFromClusterWithTimeRange, []interface{}{cluster, workloadRe, matchers, rangeSeconds}},
+		{"QlNSTotalCostFromClusterWithTimeRange", query.QlNSTotalCostFromClusterWithTimeRange, []interface{}{cluster, matchers, rangeSeconds}},
+		{"QlPodLabelsTotalCostFromClusterWithTimeRange", query.QlPodLabelsTotalCostFromClusterWithTimeRange, []interface{}{cluster, matchers, rangeSeconds}},
+		{"QlNSPodFromClusterWithTimeRange", query.QlNSPodFromClusterWithTimeRange, []interface{}{cluster, matchers, rangeSeconds}},
+		{"QlNSResourceRequestFromClusterWithTimeRange", query.QlNSResourceRequestFromClusterWithTimeRange, []interface{}{cluster, matchers, rangeSeconds}},
+		{"QlNSResourceUsageFromClusterWithTimeRange", query.QlNSResourceUsageFromClusterWithTimeRange, []interface{}{cluster, matchers, rangeSeconds}},
+		{"QlNSTotalCostFromClustersWithTimeRange", query.QlNSTotalCostFromClustersWithTimeRange, []interface{}{clustersRe, matchers, rangeSeconds}},
+		{"QlPodLabelsTotalCostFromClustersWithTimeRange", query.QlPodLabelsTotalCostFromClustersWithTimeRange, []interface{}{clustersRe, matchers, rangeSeconds}},
+		{"QlNodeResourceTotalCostFromClustersWithTimeRange", query.QlNodeResourceTotalCostFromClustersWithTimeRange, []interface{}{clustersRe, matchers, rangeSeconds}},
+		{"QlNodesTotalCostsFromClusterWithTimeRange", query.QlNodesTotalCostsFromClusterWithTimeRange, []interface{}{cluster, rangeSeconds}},
+		{"QlPodsTotalCostFromClusterWithTimeRange", query.QlPodsTotalCostFromClusterWithTimeRange, []interface{}{cluster, matchers, rangeSeconds}},
+		{"QlNodesTotalCostsWithTimeRange", query.QlNodesTotalCostsWithTimeRange, []interface{}{rangeSeconds}},
+		{"QlNodesAvgCountWithTimeRange", query.QlNodesAvgCountWithTimeRange, []interface{}{rangeSeconds, rangeSeconds}},
+		{"QlNodesAvgCountFromClusterWithTimeRange", query.QlNodesAvgCountFromClusterWithTimeRange, []interface{}{cluster, rangeSeconds, rangeSeconds}},
+		{"QlNodeTotalCostFromClusterWithTimeRange", query.QlNodeTotalCostFromClusterWithTimeRange, []interface{}{cluster, matchers, rangeSeconds}},
+		{"QlNodeHourlyPriceFromClusterWithTimeRange", query.QlNodeHourlyPriceFromClusterWithTimeRange, []interface{}{cluster, matchers, rangeSeconds}},
+		{"QlNodeResourceCostFromClusterWithTimeRange", query.QlNodeResourceCostFromClusterWithTimeRange, []interface{}{cluster, matchers, rangeSeconds}},
+		{"QlNodeResourceAvgFromClusterWithTimeRange", query.QlNodeResourceAvgFromClusterWithTimeRange, []interface{}{values.NodeResourceTotalMetricsName, cluster, matchers, rangeSeconds}},
+		{"QlNodeHourlyPriceFromCluster", query.QlNodeHourlyPriceFromCluster, []interface{}{cluster}},
+		{"QlNodeResourceFromCluster", query.QlNodeResourceFromCluster, []interface{}{values.NodeResourceTotalMetricsName, cluster}},
+		{"QlPodResourceRequestByNodeFromCluster", query.QlPodResourceRequestByNodeFromCluster, []interface{}{cluster}},
+		{"QlWorkloadContainerPerPodResource", query.QlWorkloadContainerPerPodResource, []interface{}{values.WorkloadResourceRequestMetricsName, cluster, "cpu", matchers, cluster, matchers}},
+		{"QlWorkloadAvgPodCountWithTimeRange", query.QlWorkloadAvgPodCountWithTimeRange, []interface{}{cluster, matchers, rangeSeconds}},
+		{"QlQuantileOverTime", query.QlQuantileOverTime, []interface{}{0.95, perPodUsage, rangeSeconds, int64(300)}},
+		{"QlMaxOverTime", query.QlMaxOverTime, []interface{}{perPodUsage, rangeSeconds, int64(300)}},
+		{"QlClusterAvgCPUCoreHourlyCostWithTimeRange", query.QlClusterAvgCPUCoreHourlyCostWithTimeRange, []interface{}{cluster, rangeSeconds}},
+		{"QlClusterAvgRAMGBHourlyCostWithTimeRange", query.QlClusterAvgRAMGBHourlyCostWithTimeRange, []interface{}{cluster, rangeSeconds}},
+		{"QlWorkloadSpotSuitableFromCluster", query.QlWorkloadSpotSuitableFromCluster, []interface{}{cluster, matchers}},
+		{"QlWorkloadAvgResourceRequestFromClusterWithTimeRange", query.QlWorkloadAvgResourceRequestFromClusterWithTimeRange, []interface{}{cluster, matchers, rangeSeconds}},
+		{"QlClusterAvgCPUCoreHourlyCostByBillingModeWithTimeRange", query.QlClusterAvgCPUCoreHourlyCostByBillingModeWithTimeRange, []interface{}{cluster, rangeSeconds}},
+		{"QlClusterAvgRAMGBHourlyCostByBillingModeWithTimeRange", query.QlClusterAvgRAMGBHourlyCostByBillingModeWithTimeRange, []interface{}{cluster, rangeSeconds}},
+		{"QlRollupRecordedWithTimeRange", query.QlRollupRecordedWithTimeRange, []interface{}{rollup, query.LabelMatcher(values.ClusterIdLabelKey, "cluster-1"), rangeSeconds}},
+		{"QlNodesTotalCostsRollupWithTimeRange", query.QlNodesTotalCostsRollupWithTimeRange, []interface{}{rollup, rangeSeconds}},
+		{"QlNodesBillingModeCostFromClusterRollupWithTimeRange", query.QlNodesBillingModeCostFromClusterRollupWithTimeRange, []interface{}{rollup, cluster, rangeSeconds}},
+		{"QlNodeTotalCostFromClusterRollupWithTimeRange", query.QlNodeTotalCostFromClusterRollupWithTimeRange, []interface{}{rollup, cluster, matchers, rangeSeconds}},
+		{"QlNSTotalCostFromClusterRollupWithTimeRange", query.QlNSTotalCostFromClusterRollupWithTimeRange, []interface{}{rollup, cluster, matchers, rangeSeconds}},
+		{"QlWorkloadTotalCostFromClusterRollupWithTimeRange", query.QlWorkloadTotalCostFromClusterRollupWithTimeRange, []interface{}{rollup, cluster, workloadRe, matchers, rangeSeconds}},
+		{"QlPodLabelsTotalCostFromClusterRollupWithTimeRange", query.QlPodLabelsTotalCostFromClusterRollupWithTimeRange, []interface{}{rollup, cluster, matchers, rangeSeconds}},
+		{"QlTotalCostFromClusterRollupWithTimeRange", query.QlTotalCostFromClusterRollupWithTimeRange, []interface{}{rollup, cluster, matchers, rangeSeconds}},
+		{"QlNSTotalCostFromClustersRollupWithTimeRange", query.QlNSTotalCostFromClustersRollupWithTimeRange, []interface{}{rollup, clustersRe, matchers, rangeSeconds}},
+		{"QlPodLabelsTotalCostFromClustersRollupWithTimeRange", query.QlPodLabelsTotalCostFromClustersRollupWithTimeRange, []interface{}{rollup, clustersRe, matchers, rangeSeconds}},
+		{"QlCostByWithTimeRange", query.QlCostByWithTimeRange, []interface{}{values.PodResoueceCostMetricsName, rangeSeconds, "cluster_id,namespace"}},
+		{"QlAllClustersActivity", query.QlAllClustersActivity, nil},
+		{"QlClusterActivity", query.QlClusterActivity, []interface{}{cluster}},
+		{"QlClusterActiveTime", query.QlClusterActiveTime, []interface{}{cluster, rangeSeconds}},
+		{"QlAllClustersActiveTime", query.QlAllClustersActiveTime, []interface{}{rangeSeconds}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := querytest.ValidatePromql(fmt.Sprintf(tt.template, tt.args...)); err != nil {
+				t.Errorf("Render %s error:%v", tt.name, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package querytest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/kubefin/kubefin/pkg/query"
+)
+
+// Query is a promql sent to the fake backend and the tenant it's sent with
+type Query struct {
+	TenantId string
+	Promql   string
+}
+
+// Backend is a fake query.QueryBackend answering the promql with the results set for it, the
+// promql without result returns empty result. The backends returned by WithTenantId and
+// WithWarningHandler share the results and the recorded queries.
+type Backend struct {
+	*results
+	tenantId       string
+	warningHandler query.WarningHandler
+}
+
+type results struct {
+	mutex    sync.Mutex
+	instant  map[string][]*model.Sample
+	ranges   map[string][]*model.SampleStream
+	errs     map[string]error
+	warnings []string
+	queries  []Query
+}
+
+var _ query.QueryBackend = &Backend{}
+
+func NewBackend() *Backend {
+	return &Backend{results: &results{
+		instant: make(map[string][]*model.Sample),
+		ranges:  make(map[string][]*model.SampleStream),
+		errs:    make(map[string]error),
+	}}
+}
+
+// SetInstant sets the result of the instant queries of promql
+func (b *Backend) SetInstant(promql string, samples ...*model.Sample) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.instant[promql] = samples
+}
+
+// SetRange sets the result of the range queries of promql
+func (b *Backend) SetRange(promql string, streams ...*model.SampleStream) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.ranges[promql] = streams
+}
+
+// SetError fails the queries of promql with err
+func (b *Backend) SetError(promql string, err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.errs[promql] = err
+}
+
+// SetWarnings sets the warnings passed to the warning handler by every query
+func (b *Backend) SetWarnings(warnings ...string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.warnings = warnings
+}
+
+// Queries returns the queries sent to the backend in order
+func (b *Backend) Queries() []Query {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return append([]Query(nil), b.queries...)
+}
+
+func (b *Backend) WithTenantId(id string) query.QueryBackend {
+	if id == "" {
+		return b
+	}
+	return &Backend{results: b.results, tenantId: id, warningHandler: b.warningHandler}
+}
+
+func (b *Backend) WithWarningHandler(handler query.WarningHandler) query.QueryBackend {
+	return &Backend{results: b.results, tenantId: b.tenantId, warningHandler: handler}
+}
+
+func (b *Backend) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (b *Backend) QueryInstant(ctx context.Context, promql string) ([]*model.Sample, error) {
+	if err := b.record(promql); err != nil {
+		return nil, err
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.instant[promql], nil
+}
+
+func (b *Backend) QueryInstantWithTime(ctx context.Context, promql string, time int64) ([]*model.Sample, error) {
+	return b.QueryInstant(ctx, promql)
+}
+
+func (b *Backend) QueryRange(ctx context.Context, promql string, start, end int64) ([]*model.SampleStream, error) {
+	return b.QueryRangeWithStep(ctx, promql, start, end, 0)
+}
+
+func (b *Backend) QueryRangeWithStep(ctx context.Context, promql string, start, end, stepSeconds int64) ([]*model.SampleStream, error) {
+	if err := b.record(promql); err != nil {
+		return nil, err
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.ranges[promql], nil
+}
+
+// record records the query and returns the error set for it, the warnings are passed
+// to the handler if the query succeeds
+func (b *Backend) record(promql string) error {
+	b.mutex.Lock()
+	b.queries = append(b.queries, Query{TenantId: b.tenantId, Promql: promql})
+	err, warnings := b.errs[promql], b.warnings
+	b.mutex.Unlock()
+
+	if err != nil {
+		return err
+	}
+	if b.warningHandler != nil && len(warnings) > 0 {
+		b.warningHandler(warnings)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package querytest
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	labelMatcherRe = regexp.MustCompile(`^\s*[a-zA-Z_][a-zA-Z0-9_]*\s*(=|!=|=~|!~)\s*'(\\.|[^'\\])*'\s*$`)
+	durationRe     = regexp.MustCompile(`^[0-9]+[smhdwy](:[0-9]+[smhdwy])?$`)
+)
+
+// ValidatePromql checks the promql rendered from the templates is well formed, the brackets
+// are balanced, every selector is a list of label matchers and every range is a duration.
+// It doesn't type check the promql, it catches the templates rendered with wrong arguments.
+func ValidatePromql(promql string) error {
+	if strings.TrimSpace(promql) == "" {
+		return fmt.Errorf("empty promql")
+	}
+	if strings.Contains(promql, "%") {
+		return fmt.Errorf("unrendered verb in promql %q", promql)
+	}
+
+	closing := map[byte]byte{'(': ')', '[': ']', '{': '}'}
+	var stack []int
+	for i := 0; i < len(promql); i++ {
+		switch c := promql[i]; c {
+		case '\'', '"':
+			end, err := skipString(promql, i)
+			if err != nil {
+				return err
+			}
+			i = end
+		case '(', '[', '{':
+			stack = append(stack, i)
+		case ')', ']', '}':
+			if len(stack) == 0 || closing[promql[stack[len(stack)-1]]] != c {
+				return fmt.Errorf("unbalanced %q at %d of promql %q", c, i, promql)
+			}
+			open := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			body := promql[open+1 : i]
+			switch c {
+			case '}':
+				if err := validateSelector(body); err != nil {
+					return fmt.Errorf("invalid selector {%s} of promql %q: %v", body, promql, err)
+				}
+			case ']':
+				if !durationRe.MatchString(body) {
+					return fmt.Errorf("invalid range [%s] of promql %q", body, promql)
+				}
+			}
+		}
+	}
+	if len(stack) != 0 {
+		return fmt.Errorf("unclosed %q at %d of promql %q", promql[stack[len(stack)-1]], stack[len(stack)-1], promql)
+	}
+	return nil
+}
+
+func skipString(promql string, start int) (int, error) {
+	for i := start + 1; i < len(promql); i++ {
+		switch promql[i] {
+		case '\\':
+			i++
+		case promql[start]:
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("unclosed string at %d of promql %q", start, promql)
+}
+
+func validateSelector(body string) error {
+	if strings.TrimSpace(body) == "" {
+		return nil
+	}
+	start := 0
+	for i := 0; i <= len(body); i++ {
+		if i < len(body) && body[i] == '\'' {
+			end, err := skipString(body, i)
+			if err != nil {
+				return err
+			}
+			i = end
+			continue
+		}
+		if i < len(body) && body[i] != ',' {
+			continue
+		}
+		if !labelMatcherRe.MatchString(body[start:i]) {
+			return fmt.Errorf("invalid label matcher %q", body[start:i])
+		}
+		start = i + 1
+	}
+	return nil
+}
@@ -0,0 +1,299 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package query
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+
+	"github.com/kubefin/kubefin/pkg/values"
+)
+
+var errCircuitOpen = errors.New("query backend circuit breaker is open")
+
+var (
+	queryRetriesCV = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: values.QueryRetriesMetricsName,
+		Help: "The queries sent again to the query backend after a transient failure",
+	}, []string{values.ErrorTypeLabelKey})
+	queryCircuitBreakerOpenGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: values.QueryCircuitBreakerOpenMetricsName,
+		Help: "1 if the circuit breaker of the query backend is open and the queries fast-fail",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(queryRetriesCV, queryCircuitBreakerOpenGauge)
+}
+
+// RetryConfig retries the queries failed as the backend is unavailable, all the queries
+// are read only so they are safe to send again
+type RetryConfig struct {
+	// MaxRetries is the number of retries after the first attempt, 0 disables retrying
+	MaxRetries int `json:"maxRetries"`
+	// InitialBackoff is the wait before the first retry, it's doubled for every following retry
+	InitialBackoff metav1.Duration `json:"initialBackoff,omitempty"`
+	// MaxBackoff caps the wait between the retries
+	MaxBackoff metav1.Duration `json:"maxBackoff,omitempty"`
+}
+
+// CircuitBreakerConfig fast-fails the queries while the backend is down
+type CircuitBreakerConfig struct {
+	Enabled bool `json:"enabled"`
+	// FailureThreshold is the number of queries failing in a row that opens the circuit
+	FailureThreshold int `json:"failureThreshold,omitempty"`
+	// OpenDuration is how long the queries fast-fail before one is sent to probe the backend
+	OpenDuration metav1.Duration `json:"openDuration,omitempty"`
+}
+
+// NewDefaultRetryConfig retries 3 times in about 1.4s
+func NewDefaultRetryConfig() RetryConfig {
+	return RetryConfig{
+		MaxRetries:     3,
+		InitialBackoff: metav1.Duration{Duration: 200 * time.Millisecond},
+		MaxBackoff:     metav1.Duration{Duration: 5 * time.Second},
+	}
+}
+
+func NewDefaultCircuitBreakerConfig() CircuitBreakerConfig {
+	return CircuitBreakerConfig{
+		Enabled:          true,
+		FailureThreshold: 5,
+		OpenDuration:     metav1.Duration{Duration: 30 * time.Second},
+	}
+}
+
+// isTransient returns whether the query may succeed if sent again, the queries rejected or
+// timed out by the backend are not retried as they would fail or overload it again
+func isTransient(err error) bool {
+	if errors.Is(err, errCircuitOpen) {
+		return false
+	}
+	errorType := ErrorTypeOf(err)
+	return errorType == ErrorTypeUnavailable || errorType == ErrorTypePartialData
+}
+
+// isBackendFailure returns whether the error counts against the backend in the circuit breaker,
+// the backend too slow to answer in time is as unhealthy as the one could not be reached
+func isBackendFailure(err error) bool {
+	return isTransient(err) || ErrorTypeOf(err) == ErrorTypeTimeout || errors.Is(err, context.DeadlineExceeded)
+}
+
+// resilientBackend retries the transient failures with exponential backoff, and stops sending
+// queries once the backend keeps failing. The circuit breaker is shared by all the tenants.
+type resilientBackend struct {
+	backend     QueryBackend
+	retryConfig *RetryConfig
+	breaker     *circuitBreaker
+
+	retriesCV *prometheus.CounterVec
+}
+
+// NewResilientBackend wraps the backend with the retries and the circuit breaker
+func NewResilientBackend(backend QueryBackend, retryConfig *RetryConfig, breakerConfig *CircuitBreakerConfig) QueryBackend {
+	var breaker *circuitBreaker
+	if breakerConfig.Enabled {
+		breaker = &circuitBreaker{
+			failureThreshold: breakerConfig.FailureThreshold,
+			openDuration:     breakerConfig.OpenDuration.Duration,
+			openGauge:        queryCircuitBreakerOpenGauge,
+		}
+	}
+
+	return &resilientBackend{
+		backend:     backend,
+		retryConfig: retryConfig,
+		breaker:     breaker,
+		retriesCV:   queryRetriesCV,
+	}
+}
+
+func (r *resilientBackend) WithTenantId(id string) QueryBackend {
+	backendCopy := *r
+	backendCopy.backend = r.backend.WithTenantId(id)
+	return &backendCopy
+}
+
+func (r *resilientBackend) WithWarningHandler(handler WarningHandler) QueryBackend {
+	backendCopy := *r
+	backendCopy.backend = r.backend.WithWarningHandler(handler)
+	return &backendCopy
+}
+
+// Ping is not retried so it reports the current health of the backend, it probes
+// the backend once the circuit breaker is half open
+func (r *resilientBackend) Ping(ctx context.Context) error {
+	return r.call(ctx, func() error {
+		return r.backend.Ping(ctx)
+	})
+}
+
+func (r *resilientBackend) QueryInstant(ctx context.Context, promql string) ([]*model.Sample, error) {
+	var samples []*model.Sample
+	err := r.retry(ctx, func() (err error) {
+		samples, err = r.backend.QueryInstant(ctx, promql)
+		return err
+	})
+	return samples, err
+}
+
+func (r *resilientBackend) QueryInstantWithTime(ctx context.Context, promql string, time int64) ([]*model.Sample, error) {
+	var samples []*model.Sample
+	err := r.retry(ctx, func() (err error) {
+		samples, err = r.backend.QueryInstantWithTime(ctx, promql, time)
+		return err
+	})
+	return samples, err
+}
+
+func (r *resilientBackend) QueryRange(ctx context.Context, promql string, start, end int64) ([]*model.SampleStream, error) {
+	var streams []*model.SampleStream
+	err := r.retry(ctx, func() (err error) {
+		streams, err = r.backend.QueryRange(ctx, promql, start, end)
+		return err
+	})
+	return streams, err
+}
+
+func (r *resilientBackend) QueryRangeWithStep(ctx context.Context, promql string, start, end, stepSeconds int64) ([]*model.SampleStream, error) {
+	var streams []*model.SampleStream
+	err := r.retry(ctx, func() (err error) {
+		streams, err = r.backend.QueryRangeWithStep(ctx, promql, start, end, stepSeconds)
+		return err
+	})
+	return streams, err
+}
+
+// retry calls queryFunc until it succeeds, fails with a non transient error, runs out of retries or ctx is done
+func (r *resilientBackend) retry(ctx context.Context, queryFunc func() error) error {
+	backoff := wait.Backoff{
+		Duration: r.retryConfig.InitialBackoff.Duration,
+		Factor:   2,
+		Jitter:   0.2,
+		Steps:    r.retryConfig.MaxRetries,
+		Cap:      r.retryConfig.MaxBackoff.Duration,
+	}
+	for attempt := 0; ; attempt++ {
+		err := r.call(ctx, queryFunc)
+		if err == nil || attempt >= r.retryConfig.MaxRetries || !isTransient(err) || ctx.Err() != nil {
+			return err
+		}
+
+		delay := backoff.Step()
+		klog.V(4).Infof("Retry the query in %s after attempt %d failed:%v", delay, attempt+1, err)
+		r.retriesCV.WithLabelValues(string(ErrorTypeOf(err))).Inc()
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// call sends one query through the circuit breaker
+func (r *resilientBackend) call(ctx context.Context, queryFunc func() error) error {
+	probe, err := r.breaker.allow()
+	if err != nil {
+		return err
+	}
+	err = queryFunc()
+	r.breaker.record(ctx, probe, err)
+	return err
+}
+
+// circuitBreaker opens once failureThreshold queries fail or time out in a row, the queries fast-fail while
+// it's open. After openDuration it's half open and lets one query through, the circuit is closed
+// if the query succeeds and opened again otherwise. A nil circuitBreaker lets all the queries through.
+type circuitBreaker struct {
+	failureThreshold int
+	openDuration     time.Duration
+	openGauge        prometheus.Gauge
+
+	mutex    sync.Mutex
+	failures int
+	// openedAt is zero if the circuit is closed
+	openedAt time.Time
+	probing  bool
+	lastErr  error
+}
+
+// allow returns an error if the query should fast-fail, and whether the query probes the backend
+func (b *circuitBreaker) allow() (bool, error) {
+	if b == nil {
+		return false, nil
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.openedAt.IsZero() {
+		return false, nil
+	}
+	if !b.probing && time.Since(b.openedAt) >= b.openDuration {
+		b.probing = true
+		return true, nil
+	}
+	return false, newError(ErrorTypeUnavailable,
+		fmt.Sprintf("query backend is down, last error(%v)", b.lastErr), errCircuitOpen)
+}
+
+func (b *circuitBreaker) record(ctx context.Context, probe bool, err error) {
+	if b == nil {
+		return
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if probe {
+		b.probing = false
+	}
+	// The backend is not blamed if the caller has gone, but it is if the deadline is exceeded
+	if err != nil && errors.Is(ctx.Err(), context.Canceled) {
+		return
+	}
+
+	if !isBackendFailure(err) {
+		if !b.openedAt.IsZero() {
+			klog.Infof("Query backend is recovered, close the circuit breaker")
+			b.openGauge.Set(0)
+		}
+		b.failures = 0
+		b.openedAt = time.Time{}
+		return
+	}
+
+	b.failures++
+	b.lastErr = err
+	if probe || (b.openedAt.IsZero() && b.failures >= b.failureThreshold) {
+		if b.openedAt.IsZero() {
+			klog.Errorf("Query backend failed %d times in a row, open the circuit breaker for %s:%v",
+				b.failures, b.openDuration, err)
+		}
+		b.openedAt = time.Now()
+		b.openGauge.Set(1)
+	}
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package query
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestIsBackendFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "success", err: nil, want: false},
+		{name: "unavailable", err: newError(ErrorTypeUnavailable, "connect", nil), want: true},
+		{name: "partial data", err: newError(ErrorTypePartialData, "read", nil), want: true},
+		{name: "timeout", err: newError(ErrorTypeTimeout, "query", nil), want: true},
+		{name: "deadline exceeded", err: fmt.Errorf("query:%w", context.DeadlineExceeded), want: true},
+		{name: "bad query", err: newError(ErrorTypeBadQuery, "parse", nil), want: false},
+		{name: "internal", err: errors.New("decode"), want: false},
+		{name: "circuit open", err: newError(ErrorTypeUnavailable, "down", errCircuitOpen), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isBackendFailure(tt.err); got != tt.want {
+				t.Errorf("isBackendFailure(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerRecord(t *testing.T) {
+	unavailable := newError(ErrorTypeUnavailable, "connect", nil)
+	timeout := newError(ErrorTypeTimeout, "query", nil)
+	badQuery := newError(ErrorTypeBadQuery, "parse", nil)
+
+	type call struct {
+		err      error
+		canceled bool
+	}
+	tests := []struct {
+		name     string
+		calls    []call
+		wantOpen bool
+	}{
+		{
+			name:     "opens after the failures in a row",
+			calls:    []call{{err: unavailable}, {err: unavailable}},
+			wantOpen: true,
+		},
+		{
+			name:     "stays closed below the threshold",
+			calls:    []call{{err: unavailable}},
+			wantOpen: false,
+		},
+		{
+			name:     "timeouts count as failures",
+			calls:    []call{{err: timeout}, {err: context.DeadlineExceeded}},
+			wantOpen: true,
+		},
+		{
+			name:     "success resets the failures",
+			calls:    []call{{err: unavailable}, {err: nil}, {err: unavailable}},
+			wantOpen: false,
+		},
+		{
+			name:     "bad queries do not count",
+			calls:    []call{{err: badQuery}, {err: badQuery}},
+			wantOpen: false,
+		},
+		{
+			name:     "canceled callers are not blamed",
+			calls:    []call{{err: unavailable, canceled: true}, {err: unavailable, canceled: true}},
+			wantOpen: false,
+		},
+		{
+			name:     "successful probe closes the circuit",
+			calls:    []call{{err: unavailable}, {err: unavailable}, {err: nil}},
+			wantOpen: false,
+		},
+		{
+			name:     "failed probe opens the circuit again",
+			calls:    []call{{err: unavailable}, {err: unavailable}, {err: timeout}},
+			wantOpen: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The circuit is half open right after opened, so the call following it probes the backend
+			breaker := &circuitBreaker{
+				failureThreshold: 2,
+				openGauge:        prometheus.NewGauge(prometheus.GaugeOpts{Name: "test"}),
+			}
+			for i, c := range tt.calls {
+				probe, err := breaker.allow()
+				if err != nil {
+					t.Fatalf("call %d is rejected:%v", i, err)
+				}
+				ctx, cancel := context.WithCancel(context.Background())
+				if c.canceled {
+					cancel()
+				}
+				breaker.record(ctx, probe, c.err)
+				cancel()
+			}
+			if open := !breaker.openedAt.IsZero(); open != tt.wantOpen {
+				t.Errorf("circuit open = %v, want %v", open, tt.wantOpen)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerAllow(t *testing.T) {
+	breaker := &circuitBreaker{
+		failureThreshold: 1,
+		openDuration:     time.Hour,
+		openGauge:        prometheus.NewGauge(prometheus.GaugeOpts{Name: "test"}),
+	}
+	breaker.record(context.Background(), false, newError(ErrorTypeUnavailable, "connect", nil))
+
+	if _, err := breaker.allow(); !errors.Is(err, errCircuitOpen) || ErrorTypeOf(err) != ErrorTypeUnavailable {
+		t.Fatalf("allow() error = %v, want the circuit open error", err)
+	}
+
+	breaker.openedAt = time.Now().Add(-time.Hour)
+	if probe, err := breaker.allow(); err != nil || !probe {
+		t.Fatalf("allow() = %v, %v, want a probe once the circuit is half open", probe, err)
+	}
+	if _, err := breaker.allow(); !errors.Is(err, errCircuitOpen) {
+		t.Fatalf("allow() error = %v, want only one probe at a time", err)
+	}
+
+	var nilBreaker *circuitBreaker
+	if probe, err := nilBreaker.allow(); err != nil || probe {
+		t.Fatalf("nil breaker allow() = %v, %v, want all the queries through", probe, err)
+	}
+}
@@ -0,0 +1,235 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package query
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+
+	"github.com/kubefin/kubefin/pkg/values"
+)
+
+const (
+	// rulerBaseUrl is the rule group api of the Mimir/Cortex ruler, it's served under the same prefix as the query api
+	rulerBaseUrl = "/config/v1/rules/%s"
+
+	rollupSeriesFormat = "kubefin:%s_cost:%s"
+)
+
+// RollupKind is the cost pre-aggregated by a rollup
+type RollupKind string
+
+const (
+	// RollupKindNode keeps the cost of every node
+	RollupKindNode RollupKind = "node"
+	// RollupKindNamespace keeps the pod cost of every namespace
+	RollupKindNamespace RollupKind = "namespace"
+	// RollupKindWorkload keeps the cost of every workload
+	RollupKindWorkload RollupKind = "workload"
+	// RollupKindLabel keeps the pod cost of every namespace and pod labels
+	RollupKindLabel RollupKind = "label"
+)
+
+type rollupDefinition struct {
+	kind   RollupKind
+	metric string
+	by     string
+}
+
+// rollupDefinitions keep the labels the cost queries filter and aggregate by
+var rollupDefinitions = []rollupDefinition{
+	{kind: RollupKindNode, metric: values.NodeTotalHourlyCostMetricsName,
+		by: "cluster_id,node,instance_type,billing_mode,region"},
+	{kind: RollupKindNamespace, metric: values.PodResoueceCostMetricsName,
+		by: "cluster_id,namespace"},
+	{kind: RollupKindWorkload, metric: values.WorkloadResourceCostMetricsName,
+		by: "cluster_id,namespace,workload_type,workload_name"},
+	{kind: RollupKindLabel, metric: values.PodResoueceCostMetricsName,
+		by: "cluster_id,namespace,labels"},
+}
+
+// rollupInterval is how often a rollup is recorded, every sample is the cost in the interval before it
+type rollupInterval struct {
+	name    string
+	seconds int64
+}
+
+var (
+	rollupIntervalHourly = rollupInterval{name: "1h", seconds: 3600}
+	rollupIntervalDaily  = rollupInterval{name: "1d", seconds: 24 * 3600}
+)
+
+// rollupSeries returns the name of the series the rollup is recorded as
+func rollupSeries(kind RollupKind, interval rollupInterval) string {
+	return fmt.Sprintf(rollupSeriesFormat, kind, interval.name)
+}
+
+// RuleGroups is the rule file format of Prometheus
+type RuleGroups struct {
+	Groups []RuleGroup `json:"groups"`
+}
+
+type RuleGroup struct {
+	Name     string          `json:"name"`
+	Interval string          `json:"interval"`
+	Rules    []RecordingRule `json:"rules"`
+}
+
+type RecordingRule struct {
+	Record string `json:"record"`
+	Expr   string `json:"expr"`
+}
+
+// NewRollupRuleGroups generates the recording rules of the rollups. The hourly rollups sum the raw
+// metrics sampled every 15s, the daily rollups sum the hourly ones.
+func NewRollupRuleGroups() *RuleGroups {
+	hourly := RuleGroup{Name: "kubefin-cost-rollup-hourly", Interval: rollupIntervalHourly.name}
+	daily := RuleGroup{Name: "kubefin-cost-rollup-daily", Interval: rollupIntervalDaily.name}
+	for _, definition := range rollupDefinitions {
+		hourlySeries := rollupSeries(definition.kind, rollupIntervalHourly)
+		hourly.Rules = append(hourly.Rules, RecordingRule{
+			Record: hourlySeries,
+			Expr:   fmt.Sprintf("sum(sum_over_time(%s[%s])/240) by (%s)", definition.metric, rollupIntervalHourly.name, definition.by),
+		})
+		daily.Rules = append(daily.Rules, RecordingRule{
+			Record: rollupSeries(definition.kind, rollupIntervalDaily),
+			Expr:   fmt.Sprintf("sum(sum_over_time(%s[%s])) by (%s)", hourlySeries, rollupIntervalDaily.name, definition.by),
+		})
+	}
+	return &RuleGroups{Groups: []RuleGroup{hourly, daily}}
+}
+
+// ApplyRollupRules uploads the rollup rule groups to the ruler of Mimir or Cortex in the namespace,
+// the groups uploaded before are replaced. The ruler api is served under the endpoint of the query api.
+func ApplyRollupRules(ctx context.Context, config *BackendConfig, namespace string) error {
+	if config.Type != "" && config.Type != BackendTypePrometheus {
+		return fmt.Errorf("query backend type %s has no ruler api, load the rule file into its ruler instead", config.Type)
+	}
+	client, err := NewPromQueryClient(config, values.MultiTenantHeader)
+	if err != nil {
+		return err
+	}
+
+	for _, group := range NewRollupRuleGroups().Groups {
+		data, err := yaml.Marshal(group)
+		if err != nil {
+			return fmt.Errorf("encode rule group(%s) error:%v", group.Name, err)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+			client.endpoint+fmt.Sprintf(rulerBaseUrl, url.PathEscape(namespace)), bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("create rule group request error:%v", err)
+		}
+		req.Header.Set("Content-Type", "application/yaml")
+		if client.tenantId != "" {
+			req.Header.Set(client.tenantHeader, client.tenantId)
+		}
+
+		resp, err := client.httpClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("upload rule group(%s) error:%v", group.Name, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("upload rule group(%s) error, ruler returns %d:%s", group.Name, resp.StatusCode, string(body))
+		}
+		klog.Infof("Uploaded rule group(%s) to namespace %s", group.Name, namespace)
+	}
+	return nil
+}
+
+// RollupConfig decides when the cost queries read the rollups instead of the raw metrics
+type RollupConfig struct {
+	// Enabled queries the rollups for the long ranges once they are recorded,
+	// the raw metrics are queried as usual before that
+	Enabled bool `json:"enabled"`
+	// HourlyMinRange is the shortest range summed from the hourly rollups
+	HourlyMinRange metav1.Duration `json:"hourlyMinRange,omitempty"`
+	// DailyMinRange is the shortest range summed from the daily rollups
+	DailyMinRange metav1.Duration `json:"dailyMinRange,omitempty"`
+}
+
+func NewDefaultRollupConfig() RollupConfig {
+	return RollupConfig{
+		Enabled:        true,
+		HourlyMinRange: metav1.Duration{Duration: 24 * time.Hour},
+		DailyMinRange:  metav1.Duration{Duration: 30 * 24 * time.Hour},
+	}
+}
+
+// RollupSelector picks the rollup to query the cost over a range, a nil RollupSelector never picks one
+type RollupSelector struct {
+	config *RollupConfig
+}
+
+func NewRollupSelector(config *RollupConfig) *RollupSelector {
+	return &RollupSelector{config: config}
+}
+
+// Select returns the rollup series of kind to sum the cost over rangeSeconds from start, and the range
+// rounded down to the rollup interval. The cost at both ends of the range is shifted by at most one
+// interval, so the rollups are used only for the ranges not shorter than the configured ones, and only
+// if the rollup was already recorded at start.
+func (r *RollupSelector) Select(ctx context.Context, backend QueryBackend, kind RollupKind,
+	clusterId string, start, rangeSeconds int64) (string, int64, bool) {
+	if r == nil {
+		return "", 0, false
+	}
+
+	candidates := []struct {
+		interval rollupInterval
+		minRange time.Duration
+	}{
+		{interval: rollupIntervalDaily, minRange: r.config.DailyMinRange.Duration},
+		{interval: rollupIntervalHourly, minRange: r.config.HourlyMinRange.Duration},
+	}
+	for _, candidate := range candidates {
+		if rangeSeconds < int64(candidate.minRange.Seconds()) || rangeSeconds < candidate.interval.seconds {
+			continue
+		}
+		series := rollupSeries(kind, candidate.interval)
+		if r.recorded(ctx, backend, series, clusterId, start, candidate.interval) {
+			klog.V(4).Infof("Query the cost over %ds from rollup %s", rangeSeconds, series)
+			return series, rangeSeconds / candidate.interval.seconds * candidate.interval.seconds, true
+		}
+	}
+	return "", 0, false
+}
+
+// recorded checks the rollup has a sample in the interval start is in, the check time is aligned
+// to the interval so its result is cached
+func (r *RollupSelector) recorded(ctx context.Context, backend QueryBackend, series, clusterId string,
+	start int64, interval rollupInterval) bool {
+	checkAt := (start/interval.seconds + 1) * interval.seconds
+	promql := fmt.Sprintf(QlRollupRecordedWithTimeRange, series, LabelMatcher(values.ClusterIdLabelKey, clusterId), interval.seconds)
+	ret, err := backend.QueryInstantWithTime(ctx, promql, checkAt)
+	if err != nil {
+		klog.Warningf("Check rollup(%s) recorded error, query the raw metrics instead:%v", series, err)
+		return false
+	}
+	return len(ret) > 0 && ret[0].Value > 0
+}
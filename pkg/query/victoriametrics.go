@@ -0,0 +1,39 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package query
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NewVictoriaMetricsQueryClient creates a client of VictoriaMetrics, which serves the Prometheus HTTP API.
+// The single-node version is queried directly if no tenant id is set, otherwise the endpoint should be
+// vmselect of the cluster version and the tenant id(accountID[:projectID]) is put in the url path.
+func NewVictoriaMetricsQueryClient(config *BackendConfig) (*PromQueryClient, error) {
+	client, err := NewPromQueryClient(config, "")
+	if err != nil {
+		return nil, err
+	}
+	client.apiPath = func(tenantId string) string {
+		if tenantId == "" {
+			return ""
+		}
+		return fmt.Sprintf("/select/%s/prometheus", strings.TrimSpace(tenantId))
+	}
+	return client, nil
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package recommendation
+
+import (
+	"math"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// minCPUMilliCores and minMemoryMiB are the smallest requests could be recommended
+	minCPUMilliCores = 1
+	minMemoryMiB     = 1
+)
+
+// Config holds the settings of the request right-sizing recommendations
+type Config struct {
+	// Window is how far back the usage is looked at
+	Window metav1.Duration `json:"window,omitempty"`
+	// Resolution is the step the usage percentiles are computed with
+	Resolution metav1.Duration `json:"resolution,omitempty"`
+	// CPUPercentile/MemoryPercentile are the usage percentiles the requests are sized to, 50/95/99
+	CPUPercentile    float64 `json:"cpuPercentile,omitempty"`
+	MemoryPercentile float64 `json:"memoryPercentile,omitempty"`
+	// Headroom is the fraction added on top of the usage percentile for the requests
+	Headroom float64 `json:"headroom,omitempty"`
+	// MemoryLimitHeadroom is the fraction added on top of the peak memory usage for the memory limit
+	MemoryLimitHeadroom float64 `json:"memoryLimitHeadroom,omitempty"`
+	// CPULimitRatio is the cpu limit to request ratio, no cpu limit is recommended if it's zero
+	CPULimitRatio float64 `json:"cpuLimitRatio,omitempty"`
+	// MinCPUCores/MinMemoryGiB are the lower bounds of the recommended requests
+	MinCPUCores  float64 `json:"minCPUCores,omitempty"`
+	MinMemoryGiB float64 `json:"minMemoryGiB,omitempty"`
+}
+
+func NewDefaultConfig() *Config {
+	return &Config{
+		Window:              metav1.Duration{Duration: 7 * 24 * time.Hour},
+		Resolution:          metav1.Duration{Duration: 5 * time.Minute},
+		CPUPercentile:       95,
+		MemoryPercentile:    99,
+		Headroom:            0.15,
+		MemoryLimitHeadroom: 0.3,
+		MinCPUCores:         0.01,
+		MinMemoryGiB:        0.0625,
+	}
+}
+
+// Usage is the usage percentiles of a container in one pod, in cores or GiB
+type Usage struct {
+	P50  float64
+	P95  float64
+	P99  float64
+	Peak float64
+}
+
+// Percentile returns the percentile closest to p
+func (u *Usage) Percentile(p float64) float64 {
+	switch {
+	case p <= 50:
+		return u.P50
+	case p <= 95:
+		return u.P95
+	case p < 100:
+		return u.P99
+	}
+	return u.Peak
+}
+
+// RecommendCPU returns the cpu request and limit in cores, the limit is zero if
+// CPULimitRatio is not set
+func (c *Config) RecommendCPU(usage *Usage) (request, limit float64) {
+	request = math.Max(usage.Percentile(c.CPUPercentile)*(1+c.Headroom), c.MinCPUCores)
+	request = roundUp(request*1000, minCPUMilliCores) / 1000
+	if c.CPULimitRatio > 0 {
+		limit = roundUp(request*c.CPULimitRatio*1000, minCPUMilliCores) / 1000
+	}
+	return request, limit
+}
+
+// RecommendMemory returns the memory request and limit in GiB, the limit covers the peak usage
+func (c *Config) RecommendMemory(usage *Usage) (request, limit float64) {
+	request = math.Max(usage.Percentile(c.MemoryPercentile)*(1+c.Headroom), c.MinMemoryGiB)
+	request = roundUp(request*1024, minMemoryMiB) / 1024
+	limit = math.Max(usage.Peak*(1+c.MemoryLimitHeadroom), request)
+	limit = roundUp(limit*1024, minMemoryMiB) / 1024
+	return request, limit
+}
+
+// CPUQuantity formats the cores as a kubernetes quantity, such as 250m
+func CPUQuantity(cores float64) string {
+	if cores <= 0 {
+		return ""
+	}
+	return resource.NewMilliQuantity(int64(math.Ceil(cores*1000)), resource.DecimalSI).String()
+}
+
+// MemoryQuantity formats the GiB as a kubernetes quantity, such as 512Mi
+func MemoryQuantity(gib float64) string {
+	if gib <= 0 {
+		return ""
+	}
+	return resource.NewQuantity(int64(math.Ceil(gib*1024))*1024*1024, resource.BinarySI).String()
+}
+
+// roundUp ignores the float error, so 250.0000001 millicores is not rounded up to 251
+func roundUp(value, unit float64) float64 {
+	return math.Ceil(value/unit-1e-6) * unit
+}
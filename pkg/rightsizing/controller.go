@@ -0,0 +1,494 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rightsizing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+	"k8s.io/metrics/pkg/client/clientset/versioned"
+
+	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/recommendation"
+	"github.com/kubefin/kubefin/pkg/values"
+)
+
+var rightSizingChangesCV = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: values.RightSizingChangesMetricsName,
+	Help: "The request changes made by the right-sizing controller",
+}, []string{
+	values.WorkloadTypeLabelKey,
+	values.WorkloadNameLabelKey,
+	values.NamespaceLabelKey,
+	values.ClusterNameLabelKey,
+	values.ClusterIdLabelKey,
+	values.ContainerNameLabelKey,
+	values.ResourceTypeLabelKey,
+	values.ResultLabelKey,
+})
+
+func init() {
+	prometheus.MustRegister(rightSizingChangesCV)
+}
+
+// Controller samples the container usage of the opted-in workloads and moves their
+// requests toward the usage percentiles step by step
+type Controller struct {
+	config      *Config
+	sizing      *recommendation.Config
+	clusterName string
+	clusterId   string
+
+	client        kubernetes.Interface
+	metricsClient versioned.Interface
+	lister        *api.CoreResourceInformerLister
+	recorder      record.EventRecorder
+
+	mutex sync.Mutex
+	// samples holds the usage samples of every container of the workloads, keyed by workload
+	samples map[string]map[string][]sample
+	// dryRunTime remembers the last dry run of every workload, so the cooldown applies to dry runs too
+	dryRunTime map[string]time.Time
+}
+
+type sample struct {
+	timestamp time.Time
+	// cpu in cores and memory in GiB of one container in one pod
+	cpu    float64
+	memory float64
+}
+
+// workload unifies the fields of deployment/statefulset/daemonset used by the controller
+type workload struct {
+	kind     string
+	object   runtime.Object
+	meta     metav1.Object
+	selector *metav1.LabelSelector
+	template *corev1.PodTemplateSpec
+	// rollingOut means the workload is being rolled out and must not be changed
+	rollingOut bool
+}
+
+// change is the request of one resource of one container moved from current to next,
+// target is where the usage says it should end up
+type change struct {
+	container string
+	resource  corev1.ResourceName
+	current   float64
+	next      float64
+	target    float64
+}
+
+func NewController(config *Config, clusterName, clusterId string, client kubernetes.Interface,
+	metricsClient versioned.Interface, lister *api.CoreResourceInformerLister) *Controller {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: client.CoreV1().Events("")})
+	recorder := broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: values.KubeFinAgentName})
+
+	return &Controller{
+		config: config,
+		sizing: &recommendation.Config{
+			CPUPercentile:    config.CPUPercentile,
+			MemoryPercentile: config.MemoryPercentile,
+			Headroom:         config.Headroom,
+			MinCPUCores:      config.MinCPUCores,
+			MinMemoryGiB:     config.MinMemoryGiB,
+		},
+		clusterName:   clusterName,
+		clusterId:     clusterId,
+		client:        client,
+		metricsClient: metricsClient,
+		lister:        lister,
+		recorder:      recorder,
+		samples:       make(map[string]map[string][]sample),
+		dryRunTime:    make(map[string]time.Time),
+	}
+}
+
+func (c *Controller) Run(ctx context.Context) {
+	if ok := cache.WaitForCacheSync(ctx.Done(),
+		c.lister.DeploymentInformer.HasSynced,
+		c.lister.StatefulSetInformer.HasSynced,
+		c.lister.DaemonSetInformer.HasSynced); !ok {
+		klog.Errorf("Wait workload cache sync failed, right-sizing controller is not started")
+		return
+	}
+
+	klog.Infof("Start right-sizing the workloads annotated with %s=%s every %s, dry run:%v",
+		values.RightSizingAnnotationKey, EnabledAnnotationValue, c.config.Interval.Duration, c.config.DryRun)
+	go wait.UntilWithContext(ctx, c.sampleAll, c.config.SampleInterval.Duration)
+	wait.UntilWithContext(ctx, c.reconcileAll, c.config.Interval.Duration)
+}
+
+func (c *Controller) sampleAll(ctx context.Context) {
+	now := time.Now()
+	workloads := c.listWorkloads()
+
+	active := make(map[string]bool, len(workloads))
+	for _, w := range workloads {
+		key := workloadKey(w)
+		active[key] = true
+		if err := c.sample(ctx, key, w, now); err != nil {
+			klog.Errorf("Sample usage of %s error:%v", key, err)
+		}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for key := range c.samples {
+		if !active[key] {
+			delete(c.samples, key)
+			delete(c.dryRunTime, key)
+		}
+	}
+}
+
+func (c *Controller) sample(ctx context.Context, key string, w *workload, now time.Time) error {
+	selector, err := metav1.LabelSelectorAsSelector(w.selector)
+	if err != nil {
+		return fmt.Errorf("parse selector error:%v", err)
+	}
+	pods, err := c.metricsClient.MetricsV1beta1().PodMetricses(w.meta.GetNamespace()).List(ctx, metav1.ListOptions{
+		LabelSelector: selector.String(),
+	})
+	if err != nil {
+		return fmt.Errorf("list pod metrics error:%v, kubernetes metrics server may not be installed", err)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	containers, ok := c.samples[key]
+	if !ok {
+		containers = make(map[string][]sample)
+		c.samples[key] = containers
+	}
+	for _, pod := range pods.Items {
+		for _, container := range pod.Containers {
+			containers[container.Name] = append(containers[container.Name], sample{
+				timestamp: now,
+				cpu:       float64(container.Usage.Cpu().MilliValue()) / values.CoreInMCore,
+				memory:    float64(container.Usage.Memory().Value()) / values.GBInBytes,
+			})
+		}
+	}
+	expired := now.Add(-c.config.Window.Duration)
+	for name, samples := range containers {
+		i := sort.Search(len(samples), func(i int) bool { return samples[i].timestamp.After(expired) })
+		containers[name] = samples[i:]
+	}
+	return nil
+}
+
+func (c *Controller) reconcileAll(ctx context.Context) {
+	now := time.Now()
+	for _, w := range c.listWorkloads() {
+		c.reconcile(ctx, w, now)
+	}
+}
+
+func (c *Controller) reconcile(ctx context.Context, w *workload, now time.Time) {
+	key := workloadKey(w)
+	if w.rollingOut {
+		klog.V(4).Infof("Skip right-sizing %s, it's being rolled out", key)
+		return
+	}
+	if lastUpdate, ok := w.meta.GetAnnotations()[values.RightSizingLastUpdateAnnotationKey]; ok {
+		lastUpdateTime, err := time.Parse(time.RFC3339, lastUpdate)
+		if err == nil && now.Sub(lastUpdateTime) < c.config.Cooldown.Duration {
+			klog.V(4).Infof("Skip right-sizing %s, it was changed at %s", key, lastUpdate)
+			return
+		}
+	}
+	c.mutex.Lock()
+	dryRunTime, ok := c.dryRunTime[key]
+	c.mutex.Unlock()
+	if ok && now.Sub(dryRunTime) < c.config.Cooldown.Duration {
+		return
+	}
+
+	changes := c.computeChanges(key, w)
+	if len(changes) == 0 {
+		return
+	}
+
+	if c.config.DryRun {
+		c.record(w, changes, ResultDryRun, corev1.EventTypeNormal, EventReasonDryRun, "Would change requests")
+		c.mutex.Lock()
+		c.dryRunTime[key] = now
+		c.mutex.Unlock()
+		return
+	}
+	if err := c.patch(ctx, w, changes, now); err != nil {
+		klog.Errorf("Right-size %s error:%v", key, err)
+		c.record(w, changes, ResultFailed, corev1.EventTypeWarning, EventReasonFailed,
+			fmt.Sprintf("Change requests error:%v, wanted", err))
+		return
+	}
+	klog.Infof("Right-sized %s: %s", key, describeChanges(changes))
+	c.record(w, changes, ResultApplied, corev1.EventTypeNormal, EventReasonApplied, "Changed requests")
+}
+
+// computeChanges returns the request changes of the containers having enough samples,
+// only the cpu/memory already requested by the container are changed
+func (c *Controller) computeChanges(key string, w *workload) []change {
+	c.mutex.Lock()
+	containerSamples := map[string][]sample{}
+	for name, samples := range c.samples[key] {
+		containerSamples[name] = append([]sample{}, samples...)
+	}
+	c.mutex.Unlock()
+
+	var changes []change
+	for _, container := range w.template.Spec.Containers {
+		samples := containerSamples[container.Name]
+		if len(samples) == 0 || len(samples) < c.config.MinSamples {
+			continue
+		}
+
+		cpuUsage, memoryUsage := usagePercentiles(samples)
+		requests, limits := container.Resources.Requests, container.Resources.Limits
+		if request, ok := requests[corev1.ResourceCPU]; ok && !request.IsZero() {
+			target, _ := c.sizing.RecommendCPU(cpuUsage)
+			current := float64(request.MilliValue()) / values.CoreInMCore
+			limit := float64(limits.Cpu().MilliValue()) / values.CoreInMCore
+			next := c.step(current, target, c.config.MinCPUCores, c.config.MaxCPUCores, limit)
+			next = math.Round(next*values.CoreInMCore) / values.CoreInMCore
+			if c.changed(current, next) {
+				changes = append(changes, change{container: container.Name, resource: corev1.ResourceCPU,
+					current: current, next: next, target: target})
+			}
+		}
+		if request, ok := requests[corev1.ResourceMemory]; ok && !request.IsZero() {
+			target, _ := c.sizing.RecommendMemory(memoryUsage)
+			current := float64(request.Value()) / values.GBInBytes
+			limit := float64(limits.Memory().Value()) / values.GBInBytes
+			next := c.step(current, target, c.config.MinMemoryGiB, c.config.MaxMemoryGiB, limit)
+			next = math.Round(next*1024) / 1024
+			if c.changed(current, next) {
+				changes = append(changes, change{container: container.Name, resource: corev1.ResourceMemory,
+					current: current, next: next, target: target})
+			}
+		}
+	}
+	return changes
+}
+
+// step bounds the target by min/max and the container limit, then moves the current
+// request toward it by no more than MaxChangeRatio
+func (c *Controller) step(current, target, min, max, limit float64) float64 {
+	target = math.Max(target, min)
+	if max > 0 {
+		target = math.Min(target, max)
+	}
+	if limit > 0 {
+		target = math.Min(target, limit)
+	}
+	lower, upper := current*(1-c.config.MaxChangeRatio), current*(1+c.config.MaxChangeRatio)
+	return math.Min(math.Max(target, lower), upper)
+}
+
+func (c *Controller) changed(current, next float64) bool {
+	return math.Abs(next-current) > 0 && math.Abs(next-current) >= current*c.config.MinChangeRatio
+}
+
+func (c *Controller) patch(ctx context.Context, w *workload, changes []change, now time.Time) error {
+	containers := map[string]map[corev1.ResourceName]string{}
+	var names []string
+	for _, ch := range changes {
+		if _, ok := containers[ch.container]; !ok {
+			containers[ch.container] = map[corev1.ResourceName]string{}
+			names = append(names, ch.container)
+		}
+		containers[ch.container][ch.resource] = quantity(ch.resource, ch.next)
+	}
+	containerPatches := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		containerPatches = append(containerPatches, map[string]interface{}{
+			"name":      name,
+			"resources": map[string]interface{}{"requests": containers[name]},
+		})
+	}
+	patch := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{values.RightSizingLastUpdateAnnotationKey: now.UTC().Format(time.RFC3339)},
+		},
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{"containers": containerPatches},
+			},
+		},
+	}
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+
+	namespace, name := w.meta.GetNamespace(), w.meta.GetName()
+	options := metav1.PatchOptions{FieldManager: values.KubeFinAgentName}
+	apps := c.client.AppsV1()
+	switch w.kind {
+	case WorkloadTypeDeployment:
+		_, err = apps.Deployments(namespace).Patch(ctx, name, types.StrategicMergePatchType, data, options)
+	case WorkloadTypeStatefulSet:
+		_, err = apps.StatefulSets(namespace).Patch(ctx, name, types.StrategicMergePatchType, data, options)
+	case WorkloadTypeDaemonSet:
+		_, err = apps.DaemonSets(namespace).Patch(ctx, name, types.StrategicMergePatchType, data, options)
+	}
+	return err
+}
+
+// record emits one event for the workload and counts every change
+func (c *Controller) record(w *workload, changes []change, result, eventType, reason, message string) {
+	c.recorder.Eventf(w.object, eventType, reason, "%s %s", message, describeChanges(changes))
+	for _, ch := range changes {
+		rightSizingChangesCV.With(prometheus.Labels{
+			values.WorkloadTypeLabelKey:  w.kind,
+			values.WorkloadNameLabelKey:  w.meta.GetName(),
+			values.NamespaceLabelKey:     w.meta.GetNamespace(),
+			values.ClusterNameLabelKey:   c.clusterName,
+			values.ClusterIdLabelKey:     c.clusterId,
+			values.ContainerNameLabelKey: ch.container,
+			values.ResourceTypeLabelKey:  string(ch.resource),
+			values.ResultLabelKey:        result,
+		}).Inc()
+	}
+}
+
+// listWorkloads returns the workloads opted in the right-sizing
+func (c *Controller) listWorkloads() []*workload {
+	var ret []*workload
+	deployments, err := c.lister.DeploymentLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("List all deployments error:%v", err)
+	}
+	for _, d := range deployments {
+		if !optedIn(d) {
+			continue
+		}
+		replicas := int32(1)
+		if d.Spec.Replicas != nil {
+			replicas = *d.Spec.Replicas
+		}
+		ret = append(ret, &workload{kind: WorkloadTypeDeployment, object: d, meta: d,
+			selector: d.Spec.Selector, template: &d.Spec.Template,
+			rollingOut: d.Status.ObservedGeneration < d.Generation ||
+				d.Status.UpdatedReplicas < replicas ||
+				d.Status.Replicas > d.Status.UpdatedReplicas ||
+				d.Status.AvailableReplicas < d.Status.UpdatedReplicas,
+		})
+	}
+
+	statefulSets, err := c.lister.StatefulSetLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("List all statefulSets error:%v", err)
+	}
+	for _, s := range statefulSets {
+		if !optedIn(s) {
+			continue
+		}
+		replicas := int32(1)
+		if s.Spec.Replicas != nil {
+			replicas = *s.Spec.Replicas
+		}
+		ret = append(ret, &workload{kind: WorkloadTypeStatefulSet, object: s, meta: s,
+			selector: s.Spec.Selector, template: &s.Spec.Template,
+			rollingOut: s.Status.ObservedGeneration < s.Generation ||
+				s.Status.CurrentRevision != s.Status.UpdateRevision ||
+				s.Status.UpdatedReplicas < replicas ||
+				s.Status.ReadyReplicas < replicas,
+		})
+	}
+
+	daemonSets, err := c.lister.DaemonSetLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("List all daemonSets error:%v", err)
+	}
+	for _, d := range daemonSets {
+		if !optedIn(d) {
+			continue
+		}
+		ret = append(ret, &workload{kind: WorkloadTypeDaemonSet, object: d, meta: d,
+			selector: d.Spec.Selector, template: &d.Spec.Template,
+			rollingOut: d.Status.ObservedGeneration < d.Generation ||
+				d.Status.UpdatedNumberScheduled < d.Status.DesiredNumberScheduled ||
+				d.Status.NumberAvailable < d.Status.DesiredNumberScheduled,
+		})
+	}
+	return ret
+}
+
+func optedIn(obj metav1.Object) bool {
+	return obj.GetAnnotations()[values.RightSizingAnnotationKey] == EnabledAnnotationValue
+}
+
+func workloadKey(w *workload) string {
+	return fmt.Sprintf("%s/%s/%s", w.kind, w.meta.GetNamespace(), w.meta.GetName())
+}
+
+// usagePercentiles returns the cpu and memory usage percentiles of the samples
+func usagePercentiles(samples []sample) (cpu, memory *recommendation.Usage) {
+	cpuValues, memoryValues := make([]float64, len(samples)), make([]float64, len(samples))
+	for i, s := range samples {
+		cpuValues[i], memoryValues[i] = s.cpu, s.memory
+	}
+	return percentiles(cpuValues), percentiles(memoryValues)
+}
+
+func percentiles(data []float64) *recommendation.Usage {
+	sort.Float64s(data)
+	at := func(p float64) float64 {
+		i := int(math.Ceil(p/100*float64(len(data)))) - 1
+		return data[int(math.Max(float64(i), 0))]
+	}
+	return &recommendation.Usage{P50: at(50), P95: at(95), P99: at(99), Peak: data[len(data)-1]}
+}
+
+func quantity(resource corev1.ResourceName, value float64) string {
+	if resource == corev1.ResourceCPU {
+		return recommendation.CPUQuantity(value)
+	}
+	return recommendation.MemoryQuantity(value)
+}
+
+func describeChanges(changes []change) string {
+	descriptions := make([]string, 0, len(changes))
+	for _, ch := range changes {
+		descriptions = append(descriptions, fmt.Sprintf("container %s %s request %s -> %s (target %s)",
+			ch.container, ch.resource, quantity(ch.resource, ch.current),
+			quantity(ch.resource, ch.next), quantity(ch.resource, ch.target)))
+	}
+	return strings.Join(descriptions, ", ")
+}
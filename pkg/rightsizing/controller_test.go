@@ -0,0 +1,34 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rightsizing
+
+import (
+	"testing"
+
+	"k8s.io/client-go/kubernetes/fake"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+)
+
+func TestNewControllerTwice(t *testing.T) {
+	// The metrics are registered once, so the controllers could be created again
+	for i := 0; i < 2; i++ {
+		if c := NewController(&Config{}, "cluster", "cluster-1", fake.NewSimpleClientset(),
+			metricsfake.NewSimpleClientset(), nil); c == nil {
+			t.Fatalf("NewController() returns nil")
+		}
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rightsizing
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// EnabledAnnotationValue is the value of values.RightSizingAnnotationKey opting the workload in
+	EnabledAnnotationValue = "enabled"
+
+	WorkloadTypeDeployment  = "deployment"
+	WorkloadTypeStatefulSet = "statefulset"
+	WorkloadTypeDaemonSet   = "daemonset"
+
+	ResultApplied = "applied"
+	ResultDryRun  = "dry_run"
+	ResultFailed  = "failed"
+
+	EventReasonApplied = "RightSized"
+	EventReasonDryRun  = "RightSizingDryRun"
+	EventReasonFailed  = "RightSizingFailed"
+)
+
+// Config holds the settings of the right-sizing controller, it only changes the
+// workloads annotated with values.RightSizingAnnotationKey=enabled
+type Config struct {
+	Enabled bool `json:"enabled,omitempty"`
+	// DryRun records the changes as events and metrics without patching the workloads
+	DryRun bool `json:"dryRun,omitempty"`
+	// SampleInterval is how often the container usage is sampled from metrics server
+	SampleInterval metav1.Duration `json:"sampleInterval,omitempty"`
+	// Interval is how often the requests of the workloads are adjusted
+	Interval metav1.Duration `json:"interval,omitempty"`
+	// Window is how long the usage samples are kept
+	Window metav1.Duration `json:"window,omitempty"`
+	// MinSamples is the least samples of a container needed before its requests are changed
+	MinSamples int `json:"minSamples,omitempty"`
+	// CPUPercentile/MemoryPercentile are the usage percentiles the requests are sized to, 50/95/99/100
+	CPUPercentile    float64 `json:"cpuPercentile,omitempty"`
+	MemoryPercentile float64 `json:"memoryPercentile,omitempty"`
+	// Headroom is the fraction added on top of the usage percentile
+	Headroom float64 `json:"headroom,omitempty"`
+	// MinCPUCores/MaxCPUCores and MinMemoryGiB/MaxMemoryGiB bound the requests, a zero max means no bound
+	MinCPUCores  float64 `json:"minCPUCores,omitempty"`
+	MaxCPUCores  float64 `json:"maxCPUCores,omitempty"`
+	MinMemoryGiB float64 `json:"minMemoryGiB,omitempty"`
+	MaxMemoryGiB float64 `json:"maxMemoryGiB,omitempty"`
+	// MaxChangeRatio is the largest fraction a request is changed by in one step
+	MaxChangeRatio float64 `json:"maxChangeRatio,omitempty"`
+	// MinChangeRatio skips the changes smaller than this fraction of the current request
+	MinChangeRatio float64 `json:"minChangeRatio,omitempty"`
+	// Cooldown is the least time between two changes of the same workload
+	Cooldown metav1.Duration `json:"cooldown,omitempty"`
+}
+
+func NewDefaultConfig() *Config {
+	return &Config{
+		SampleInterval:   metav1.Duration{Duration: time.Minute},
+		Interval:         metav1.Duration{Duration: time.Hour},
+		Window:           metav1.Duration{Duration: 24 * time.Hour},
+		MinSamples:       60,
+		CPUPercentile:    95,
+		MemoryPercentile: 99,
+		Headroom:         0.15,
+		MinCPUCores:      0.01,
+		MinMemoryGiB:     0.0625,
+		MaxChangeRatio:   0.2,
+		MinChangeRatio:   0.05,
+		Cooldown:         metav1.Duration{Duration: 6 * time.Hour},
+	}
+}
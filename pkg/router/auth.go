@@ -0,0 +1,118 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package router
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/klog/v2"
+
+	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/auth"
+	"github.com/kubefin/kubefin/pkg/utils"
+	"github.com/kubefin/kubefin/pkg/values"
+)
+
+// authMiddleware authenticates the caller, then checks the tenant and the cluster_id
+// in the path are granted to it. The resolved tenant is written back to the tenant
+// header so the handlers always query the tenant that has been authorized.
+func authMiddleware(authn authenticator.Request, authz *auth.Authorizer, defaultTenantId string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		resp, ok, err := authn.AuthenticateRequest(ctx.Request)
+		if err != nil || !ok {
+			message := "invalid or missing bearer token"
+			if err != nil {
+				message = err.Error()
+			}
+			klog.Warningf("Deny unauthenticated request %s %s from %s:%s",
+				ctx.Request.Method, ctx.Request.URL.Path, ctx.ClientIP(), message)
+			utils.ForwardStatusError(ctx, http.StatusUnauthorized,
+				api.UnauthorizedStatus, api.UnauthorizedReason, message)
+			ctx.Abort()
+			return
+		}
+
+		access := authz.AccessFor(resp.User)
+		userName := resp.User.GetName()
+
+		tenantId := utils.ParserTenantIdFromCtx(ctx)
+		if tenantId == "" {
+			if singleTenant, ok := access.SingleTenant(); ok {
+				tenantId = singleTenant
+			} else {
+				tenantId = defaultTenantId
+			}
+		}
+		// An empty tenant means the backend is not multi-tenant
+		if tenantId != "" && !access.TenantAllowed(tenantId) {
+			denyForbidden(ctx, userName, fmt.Sprintf("user %s is not allowed to access tenant %s", userName, tenantId))
+			return
+		}
+		ctx.Request.Header.Set(values.MultiTenantHeader, tenantId)
+
+		clusterId := utils.ParseClusterFromCtx(ctx)
+		if clusterId != "" && !access.ClusterAllowed(clusterId) {
+			denyForbidden(ctx, userName, fmt.Sprintf("user %s is not allowed to access cluster %s", userName, clusterId))
+			return
+		}
+
+		klog.V(2).Infof("Allow user %s to %s %s, tenant:%s", userName, ctx.Request.Method, ctx.Request.URL.Path, tenantId)
+		auth.WithAccess(ctx, access)
+		ctx.Next()
+	}
+}
+
+// validateClusterId rejects the cluster_id in the path out of the strict charset, it runs
+// before the caller is authorized so the id could never be matched against the grants
+func validateClusterId(ctx *gin.Context) {
+	clusterId := utils.ParseClusterFromCtx(ctx)
+	if clusterId == "" {
+		ctx.Next()
+		return
+	}
+	if err := utils.ValidateClusterId(clusterId); err != nil {
+		klog.Warningf("Deny request %s %s with invalid cluster id:%v", ctx.Request.Method, ctx.Request.URL.Path, err)
+		utils.ForwardStatusError(ctx, http.StatusBadRequest,
+			api.QueryParaErrorStatus, api.QueryParaErrorReason, err.Error())
+		ctx.Abort()
+		return
+	}
+	ctx.Next()
+}
+
+func denyForbidden(ctx *gin.Context, userName, message string) {
+	klog.Warningf("Deny request %s %s from user %s:%s", ctx.Request.Method, ctx.Request.URL.Path, userName, message)
+	utils.ForwardStatusError(ctx, http.StatusForbidden, api.ForbiddenStatus, api.ForbiddenReason, message)
+	ctx.Abort()
+}
+
+// requireClusterScope guards the endpoints returning cluster level data, which are
+// not accessible if only some namespaces of the cluster are granted
+func requireClusterScope(ctx *gin.Context) {
+	access := auth.AccessFromContext(ctx)
+	clusterId := utils.ParseClusterFromCtx(ctx)
+	if access == nil || clusterId == "" || access.ClusterScopeAllowed(clusterId) {
+		ctx.Next()
+		return
+	}
+
+	userName := access.User.GetName()
+	denyForbidden(ctx, userName, fmt.Sprintf("user %s is only allowed to access some namespaces of cluster %s", userName, clusterId))
+}
@@ -17,51 +17,144 @@ limitations under the License.
 package router
 
 import (
+	"context"
+	"time"
+
 	"github.com/gin-contrib/cors"
 	"github.com/gin-contrib/gzip"
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	swaggerfiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
 
 	_ "github.com/kubefin/kubefin/api"
+	"github.com/kubefin/kubefin/pkg/auth"
+	"github.com/kubefin/kubefin/pkg/query"
+	"github.com/kubefin/kubefin/pkg/server"
+	"github.com/kubefin/kubefin/pkg/server/anomalies_handler"
+	"github.com/kubefin/kubefin/pkg/server/budgets_handler"
 	"github.com/kubefin/kubefin/pkg/server/costs_handler"
+	"github.com/kubefin/kubefin/pkg/server/ledger_handler"
 	"github.com/kubefin/kubefin/pkg/server/metrics_handler"
+	"github.com/kubefin/kubefin/pkg/server/recommendations_handler"
+	"github.com/kubefin/kubefin/pkg/values"
 )
 
-func NewServerRouter() *gin.Engine {
+// Config holds the settings of the API router
+type Config struct {
+	// CORSAllowedOrigins is the list of origins allowed to call the API, "*" allows all
+	CORSAllowedOrigins []string
+	// Authenticator is nil if authentication is disabled
+	Authenticator authenticator.Request
+	Authorizer    *auth.Authorizer
+	// DefaultTenantId is the tenant of requests without the tenant header
+	DefaultTenantId string
+	// Server holds the dependencies of the handlers
+	Server *server.Config
+	// RequestTimeout is the deadline of the queries of every request, 0 means no deadline
+	RequestTimeout time.Duration
+}
+
+func NewServerRouter(routerConfig *Config) *gin.Engine {
 	router := gin.New()
 
 	config := cors.DefaultConfig()
-	config.AllowOrigins = []string{"*"}
+	config.AllowOrigins = routerConfig.CORSAllowedOrigins
 	config.AllowHeaders = []string{"*"}
+	config.ExposeHeaders = []string{values.WarningHeader}
 	corsHandler := cors.New(config)
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	router.GET("/readyz", gin.WrapH(query.NewReadyzHandler(routerConfig.Server.QueryBackend)))
 	router.Use(corsHandler)
+	if routerConfig.RequestTimeout > 0 {
+		router.Use(requestTimeout(routerConfig.RequestTimeout))
+	}
+	router.Use(validateClusterId)
+	if routerConfig.Authenticator != nil {
+		router.Use(authMiddleware(routerConfig.Authenticator, routerConfig.Authorizer, routerConfig.DefaultTenantId))
+	}
 
-	initMetricsRouter(router, corsHandler)
-	initCostAnalyzeRouter(router, corsHandler)
+	s := server.NewServer(routerConfig.Server)
+	initMetricsRouter(router, corsHandler, metrics_handler.NewHandler(s))
+	initCostAnalyzeRouter(router, corsHandler, costs_handler.NewHandler(s))
+	initBudgetsRouter(router, corsHandler, budgets_handler.NewHandler(s))
+	initAnomaliesRouter(router, corsHandler, anomalies_handler.NewHandler(s))
+	initLedgerRouter(router, corsHandler, ledger_handler.NewHandler(s))
+	initRecommendationsRouter(router, corsHandler, recommendations_handler.NewHandler(s))
 
 	return router
 }
 
-func initMetricsRouter(router *gin.Engine, corsHandler gin.HandlerFunc) {
+// requestTimeout sets the deadline of the request context, the queries still running are
+// canceled once it's exceeded or the client disconnects
+func requestTimeout(timeout time.Duration) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		timeoutCtx, cancel := context.WithTimeout(ctx.Request.Context(), timeout)
+		defer cancel()
+		ctx.Request = ctx.Request.WithContext(timeoutCtx)
+		ctx.Next()
+	}
+}
+
+func initMetricsRouter(router *gin.Engine, corsHandler gin.HandlerFunc, handler *metrics_handler.Handler) {
 	metricsGroup := router.Group("/api/v1/metrics")
-	metricsGroup.GET("/summary", metrics_handler.ClustersMetricsSummaryHandler)
-	metricsGroup.GET("/clusters/:cluster_id/summary", metrics_handler.ClusterMetricsSummaryHandler)
-	metricsGroup.GET("/clusters/:cluster_id/cpu", metrics_handler.ClusterCPUMetricsHandler)
-	metricsGroup.GET("/clusters/:cluster_id/memory", metrics_handler.ClusterMemoryMetricsHandler)
+	metricsGroup.GET("/summary", handler.ClustersMetricsSummaryHandler)
+	metricsGroup.GET("/clusters/:cluster_id/summary", requireClusterScope, handler.ClusterMetricsSummaryHandler)
+	metricsGroup.GET("/clusters/:cluster_id/cpu", requireClusterScope, handler.ClusterCPUMetricsHandler)
+	metricsGroup.GET("/clusters/:cluster_id/memory", requireClusterScope, handler.ClusterMemoryMetricsHandler)
 	metricsGroup.Use(gzip.Gzip(gzip.DefaultCompression))
 	metricsGroup.Use(corsHandler)
 }
 
-func initCostAnalyzeRouter(router *gin.Engine, corsHandler gin.HandlerFunc) {
+func initCostAnalyzeRouter(router *gin.Engine, corsHandler gin.HandlerFunc, handler *costs_handler.Handler) {
 	costsGroup := router.Group("/api/v1/costs")
-	costsGroup.GET("/summary", costs_handler.ClustersCostsSummaryHandler)
-	costsGroup.GET("/clusters/:cluster_id/summary", costs_handler.ClusterCostsSummaryHandler)
-	costsGroup.GET("/clusters/:cluster_id/resource", costs_handler.ClusterResourceCostsHandler)
-	costsGroup.GET("/clusters/:cluster_id/workload", costs_handler.ClusterWorkloadsCostsHandler)
-	costsGroup.GET("/clusters/:cluster_id/namespace", costs_handler.ClusterNamespacesCostsHandler)
+	costsGroup.GET("/summary", handler.ClustersCostsSummaryHandler)
+	costsGroup.GET("/breakdown", handler.ClustersCostBreakdownHandler)
+	costsGroup.GET("/clusters/:cluster_id/summary", requireClusterScope, handler.ClusterCostsSummaryHandler)
+	costsGroup.GET("/clusters/:cluster_id/resource", requireClusterScope, handler.ClusterResourceCostsHandler)
+	costsGroup.GET("/clusters/:cluster_id/workload", handler.ClusterWorkloadsCostsHandler)
+	costsGroup.GET("/clusters/:cluster_id/namespace", handler.ClusterNamespacesCostsHandler)
+	costsGroup.GET("/clusters/:cluster_id/nodes", requireClusterScope, handler.ClusterNodesCostsHandler)
+	costsGroup.GET("/clusters/:cluster_id/forecast", handler.ClusterCostForecastHandler)
+	costsGroup.GET("/clusters/:cluster_id/compare", handler.ClusterCostComparisonHandler)
+	costsGroup.GET("/clusters/:cluster_id/efficiency", handler.ClusterWorkloadsEfficiencyHandler)
 	costsGroup.Use(gzip.Gzip(gzip.DefaultCompression))
 	costsGroup.Use(corsHandler)
 }
+
+func initBudgetsRouter(router *gin.Engine, corsHandler gin.HandlerFunc, handler *budgets_handler.Handler) {
+	budgetsGroup := router.Group("/api/v1/budgets")
+	budgetsGroup.GET("", handler.BudgetsHandler)
+	budgetsGroup.GET("/:budget_name", handler.BudgetHandler)
+	budgetsGroup.Use(gzip.Gzip(gzip.DefaultCompression))
+	budgetsGroup.Use(corsHandler)
+}
+
+func initAnomaliesRouter(router *gin.Engine, corsHandler gin.HandlerFunc, handler *anomalies_handler.Handler) {
+	anomaliesGroup := router.Group("/api/v1/anomalies")
+	anomaliesGroup.GET("", handler.AnomaliesHandler)
+	anomaliesGroup.Use(gzip.Gzip(gzip.DefaultCompression))
+	anomaliesGroup.Use(corsHandler)
+}
+
+func initLedgerRouter(router *gin.Engine, corsHandler gin.HandlerFunc, handler *ledger_handler.Handler) {
+	ledgerGroup := router.Group("/api/v1/ledger")
+	ledgerGroup.GET("/costs", handler.LedgerCostsHandler)
+	ledgerGroup.Use(gzip.Gzip(gzip.DefaultCompression))
+	ledgerGroup.Use(corsHandler)
+}
+
+func initRecommendationsRouter(router *gin.Engine, corsHandler gin.HandlerFunc,
+	handler *recommendations_handler.Handler) {
+	recommendationsGroup := router.Group("/api/v1/recommendations")
+	recommendationsGroup.GET("/clusters/:cluster_id/workloads", handler.WorkloadsRecommendationsHandler)
+	recommendationsGroup.GET("/clusters/:cluster_id/nodes", requireClusterScope, handler.NodesRecommendationsHandler)
+	recommendationsGroup.GET("/clusters/:cluster_id/nodes/snapshot", requireClusterScope,
+		handler.NodesSimulationSnapshotHandler)
+	recommendationsGroup.GET("/clusters/:cluster_id/spot", handler.SpotRecommendationsHandler)
+	recommendationsGroup.Use(gzip.Gzip(gzip.DefaultCompression))
+	recommendationsGroup.Use(corsHandler)
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package anomalies_handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/klog/v2"
+
+	"github.com/kubefin/kubefin/pkg/anomaly"
+	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/auth"
+	"github.com/kubefin/kubefin/pkg/server/implementation"
+	"github.com/kubefin/kubefin/pkg/utils"
+)
+
+// AnomaliesHandler  godoc
+//
+//	@Summary		Get cost anomalies
+//	@Description	Get the cost spikes of clusters, namespaces and workloads detected in the time range, the latest ones first
+//	@Tags			Anomalies
+//	@Produce		json
+//	@Param			startTime	query		uint64	false	"The start time to query"
+//	@Param			endTime		query		uint64	false	"The end time to query"
+//	@Param			clusterId	query		string	false	"Only return the anomalies of this cluster"
+//	@Param			namespace	query		string	false	"Only return the anomalies of this namespace"
+//	@Param			scope		query		string	false	"Only return the anomalies of this scope, cluster/namespace/workload"
+//	@Param			severity	query		string	false	"Only return the anomalies of this severity, warning/critical"
+//	@Success		200			{object}	api.AnomalyList
+//	@Failure		500			{object}	api.StatusError
+//	@Router			/anomalies [get]
+func (h *Handler) AnomaliesHandler(ctx *gin.Context) {
+	klog.V(6).Info("Start to query cost anomalies")
+	startTime, endTime, err := implementation.GetStartEndTimeFromCtx(ctx)
+	if err != nil {
+		utils.ForwardStatusError(ctx, http.StatusBadRequest,
+			api.QueryParaErrorStatus, api.QueryParaErrorReason, err.Error())
+		return
+	}
+
+	detector := h.Detector()
+	if detector == nil {
+		ctx.JSON(http.StatusOK, &api.AnomalyList{Items: []*api.Anomaly{}})
+		return
+	}
+	anomalies := detector.Anomalies(utils.ParserTenantIdFromCtx(ctx), &anomaly.Filter{
+		ClusterId: ctx.Query(api.QueryClusterIdPara),
+		Namespace: ctx.Query(api.QueryNamespacePara),
+		Scope:     ctx.Query(api.QueryScopePara),
+		Severity:  ctx.Query(api.QuerySeverityPara),
+		Start:     startTime,
+		End:       endTime,
+	})
+
+	if access := auth.AccessFromContext(ctx); access != nil {
+		allowed := make([]*api.Anomaly, 0, len(anomalies))
+		for _, item := range anomalies {
+			if item.Scope == api.AnomalyScopeCluster && access.ClusterScopeAllowed(item.ClusterId) ||
+				item.Scope != api.AnomalyScopeCluster && access.NamespaceAllowed(item.ClusterId, item.Namespace) {
+				allowed = append(allowed, item)
+			}
+		}
+		anomalies = allowed
+	}
+
+	ctx.JSON(http.StatusOK, &api.AnomalyList{Items: anomalies})
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package budgets_handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/klog/v2"
+
+	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/auth"
+	"github.com/kubefin/kubefin/pkg/utils"
+	"github.com/kubefin/kubefin/pkg/values"
+)
+
+// BudgetsHandler  godoc
+//
+//	@Summary		Get all budgets status
+//	@Description	Get the actual and forecast cost of all budgets in current period
+//	@Tags			Budgets
+//	@Produce		json
+//	@Success		200	{object}	api.BudgetStatusList
+//	@Failure		500	{object}	api.StatusError
+//	@Router			/budgets [get]
+func (h *Handler) BudgetsHandler(ctx *gin.Context) {
+	klog.V(6).Info("Start to query budgets status")
+	ctx.JSON(http.StatusOK, &api.BudgetStatusList{Items: h.budgetStatuses(ctx)})
+}
+
+// BudgetHandler  godoc
+//
+//	@Summary		Get specific budget status
+//	@Description	Get the actual and forecast cost of specific budget in current period
+//	@Tags			Budgets
+//	@Produce		json
+//	@Param			budget_name	path		string	true	"Budget Name"
+//	@Success		200			{object}	api.BudgetStatus
+//	@Failure		404			{object}	api.StatusError
+//	@Failure		500			{object}	api.StatusError
+//	@Router			/budgets/{budget_name} [get]
+func (h *Handler) BudgetHandler(ctx *gin.Context) {
+	klog.V(6).Info("Start to query budget status")
+	name := ctx.Param(values.BudgetNameQueryParameter)
+	for _, status := range h.budgetStatuses(ctx) {
+		if status.Name == name {
+			ctx.JSON(http.StatusOK, status)
+			return
+		}
+	}
+	utils.ForwardStatusError(ctx, http.StatusNotFound,
+		api.QueryNotFoundStatus, api.QueryNotFoundReason, "budget "+name+" not found")
+}
+
+// budgetStatuses returns the status of the budgets in the request tenant that the caller could access
+func (h *Handler) budgetStatuses(ctx *gin.Context) []*api.BudgetStatus {
+	evaluator := h.Evaluator()
+	if evaluator == nil {
+		return []*api.BudgetStatus{}
+	}
+
+	statuses := evaluator.Statuses(utils.ParserTenantIdFromCtx(ctx))
+	access := auth.AccessFromContext(ctx)
+	if access == nil {
+		return statuses
+	}
+	ret := make([]*api.BudgetStatus, 0, len(statuses))
+	for _, status := range statuses {
+		if status.Namespace != "" && access.NamespaceAllowed(status.ClusterId, status.Namespace) ||
+			access.ClusterScopeAllowed(status.ClusterId) {
+			ret = append(ret, status)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package budgets_handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/budget"
+	"github.com/kubefin/kubefin/pkg/query/querytest"
+	"github.com/kubefin/kubefin/pkg/server"
+	"github.com/kubefin/kubefin/pkg/values"
+)
+
+func newTestRouter(evaluator *budget.Evaluator) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	handler := NewHandler(server.NewServer(&server.Config{
+		QueryBackend: querytest.NewBackend(),
+		Evaluator:    evaluator,
+	}))
+	router := gin.New()
+	router.GET("/budgets", handler.BudgetsHandler)
+	router.GET("/budgets/:budget_name", handler.BudgetHandler)
+	return router
+}
+
+func TestBudgetsHandler(t *testing.T) {
+	evaluator := budget.NewEvaluator(&budget.Config{Items: []api.Budget{
+		{Name: "team-a", ClusterId: "cluster-1", Period: "monthly", Amount: 100},
+		{Name: "team-b", TenantId: "tenant-2", ClusterId: "cluster-2", Period: "weekly", Amount: 50},
+	}}, "tenant-1", querytest.NewBackend(), nil)
+
+	tests := []struct {
+		name      string
+		evaluator *budget.Evaluator
+		tenantId  string
+		want      []string
+	}{
+		{
+			name: "no budget configured",
+			want: []string{},
+		},
+		{
+			name:      "default tenant",
+			evaluator: evaluator,
+			want:      []string{"team-a"},
+		},
+		{
+			name:      "tenant of the request",
+			evaluator: evaluator,
+			tenantId:  "tenant-2",
+			want:      []string{"team-b"},
+		},
+		{
+			name:      "tenant without budgets",
+			evaluator: evaluator,
+			tenantId:  "tenant-3",
+			want:      []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/budgets", nil)
+			if tt.tenantId != "" {
+				req.Header.Set(values.MultiTenantHeader, tt.tenantId)
+			}
+			w := httptest.NewRecorder()
+			newTestRouter(tt.evaluator).ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("code = %d, want %d", w.Code, http.StatusOK)
+			}
+			list := &api.BudgetStatusList{}
+			if err := json.Unmarshal(w.Body.Bytes(), list); err != nil {
+				t.Fatalf("decode response error:%v", err)
+			}
+			got := []string{}
+			for _, status := range list.Items {
+				got = append(got, status.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("budgets = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBudgetHandler(t *testing.T) {
+	evaluator := budget.NewEvaluator(&budget.Config{Items: []api.Budget{
+		{Name: "team-a", ClusterId: "cluster-1", Period: "monthly", Amount: 100},
+		{Name: "team-b", TenantId: "tenant-2", ClusterId: "cluster-2", Period: "weekly", Amount: 50},
+	}}, "tenant-1", querytest.NewBackend(), nil)
+
+	tests := []struct {
+		name      string
+		evaluator *budget.Evaluator
+		budget    string
+		wantCode  int
+	}{
+		{name: "found", evaluator: evaluator, budget: "team-a", wantCode: http.StatusOK},
+		{name: "budget of other tenant", evaluator: evaluator, budget: "team-b", wantCode: http.StatusNotFound},
+		{name: "unknown budget", evaluator: evaluator, budget: "team-c", wantCode: http.StatusNotFound},
+		{name: "no budget configured", budget: "team-a", wantCode: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			newTestRouter(tt.evaluator).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/budgets/"+tt.budget, nil))
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("code = %d, want %d", w.Code, tt.wantCode)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			status := &api.BudgetStatus{}
+			if err := json.Unmarshal(w.Body.Bytes(), status); err != nil {
+				t.Fatalf("decode response error:%v", err)
+			}
+			if status.Name != tt.budget || status.State != api.BudgetStateOK {
+				t.Errorf("status = %s/%s, want %s/%s", status.Name, status.State, tt.budget, api.BudgetStateOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package costs_handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/klog/v2"
+
+	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/auth"
+	"github.com/kubefin/kubefin/pkg/server/implementation"
+	"github.com/kubefin/kubefin/pkg/utils"
+)
+
+// ClusterCostComparisonHandler  godoc
+//
+//	@Summary		Compare specific cluster costs of two periods
+//	@Description	Compare the cost of every namespace, workload, label value or node instance type in specific cluster between two periods
+//	@Tags			Costs
+//	@Produce		json
+//	@Param			cluster_id		path		string	true	"Cluster Id"
+//	@Param			startTime		query		uint64	false	"The start time of the period"
+//	@Param			endTime			query		uint64	false	"The end time of the period"
+//	@Param			baseStartTime	query		uint64	false	"The start time of the period compared with, the period right before by default"
+//	@Param			baseEndTime		query		uint64	false	"The end time of the period compared with"
+//	@Param			dimension		query		string	false	"Compare the cost by namespace/workload/label/instanceType, namespace by default"
+//	@Param			labelKey		query		string	false	"The pod label to compare the cost by, required by the label dimension"
+//	@Param			namespace		query		string	false	"Only compare the cost of this namespace"
+//	@Param			workloadType	query		string	false	"Only compare the workloads of this type, deployment/statefulset/daemonset"
+//	@Param			nameRegex		query		string	false	"Only compare the items whose names fully match this regex"
+//	@Success		200				{object}	api.CostComparison
+//	@Failure		400				{object}	api.StatusError
+//	@Failure		403				{object}	api.StatusError
+//	@Failure		500				{object}	api.StatusError
+//	@Router			/costs/clusters/{cluster_id}/compare [get]
+func (h *Handler) ClusterCostComparisonHandler(ctx *gin.Context) {
+	klog.V(6).Info("Start to compare cluster costs")
+	backend := h.QueryBackend(ctx)
+	clusterId := utils.ParseClusterFromCtx(ctx)
+	if clusterId == "" {
+		utils.ForwardStatusError(ctx, http.StatusBadRequest,
+			api.QueryParaErrorStatus, api.QueryParaErrorReason, "")
+		return
+	}
+	startTime, endTime, err := implementation.GetStartEndTimeFromCtx(ctx)
+	if err == nil && startTime >= endTime {
+		err = fmt.Errorf("start time %d should be before end time %d", startTime, endTime)
+	}
+	if err != nil {
+		utils.ForwardStatusError(ctx, http.StatusBadRequest,
+			api.QueryParaErrorStatus, api.QueryParaErrorReason, err.Error())
+		return
+	}
+	baseStartTime, baseEndTime, err := getBasePeriodFromCtx(ctx, startTime, endTime)
+	if err != nil {
+		utils.ForwardStatusError(ctx, http.StatusBadRequest,
+			api.QueryParaErrorStatus, api.QueryParaErrorReason, err.Error())
+		return
+	}
+	filter, err := implementation.GetCostListFilterFromCtx(ctx)
+	if err == nil && filter.WorkloadType == api.AggregateByPod {
+		err = fmt.Errorf("the pods are not compared, compare the workloads owning them instead")
+	}
+	if err != nil {
+		utils.ForwardStatusError(ctx, http.StatusBadRequest,
+			api.QueryParaErrorStatus, api.QueryParaErrorReason, err.Error())
+		return
+	}
+
+	dimension := ctx.DefaultQuery(api.QueryDimensionPara, api.CompareDimensionNamespace)
+	labelKey := ctx.Query(api.QueryLabelKeyPara)
+	switch dimension {
+	case api.CompareDimensionNamespace, api.CompareDimensionWorkload, api.CompareDimensionInstanceType:
+	case api.CompareDimensionLabel:
+		if labelKey == "" {
+			utils.ForwardStatusError(ctx, http.StatusBadRequest, api.QueryParaErrorStatus, api.QueryParaErrorReason,
+				"labelKey is required by the label dimension")
+			return
+		}
+	default:
+		utils.ForwardStatusError(ctx, http.StatusBadRequest, api.QueryParaErrorStatus, api.QueryParaErrorReason,
+			fmt.Sprintf("dimension %s is not supported, should be one of namespace/workload/label/instanceType", dimension))
+		return
+	}
+	// The nodes are cluster level data
+	if access := auth.AccessFromContext(ctx); access != nil && dimension == api.CompareDimensionInstanceType &&
+		!access.ClusterScopeAllowed(clusterId) {
+		utils.ForwardStatusError(ctx, http.StatusForbidden, api.ForbiddenStatus, api.ForbiddenReason,
+			fmt.Sprintf("user %s is only allowed to access some namespaces of cluster %s", access.User.GetName(), clusterId))
+		return
+	}
+
+	filter.NamespaceRe = auth.NamespaceRegexFromContext(ctx, clusterId)
+	comparison, err := implementation.QueryCostComparison(h.QueryContext(ctx), backend, clusterId, filter,
+		dimension, labelKey, baseStartTime, baseEndTime, startTime, endTime)
+	if err != nil {
+		utils.ForwardQueryError(ctx, err)
+		return
+	}
+	if dimension == api.CompareDimensionWorkload {
+		comparison.Items = auth.FilterNamespaces(ctx, clusterId, comparison.Items, func(item *api.CostComparisonItem) string {
+			return item.Namespace
+		})
+	} else if dimension == api.CompareDimensionNamespace {
+		comparison.Items = auth.FilterNamespaces(ctx, clusterId, comparison.Items, func(item *api.CostComparisonItem) string {
+			return item.Name
+		})
+	}
+
+	ctx.JSON(http.StatusOK, comparison)
+}
+
+// getBasePeriodFromCtx returns the period compared with, it's the period of the same length right before the
+// compared one if it's not set
+func getBasePeriodFromCtx(ctx *gin.Context, startTime, endTime int64) (int64, int64, error) {
+	baseStartStr := ctx.Query(api.QueryBaseStartTimePara)
+	baseEndStr := ctx.Query(api.QueryBaseEndTimePara)
+	if baseStartStr == "" && baseEndStr == "" {
+		return 2*startTime - endTime, startTime, nil
+	}
+	if baseStartStr == "" || baseEndStr == "" {
+		return 0, 0, fmt.Errorf("baseStartTime and baseEndTime must be set together")
+	}
+
+	baseStartTime, err := strconv.ParseInt(baseStartStr, 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	baseEndTime, err := strconv.ParseInt(baseEndStr, 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	if baseStartTime >= baseEndTime {
+		return 0, 0, fmt.Errorf("base start time %d should be before base end time %d", baseStartTime, baseEndTime)
+	}
+	return baseStartTime, baseEndTime, nil
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package costs_handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/klog/v2"
+
+	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/auth"
+	"github.com/kubefin/kubefin/pkg/server/implementation"
+	"github.com/kubefin/kubefin/pkg/utils"
+	"github.com/kubefin/kubefin/pkg/values"
+)
+
+// ClusterWorkloadsEfficiencyHandler  godoc
+//
+//	@Summary		Get the least efficient workloads of specific cluster
+//	@Description	Get the workloads of specific cluster wasting the most cost on the cpu and ram requested but not used in the time range
+//	@Tags			Costs
+//	@Produce		json
+//	@Param			cluster_id		path		string	true	"Cluster Id"
+//	@Param			startTime		query		uint64	false	"The start time to query"
+//	@Param			endTime			query		uint64	false	"The end time to query"
+//	@Param			namespace		query		string	false	"Only return the workloads of this namespace"
+//	@Param			workloadType	query		string	false	"Only return the workloads of this type, pod/deployment/statefulset/daemonset, deployment/statefulset/daemonset by default"
+//	@Param			nameRegex		query		string	false	"Only return the workloads whose names fully match this regex"
+//	@Param			topN			query		uint64	false	"The number of the workloads to return, 10 by default and 0 returns all"
+//	@Success		200				{object}	api.WorkloadEfficiencyList
+//	@Failure		400				{object}	api.StatusError
+//	@Failure		500				{object}	api.StatusError
+//	@Router			/costs/clusters/{cluster_id}/efficiency [get]
+func (h *Handler) ClusterWorkloadsEfficiencyHandler(ctx *gin.Context) {
+	klog.V(6).Info("Start to query cluster workloads efficiency")
+	backend := h.QueryBackend(ctx)
+	clusterId := utils.ParseClusterFromCtx(ctx)
+	if clusterId == "" {
+		utils.ForwardStatusError(ctx, http.StatusBadRequest,
+			api.QueryParaErrorStatus, api.QueryParaErrorReason, "")
+		return
+	}
+	startTime, endTime, err := implementation.GetStartEndTimeFromCtx(ctx)
+	if err == nil && startTime >= endTime {
+		err = fmt.Errorf("start time %d should be before end time %d", startTime, endTime)
+	}
+	if err != nil {
+		utils.ForwardStatusError(ctx, http.StatusBadRequest,
+			api.QueryParaErrorStatus, api.QueryParaErrorReason, err.Error())
+		return
+	}
+	filter, err := implementation.GetCostListFilterFromCtx(ctx)
+	if err != nil {
+		utils.ForwardStatusError(ctx, http.StatusBadRequest,
+			api.QueryParaErrorStatus, api.QueryParaErrorReason, err.Error())
+		return
+	}
+	topN := values.DefaultEfficiencyTopN
+	if topNStr := ctx.Query(api.QueryTopNPara); topNStr != "" {
+		topN, err = strconv.Atoi(topNStr)
+		if err != nil || topN < 0 {
+			utils.ForwardStatusError(ctx, http.StatusBadRequest, api.QueryParaErrorStatus, api.QueryParaErrorReason,
+				fmt.Sprintf("topN %s should be a non-negative integer", topNStr))
+			return
+		}
+	}
+
+	filter.NamespaceRe = auth.NamespaceRegexFromContext(ctx, clusterId)
+	keep := func(workload *api.ClusterWorkloadCost) bool {
+		access := auth.AccessFromContext(ctx)
+		return access == nil || access.NamespaceAllowed(clusterId, workload.Namespace)
+	}
+	efficiency, err := implementation.QueryWorkloadsEfficiency(h.QueryContext(ctx), backend, clusterId, filter,
+		startTime, endTime, topN, keep)
+	if err != nil {
+		utils.ForwardQueryError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, efficiency)
+}
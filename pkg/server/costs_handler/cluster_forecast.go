@@ -0,0 +1,80 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package costs_handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/klog/v2"
+
+	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/auth"
+	"github.com/kubefin/kubefin/pkg/server/implementation"
+	"github.com/kubefin/kubefin/pkg/utils"
+	"github.com/kubefin/kubefin/pkg/values"
+)
+
+// ClusterCostForecastHandler  godoc
+//
+//	@Summary		Get specific cluster cost forecast
+//	@Description	Forecast the month end, next 30 days and next 90 days cost of the cluster and its namespaces with trend and weekly seasonality
+//	@Tags			Costs
+//	@Produce		json
+//	@Param			cluster_id	path		string	true	"Cluster Id"
+//	@Param			historyDays	query		uint64	false	"The days of history to fit the forecast on, 90 by default"
+//	@Success		200			{object}	api.ClusterCostForecast
+//	@Failure		500			{object}	api.StatusError
+//	@Router			/costs/clusters/{cluster_id}/forecast [get]
+func (h *Handler) ClusterCostForecastHandler(ctx *gin.Context) {
+	klog.V(6).Info("Start to forecast cluster cost")
+	backend := h.QueryBackend(ctx)
+	clusterId := utils.ParseClusterFromCtx(ctx)
+	if clusterId == "" {
+		utils.ForwardStatusError(ctx, http.StatusBadRequest,
+			api.QueryParaErrorStatus, api.QueryParaErrorReason, "")
+		return
+	}
+
+	historyDays := int64(values.DefaultForecastHistoryDays)
+	if historyDaysPara := ctx.Query(api.QueryHistoryDaysPara); historyDaysPara != "" {
+		days, err := strconv.ParseInt(historyDaysPara, 10, 64)
+		if err != nil || days < 1 || days > 365 {
+			utils.ForwardStatusError(ctx, http.StatusBadRequest,
+				api.QueryParaErrorStatus, api.QueryParaErrorReason, "historyDays must be between 1 and 365")
+			return
+		}
+		historyDays = days
+	}
+
+	clusterScope := true
+	if access := auth.AccessFromContext(ctx); access != nil {
+		clusterScope = access.ClusterScopeAllowed(clusterId)
+	}
+	namespaceRe := auth.NamespaceRegexFromContext(ctx, clusterId)
+	costForecast, err := implementation.QueryClusterCostForecast(h.QueryContext(ctx), backend, clusterId, namespaceRe, clusterScope, historyDays)
+	if err != nil {
+		utils.ForwardQueryError(ctx, err)
+		return
+	}
+	costForecast.Namespaces = auth.FilterNamespaces(ctx, clusterId, costForecast.Namespaces, func(item *api.CostForecast) string {
+		return item.Namespace
+	})
+
+	ctx.JSON(http.StatusOK, costForecast)
+}
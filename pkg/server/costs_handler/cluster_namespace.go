@@ -24,6 +24,8 @@ import (
 	"k8s.io/klog/v2"
 
 	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/auth"
+	"github.com/kubefin/kubefin/pkg/export"
 	"github.com/kubefin/kubefin/pkg/server/implementation"
 	"github.com/kubefin/kubefin/pkg/utils"
 	"github.com/kubefin/kubefin/pkg/values"
@@ -34,17 +36,26 @@ import (
 //	@Summary		Get specific cluster namespace costs
 //	@Description	Get specific cluster namespace costs with time range
 //	@Tags			Costs
-//	@Produce		json
+//	@Produce		json,text/csv,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
 //	@Param			cluster_id	path		string	true	"Cluster Id"
 //	@Param			startTime	query		uint64	false	"The start time to query"
 //	@Param			endTime		query		uint64	false	"The end time to query"
 //	@Param			stepSeconds	query		uint64	false	"The step seconds of the data to return"
+//	@Param			namespace	query		string	false	"Only return this namespace"
+//	@Param			nameRegex	query		string	false	"Only return the namespaces whose names fully match this regex"
+//	@Param			sortBy		query		string	false	"Sort the namespaces by name/totalCost/cpuCoreRequest/ramGiBRequest/efficiency/wastedCost, by totalCost if topN is set"
+//	@Param			sortOrder	query		string	false	"asc or desc, values are sorted from the highest by default"
+//	@Param			topN		query		uint64	false	"Only return the first N namespaces after sorting"
+//	@Param			limit		query		uint64	false	"The max number of namespaces in a page, all are returned if it's not set"
+//	@Param			continue	query		string	false	"The continue token returned with the previous page"
+//	@Param			format		query		string	false	"The response format, json/csv/xlsx, the Accept header is used if it's not set"
 //	@Success		200			{object}	api.ClusterNamespaceCostList
+//	@Failure		400			{object}	api.StatusError
 //	@Failure		500			{object}	api.StatusError
 //	@Router			/costs/clusters/{cluster_id}/namespace [get]
-func ClusterNamespacesCostsHandler(ctx *gin.Context) {
+func (h *Handler) ClusterNamespacesCostsHandler(ctx *gin.Context) {
 	klog.V(4).Infof("Start query cluster namesapce cost")
-	tenantId := utils.ParserTenantIdFromCtx(ctx)
+	backend := h.QueryBackend(ctx)
 	clusterId := utils.ParseClusterFromCtx(ctx)
 	if clusterId == "" {
 		utils.ForwardStatusError(ctx, http.StatusBadRequest,
@@ -52,16 +63,43 @@ func ClusterNamespacesCostsHandler(ctx *gin.Context) {
 		return
 	}
 
+	format, err := export.FormatFromCtx(ctx)
+	if err != nil {
+		utils.ForwardStatusError(ctx, http.StatusBadRequest,
+			api.QueryParaErrorStatus, api.QueryParaErrorReason, err.Error())
+		return
+	}
 	startTime, endTime, stepSeconds, err := implementation.GetStartEndStepsTimeFromCtx(ctx, values.DefaultStepSeconds)
 	if err != nil {
 		utils.ForwardStatusError(ctx, http.StatusBadRequest,
 			api.QueryParaErrorStatus, api.QueryParaErrorReason, err.Error())
 		return
 	}
-	nsCost, err := implementation.QueryNamespaceCostsWithTimeRange(tenantId, clusterId, startTime, endTime, stepSeconds)
+	filter, err := implementation.GetCostListFilterFromCtx(ctx)
 	if err != nil {
-		utils.ForwardStatusError(ctx, http.StatusInternalServerError,
-			api.QueryFailedStatus, api.QueryFailedReason, err.Error())
+		utils.ForwardStatusError(ctx, http.StatusBadRequest,
+			api.QueryParaErrorStatus, api.QueryParaErrorReason, err.Error())
+		return
+	}
+	page, err := implementation.GetCostListPageFromCtx(ctx)
+	if err != nil {
+		utils.ForwardStatusError(ctx, http.StatusBadRequest,
+			api.QueryParaErrorStatus, api.QueryParaErrorReason, err.Error())
+		return
+	}
+
+	filter.NamespaceRe = auth.NamespaceRegexFromContext(ctx, clusterId)
+	nsCost, err := implementation.QueryNamespaceCostsWithTimeRange(h.QueryContext(ctx), backend, clusterId, filter, startTime, endTime, stepSeconds)
+	if err != nil {
+		utils.ForwardQueryError(ctx, err)
+		return
+	}
+	nsCost.Items = auth.FilterNamespaces(ctx, clusterId, nsCost.Items, func(item *api.ClusterNamespaceCost) string {
+		return item.Namespace
+	})
+	implementation.PageNamespaceCosts(nsCost, page)
+	if format != export.FormatJSON {
+		export.Forward(ctx, format, "namespace-costs-"+clusterId, export.NamespaceCostsTable(nsCost))
 		return
 	}
 	bodyBytes, err := json.Marshal(nsCost)
@@ -0,0 +1,98 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package costs_handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/klog/v2"
+
+	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/export"
+	"github.com/kubefin/kubefin/pkg/server/implementation"
+	"github.com/kubefin/kubefin/pkg/utils"
+	"github.com/kubefin/kubefin/pkg/values"
+)
+
+// ClusterNodesCostsHandler  godoc
+//
+//	@Summary		Get specific cluster nodes costs
+//	@Description	Get the cost, price, request/usage ratio and idle cost of every node in specific cluster with time range
+//	@Tags			Costs
+//	@Produce		json,text/csv,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+//	@Param			cluster_id		path		string	true	"Cluster Id"
+//	@Param			startTime		query		uint64	false	"The start time to query"
+//	@Param			endTime			query		uint64	false	"The end time to query"
+//	@Param			stepSeconds		query		uint64	false	"The step seconds of the data to return"
+//	@Param			instanceType	query		string	false	"Only return the nodes of this instance type"
+//	@Param			billingMode		query		string	false	"Only return the nodes of this billing mode"
+//	@Param			sortBy			query		string	false	"Sort the nodes by name/totalCost/idleCost/hourlyPrice"
+//	@Param			sortOrder		query		string	false	"asc or desc, costs are sorted from the highest by default"
+//	@Param			format			query		string	false	"The response format, json/csv/xlsx, the Accept header is used if it's not set"
+//	@Success		200				{object}	api.ClusterNodeCostList
+//	@Failure		500				{object}	api.StatusError
+//	@Router			/costs/clusters/{cluster_id}/nodes [get]
+func (h *Handler) ClusterNodesCostsHandler(ctx *gin.Context) {
+	klog.V(6).Info("Start to query cluster nodes cost")
+	backend := h.QueryBackend(ctx)
+	clusterId := utils.ParseClusterFromCtx(ctx)
+	if clusterId == "" {
+		utils.ForwardStatusError(ctx, http.StatusBadRequest,
+			api.QueryParaErrorStatus, api.QueryParaErrorReason, "")
+		return
+	}
+	format, err := export.FormatFromCtx(ctx)
+	if err != nil {
+		utils.ForwardStatusError(ctx, http.StatusBadRequest,
+			api.QueryParaErrorStatus, api.QueryParaErrorReason, err.Error())
+		return
+	}
+	startTime, endTime, stepSeconds, err := implementation.GetStartEndStepsTimeFromCtx(ctx, values.DefaultStepSeconds)
+	if err != nil {
+		utils.ForwardStatusError(ctx, http.StatusBadRequest,
+			api.QueryParaErrorStatus, api.QueryParaErrorReason, err.Error())
+		return
+	}
+
+	instanceType := ctx.Query(api.QueryInstanceTypePara)
+	billingMode := ctx.Query(api.QueryBillingModePara)
+	nodeCosts, err := implementation.QueryNodeCostsWithTimeRange(h.QueryContext(ctx), backend, clusterId, instanceType, billingMode,
+		startTime, endTime, stepSeconds)
+	if err != nil {
+		utils.ForwardQueryError(ctx, err)
+		return
+	}
+	err = implementation.SortNodeCosts(nodeCosts, ctx.Query(api.QuerySortByPara), ctx.Query(api.QuerySortOrderPara))
+	if err != nil {
+		utils.ForwardStatusError(ctx, http.StatusBadRequest,
+			api.QueryParaErrorStatus, api.QueryParaErrorReason, err.Error())
+		return
+	}
+	if format != export.FormatJSON {
+		export.Forward(ctx, format, "node-costs-"+clusterId, export.NodeCostsTable(nodeCosts))
+		return
+	}
+	bodyBytes, err := json.Marshal(nodeCosts)
+	if err != nil {
+		utils.ForwardStatusError(ctx, http.StatusInternalServerError,
+			api.QueryFailedStatus, api.QueryFailedReason, err.Error())
+		return
+	}
+	ctx.Data(http.StatusOK, "application/json", bodyBytes)
+}
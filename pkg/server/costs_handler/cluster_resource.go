@@ -24,6 +24,7 @@ import (
 	"k8s.io/klog/v2"
 
 	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/export"
 	"github.com/kubefin/kubefin/pkg/server/implementation"
 	"github.com/kubefin/kubefin/pkg/utils"
 	"github.com/kubefin/kubefin/pkg/values"
@@ -34,23 +35,30 @@ import (
 //	@Summary		Get detailed information on cluster resource costs, including CPU, memory, and different billing modes.
 //	@Description	Get detailed information on cluster resource costs, including CPU, memory, and different billing modes.
 //	@Tags			Costs
-//	@Produce		json
+//	@Produce		json,text/csv,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
 //	@Param			cluster_id	path		string	true	"Cluster Id"
 //	@Param			startTime	query		uint64	false	"The start time to query"
 //	@Param			endTime		query		uint64	false	"The end time to query"
 //	@Param			stepSeconds	query		uint64	false	"The step seconds of the data to return"
+//	@Param			format		query		string	false	"The response format, json/csv/xlsx, the Accept header is used if it's not set"
 //	@Success		200			{object}	api.ClusterResourceCostList
 //	@Failure		500			{object}	api.StatusError
 //	@Router			/costs/clusters/{cluster_id}/resource [get]
-func ClusterResourceCostsHandler(ctx *gin.Context) {
+func (h *Handler) ClusterResourceCostsHandler(ctx *gin.Context) {
 	klog.V(6).Info("Start query cluster resource cost")
-	tenantId := utils.ParserTenantIdFromCtx(ctx)
+	backend := h.QueryBackend(ctx)
 	clusterId := utils.ParseClusterFromCtx(ctx)
 	if clusterId == "" {
 		utils.ForwardStatusError(ctx, http.StatusBadRequest,
 			api.QueryParaErrorStatus, api.QueryParaErrorReason, "")
 		return
 	}
+	format, err := export.FormatFromCtx(ctx)
+	if err != nil {
+		utils.ForwardStatusError(ctx, http.StatusBadRequest,
+			api.QueryParaErrorStatus, api.QueryParaErrorReason, err.Error())
+		return
+	}
 	startTime, endTime, stepSeconds, err := implementation.GetStartEndStepsTimeFromCtx(ctx, values.DefaultStepSeconds)
 	if err != nil {
 		utils.ForwardStatusError(ctx, http.StatusBadRequest,
@@ -58,10 +66,13 @@ func ClusterResourceCostsHandler(ctx *gin.Context) {
 		return
 	}
 
-	nodeCosts, err := implementation.QueryClusterResourceCost(tenantId, clusterId, startTime, endTime, stepSeconds)
+	nodeCosts, err := implementation.QueryClusterResourceCost(h.QueryContext(ctx), backend, clusterId, startTime, endTime, stepSeconds)
 	if err != nil {
-		utils.ForwardStatusError(ctx, http.StatusInternalServerError,
-			api.QueryFailedStatus, api.QueryFailedReason, err.Error())
+		utils.ForwardQueryError(ctx, err)
+		return
+	}
+	if format != export.FormatJSON {
+		export.Forward(ctx, format, "resource-costs-"+clusterId, export.ResourceCostsTable(nodeCosts))
 		return
 	}
 	bodyBytes, err := json.Marshal(nodeCosts)
@@ -0,0 +1,144 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package costs_handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/common/model"
+
+	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/query"
+	"github.com/kubefin/kubefin/pkg/query/querytest"
+	"github.com/kubefin/kubefin/pkg/server"
+	"github.com/kubefin/kubefin/pkg/values"
+)
+
+func TestClusterResourceCostsHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	totalCostQl := fmt.Sprintf(query.QlNodesTotalHourlyCostFromClusterWithTimeRange, query.ClusterMatcher("cluster-1"), 3600)
+	totalCosts := &model.SampleStream{Values: []model.SamplePair{
+		{Timestamp: model.TimeFromUnix(3600), Value: 2},
+		{Timestamp: model.TimeFromUnix(7200), Value: 3},
+	}}
+
+	tests := []struct {
+		name         string
+		url          string
+		tenantId     string
+		err          error
+		warnings     []string
+		wantCode     int
+		wantCosts    map[int64]float64
+		wantWarnings []string
+		wantQueried  bool
+	}{
+		{
+			name:        "total cost of every step",
+			url:         "/clusters/cluster-1/resource?startTime=3600&endTime=7200&stepSeconds=3600",
+			wantCode:    http.StatusOK,
+			wantCosts:   map[int64]float64{3600: 2, 7200: 3},
+			wantQueried: true,
+		},
+		{
+			name:        "queried with the tenant of the request",
+			url:         "/clusters/cluster-1/resource?startTime=3600&endTime=7200&stepSeconds=3600",
+			tenantId:    "tenant-1",
+			wantCode:    http.StatusOK,
+			wantCosts:   map[int64]float64{3600: 2, 7200: 3},
+			wantQueried: true,
+		},
+		{
+			name:         "warnings forwarded once",
+			url:          "/clusters/cluster-1/resource?startTime=3600&endTime=7200&stepSeconds=3600",
+			warnings:     []string{"partial response"},
+			wantCode:     http.StatusOK,
+			wantCosts:    map[int64]float64{3600: 2, 7200: 3},
+			wantWarnings: []string{`299 - "partial response"`},
+			wantQueried:  true,
+		},
+		{
+			name:     "invalid step",
+			url:      "/clusters/cluster-1/resource?startTime=3600&endTime=7200&stepSeconds=hour",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:        "backend unavailable",
+			url:         "/clusters/cluster-1/resource?startTime=3600&endTime=7200&stepSeconds=3600",
+			err:         &query.Error{Type: query.ErrorTypeUnavailable, Message: "connection refused"},
+			wantCode:    http.StatusServiceUnavailable,
+			wantQueried: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := querytest.NewBackend()
+			backend.SetRange(totalCostQl, totalCosts)
+			if tt.err != nil {
+				backend.SetError(totalCostQl, tt.err)
+			}
+			backend.SetWarnings(tt.warnings...)
+			router := gin.New()
+			router.GET("/clusters/:cluster_id/resource",
+				NewHandler(server.NewServer(&server.Config{QueryBackend: backend})).ClusterResourceCostsHandler)
+
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			if tt.tenantId != "" {
+				req.Header.Set(values.MultiTenantHeader, tt.tenantId)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("code = %d, want %d, body:%s", w.Code, tt.wantCode, w.Body.String())
+			}
+			if got := w.Header().Values(values.WarningHeader); !reflect.DeepEqual(got, tt.wantWarnings) {
+				t.Errorf("warnings = %v, want %v", got, tt.wantWarnings)
+			}
+			queries := backend.Queries()
+			if queried := len(queries) > 0; queried != tt.wantQueried {
+				t.Fatalf("queried = %v, want %v", queried, tt.wantQueried)
+			}
+			for _, q := range queries {
+				if q.TenantId != tt.tenantId {
+					t.Errorf("query %s tenant = %q, want %q", q.Promql, q.TenantId, tt.tenantId)
+				}
+			}
+			if tt.wantCosts == nil {
+				return
+			}
+
+			list := &api.ClusterResourceCostList{}
+			if err := json.Unmarshal(w.Body.Bytes(), list); err != nil {
+				t.Fatalf("decode response error:%v", err)
+			}
+			got := make(map[int64]float64)
+			for _, item := range list.Items {
+				got[item.Timestamp] = item.TotalCost
+			}
+			if !reflect.DeepEqual(got, tt.wantCosts) {
+				t.Errorf("total costs = %v, want %v", got, tt.wantCosts)
+			}
+		})
+	}
+}
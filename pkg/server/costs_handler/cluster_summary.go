@@ -18,12 +18,16 @@ package costs_handler
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"k8s.io/klog/v2"
 
 	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/auth"
+	"github.com/kubefin/kubefin/pkg/export"
 	"github.com/kubefin/kubefin/pkg/server/implementation"
 	"github.com/kubefin/kubefin/pkg/utils"
 )
@@ -33,28 +37,45 @@ import (
 //	@Summary		Get all clusters costs summary
 //	@Description	Get all clusters costs summary in current two month
 //	@Tags			Costs
-//	@Produce		json
+//	@Produce		json,text/csv,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+//	@Param			format		query		string	false	"The response format, json/csv/xlsx, the Accept header is used if it's not set"
+//	@Param			forecast	query		bool	false	"Add the month end forecast, it's false by default"
 //	@Success		200	{object}	api.ClusterCostsSummaryList
 //	@Failure		500	{object}	api.StatusError
 //	@Router			/costs/summary   [get]
-func ClustersCostsSummaryHandler(ctx *gin.Context) {
+func (h *Handler) ClustersCostsSummaryHandler(ctx *gin.Context) {
 	klog.V(6).Info("Start to query clusters costs summary")
-	tenantId := utils.ParserTenantIdFromCtx(ctx)
+	backend := h.QueryBackend(ctx)
+	format, err := export.FormatFromCtx(ctx)
+	if err != nil {
+		utils.ForwardStatusError(ctx, http.StatusBadRequest,
+			api.QueryParaErrorStatus, api.QueryParaErrorReason, err.Error())
+		return
+	}
+	withForecast, err := getForecastFromCtx(ctx)
+	if err != nil {
+		utils.ForwardStatusError(ctx, http.StatusBadRequest,
+			api.QueryParaErrorStatus, api.QueryParaErrorReason, err.Error())
+		return
+	}
 	// If data not comes up in two-month period, we will ignore it
 	start, end := utils.GetCurrentTwoMonthStartEndTime()
-	allClustersProperty, err := implementation.QueryAllClustersBasicProperty(tenantId, start, end)
+	allClustersProperty, err := implementation.QueryAllClustersBasicProperty(h.QueryContext(ctx), backend, start, end)
 	if err != nil {
-		utils.ForwardStatusError(ctx, http.StatusInternalServerError,
-			api.QueryFailedStatus, api.QueryFailedReason, err.Error())
+		utils.ForwardQueryError(ctx, err)
 		return
 	}
-	allClustersSummary, err := implementation.QueryAllClustersCurrentMonthCost(tenantId)
+	allClustersSummary, err := implementation.QueryAllClustersCurrentMonthCost(h.QueryContext(ctx), backend, withForecast)
 	if err != nil {
-		utils.ForwardStatusError(ctx, http.StatusInternalServerError,
-			api.QueryFailedStatus, api.QueryFailedReason, err.Error())
+		utils.ForwardQueryError(ctx, err)
 		return
 	}
+	auth.FilterClusters(ctx, allClustersSummary)
 	summaries := implementation.ConvertToMultiClustersCostsList(allClustersSummary, allClustersProperty)
+	if format != export.FormatJSON {
+		export.Forward(ctx, format, "clusters-costs-summary", export.ClustersCostsSummaryTable(summaries))
+		return
+	}
 	bodyBytes, err := json.Marshal(summaries)
 	if err != nil {
 		utils.ForwardStatusError(ctx, http.StatusInternalServerError,
@@ -69,31 +90,49 @@ func ClustersCostsSummaryHandler(ctx *gin.Context) {
 //	@Summary		Get specific cluster costs summary
 //	@Description	Get specific cluster costs summary in current two month
 //	@Tags			Costs
-//	@Produce		json
+//	@Produce		json,text/csv,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
 //	@Param			cluster_id	path		string	true	"Cluster Id"
+//	@Param			format		query		string	false	"The response format, json/csv/xlsx, the Accept header is used if it's not set"
+//	@Param			forecast	query		bool	false	"Add the month end forecast, it's false by default"
 //	@Success		200			{object}	api.ClusterCostsSummary
 //	@Failure		500			{object}	api.StatusError
 //	@Router			/costs/clusters/{cluster_id}/summary [get]
-func ClusterCostsSummaryHandler(ctx *gin.Context) {
+func (h *Handler) ClusterCostsSummaryHandler(ctx *gin.Context) {
 	klog.V(4).Info("Start to query specific cluster costs summary")
-	tenantId := utils.ParserTenantIdFromCtx(ctx)
+	backend := h.QueryBackend(ctx)
 	clusterId := utils.ParseClusterFromCtx(ctx)
+	format, err := export.FormatFromCtx(ctx)
+	if err != nil {
+		utils.ForwardStatusError(ctx, http.StatusBadRequest,
+			api.QueryParaErrorStatus, api.QueryParaErrorReason, err.Error())
+		return
+	}
+	withForecast, err := getForecastFromCtx(ctx)
+	if err != nil {
+		utils.ForwardStatusError(ctx, http.StatusBadRequest,
+			api.QueryParaErrorStatus, api.QueryParaErrorReason, err.Error())
+		return
+	}
 	// If data not comes up in two-month period, we will ignore it
 	start, end := utils.GetCurrentTwoMonthStartEndTime()
-	clusterProperty, err := implementation.QueryClusterBasicProperty(tenantId, clusterId, start, end)
+	clusterProperty, err := implementation.QueryClusterBasicProperty(h.QueryContext(ctx), backend, clusterId, start, end)
 	if err != nil {
 		utils.ForwardStatusError(ctx, http.StatusNotFound,
 			api.QueryNotFoundStatus, api.QueryNotFoundReason, "no clusters found")
 		return
 	}
-	summary, err := implementation.QueryClusterCurrentMonthCost(tenantId, clusterId)
+	summary, err := implementation.QueryClusterCurrentMonthCost(h.QueryContext(ctx), backend, clusterId, withForecast)
 	if err != nil {
-		utils.ForwardStatusError(ctx, http.StatusInternalServerError,
-			api.QueryFailedStatus, api.QueryFailedReason, err.Error())
+		utils.ForwardQueryError(ctx, err)
 		return
 	}
 	summary.ClusterBasicProperty = *clusterProperty
 	klog.Infof("%v", summary)
+	if format != export.FormatJSON {
+		export.Forward(ctx, format, "cluster-costs-summary-"+clusterId,
+			export.ClustersCostsSummaryTable(&api.ClusterCostsSummaryList{Items: []*api.ClusterCostsSummary{summary}}))
+		return
+	}
 	bodyBytes, err := json.Marshal(summary)
 	if err != nil {
 		utils.ForwardStatusError(ctx, http.StatusInternalServerError,
@@ -102,3 +141,15 @@ func ClusterCostsSummaryHandler(ctx *gin.Context) {
 	}
 	ctx.Data(http.StatusOK, "application/json", bodyBytes)
 }
+
+func getForecastFromCtx(ctx *gin.Context) (bool, error) {
+	forecastStr := ctx.Query(api.QueryForecastPara)
+	if forecastStr == "" {
+		return false, nil
+	}
+	withForecast, err := strconv.ParseBool(forecastStr)
+	if err != nil {
+		return false, fmt.Errorf("forecast %s is invalid, should be true or false", forecastStr)
+	}
+	return withForecast, nil
+}
@@ -24,6 +24,8 @@ import (
 	"k8s.io/klog/v2"
 
 	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/auth"
+	"github.com/kubefin/kubefin/pkg/export"
 	"github.com/kubefin/kubefin/pkg/server/implementation"
 	"github.com/kubefin/kubefin/pkg/utils"
 	"github.com/kubefin/kubefin/pkg/values"
@@ -34,18 +36,28 @@ import (
 //	@Summary		Get specific cluster workloads costs
 //	@Description	Get specific cluster workloads costs with time range
 //	@Tags			Costs
-//	@Produce		json
-//	@Param			cluster_id	path		string	true	"Cluster Id"
-//	@Param			startTime	query		uint64	false	"The start time to query"
-//	@Param			endTime		query		uint64	false	"The end time to query"
-//	@Param			stepSeconds	query		uint64	false	"The step seconds of the data to return"
-//	@Param			aggregateBy	query		string	false	"The aggregated way to show workload costs"
-//	@Success		200			{object}	api.ClusterWorkloadCostList
-//	@Failure		500			{object}	api.StatusError
+//	@Produce		json,text/csv,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+//	@Param			cluster_id		path		string	true	"Cluster Id"
+//	@Param			startTime		query		uint64	false	"The start time to query"
+//	@Param			endTime			query		uint64	false	"The end time to query"
+//	@Param			stepSeconds		query		uint64	false	"The step seconds of the data to return"
+//	@Param			aggregateBy		query		string	false	"The aggregated way to show workload costs"
+//	@Param			namespace		query		string	false	"Only return the workloads of this namespace"
+//	@Param			workloadType	query		string	false	"Only return the workloads of this type, pod/deployment/statefulset/daemonset"
+//	@Param			nameRegex		query		string	false	"Only return the workloads whose names fully match this regex"
+//	@Param			sortBy			query		string	false	"Sort the workloads by name/totalCost/cpuCoreRequest/ramGiBRequest/efficiency/wastedCost, by totalCost if topN is set"
+//	@Param			sortOrder		query		string	false	"asc or desc, values are sorted from the highest by default"
+//	@Param			topN			query		uint64	false	"Only return the first N workloads after sorting"
+//	@Param			limit			query		uint64	false	"The max number of workloads in a page, all are returned if it's not set"
+//	@Param			continue		query		string	false	"The continue token returned with the previous page"
+//	@Param			format			query		string	false	"The response format, json/csv/xlsx, the Accept header is used if it's not set"
+//	@Success		200				{object}	api.ClusterWorkloadCostList
+//	@Failure		400				{object}	api.StatusError
+//	@Failure		500				{object}	api.StatusError
 //	@Router			/costs/clusters/{cluster_id}/workload [get]
-func ClusterWorkloadsCostsHandler(ctx *gin.Context) {
+func (h *Handler) ClusterWorkloadsCostsHandler(ctx *gin.Context) {
 	klog.V(6).Info("Start to query clusters workload cost")
-	tenantId := utils.ParserTenantIdFromCtx(ctx)
+	backend := h.QueryBackend(ctx)
 	clusterId := utils.ParseClusterFromCtx(ctx)
 	if clusterId == "" {
 		utils.ForwardStatusError(ctx, http.StatusBadRequest,
@@ -56,17 +68,43 @@ func ClusterWorkloadsCostsHandler(ctx *gin.Context) {
 	if aggregateBy == "" {
 		aggregateBy = api.AggregateByAll
 	}
+	format, err := export.FormatFromCtx(ctx)
+	if err != nil {
+		utils.ForwardStatusError(ctx, http.StatusBadRequest,
+			api.QueryParaErrorStatus, api.QueryParaErrorReason, err.Error())
+		return
+	}
 	startTime, endTime, stepSeconds, err := implementation.GetStartEndStepsTimeFromCtx(ctx, values.DefaultStepSeconds)
 	if err != nil {
 		utils.ForwardStatusError(ctx, http.StatusBadRequest,
 			api.QueryParaErrorStatus, api.QueryParaErrorReason, err.Error())
 		return
 	}
+	filter, err := implementation.GetCostListFilterFromCtx(ctx)
+	if err != nil {
+		utils.ForwardStatusError(ctx, http.StatusBadRequest,
+			api.QueryParaErrorStatus, api.QueryParaErrorReason, err.Error())
+		return
+	}
+	page, err := implementation.GetCostListPageFromCtx(ctx)
+	if err != nil {
+		utils.ForwardStatusError(ctx, http.StatusBadRequest,
+			api.QueryParaErrorStatus, api.QueryParaErrorReason, err.Error())
+		return
+	}
 
-	workloadCost, err := implementation.QueryWorkloadCostsWithTimeRange(tenantId, clusterId, startTime, endTime, stepSeconds, aggregateBy)
+	filter.NamespaceRe = auth.NamespaceRegexFromContext(ctx, clusterId)
+	workloadCost, err := implementation.QueryWorkloadCostsWithTimeRange(h.QueryContext(ctx), backend, clusterId, filter, startTime, endTime, stepSeconds, aggregateBy)
 	if err != nil {
-		utils.ForwardStatusError(ctx, http.StatusInternalServerError,
-			api.QueryFailedStatus, api.QueryFailedReason, err.Error())
+		utils.ForwardQueryError(ctx, err)
+		return
+	}
+	workloadCost.Items = auth.FilterNamespaces(ctx, clusterId, workloadCost.Items, func(item *api.ClusterWorkloadCost) string {
+		return item.Namespace
+	})
+	implementation.PageWorkloadCosts(workloadCost, page)
+	if format != export.FormatJSON {
+		export.Forward(ctx, format, "workload-costs-"+clusterId, export.WorkloadCostsTable(workloadCost))
 		return
 	}
 	bodyBytes, err := json.Marshal(workloadCost)
@@ -0,0 +1,130 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package costs_handler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/auth"
+	"github.com/kubefin/kubefin/pkg/server/implementation"
+	"github.com/kubefin/kubefin/pkg/utils"
+)
+
+// ClustersCostBreakdownHandler  godoc
+//
+//	@Summary		Get the costs breakdown across clusters
+//	@Description	Get the cost of every namespace, label value or resource type aggregated across all clusters or the selected ones
+//	@Tags			Costs
+//	@Produce		json
+//	@Param			startTime		query		uint64	false	"The start time to query"
+//	@Param			endTime			query		uint64	false	"The end time to query"
+//	@Param			dimension		query		string	false	"Aggregate the cost by namespace/label/resourceType, namespace by default"
+//	@Param			labelKey		query		string	false	"The pod label to aggregate the cost by, required by the label dimension"
+//	@Param			clusterIds		query		string	false	"The comma separated clusters to aggregate, all clusters by default"
+//	@Param			clusterSelector	query		string	false	"The label selector on the cluster_name/cloud_provider/region labels of the clusters"
+//	@Param			namespace		query		string	false	"Only aggregate the cost of this namespace"
+//	@Param			nameRegex		query		string	false	"Only return the items whose names fully match this regex"
+//	@Success		200				{object}	api.MultiClustersCostBreakdown
+//	@Failure		400				{object}	api.StatusError
+//	@Failure		500				{object}	api.StatusError
+//	@Router			/costs/breakdown [get]
+func (h *Handler) ClustersCostBreakdownHandler(ctx *gin.Context) {
+	klog.V(6).Info("Start to query clusters costs breakdown")
+	backend := h.QueryBackend(ctx)
+	startTime, endTime, err := implementation.GetStartEndTimeFromCtx(ctx)
+	if err == nil && startTime >= endTime {
+		err = fmt.Errorf("start time %d should be before end time %d", startTime, endTime)
+	}
+	if err != nil {
+		utils.ForwardStatusError(ctx, http.StatusBadRequest,
+			api.QueryParaErrorStatus, api.QueryParaErrorReason, err.Error())
+		return
+	}
+	filter, err := implementation.GetCostListFilterFromCtx(ctx)
+	if err != nil {
+		utils.ForwardStatusError(ctx, http.StatusBadRequest,
+			api.QueryParaErrorStatus, api.QueryParaErrorReason, err.Error())
+		return
+	}
+	selector, err := labels.Parse(ctx.Query(api.QueryClusterSelectorPara))
+	if err != nil {
+		utils.ForwardStatusError(ctx, http.StatusBadRequest,
+			api.QueryParaErrorStatus, api.QueryParaErrorReason, err.Error())
+		return
+	}
+
+	dimension := ctx.DefaultQuery(api.QueryDimensionPara, api.BreakdownDimensionNamespace)
+	labelKey := ctx.Query(api.QueryLabelKeyPara)
+	var paraErr string
+	switch {
+	case dimension != api.BreakdownDimensionNamespace && dimension != api.BreakdownDimensionLabel &&
+		dimension != api.BreakdownDimensionResourceType:
+		paraErr = fmt.Sprintf("dimension %s is not supported, should be one of namespace/label/resourceType", dimension)
+	case dimension == api.BreakdownDimensionLabel && labelKey == "":
+		paraErr = "labelKey is required by the label dimension"
+	case dimension == api.BreakdownDimensionResourceType && filter.Namespace != "":
+		paraErr = "the resource costs are not in any namespace"
+	case filter.WorkloadType != "":
+		paraErr = "workloadType is not supported by the breakdown"
+	}
+	if paraErr != "" {
+		utils.ForwardStatusError(ctx, http.StatusBadRequest,
+			api.QueryParaErrorStatus, api.QueryParaErrorReason, paraErr)
+		return
+	}
+
+	var wantedClusterIds []string
+	for _, clusterId := range strings.Split(ctx.Query(api.QueryClusterIdsPara), ",") {
+		if clusterId = strings.TrimSpace(clusterId); clusterId != "" {
+			wantedClusterIds = append(wantedClusterIds, clusterId)
+		}
+	}
+	clusterIds, err := implementation.QuerySelectedClusters(h.QueryContext(ctx), backend, wantedClusterIds, selector, startTime, endTime)
+	if err != nil {
+		utils.ForwardQueryError(ctx, err)
+		return
+	}
+	// The resource costs are cluster level data, only the namespaces granted are counted for the others
+	var namespaceAllowed func(clusterId, namespace string) bool
+	if access := auth.AccessFromContext(ctx); access != nil {
+		allowed := make([]string, 0, len(clusterIds))
+		for _, clusterId := range clusterIds {
+			if access.ClusterScopeAllowed(clusterId) ||
+				(dimension != api.BreakdownDimensionResourceType && access.ClusterAllowed(clusterId)) {
+				allowed = append(allowed, clusterId)
+			}
+		}
+		clusterIds = allowed
+		namespaceAllowed = access.NamespaceAllowed
+	}
+
+	breakdown, err := implementation.QueryMultiClustersCostBreakdown(h.QueryContext(ctx), backend, clusterIds, filter,
+		dimension, labelKey, startTime, endTime, namespaceAllowed)
+	if err != nil {
+		utils.ForwardQueryError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, breakdown)
+}
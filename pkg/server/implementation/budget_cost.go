@@ -0,0 +1,73 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package implementation
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/klog/v2"
+
+	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/query"
+	"github.com/kubefin/kubefin/pkg/values"
+)
+
+// QueryBudgetCost queries the cost covered by the budget between start and end, and the
+// seconds the cluster was active in it which is used to estimate the cost of the period
+func QueryBudgetCost(ctx context.Context, backend query.QueryBackend, budget *api.Budget, start, end int64) (cost, activeSeconds float64, err error) {
+	err = runQueries(ctx,
+		func(ctx context.Context) (err error) {
+			cost, err = queryBudgetCost(ctx, backend, budget, start, end)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			activeSeconds, err = queryClusterActiveTime(ctx, backend.WithTenantId(budget.TenantId), budget.ClusterId, start, end)
+			return err
+		},
+	)
+	if err != nil {
+		return 0, 0, err
+	}
+	return cost, activeSeconds, nil
+}
+
+func queryBudgetCost(ctx context.Context, backend query.QueryBackend, budget *api.Budget, start, end int64) (float64, error) {
+	backend = backend.WithTenantId(budget.TenantId)
+	// The cluster budget covers the nodes cost, including the resource not requested by any pod
+	promql := fmt.Sprintf(query.QlNodesTotalCostsFromClusterWithTimeRange, query.ClusterMatcher(budget.ClusterId), end-start)
+	rollupKind, matcher := query.RollupKindNode, ""
+	if budget.Namespace != "" || budget.LabelKey != "" {
+		matcher = query.LabelMatcher(values.NamespaceLabelKey, budget.Namespace) +
+			query.PodLabelMatcher(budget.LabelKey, budget.LabelValue)
+		promql = fmt.Sprintf(query.QlPodsTotalCostFromClusterWithTimeRange, query.ClusterMatcher(budget.ClusterId), matcher, end-start)
+		rollupKind = query.RollupKindLabel
+	}
+	if series, rangeSeconds, ok := queryOptionsFrom(ctx).RollupSelector.Select(ctx, backend, rollupKind, budget.ClusterId, start, end-start); ok {
+		promql = fmt.Sprintf(query.QlTotalCostFromClusterRollupWithTimeRange, series, query.ClusterMatcher(budget.ClusterId), matcher, rangeSeconds)
+	}
+
+	ret, err := backend.QueryInstantWithTime(ctx, promql, end)
+	if err != nil {
+		klog.Errorf("Query budget(%s) cost error:%v", budget.Name, err)
+		return 0, err
+	}
+	if len(ret) == 0 {
+		return 0, nil
+	}
+	return float64(ret[0].Value), nil
+}
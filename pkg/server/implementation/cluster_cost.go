@@ -17,11 +17,11 @@ limitations under the License.
 package implementation
 
 import (
+	"context"
 	"fmt"
-	"sync"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/klog/v2"
 
 	"github.com/kubefin/kubefin/pkg/api"
@@ -31,7 +31,9 @@ import (
 	"github.com/prometheus/common/model"
 )
 
-func QueryAllClustersCurrentMonthCost(tenantId string) (map[string]*api.ClusterCostsSummary, error) {
+// QueryAllClustersCurrentMonthCost queries the month costs of all clusters, the month end forecast
+// is added only if withForecast is true
+func QueryAllClustersCurrentMonthCost(ctx context.Context, backend query.QueryBackend, withForecast bool) (map[string]*api.ClusterCostsSummary, error) {
 	start, end, err := utils.GetCurrentMonthFirstLastDay()
 	if err != nil {
 		klog.Errorf("Query current time error:%v", err)
@@ -43,34 +45,27 @@ func QueryAllClustersCurrentMonthCost(tenantId string) (map[string]*api.ClusterC
 	var cpuTotalCost map[string]float64
 	var cpuTotalCount map[string]float64
 
-	var errs []error
-	var wg sync.WaitGroup
-
-	wg.Add(4)
-	go func() {
-		defer wg.Done()
-		allClustersActiveTime, err = queryAllClustersActiveTime(tenantId, start, end)
-		errs = append(errs, err)
-	}()
-	go func() {
-		defer wg.Done()
-		monthCostCurrent, err = queryAllClustersCurrentMonthCost(tenantId, start, end)
-		errs = append(errs, err)
-	}()
-	go func() {
-		defer wg.Done()
-		cpuTotalCost, err = queryAllClustersCPUTotalCost(tenantId, start, end)
-		errs = append(errs, err)
-	}()
-	go func() {
-		defer wg.Done()
-		cpuTotalCount, err = queryAllClustersCPUTotalCount(tenantId, start, end)
-		errs = append(errs, err)
-	}()
-
-	wg.Wait()
-	if errors.NewAggregate(errs) != nil {
-		return nil, errors.NewAggregate(errs)
+	err = runQueries(ctx,
+		func(ctx context.Context) (err error) {
+			allClustersActiveTime, err = queryAllClustersActiveTime(ctx, backend, start, end)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			monthCostCurrent, err = queryAllClustersCurrentMonthCost(ctx, backend, start, end)
+			return err
+		},
+		// The average core cost is optional, the summary is still returned without it
+		optionalQuery("cpu total cost", func(ctx context.Context) (err error) {
+			cpuTotalCost, err = queryAllClustersCPUTotalCost(ctx, backend, start, end)
+			return err
+		}),
+		optionalQuery("cpu total count", func(ctx context.Context) (err error) {
+			cpuTotalCount, err = queryAllClustersCPUTotalCount(ctx, backend, start, end)
+			return err
+		}),
+	)
+	if err != nil {
+		return nil, err
 	}
 
 	clusterCostSummary := make(map[string]*api.ClusterCostsSummary)
@@ -78,18 +73,32 @@ func QueryAllClustersCurrentMonthCost(tenantId string) (map[string]*api.ClusterC
 		costCurrent, cpuCost, cpuCount := monthCostCurrent[clusterId], cpuTotalCost[clusterId], cpuTotalCount[clusterId]
 		clusterCostSummary[clusterId] = &api.ClusterCostsSummary{
 			ClusterMonthCostCurrent:  costCurrent,
-			ClusterMonthEstimateCost: 730 * costCurrent / (activeTime / values.HourInSeconds),
+			ClusterMonthEstimateCost: EstimateCost(costCurrent, activeTime, values.MonthInHours),
 			ClusterAvgDailyCost:      24 * costCurrent / (activeTime / values.HourInSeconds),
-			ClusterAvgHourlyCoreCost: cpuCost / cpuCount,
+			ClusterAvgHourlyCoreCost: safeRatio(cpuCost, cpuCount),
+		}
+	}
+
+	if !withForecast {
+		return clusterCostSummary, nil
+	}
+	// The forecast is optional, the summary is still returned if it failed
+	monthForecast, err := queryAllClustersMonthForecast(ctx, backend, monthCostCurrent)
+	if err != nil {
+		klog.Warningf("Forecast clusters month cost error:%v", err)
+	}
+	for clusterId, value := range monthForecast {
+		if summary, ok := clusterCostSummary[clusterId]; ok {
+			summary.ClusterMonthForecast = value
 		}
 	}
 
 	return clusterCostSummary, nil
 }
 
-func queryAllClustersActiveTime(tenantId string, start, end int64) (map[string]float64, error) {
+func queryAllClustersActiveTime(ctx context.Context, backend query.QueryBackend, start, end int64) (map[string]float64, error) {
 	allClustersActiveTime := make(map[string]float64)
-	allClustersProperty, err := QueryAllClustersBasicProperty(tenantId, start, end)
+	allClustersProperty, err := QueryAllClustersBasicProperty(ctx, backend, start, end)
 	if err != nil {
 		return nil, err
 	}
@@ -99,12 +108,21 @@ func queryAllClustersActiveTime(tenantId string, start, end int64) (map[string]f
 	return allClustersActiveTime, nil
 }
 
-func queryAllClustersCurrentMonthCost(tenantId string, start, end int64) (map[string]float64, error) {
+// QueryAllClustersCostWithTimeRange queries the total cost of every cluster between start and now
+func QueryAllClustersCostWithTimeRange(ctx context.Context, backend query.QueryBackend, start, end int64) (map[string]float64, error) {
+	return queryAllClustersCurrentMonthCost(ctx, backend, start, end)
+}
+
+func queryAllClustersCurrentMonthCost(ctx context.Context, backend query.QueryBackend, start, end int64) (map[string]float64, error) {
 	monthCostCurrent := make(map[string]float64)
-	promql := fmt.Sprintf(query.QlNodesTotalCostsWithTimeRange, end-start)
-	allCotalCost, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryInstant(promql)
+	allCotalCost, err := querySumOverTime(ctx, backend, start, end, func(windowStart, rangeSeconds int64) string {
+		if series, rollupRange, ok := queryOptionsFrom(ctx).RollupSelector.Select(ctx, backend, query.RollupKindNode, "", windowStart, rangeSeconds); ok {
+			return fmt.Sprintf(query.QlNodesTotalCostsRollupWithTimeRange, series, rollupRange)
+		}
+		return fmt.Sprintf(query.QlNodesTotalCostsWithTimeRange, rangeSeconds)
+	})
 	if err != nil {
-		klog.Errorf("Query clusters current month cost error:%v,%s", err, promql)
+		klog.Errorf("Query clusters current month cost error:%v", err)
 		return nil, err
 	}
 
@@ -115,10 +133,11 @@ func queryAllClustersCurrentMonthCost(tenantId string, start, end int64) (map[st
 	return monthCostCurrent, nil
 }
 
-func queryAllClustersCPUTotalCost(tenantId string, start, end int64) (map[string]float64, error) {
+func queryAllClustersCPUTotalCost(ctx context.Context, backend query.QueryBackend, start, end int64) (map[string]float64, error) {
 	cpuTotalCost := make(map[string]float64)
-	promql := fmt.Sprintf(query.QlNodeCPUTotalCostWithTimeRange, end-start)
-	allCpuCost, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryInstant(promql)
+	allCpuCost, err := querySumOverTime(ctx, backend, start, end, func(_, rangeSeconds int64) string {
+		return fmt.Sprintf(query.QlNodeCPUTotalCostWithTimeRange, rangeSeconds)
+	})
 	if err != nil {
 		klog.Errorf("Query clusters current month cpu cost error:%v", err)
 		return nil, err
@@ -130,12 +149,13 @@ func queryAllClustersCPUTotalCost(tenantId string, start, end int64) (map[string
 	return cpuTotalCost, nil
 }
 
-func queryAllClustersCPUTotalCount(tenantId string, start, end int64) (map[string]float64, error) {
+func queryAllClustersCPUTotalCount(ctx context.Context, backend query.QueryBackend, start, end int64) (map[string]float64, error) {
 	cpuTotalCount := make(map[string]float64)
-	promql := fmt.Sprintf(query.QlNodeCPUTotalCountWithTimeRange, corev1.ResourceCPU, end-start)
-	allCpuCount, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryInstant(promql)
+	allCpuCount, err := querySumOverTime(ctx, backend, start, end, func(_, rangeSeconds int64) string {
+		return fmt.Sprintf(query.QlNodeCPUTotalCountWithTimeRange, corev1.ResourceCPU, rangeSeconds)
+	})
 	if err != nil {
-		klog.Errorf("Query clusters current month cpu count error:%v,%s", err, promql)
+		klog.Errorf("Query clusters current month cpu count error:%v", err)
 		return nil, err
 	}
 	for _, c := range allCpuCount {
@@ -145,7 +165,9 @@ func queryAllClustersCPUTotalCount(tenantId string, start, end int64) (map[strin
 	return cpuTotalCount, nil
 }
 
-func QueryClusterCurrentMonthCost(tenantId, clusterId string) (*api.ClusterCostsSummary, error) {
+// QueryClusterCurrentMonthCost queries the month cost of the cluster, the month end forecast
+// is added only if withForecast is true
+func QueryClusterCurrentMonthCost(ctx context.Context, backend query.QueryBackend, clusterId string, withForecast bool) (*api.ClusterCostsSummary, error) {
 	start, end, err := utils.GetCurrentMonthFirstLastDay()
 	if err != nil {
 		klog.Errorf("Query current time error:%v", err)
@@ -157,47 +179,54 @@ func QueryClusterCurrentMonthCost(tenantId, clusterId string) (*api.ClusterCosts
 	var cpuTotalCost float64
 	var cpuTotalCount float64
 
-	var errs []error
-	var wg sync.WaitGroup
-
-	wg.Add(4)
-	go func() {
-		defer wg.Done()
-		clusterActiveTime, err = queryClusterActiveTime(tenantId, clusterId, start, end)
-		errs = append(errs, err)
-	}()
-	go func() {
-		defer wg.Done()
-		monthCostCurrent, err = queryClusterCurrentMonthCost(tenantId, clusterId, start, end)
-		errs = append(errs, err)
-	}()
-	go func() {
-		defer wg.Done()
-		cpuTotalCost, err = queryClusterCPUTotalCost(tenantId, clusterId, start, end)
-		errs = append(errs, err)
-	}()
-	go func() {
-		defer wg.Done()
-		cpuTotalCount, err = queryClusterCPUTotalCount(tenantId, clusterId, start, end)
-		errs = append(errs, err)
-	}()
-
-	wg.Wait()
-	if errors.NewAggregate(errs) != nil {
-		return nil, errors.NewAggregate(errs)
-	}
-
-	return &api.ClusterCostsSummary{
+	err = runQueries(ctx,
+		func(ctx context.Context) (err error) {
+			clusterActiveTime, err = queryClusterActiveTime(ctx, backend, clusterId, start, end)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			monthCostCurrent, err = queryClusterCurrentMonthCost(ctx, backend, clusterId, start, end)
+			return err
+		},
+		// The average core cost is optional, the summary is still returned without it
+		optionalQuery("cpu total cost", func(ctx context.Context) (err error) {
+			cpuTotalCost, err = queryClusterCPUTotalCost(ctx, backend, clusterId, start, end)
+			return err
+		}),
+		optionalQuery("cpu total count", func(ctx context.Context) (err error) {
+			cpuTotalCount, err = queryClusterCPUTotalCount(ctx, backend, clusterId, start, end)
+			return err
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &api.ClusterCostsSummary{
 		ClusterMonthCostCurrent:  monthCostCurrent,
-		ClusterMonthEstimateCost: 730 * monthCostCurrent / (clusterActiveTime / values.HourInSeconds),
+		ClusterMonthEstimateCost: EstimateCost(monthCostCurrent, clusterActiveTime, values.MonthInHours),
 		ClusterAvgDailyCost:      24 * monthCostCurrent / (clusterActiveTime / values.HourInSeconds),
-		ClusterAvgHourlyCoreCost: cpuTotalCost / cpuTotalCount,
-	}, nil
+		ClusterAvgHourlyCoreCost: safeRatio(cpuTotalCost, cpuTotalCount),
+	}
+	if !withForecast {
+		return summary, nil
+	}
+	// The forecast is optional, the summary is still returned if it failed
+	summary.ClusterMonthForecast, err = queryClusterMonthForecast(ctx, backend, clusterId, monthCostCurrent)
+	if err != nil {
+		klog.Warningf("Forecast cluster(%s) month cost error:%v", clusterId, err)
+	}
+	return summary, nil
+}
+
+// EstimateCost projects the cost spent in the active seconds to the whole period
+func EstimateCost(cost, activeSeconds, periodHours float64) float64 {
+	return periodHours * cost / (activeSeconds / values.HourInSeconds)
 }
 
-func queryClusterActiveTime(tenantId, clusterId string, start, end int64) (float64, error) {
+func queryClusterActiveTime(ctx context.Context, backend query.QueryBackend, clusterId string, start, end int64) (float64, error) {
 	var clusterActiveTime float64
-	clusterProperty, err := QueryClusterBasicProperty(tenantId, clusterId, start, end)
+	clusterProperty, err := QueryClusterBasicProperty(ctx, backend, clusterId, start, end)
 	if err != nil {
 		return 0, err
 	}
@@ -205,10 +234,14 @@ func queryClusterActiveTime(tenantId, clusterId string, start, end int64) (float
 	return clusterActiveTime, nil
 }
 
-func queryClusterCurrentMonthCost(tenantId, clusterId string, start, end int64) (float64, error) {
+func queryClusterCurrentMonthCost(ctx context.Context, backend query.QueryBackend, clusterId string, start, end int64) (float64, error) {
 	monthCostCurrent := float64(0)
-	promql := fmt.Sprintf(query.QlNodesTotalCostsFromClusterWithTimeRange, clusterId, end-start)
-	totalCost, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryInstant(promql)
+	totalCost, err := querySumOverTime(ctx, backend, start, end, func(windowStart, rangeSeconds int64) string {
+		if series, rollupRange, ok := queryOptionsFrom(ctx).RollupSelector.Select(ctx, backend, query.RollupKindNode, clusterId, windowStart, rangeSeconds); ok {
+			return fmt.Sprintf(query.QlTotalCostFromClusterRollupWithTimeRange, series, query.ClusterMatcher(clusterId), "", rollupRange)
+		}
+		return fmt.Sprintf(query.QlNodesTotalCostsFromClusterWithTimeRange, query.ClusterMatcher(clusterId), rangeSeconds)
+	})
 	if err != nil {
 		klog.Errorf("Query cluster(%s) current month cost error:%v", clusterId, err)
 		return 0, err
@@ -220,10 +253,11 @@ func queryClusterCurrentMonthCost(tenantId, clusterId string, start, end int64)
 	return monthCostCurrent, nil
 }
 
-func queryClusterCPUTotalCost(tenantId, clusterId string, start, end int64) (float64, error) {
+func queryClusterCPUTotalCost(ctx context.Context, backend query.QueryBackend, clusterId string, start, end int64) (float64, error) {
 	cpuTotalCost := float64(0)
-	promql := fmt.Sprintf(query.QlNodeCPUTotalCostFromClusterWithTimeRange, clusterId, end-start)
-	cpuCost, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryInstant(promql)
+	cpuCost, err := querySumOverTime(ctx, backend, start, end, func(_, rangeSeconds int64) string {
+		return fmt.Sprintf(query.QlNodeCPUTotalCostFromClusterWithTimeRange, query.ClusterMatcher(clusterId), rangeSeconds)
+	})
 	if err != nil {
 		klog.Errorf("Query cluster(%s) current month cpu cost error:%v", clusterId, err)
 		return 0, err
@@ -235,10 +269,11 @@ func queryClusterCPUTotalCost(tenantId, clusterId string, start, end int64) (flo
 	return cpuTotalCost, nil
 }
 
-func queryClusterCPUTotalCount(tenantId, clusterId string, start, end int64) (float64, error) {
+func queryClusterCPUTotalCount(ctx context.Context, backend query.QueryBackend, clusterId string, start, end int64) (float64, error) {
 	cpuTotalCount := float64(0)
-	promql := fmt.Sprintf(query.QlNodeResourceTotalCountFromClusterWithTimeRange, clusterId, corev1.ResourceCPU, end-start)
-	cpuCount, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryInstant(promql)
+	cpuCount, err := querySumOverTime(ctx, backend, start, end, func(_, rangeSeconds int64) string {
+		return fmt.Sprintf(query.QlNodeResourceTotalCountFromClusterWithTimeRange, query.ClusterMatcher(clusterId), corev1.ResourceCPU, rangeSeconds)
+	})
 	if err != nil {
 		klog.Errorf("Query cluster(%s) current month cpu count error:%v", clusterId, err)
 		return 0, err
@@ -249,3 +284,52 @@ func queryClusterCPUTotalCount(tenantId, clusterId string, start, end int64) (fl
 	cpuTotalCount = float64(cpuCount[0].Value)
 	return cpuTotalCount, nil
 }
+
+// querySumOverTime sums the promql over the time between start and end, the end in the future is
+// cut to now. The completed days are summed by a daily range query so they are cached once they
+// could not change, and only the live tail of the current day is queried every time.
+// promqlFunc formats the promql summing over rangeSeconds up to the evaluation time, from windowStart.
+// The promql must be additive over the adjacent ranges, such as the sum of sum_over_time.
+func querySumOverTime(ctx context.Context, backend query.QueryBackend, start, end int64,
+	promqlFunc func(windowStart, rangeSeconds int64) string) ([]*model.Sample, error) {
+	if now := time.Now().Unix(); end > now {
+		end = now
+	}
+	days := (end - start) / daySeconds
+	tailStart := start + days*daySeconds
+
+	sums := make(map[model.Fingerprint]*model.Sample)
+	var ret []*model.Sample
+	add := func(metric model.Metric, value model.SampleValue) {
+		fingerprint := metric.Fingerprint()
+		sample, ok := sums[fingerprint]
+		if !ok {
+			sample = &model.Sample{Metric: metric, Timestamp: model.TimeFromUnix(end)}
+			sums[fingerprint] = sample
+			ret = append(ret, sample)
+		}
+		sample.Value += value
+	}
+
+	if days > 0 {
+		streams, err := backend.QueryRangeWithStep(ctx, promqlFunc(start, daySeconds), start+daySeconds, tailStart, daySeconds)
+		if err != nil {
+			return nil, err
+		}
+		for _, stream := range streams {
+			for _, v := range stream.Values {
+				add(stream.Metric, v.Value)
+			}
+		}
+	}
+	if end > tailStart {
+		samples, err := backend.QueryInstantWithTime(ctx, promqlFunc(tailStart, end-tailStart), end)
+		if err != nil {
+			return nil, err
+		}
+		for _, sample := range samples {
+			add(sample.Metric, sample.Value)
+		}
+	}
+	return ret, nil
+}
@@ -17,12 +17,11 @@ limitations under the License.
 package implementation
 
 import (
+	"context"
 	"fmt"
-	"sync"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/klog/v2"
 
 	"github.com/kubefin/kubefin/pkg/api"
@@ -31,7 +30,7 @@ import (
 	"github.com/prometheus/common/model"
 )
 
-func QueryClusterMetricsSummaryWithTimeRange(tenantId, clusterId string,
+func QueryClusterMetricsSummaryWithTimeRange(ctx context.Context, backend query.QueryBackend, clusterId string,
 	resourceType v1.ResourceName, start, end, stepSeconds int64) (*api.ClusterResourceMetrics, error) {
 	var usage []model.SamplePair
 	var total []model.SamplePair
@@ -39,40 +38,30 @@ func QueryClusterMetricsSummaryWithTimeRange(tenantId, clusterId string,
 	var request []model.SamplePair
 	var systemTaken []model.SamplePair
 
-	var wg sync.WaitGroup
-	var err error
-	var errs []error
-
-	wg.Add(5)
-	go func() {
-		defer wg.Done()
-		total, err = queryClusterResourceTotalWithTimeRange(tenantId, clusterId, resourceType, start, end, stepSeconds)
-		errs = append(errs, err)
-	}()
-	go func() {
-		defer wg.Done()
-		available, err = queryClusterResourceAvailableWithTimeRange(tenantId, clusterId, resourceType, start, end, stepSeconds)
-		errs = append(errs, err)
-	}()
-	go func() {
-		defer wg.Done()
-		systemTaken, err = queryClusterResourceSystemTakenWithTimeRange(tenantId, clusterId, resourceType, start, end, stepSeconds)
-		errs = append(errs, err)
-	}()
-	go func() {
-		defer wg.Done()
-		request, err = queryClusterResourceRequestWithTimeRange(tenantId, clusterId, resourceType, start, end, stepSeconds)
-		errs = append(errs, err)
-	}()
-	go func() {
-		defer wg.Done()
-		usage, err = queryClusterResourceUsageWithTimeRange(tenantId, clusterId, resourceType, start, end, stepSeconds)
-		errs = append(errs, err)
-	}()
-
-	wg.Wait()
-	if errors.NewAggregate(errs) != nil {
-		return nil, errors.NewAggregate(errs)
+	err := runQueries(ctx,
+		func(ctx context.Context) (err error) {
+			total, err = queryClusterResourceTotalWithTimeRange(ctx, backend, clusterId, resourceType, start, end, stepSeconds)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			available, err = queryClusterResourceAvailableWithTimeRange(ctx, backend, clusterId, resourceType, start, end, stepSeconds)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			systemTaken, err = queryClusterResourceSystemTakenWithTimeRange(ctx, backend, clusterId, resourceType, start, end, stepSeconds)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			request, err = queryClusterResourceRequestWithTimeRange(ctx, backend, clusterId, resourceType, start, end, stepSeconds)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			usage, err = queryClusterResourceUsageWithTimeRange(ctx, backend, clusterId, resourceType, start, end, stepSeconds)
+			return err
+		},
+	)
+	if err != nil {
+		return nil, err
 	}
 
 	unit := "core"
@@ -91,11 +80,11 @@ func QueryClusterMetricsSummaryWithTimeRange(tenantId, clusterId string,
 	}, nil
 }
 
-func queryClusterResourceTotalWithTimeRange(tenantId, clusterId string,
+func queryClusterResourceTotalWithTimeRange(ctx context.Context, backend query.QueryBackend, clusterId string,
 	resourceType v1.ResourceName, start, end, stepSeconds int64) ([]model.SamplePair, error) {
 	var total []model.SamplePair
-	promql := fmt.Sprintf(query.QlSumNodesResourceTotalFromCluster, clusterId, resourceType)
-	ret, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryRangeWithStep(promql, start, end, stepSeconds)
+	promql := fmt.Sprintf(query.QlSumNodesResourceTotalFromCluster, query.ClusterMatcher(clusterId), resourceType)
+	ret, err := backend.QueryRangeWithStep(ctx, promql, start, end, stepSeconds)
 	if err != nil {
 		klog.Errorf("Query cluster(%s) resource total error:%v", clusterId, err)
 		return nil, err
@@ -110,10 +99,10 @@ func queryClusterResourceTotalWithTimeRange(tenantId, clusterId string,
 	return total, nil
 }
 
-func queryClusterResourceAvailableWithTimeRange(tenantId, clusterId string, resourceType v1.ResourceName, start, end, stepSeconds int64) ([]model.SamplePair, error) {
+func queryClusterResourceAvailableWithTimeRange(ctx context.Context, backend query.QueryBackend, clusterId string, resourceType v1.ResourceName, start, end, stepSeconds int64) ([]model.SamplePair, error) {
 	var capacity []model.SamplePair
-	promql := fmt.Sprintf(query.QlSumNodesResourceAvailableFromCluster, clusterId, resourceType)
-	ret, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryRangeWithStep(promql, start, end, stepSeconds)
+	promql := fmt.Sprintf(query.QlSumNodesResourceAvailableFromCluster, query.ClusterMatcher(clusterId), resourceType)
+	ret, err := backend.QueryRangeWithStep(ctx, promql, start, end, stepSeconds)
 	if err != nil {
 		klog.Errorf("Query cluster(%s) resource available error:%v", clusterId, err)
 		return nil, err
@@ -127,10 +116,10 @@ func queryClusterResourceAvailableWithTimeRange(tenantId, clusterId string, reso
 	return capacity, nil
 }
 
-func queryClusterResourceSystemTakenWithTimeRange(tenantId, clusterId string, resourceType v1.ResourceName, start, end, stepSeconds int64) ([]model.SamplePair, error) {
+func queryClusterResourceSystemTakenWithTimeRange(ctx context.Context, backend query.QueryBackend, clusterId string, resourceType v1.ResourceName, start, end, stepSeconds int64) ([]model.SamplePair, error) {
 	var systemTaken []model.SamplePair
-	promql := fmt.Sprintf(query.QlSumNodesResourceSystemTakenFromCluster, clusterId, resourceType)
-	ret, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryRangeWithStep(promql, start, end, stepSeconds)
+	promql := fmt.Sprintf(query.QlSumNodesResourceSystemTakenFromCluster, query.ClusterMatcher(clusterId), resourceType)
+	ret, err := backend.QueryRangeWithStep(ctx, promql, start, end, stepSeconds)
 	if err != nil {
 		klog.Errorf("Query cluster(%s) resource system takne error:%v", clusterId, err)
 		return nil, err
@@ -144,10 +133,10 @@ func queryClusterResourceSystemTakenWithTimeRange(tenantId, clusterId string, re
 	return systemTaken, nil
 }
 
-func queryClusterResourceRequestWithTimeRange(tenantId, clusterId string, resourceType v1.ResourceName, start, end, stepSeconds int64) ([]model.SamplePair, error) {
+func queryClusterResourceRequestWithTimeRange(ctx context.Context, backend query.QueryBackend, clusterId string, resourceType v1.ResourceName, start, end, stepSeconds int64) ([]model.SamplePair, error) {
 	var request []model.SamplePair
-	promql := fmt.Sprintf(query.QlSumPodResourceRequestFromCluster, clusterId, resourceType)
-	ret, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryRangeWithStep(promql, start, end, stepSeconds)
+	promql := fmt.Sprintf(query.QlSumPodResourceRequestFromCluster, query.ClusterMatcher(clusterId), resourceType)
+	ret, err := backend.QueryRangeWithStep(ctx, promql, start, end, stepSeconds)
 	if err != nil {
 		klog.Errorf("Query cluster(%s) resource request error:%v", clusterId, err)
 		return nil, err
@@ -162,10 +151,10 @@ func queryClusterResourceRequestWithTimeRange(tenantId, clusterId string, resour
 	return request, nil
 }
 
-func queryClusterResourceUsageWithTimeRange(tenantId, clusterId string, resourceType v1.ResourceName, start, end, stepSeconds int64) ([]model.SamplePair, error) {
+func queryClusterResourceUsageWithTimeRange(ctx context.Context, backend query.QueryBackend, clusterId string, resourceType v1.ResourceName, start, end, stepSeconds int64) ([]model.SamplePair, error) {
 	var usage []model.SamplePair
-	promql := fmt.Sprintf(query.QlSumNodesResourceUsageFromCluster, clusterId, resourceType)
-	ret, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryRangeWithStep(promql, start, end, stepSeconds)
+	promql := fmt.Sprintf(query.QlSumNodesResourceUsageFromCluster, query.ClusterMatcher(clusterId), resourceType)
+	ret, err := backend.QueryRangeWithStep(ctx, promql, start, end, stepSeconds)
 	if err != nil {
 		klog.Errorf("Query cluster(%s) resource usage error:%v", clusterId, err)
 		return nil, err
@@ -180,12 +169,11 @@ func queryClusterResourceUsageWithTimeRange(tenantId, clusterId string, resource
 	return usage, nil
 }
 
-func QueryAllClustersBasicProperty(tenantId string, start, end int64) (map[string]*api.ClusterBasicProperty, error) {
-	var err error
+func QueryAllClustersBasicProperty(ctx context.Context, backend query.QueryBackend, start, end int64) (map[string]*api.ClusterBasicProperty, error) {
 	var allClustersActiveTime []*model.Sample
-	queryAllClustersActiveTimeFunc := func() error {
+	queryAllClustersActiveTimeFunc := func(ctx context.Context) (err error) {
 		promql := fmt.Sprintf(query.QlAllClustersActiveTime, end-start)
-		allClustersActiveTime, err = query.GetPromQueryClient().WithTenantId(tenantId).QueryInstant(promql)
+		allClustersActiveTime, err = backend.QueryInstant(ctx, promql)
 		if err != nil {
 			klog.Errorf("Query cluster activity data error:%v", err)
 			return err
@@ -195,9 +183,9 @@ func QueryAllClustersBasicProperty(tenantId string, start, end int64) (map[strin
 	}
 
 	var allClustersLastActiveInfo []*model.Sample
-	queryAllClustersLastActiveFunc := func() error {
+	queryAllClustersLastActiveFunc := func(ctx context.Context) (err error) {
 		promql := fmt.Sprintf(query.QlAllClustersActivity)
-		allClustersLastActiveInfo, err = query.GetPromQueryClient().WithTenantId(tenantId).QueryInstant(promql)
+		allClustersLastActiveInfo, err = backend.QueryInstant(ctx, promql)
 		if err != nil {
 			klog.Errorf("Query cluster last active time data error:%v", err)
 			return err
@@ -205,33 +193,18 @@ func QueryAllClustersBasicProperty(tenantId string, start, end int64) (map[strin
 		return nil
 	}
 
-	var wg sync.WaitGroup
-	var errs []error
-
-	wg.Add(2)
-	go func() {
-		defer wg.Done()
-		errs = append(errs, queryAllClustersActiveTimeFunc())
-	}()
-	go func() {
-		defer wg.Done()
-		errs = append(errs, queryAllClustersLastActiveFunc())
-	}()
-
-	wg.Wait()
-	if errors.NewAggregate(errs) != nil {
-		return nil, errors.NewAggregate(errs)
+	if err := runQueries(ctx, queryAllClustersActiveTimeFunc, queryAllClustersLastActiveFunc); err != nil {
+		return nil, err
 	}
 
 	return ParseMultiClustersBasicProperty(allClustersActiveTime, allClustersLastActiveInfo), nil
 }
 
-func QueryClusterBasicProperty(tenantId, clusterId string, start, end int64) (*api.ClusterBasicProperty, error) {
-	var err error
+func QueryClusterBasicProperty(ctx context.Context, backend query.QueryBackend, clusterId string, start, end int64) (*api.ClusterBasicProperty, error) {
 	var clusterActiveTime []*model.Sample
-	queryClusterActiveTimeFunc := func() error {
-		promql := fmt.Sprintf(query.QlClusterActiveTime, clusterId, end-start)
-		clusterActiveTime, err = query.GetPromQueryClient().WithTenantId(tenantId).QueryInstant(promql)
+	queryClusterActiveTimeFunc := func(ctx context.Context) (err error) {
+		promql := fmt.Sprintf(query.QlClusterActiveTime, query.ClusterMatcher(clusterId), end-start)
+		clusterActiveTime, err = backend.QueryInstant(ctx, promql)
 		if err != nil {
 			klog.Errorf("Query cluster activity data error:%v", err)
 			return err
@@ -246,9 +219,9 @@ func QueryClusterBasicProperty(tenantId, clusterId string, start, end int64) (*a
 	}
 
 	var clusterLastActiveInfo []*model.Sample
-	queryClusterLastActiveFunc := func() error {
-		promql := fmt.Sprintf(query.QlClusterActivity, clusterId)
-		clusterLastActiveInfo, err = query.GetPromQueryClient().WithTenantId(tenantId).QueryInstant(promql)
+	queryClusterLastActiveFunc := func(ctx context.Context) (err error) {
+		promql := fmt.Sprintf(query.QlClusterActivity, query.ClusterMatcher(clusterId))
+		clusterLastActiveInfo, err = backend.QueryInstant(ctx, promql)
 		if err != nil {
 			klog.Errorf("Query cluster activity data error:%v", err)
 			return err
@@ -262,22 +235,8 @@ func QueryClusterBasicProperty(tenantId, clusterId string, start, end int64) (*a
 		return nil
 	}
 
-	var wg sync.WaitGroup
-	var errs []error
-
-	wg.Add(2)
-	go func() {
-		defer wg.Done()
-		errs = append(errs, queryClusterActiveTimeFunc())
-	}()
-	go func() {
-		defer wg.Done()
-		errs = append(errs, queryClusterLastActiveFunc())
-	}()
-
-	wg.Wait()
-	if errors.NewAggregate(errs) != nil {
-		return nil, errors.NewAggregate(errs)
+	if err := runQueries(ctx, queryClusterActiveTimeFunc, queryClusterLastActiveFunc); err != nil {
+		return nil, err
 	}
 
 	return ParseClusterBasicProperty(clusterActiveTime[0], clusterLastActiveInfo[0]), nil
@@ -345,7 +304,7 @@ func ConvertToMultiClustersCostsList(clustersSummary map[string]*api.ClusterCost
 	return retList
 }
 
-func QueryAllClustersCurrentMetrics(tenantId string) (map[string]*api.ClusterMetricsSummary, error) {
+func QueryAllClustersCurrentMetrics(ctx context.Context, backend query.QueryBackend) (map[string]*api.ClusterMetricsSummary, error) {
 	var nodesNumber map[string]map[string]int64
 	var podsNumber map[string]map[string]int64
 	var resourceTotal map[string]map[string]float64
@@ -354,50 +313,38 @@ func QueryAllClustersCurrentMetrics(tenantId string) (map[string]*api.ClusterMet
 	var resourceAvailable map[string]map[string]float64
 	var resourceSystemTaken map[string]map[string]float64
 
-	var wg sync.WaitGroup
-	var err error
-	var errs []error
-
-	wg.Add(7)
-	go func() {
-		defer wg.Done()
-		nodesNumber, err = queryAllClustersNodesNumer(tenantId)
-		errs = append(errs, err)
-	}()
-	go func() {
-		defer wg.Done()
-		podsNumber, err = queryAllClustersPodsNumber(tenantId)
-		errs = append(errs, err)
-	}()
-	go func() {
-		defer wg.Done()
-		resourceTotal, err = queryAllClustersResourceTotal(tenantId)
-		errs = append(errs, err)
-	}()
-	go func() {
-		defer wg.Done()
-		resourceUsage, err = queryAllClustersResourceUsage(tenantId)
-		errs = append(errs, err)
-	}()
-	go func() {
-		defer wg.Done()
-		resourceRequest, err = queryAllClustersResourceRequest(tenantId)
-		errs = append(errs, err)
-	}()
-	go func() {
-		defer wg.Done()
-		resourceAvailable, err = queryAllClustersResourceAvailable(tenantId)
-		errs = append(errs, err)
-	}()
-	go func() {
-		defer wg.Done()
-		resourceSystemTaken, err = queryAllClustersResourceSystemTaken(tenantId)
-		errs = append(errs, err)
-	}()
-
-	wg.Wait()
-	if errors.NewAggregate(errs) != nil {
-		return nil, errors.NewAggregate(errs)
+	err := runQueries(ctx,
+		func(ctx context.Context) (err error) {
+			nodesNumber, err = queryAllClustersNodesNumer(ctx, backend)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			podsNumber, err = queryAllClustersPodsNumber(ctx, backend)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			resourceTotal, err = queryAllClustersResourceTotal(ctx, backend)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			resourceUsage, err = queryAllClustersResourceUsage(ctx, backend)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			resourceRequest, err = queryAllClustersResourceRequest(ctx, backend)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			resourceAvailable, err = queryAllClustersResourceAvailable(ctx, backend)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			resourceSystemTaken, err = queryAllClustersResourceSystemTaken(ctx, backend)
+			return err
+		},
+	)
+	if err != nil {
+		return nil, err
 	}
 
 	ret := make(map[string]*api.ClusterMetricsSummary)
@@ -564,10 +511,10 @@ func parseAllClustersResourceSystemTaken(data map[string]*api.ClusterMetricsSumm
 	}
 }
 
-func queryAllClustersNodesNumer(tenantId string) (map[string]map[string]int64, error) {
+func queryAllClustersNodesNumer(ctx context.Context, backend query.QueryBackend) (map[string]map[string]int64, error) {
 	// maps [cluster id][billing mode]count
 	nodesNumber := make(map[string]map[string]int64)
-	ret, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryInstant(query.QlNodesNumber)
+	ret, err := backend.QueryInstant(ctx, query.QlNodesNumber)
 	if err != nil {
 		klog.Errorf("Query all clusters nodes error:%v", err)
 		return nil, err
@@ -583,10 +530,10 @@ func queryAllClustersNodesNumer(tenantId string) (map[string]map[string]int64, e
 	return nodesNumber, nil
 }
 
-func queryAllClustersPodsNumber(tenantId string) (map[string]map[string]int64, error) {
+func queryAllClustersPodsNumber(ctx context.Context, backend query.QueryBackend) (map[string]map[string]int64, error) {
 	// maps [cluster id][schedule status]count
 	podsNumber := make(map[string]map[string]int64)
-	ret, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryInstant(query.QlPodsNumber)
+	ret, err := backend.QueryInstant(ctx, query.QlPodsNumber)
 	if err != nil {
 		klog.Errorf("Query all clusters pods error:%v", err)
 		return nil, err
@@ -601,10 +548,10 @@ func queryAllClustersPodsNumber(tenantId string) (map[string]map[string]int64, e
 	return podsNumber, nil
 }
 
-func queryAllClustersResourceTotal(tenantId string) (map[string]map[string]float64, error) {
+func queryAllClustersResourceTotal(ctx context.Context, backend query.QueryBackend) (map[string]map[string]float64, error) {
 	// maps [cluster id][cpu/memory]float64
 	resourceTotal := make(map[string]map[string]float64)
-	ret, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryInstant(query.QlResourceTotal)
+	ret, err := backend.QueryInstant(ctx, query.QlResourceTotal)
 	if err != nil {
 		klog.Errorf("Query all clusters resource total error:%v", err)
 		return nil, err
@@ -620,10 +567,10 @@ func queryAllClustersResourceTotal(tenantId string) (map[string]map[string]float
 	return resourceTotal, nil
 }
 
-func queryAllClustersResourceUsage(tenantId string) (map[string]map[string]float64, error) {
+func queryAllClustersResourceUsage(ctx context.Context, backend query.QueryBackend) (map[string]map[string]float64, error) {
 	// maps [cluster id][cpu/memory]float64
 	resourceUsage := make(map[string]map[string]float64)
-	ret, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryInstant(query.QlResourceUsage)
+	ret, err := backend.QueryInstant(ctx, query.QlResourceUsage)
 	if err != nil {
 		klog.Errorf("Query all clusters resource usage error:%v", err)
 		return nil, err
@@ -639,10 +586,10 @@ func queryAllClustersResourceUsage(tenantId string) (map[string]map[string]float
 	return resourceUsage, nil
 }
 
-func queryAllClustersResourceRequest(tenantId string) (map[string]map[string]float64, error) {
+func queryAllClustersResourceRequest(ctx context.Context, backend query.QueryBackend) (map[string]map[string]float64, error) {
 	// maps [cluster id][cpu/memory]float64
 	resourceRequest := make(map[string]map[string]float64)
-	ret, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryInstant(query.QlResourceRequest)
+	ret, err := backend.QueryInstant(ctx, query.QlResourceRequest)
 	if err != nil {
 		klog.Errorf("Query all clusters resource request error:%v", err)
 		return nil, err
@@ -658,9 +605,9 @@ func queryAllClustersResourceRequest(tenantId string) (map[string]map[string]flo
 	return resourceRequest, nil
 }
 
-func queryAllClustersResourceAvailable(tenantId string) (map[string]map[string]float64, error) {
+func queryAllClustersResourceAvailable(ctx context.Context, backend query.QueryBackend) (map[string]map[string]float64, error) {
 	resourceAvailable := make(map[string]map[string]float64)
-	ret, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryInstant(query.QlResourceAvailable)
+	ret, err := backend.QueryInstant(ctx, query.QlResourceAvailable)
 	if err != nil {
 		klog.Errorf("Query all clusters resource available error:%v", err)
 		return nil, err
@@ -676,9 +623,9 @@ func queryAllClustersResourceAvailable(tenantId string) (map[string]map[string]f
 	return resourceAvailable, nil
 }
 
-func queryAllClustersResourceSystemTaken(tenantId string) (map[string]map[string]float64, error) {
+func queryAllClustersResourceSystemTaken(ctx context.Context, backend query.QueryBackend) (map[string]map[string]float64, error) {
 	resourceSystemTaken := make(map[string]map[string]float64)
-	ret, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryInstant(query.QlResoruceSystemTaken)
+	ret, err := backend.QueryInstant(ctx, query.QlResoruceSystemTaken)
 	if err != nil {
 		klog.Errorf("Query all clusters resource system taken error:%v", err)
 		return nil, err
@@ -707,7 +654,7 @@ func ConvertToMultiClustersMetricsList(clusterMetrics map[string]*api.ClusterMet
 	return retList
 }
 
-func QueryClusterCurrentMetrics(tenantId, clusterId string) (*api.ClusterMetricsSummary, error) {
+func QueryClusterCurrentMetrics(ctx context.Context, backend query.QueryBackend, clusterId string) (*api.ClusterMetricsSummary, error) {
 	var nodesNumber map[string]int64
 	var podsNumber map[string]int64
 	var resourceTotal map[string]float64
@@ -716,50 +663,38 @@ func QueryClusterCurrentMetrics(tenantId, clusterId string) (*api.ClusterMetrics
 	var resourceAvailable map[string]float64
 	var resourceSystemTaken map[string]float64
 
-	var wg sync.WaitGroup
-	var err error
-	var errs []error
-
-	wg.Add(7)
-	go func() {
-		defer wg.Done()
-		nodesNumber, err = queryClusterNodesNumber(tenantId, clusterId)
-		errs = append(errs, err)
-	}()
-	go func() {
-		defer wg.Done()
-		podsNumber, err = queryClusterPodsNumber(tenantId, clusterId)
-		errs = append(errs, err)
-	}()
-	go func() {
-		defer wg.Done()
-		resourceTotal, err = queryClusterResourceTotal(tenantId, clusterId)
-		errs = append(errs, err)
-	}()
-	go func() {
-		defer wg.Done()
-		resourceUsage, err = queryClusterResoruceUsage(tenantId, clusterId)
-		errs = append(errs, err)
-	}()
-	go func() {
-		defer wg.Done()
-		resourceRequest, err = queryClusterResoruceRequest(tenantId, clusterId)
-		errs = append(errs, err)
-	}()
-	go func() {
-		defer wg.Done()
-		resourceAvailable, err = queryClusterResourceAvailable(tenantId, clusterId)
-		errs = append(errs, err)
-	}()
-	go func() {
-		defer wg.Done()
-		resourceSystemTaken, err = queryClusterResourceSystemTaken(tenantId, clusterId)
-		errs = append(errs, err)
-	}()
-
-	wg.Wait()
-	if errors.NewAggregate(errs) != nil {
-		return nil, errors.NewAggregate(errs)
+	err := runQueries(ctx,
+		func(ctx context.Context) (err error) {
+			nodesNumber, err = queryClusterNodesNumber(ctx, backend, clusterId)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			podsNumber, err = queryClusterPodsNumber(ctx, backend, clusterId)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			resourceTotal, err = queryClusterResourceTotal(ctx, backend, clusterId)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			resourceUsage, err = queryClusterResoruceUsage(ctx, backend, clusterId)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			resourceRequest, err = queryClusterResoruceRequest(ctx, backend, clusterId)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			resourceAvailable, err = queryClusterResourceAvailable(ctx, backend, clusterId)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			resourceSystemTaken, err = queryClusterResourceSystemTaken(ctx, backend, clusterId)
+			return err
+		},
+	)
+	if err != nil {
+		return nil, err
 	}
 	ret := &api.ClusterMetricsSummary{}
 	parseClusterNodesNumber(ret, nodesNumber)
@@ -852,11 +787,11 @@ func parseClusterResourceSystemTaken(data *api.ClusterMetricsSummary, resourceSy
 	}
 }
 
-func queryClusterNodesNumber(tenantId, clusterId string) (map[string]int64, error) {
+func queryClusterNodesNumber(ctx context.Context, backend query.QueryBackend, clusterId string) (map[string]int64, error) {
 	// maps [billing mode]count
 	nodesNumber := make(map[string]int64)
-	promql := fmt.Sprintf(query.QlNodesNumberFromCluster, clusterId)
-	ret, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryInstant(promql)
+	promql := fmt.Sprintf(query.QlNodesNumberFromCluster, query.ClusterMatcher(clusterId))
+	ret, err := backend.QueryInstant(ctx, promql)
 	if err != nil {
 		klog.Errorf("Query cluster(%s) nodes number error:%v", clusterId, err)
 		return nil, err
@@ -868,11 +803,11 @@ func queryClusterNodesNumber(tenantId, clusterId string) (map[string]int64, erro
 	return nodesNumber, nil
 }
 
-func queryClusterPodsNumber(tenantId, clusterId string) (map[string]int64, error) {
+func queryClusterPodsNumber(ctx context.Context, backend query.QueryBackend, clusterId string) (map[string]int64, error) {
 	// maps [schedule status]count
 	podsNumber := make(map[string]int64)
-	promql := fmt.Sprintf(query.QlPodsNumberFromCluster, clusterId)
-	ret, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryInstant(promql)
+	promql := fmt.Sprintf(query.QlPodsNumberFromCluster, query.ClusterMatcher(clusterId))
+	ret, err := backend.QueryInstant(ctx, promql)
 	if err != nil {
 		klog.Errorf("Query cluster(%s) pods number error:%v", clusterId, err)
 		return nil, err
@@ -884,11 +819,11 @@ func queryClusterPodsNumber(tenantId, clusterId string) (map[string]int64, error
 	return podsNumber, nil
 }
 
-func queryClusterResourceTotal(tenantId, clusterId string) (map[string]float64, error) {
+func queryClusterResourceTotal(ctx context.Context, backend query.QueryBackend, clusterId string) (map[string]float64, error) {
 	// maps [cpu/memory]float64
 	resourceTotal := make(map[string]float64)
-	promql := fmt.Sprintf(query.QlResourceTotalFromCluster, clusterId)
-	ret, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryInstant(promql)
+	promql := fmt.Sprintf(query.QlResourceTotalFromCluster, query.ClusterMatcher(clusterId))
+	ret, err := backend.QueryInstant(ctx, promql)
 	if err != nil {
 		klog.Errorf("Query cluster(%s) resource total error:%v", clusterId, err)
 		return nil, err
@@ -900,11 +835,11 @@ func queryClusterResourceTotal(tenantId, clusterId string) (map[string]float64,
 	return resourceTotal, nil
 }
 
-func queryClusterResoruceUsage(tenantId, clusterId string) (map[string]float64, error) {
+func queryClusterResoruceUsage(ctx context.Context, backend query.QueryBackend, clusterId string) (map[string]float64, error) {
 	// maps [cpu/memory]float64
 	resourceUsage := make(map[string]float64)
-	promql := fmt.Sprintf(query.QlResourceUsageFromCluster, clusterId)
-	ret, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryInstant(promql)
+	promql := fmt.Sprintf(query.QlResourceUsageFromCluster, query.ClusterMatcher(clusterId))
+	ret, err := backend.QueryInstant(ctx, promql)
 	if err != nil {
 		klog.Errorf("Query cluster(%s) resource usage error:%v", clusterId, err)
 		return nil, err
@@ -916,11 +851,11 @@ func queryClusterResoruceUsage(tenantId, clusterId string) (map[string]float64,
 	return resourceUsage, nil
 }
 
-func queryClusterResoruceRequest(tenantId, clusterId string) (map[string]float64, error) {
+func queryClusterResoruceRequest(ctx context.Context, backend query.QueryBackend, clusterId string) (map[string]float64, error) {
 	// maps [cpu/memory]float64
 	resourceRequest := make(map[string]float64)
-	promql := fmt.Sprintf(query.QlResourceRequestFromCluster, clusterId)
-	ret, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryInstant(promql)
+	promql := fmt.Sprintf(query.QlResourceRequestFromCluster, query.ClusterMatcher(clusterId))
+	ret, err := backend.QueryInstant(ctx, promql)
 	if err != nil {
 		klog.Errorf("Query cluster(%s) resource request error:%v", clusterId, err)
 		return nil, err
@@ -932,11 +867,11 @@ func queryClusterResoruceRequest(tenantId, clusterId string) (map[string]float64
 	return resourceRequest, nil
 }
 
-func queryClusterResourceAvailable(tenantId, clusterId string) (map[string]float64, error) {
+func queryClusterResourceAvailable(ctx context.Context, backend query.QueryBackend, clusterId string) (map[string]float64, error) {
 	// maps [cpu/memory]float64
 	resourceAvailale := make(map[string]float64)
-	promql := fmt.Sprintf(query.QlResourceAvailableFromCluster, clusterId)
-	ret, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryInstant(promql)
+	promql := fmt.Sprintf(query.QlResourceAvailableFromCluster, query.ClusterMatcher(clusterId))
+	ret, err := backend.QueryInstant(ctx, promql)
 	if err != nil {
 		klog.Errorf("Query cluster(%s) resource capacity error:%v", clusterId, err)
 		return nil, err
@@ -948,11 +883,11 @@ func queryClusterResourceAvailable(tenantId, clusterId string) (map[string]float
 	return resourceAvailale, nil
 }
 
-func queryClusterResourceSystemTaken(tenantId, clusterId string) (map[string]float64, error) {
+func queryClusterResourceSystemTaken(ctx context.Context, backend query.QueryBackend, clusterId string) (map[string]float64, error) {
 	// maps [cpu/memory]float64
 	resourceSystemTaken := make(map[string]float64)
-	promql := fmt.Sprintf(query.QlResourceSystemTakenFromCluster, clusterId)
-	ret, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryInstant(promql)
+	promql := fmt.Sprintf(query.QlResourceSystemTakenFromCluster, query.ClusterMatcher(clusterId))
+	ret, err := backend.QueryInstant(ctx, promql)
 	if err != nil {
 		klog.Errorf("Query cluster(%s) resource capacity error:%v", clusterId, err)
 		return nil, err
@@ -0,0 +1,178 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package implementation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/query"
+	"github.com/kubefin/kubefin/pkg/values"
+	"github.com/prometheus/common/model"
+)
+
+// QuerySelectedClusters returns the clusters active between start and end which are in clusterIds
+// and match the selector on their cluster_name/cloud_provider/region labels, empty clusterIds
+// selects all clusters
+func QuerySelectedClusters(ctx context.Context, backend query.QueryBackend, clusterIds []string,
+	selector labels.Selector, start, end int64) ([]string, error) {
+	allClustersProperty, err := QueryAllClustersBasicProperty(ctx, backend, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[string]bool)
+	for _, clusterId := range clusterIds {
+		wanted[clusterId] = true
+	}
+	selected := []string{}
+	for clusterId, property := range allClustersProperty {
+		if len(wanted) > 0 && !wanted[clusterId] {
+			continue
+		}
+		clusterLabels := labels.Set{
+			values.ClusterNameLabelKey:   property.ClusterName,
+			values.CloudProviderLabelKey: property.CloudProvider,
+			values.RegionLabelKey:        property.ClusterRegion,
+		}
+		if selector != nil && !selector.Matches(clusterLabels) {
+			continue
+		}
+		selected = append(selected, clusterId)
+	}
+	sort.Strings(selected)
+	return selected, nil
+}
+
+// QueryMultiClustersCostBreakdown queries the cost of the clusters between start and end aggregated
+// by the dimension across the clusters, the namespaces namespaceAllowed rejects are not counted
+func QueryMultiClustersCostBreakdown(ctx context.Context, backend query.QueryBackend, clusterIds []string,
+	filter *CostListFilter, dimension, labelKey string, start, end int64,
+	namespaceAllowed func(clusterId, namespace string) bool) (*api.MultiClustersCostBreakdown, error) {
+	breakdown := &api.MultiClustersCostBreakdown{
+		Dimension:  dimension,
+		LabelKey:   labelKey,
+		StartTime:  start,
+		EndTime:    end,
+		ClusterIds: clusterIds,
+		Items:      []*api.MultiClustersCostBreakdownItem{},
+	}
+	if len(clusterIds) == 0 {
+		return breakdown, nil
+	}
+
+	costs, err := queryClustersCostByDimension(ctx, backend, clusterIds, filter, dimension, start, end)
+	if err != nil {
+		return nil, err
+	}
+	// The label values are filtered here as they are kept in the labels json
+	nameRe, err := regexp.Compile("^(?:" + filter.NameRegex + ")$")
+	if err != nil {
+		return nil, err
+	}
+
+	items := make(map[string]*api.MultiClustersCostBreakdownItem)
+	for _, sample := range costs {
+		clusterId := string(sample.Metric[model.LabelName(values.ClusterIdLabelKey)])
+		namespace := string(sample.Metric[model.LabelName(values.NamespaceLabelKey)])
+		var name string
+		switch dimension {
+		case api.BreakdownDimensionNamespace:
+			name = namespace
+		case api.BreakdownDimensionLabel:
+			podLabels := make(map[string]string)
+			podLabelsJson := string(sample.Metric[model.LabelName(values.LabelsLabelKey)])
+			if err := json.Unmarshal([]byte(podLabelsJson), &podLabels); err != nil {
+				klog.Warningf("Parse pod labels(%s) error:%v", podLabelsJson, err)
+			}
+			name = podLabels[labelKey]
+			if filter.NameRegex != "" && !nameRe.MatchString(name) {
+				continue
+			}
+		case api.BreakdownDimensionResourceType:
+			name = string(sample.Metric[model.LabelName(values.ResourceTypeLabelKey)])
+		}
+		// The resource costs are the node costs which are not in any namespace
+		if dimension != api.BreakdownDimensionResourceType && namespaceAllowed != nil &&
+			!namespaceAllowed(clusterId, namespace) {
+			continue
+		}
+
+		item, ok := items[name]
+		if !ok {
+			item = &api.MultiClustersCostBreakdownItem{Name: name, ClusterCosts: make(map[string]float64)}
+			items[name] = item
+		}
+		item.TotalCost += float64(sample.Value)
+		item.ClusterCosts[clusterId] += float64(sample.Value)
+		breakdown.TotalCost += float64(sample.Value)
+	}
+
+	for _, item := range items {
+		breakdown.Items = append(breakdown.Items, item)
+	}
+	sort.Slice(breakdown.Items, func(i, j int) bool {
+		if breakdown.Items[i].TotalCost != breakdown.Items[j].TotalCost {
+			return breakdown.Items[i].TotalCost > breakdown.Items[j].TotalCost
+		}
+		return breakdown.Items[i].Name < breakdown.Items[j].Name
+	})
+	return breakdown, nil
+}
+
+// queryClustersCostByDimension queries the cost between start and end of the clusters by the cluster id
+// and the labels of the dimension
+func queryClustersCostByDimension(ctx context.Context, backend query.QueryBackend, clusterIds []string,
+	filter *CostListFilter, dimension string, start, end int64) ([]*model.Sample, error) {
+	clustersRe := query.ClusterIdsRegex(clusterIds)
+	rangeSeconds := end - start
+
+	var promql string
+	switch dimension {
+	case api.BreakdownDimensionNamespace:
+		matcher := filter.matchers(values.NamespaceLabelKey)
+		promql = fmt.Sprintf(query.QlNSTotalCostFromClustersWithTimeRange, clustersRe, matcher, rangeSeconds)
+		if series, rollupRange, ok := queryOptionsFrom(ctx).RollupSelector.Select(ctx, backend, query.RollupKindNamespace, "", start, rangeSeconds); ok {
+			promql = fmt.Sprintf(query.QlNSTotalCostFromClustersRollupWithTimeRange, series, clustersRe, matcher, rollupRange)
+		}
+	case api.BreakdownDimensionLabel:
+		matcher := filter.namespaceMatchers()
+		promql = fmt.Sprintf(query.QlPodLabelsTotalCostFromClustersWithTimeRange, clustersRe, matcher, rangeSeconds)
+		if series, rollupRange, ok := queryOptionsFrom(ctx).RollupSelector.Select(ctx, backend, query.RollupKindLabel, "", start, rangeSeconds); ok {
+			promql = fmt.Sprintf(query.QlPodLabelsTotalCostFromClustersRollupWithTimeRange, series, clustersRe, matcher, rollupRange)
+		}
+	case api.BreakdownDimensionResourceType:
+		promql = fmt.Sprintf(query.QlNodeResourceTotalCostFromClustersWithTimeRange, clustersRe,
+			query.RegexMatcher(values.ResourceTypeLabelKey, filter.NameRegex), rangeSeconds)
+	default:
+		return nil, fmt.Errorf("dimension %s is not supported", dimension)
+	}
+
+	ret, err := backend.QueryInstantWithTime(ctx, promql, end)
+	if err != nil {
+		klog.Errorf("Query clusters cost by %s error:%v,%s", dimension, err, promql)
+		return nil, err
+	}
+	return ret, nil
+}
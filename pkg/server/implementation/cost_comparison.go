@@ -0,0 +1,214 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package implementation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+
+	"github.com/prometheus/common/model"
+	"k8s.io/klog/v2"
+
+	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/query"
+	"github.com/kubefin/kubefin/pkg/values"
+)
+
+// dimensionCost is the cost of an item of the dimension in a period
+type dimensionCost struct {
+	name      string
+	namespace string
+	cost      float64
+}
+
+// QueryCostComparison sums the cost of every item of the dimension in the base period and the period,
+// the items are compared by their names. The workloads are the deployments, statefulsets and daemonsets.
+func QueryCostComparison(ctx context.Context, backend query.QueryBackend, clusterId string, filter *CostListFilter,
+	dimension, labelKey string, baseStart, baseEnd, start, end int64) (*api.CostComparison, error) {
+	var baseCosts, costs map[string]*dimensionCost
+	err := runQueries(ctx,
+		func(ctx context.Context) (err error) {
+			baseCosts, err = queryCostByDimension(ctx, backend, clusterId, filter, dimension, labelKey, baseStart, baseEnd)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			costs, err = queryCostByDimension(ctx, backend, clusterId, filter, dimension, labelKey, start, end)
+			return err
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &api.CostComparison{
+		ClusterId:     clusterId,
+		Dimension:     dimension,
+		LabelKey:      labelKey,
+		BaseStartTime: baseStart,
+		BaseEndTime:   baseEnd,
+		StartTime:     start,
+		EndTime:       end,
+		Items:         []*api.CostComparisonItem{},
+	}
+	newItem := func(item *dimensionCost) *api.CostComparisonItem {
+		return &api.CostComparisonItem{Name: item.name, Namespace: item.namespace}
+	}
+	items := make(map[string]*api.CostComparisonItem)
+	for key, baseCost := range baseCosts {
+		items[key] = newItem(baseCost)
+		items[key].BaseCost = baseCost.cost
+		items[key].Status = api.CompareStatusRemoved
+	}
+	for key, cost := range costs {
+		item, ok := items[key]
+		if !ok {
+			item = newItem(cost)
+			item.Status = api.CompareStatusNew
+			items[key] = item
+		} else {
+			item.Status = api.CompareStatusExisting
+		}
+		item.Cost = cost.cost
+	}
+	for _, item := range items {
+		item.CostDelta = item.Cost - item.BaseCost
+		item.CostDeltaPercent = deltaPercent(item.BaseCost, item.Cost)
+		ret.BaseTotalCost += item.BaseCost
+		ret.TotalCost += item.Cost
+		ret.Items = append(ret.Items, item)
+	}
+	ret.CostDelta = ret.TotalCost - ret.BaseTotalCost
+	ret.CostDeltaPercent = deltaPercent(ret.BaseTotalCost, ret.TotalCost)
+
+	sort.Slice(ret.Items, func(i, j int) bool {
+		deltaI, deltaJ := math.Abs(ret.Items[i].CostDelta), math.Abs(ret.Items[j].CostDelta)
+		if deltaI != deltaJ {
+			return deltaI > deltaJ
+		}
+		if ret.Items[i].Namespace != ret.Items[j].Namespace {
+			return ret.Items[i].Namespace < ret.Items[j].Namespace
+		}
+		return ret.Items[i].Name < ret.Items[j].Name
+	})
+	return ret, nil
+}
+
+func deltaPercent(base, cost float64) float64 {
+	if base == 0 {
+		return 0
+	}
+	return (cost - base) / base * 100
+}
+
+// queryCostByDimension queries the cost series of the dimension with one step covering the period
+func queryCostByDimension(ctx context.Context, backend query.QueryBackend, clusterId string, filter *CostListFilter,
+	dimension, labelKey string, start, end int64) (map[string]*dimensionCost, error) {
+	stepSeconds := end - start
+	costs := make(map[string]*dimensionCost)
+	add := func(name, namespace string, series map[int64]float64) {
+		key := namespace + "/" + name
+		if _, ok := costs[key]; !ok {
+			costs[key] = &dimensionCost{name: name, namespace: namespace}
+		}
+		for _, v := range series {
+			costs[key].cost += v
+		}
+	}
+
+	switch dimension {
+	case api.CompareDimensionNamespace:
+		totalCosts, err := queryNamespaceTotalCost(ctx, backend, clusterId, filter.matchers(values.NamespaceLabelKey), end, end, stepSeconds)
+		if err != nil {
+			return nil, err
+		}
+		for namespace, series := range totalCosts {
+			add(namespace, "", series)
+		}
+	case api.CompareDimensionWorkload:
+		queryRe := "deployment|statefulset|daemonset"
+		if filter.WorkloadType != "" {
+			queryRe = filter.WorkloadType
+		}
+		totalCosts, err := queryHighLevelWorkloadTotalCost(ctx, backend, clusterId, filter.matchers(values.WorkloadNameLabelKey),
+			queryRe, end, end, stepSeconds)
+		if err != nil {
+			return nil, err
+		}
+		for key, series := range totalCosts {
+			namespace, name, workloadType := ParseWorkloadKey(key)
+			add(workloadType+"/"+name, namespace, series)
+		}
+	case api.CompareDimensionLabel:
+		totalCosts, err := queryPodLabelsTotalCost(ctx, backend, clusterId, filter.namespaceMatchers(), end, end, stepSeconds)
+		if err != nil {
+			return nil, err
+		}
+		// The label values are filtered here as they are kept in the labels json
+		nameRe, err := regexp.Compile("^(?:" + filter.NameRegex + ")$")
+		if err != nil {
+			return nil, err
+		}
+		for labels, series := range totalCosts {
+			podLabels := make(map[string]string)
+			if err := json.Unmarshal([]byte(labels), &podLabels); err != nil {
+				klog.Warningf("Parse pod labels(%s) error:%v", labels, err)
+			}
+			if value := podLabels[labelKey]; filter.NameRegex == "" || nameRe.MatchString(value) {
+				add(value, "", series)
+			}
+		}
+	case api.CompareDimensionInstanceType:
+		nodeCosts, totalCosts, err := queryNodeTotalCostWithTimeRange(ctx, backend, clusterId,
+			query.RegexMatcher(values.NodeInstanceTypeLabelKey, filter.NameRegex), end, end, stepSeconds)
+		if err != nil {
+			return nil, err
+		}
+		for node, series := range totalCosts {
+			add(nodeCosts[node].InstanceType, "", series)
+		}
+	default:
+		return nil, fmt.Errorf("dimension %s is not supported", dimension)
+	}
+	return costs, nil
+}
+
+// queryPodLabelsTotalCost queries the pod cost series by the pod labels json
+func queryPodLabelsTotalCost(ctx context.Context, backend query.QueryBackend, clusterId, matcher string,
+	start, end, stepSeconds int64) (map[string]map[int64]float64, error) {
+	totalCosts := make(map[string]map[int64]float64)
+	promql := fmt.Sprintf(query.QlPodLabelsTotalCostFromClusterWithTimeRange, query.ClusterMatcher(clusterId), matcher, stepSeconds)
+	if series, rangeSeconds, ok := queryOptionsFrom(ctx).RollupSelector.Select(ctx, backend, query.RollupKindLabel, clusterId, start-stepSeconds, stepSeconds); ok {
+		promql = fmt.Sprintf(query.QlPodLabelsTotalCostFromClusterRollupWithTimeRange, series, query.ClusterMatcher(clusterId), matcher, rangeSeconds)
+	}
+	ret, err := backend.QueryRangeWithStep(ctx, promql, start, end, stepSeconds)
+	if err != nil {
+		klog.Errorf("Query cluster(%s) pod labels cost error:%v", clusterId, err)
+		return nil, err
+	}
+	for _, pods := range ret {
+		key := string(pods.Metric[model.LabelName(values.LabelsLabelKey)])
+		totalCosts[key] = make(map[int64]float64)
+		for _, v := range pods.Values {
+			totalCosts[key][v.Timestamp.Unix()] = float64(v.Value)
+		}
+	}
+	return totalCosts, nil
+}
@@ -0,0 +1,308 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package implementation
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/query"
+	"github.com/kubefin/kubefin/pkg/values"
+)
+
+// CostListFilter selects the items of the namespace and workload cost lists, the empty fields match all
+type CostListFilter struct {
+	// NamespaceRe limits the namespaces to the ones the caller could access
+	NamespaceRe string
+	Namespace   string
+	// WorkloadType could be pod/deployment/statefulset/daemonset
+	WorkloadType string
+	// NameRegex is fully matched by the pod, workload or namespace names
+	NameRegex string
+}
+
+// matchers renders the filter as the extra label matchers, the name regex matches nameLabel
+func (f *CostListFilter) matchers(nameLabel string) string {
+	if f == nil {
+		return ""
+	}
+	return f.namespaceMatchers() + query.RegexMatcher(nameLabel, f.NameRegex)
+}
+
+// namespaceMatchers renders the namespace filters only
+func (f *CostListFilter) namespaceMatchers() string {
+	if f == nil {
+		return ""
+	}
+	return query.NamespaceMatcher(f.NamespaceRe) + query.LabelMatcher(values.NamespaceLabelKey, f.Namespace)
+}
+
+// nameMatcher returns the func matching the namespace and the name in go as the matchers do in
+// promql, the name regex matches the name
+func (f *CostListFilter) nameMatcher() (func(namespace, name string) bool, error) {
+	if f == nil {
+		return func(namespace, name string) bool { return true }, nil
+	}
+	namespaceRe, err := fullMatchRegexp(f.NamespaceRe)
+	if err != nil {
+		return nil, err
+	}
+	nameRe, err := fullMatchRegexp(f.NameRegex)
+	if err != nil {
+		return nil, err
+	}
+	return func(namespace, name string) bool {
+		return (f.Namespace == "" || namespace == f.Namespace) &&
+			(namespaceRe == nil || namespaceRe.MatchString(namespace)) &&
+			(nameRe == nil || nameRe.MatchString(name))
+	}, nil
+}
+
+// fullMatchRegexp compiles the promql regex, which is anchored at both ends, nil means all match
+func fullMatchRegexp(re string) (*regexp.Regexp, error) {
+	if re == "" {
+		return nil, nil
+	}
+	return regexp.Compile("^(?:" + re + ")$")
+}
+
+// CostListPage sorts the cost list, keeps the top N items and returns a page of them
+type CostListPage struct {
+	SortBy    string
+	SortOrder string
+	// TopN keeps the first N items after sorting, 0 keeps all
+	TopN int
+	// Limit is the max number of items in the page, 0 returns all
+	Limit int
+	// offset is decoded from the continue token returned with the previous page
+	offset int
+}
+
+// GetCostListFilterFromCtx parses the filter, NamespaceRe is left to the caller
+func GetCostListFilterFromCtx(ctx *gin.Context) (*CostListFilter, error) {
+	filter := &CostListFilter{
+		Namespace:    ctx.Query(api.QueryNamespacePara),
+		WorkloadType: ctx.Query(api.QueryWorkloadTypePara),
+		NameRegex:    ctx.Query(api.QueryNameRegexPara),
+	}
+	switch filter.WorkloadType {
+	case "", api.AggregateByPod, api.AggregateByDeployment, api.AggregateByStatefulSet, api.AggregateByDaemonSet:
+	default:
+		return nil, fmt.Errorf("workload type %s is not supported, should be one of pod/deployment/statefulset/daemonset", filter.WorkloadType)
+	}
+	// The promql regex is RE2 as the one of go, it's anchored at both ends
+	if _, err := fullMatchRegexp(filter.NameRegex); err != nil {
+		return nil, fmt.Errorf("name regex %s is invalid:%v", filter.NameRegex, err)
+	}
+	return filter, nil
+}
+
+// GetCostListPageFromCtx parses the sorting and the pagination, the items are sorted by name by default
+// and by the total cost if only the top N items are kept
+func GetCostListPageFromCtx(ctx *gin.Context) (*CostListPage, error) {
+	page := &CostListPage{
+		SortBy:    ctx.Query(api.QuerySortByPara),
+		SortOrder: ctx.Query(api.QuerySortOrderPara),
+	}
+	var err error
+	if page.TopN, err = parseNonNegativeInt(ctx.Query(api.QueryTopNPara), api.QueryTopNPara); err != nil {
+		return nil, err
+	}
+	if page.Limit, err = parseNonNegativeInt(ctx.Query(api.QueryLimitPara), api.QueryLimitPara); err != nil {
+		return nil, err
+	}
+	if token := ctx.Query(api.QueryContinuePara); token != "" {
+		offset, err := base64.RawURLEncoding.DecodeString(token)
+		if err == nil {
+			page.offset, err = strconv.Atoi(string(offset))
+		}
+		if err != nil || page.offset < 0 {
+			return nil, fmt.Errorf("continue token %s is invalid", token)
+		}
+	}
+
+	if page.SortBy == "" {
+		page.SortBy = api.SortByName
+		if page.TopN > 0 {
+			page.SortBy = api.SortByTotalCost
+		}
+	}
+	switch page.SortBy {
+	case api.SortByName, api.SortByTotalCost, api.SortByCPURequest, api.SortByRAMRequest, api.SortByEfficiency,
+		api.SortByWastedCost:
+	default:
+		return nil, fmt.Errorf("sort by %s is not supported, should be one of name/totalCost/cpuCoreRequest/ramGiBRequest/efficiency/wastedCost", page.SortBy)
+	}
+	if page.SortOrder == "" {
+		page.SortOrder = api.SortOrderDesc
+		if page.SortBy == api.SortByName {
+			page.SortOrder = api.SortOrderAsc
+		}
+	}
+	if page.SortOrder != api.SortOrderAsc && page.SortOrder != api.SortOrderDesc {
+		return nil, fmt.Errorf("sort order %s is not supported, should be asc or desc", page.SortOrder)
+	}
+	return page, nil
+}
+
+func parseNonNegativeInt(value, name string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	ret, err := strconv.Atoi(value)
+	if err != nil || ret < 0 {
+		return 0, fmt.Errorf("%s %s should be a non-negative integer", name, value)
+	}
+	return ret, nil
+}
+
+// PageWorkloadCosts sorts the workloads and keeps the requested page of them in the list
+func PageWorkloadCosts(list *api.ClusterWorkloadCostList, page *CostListPage) {
+	sortCostItems(list.Items, page, func(item *api.ClusterWorkloadCost) string {
+		return item.Namespace + "/" + item.WorkloadName + "/" + item.WorkloadType
+	}, func(item *api.ClusterWorkloadCost) *costTotals {
+		totals := &costTotals{}
+		for _, detail := range item.CostList {
+			totals.add(detail.TotalCost, detail.CPUCoreRequest, detail.CPUCoreUsage, detail.RAMGiBRequest, detail.RAMGiBUsage,
+				detail.WastedCost)
+		}
+		return totals
+	})
+	list.Items, list.TotalItems, list.Continue = paginate(list.Items, page)
+}
+
+// PageNamespaceCosts sorts the namespaces and keeps the requested page of them in the list
+func PageNamespaceCosts(list *api.ClusterNamespaceCostList, page *CostListPage) {
+	sortCostItems(list.Items, page, func(item *api.ClusterNamespaceCost) string {
+		return item.Namespace
+	}, func(item *api.ClusterNamespaceCost) *costTotals {
+		totals := &costTotals{}
+		for _, detail := range item.CostList {
+			totals.add(detail.TotalCost, detail.CPUCoreRequest, detail.CPUCoreUsage, detail.RAMGiBRequest, detail.RAMGiBUsage,
+				detail.WastedCost)
+		}
+		return totals
+	})
+	list.Items, list.TotalItems, list.Continue = paginate(list.Items, page)
+}
+
+// costTotals are the sums of a cost series the items are sorted by
+type costTotals struct {
+	totalCost  float64
+	cpuRequest float64
+	cpuUsage   float64
+	ramRequest float64
+	ramUsage   float64
+	wastedCost float64
+}
+
+func (t *costTotals) add(totalCost, cpuRequest, cpuUsage, ramRequest, ramUsage, wastedCost float64) {
+	t.totalCost += totalCost
+	t.cpuRequest += cpuRequest
+	t.cpuUsage += cpuUsage
+	t.ramRequest += ramRequest
+	t.ramUsage += ramUsage
+	t.wastedCost += wastedCost
+}
+
+// efficiency is the average of the cpu and ram usage/request ratios, the resources not requested are skipped
+func (t *costTotals) efficiency() float64 {
+	sum, count := 0.0, 0
+	if t.cpuRequest > 0 {
+		sum += t.cpuUsage / t.cpuRequest
+		count++
+	}
+	if t.ramRequest > 0 {
+		sum += t.ramUsage / t.ramRequest
+		count++
+	}
+	if count == 0 {
+		return 0
+	}
+	return sum / float64(count)
+}
+
+func (t *costTotals) value(sortBy string) float64 {
+	switch sortBy {
+	case api.SortByTotalCost:
+		return t.totalCost
+	case api.SortByCPURequest:
+		return t.cpuRequest
+	case api.SortByRAMRequest:
+		return t.ramRequest
+	case api.SortByEfficiency:
+		return t.efficiency()
+	case api.SortByWastedCost:
+		return t.wastedCost
+	}
+	return 0
+}
+
+// sortCostItems sorts the items by the sum of their cost series, the items with the same value are sorted by name
+func sortCostItems[T any](items []T, page *CostListPage, name func(T) string, totals func(T) *costTotals) {
+	type entry struct {
+		item  T
+		name  string
+		value float64
+	}
+	entries := make([]entry, len(items))
+	for i, item := range items {
+		entries[i] = entry{item: item, name: name(item)}
+		if page.SortBy != api.SortByName {
+			entries[i].value = totals(item).value(page.SortBy)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if page.SortBy == api.SortByName || entries[i].value == entries[j].value {
+			if page.SortBy == api.SortByName && page.SortOrder == api.SortOrderDesc {
+				return entries[i].name > entries[j].name
+			}
+			return entries[i].name < entries[j].name
+		}
+		if page.SortOrder == api.SortOrderAsc {
+			return entries[i].value < entries[j].value
+		}
+		return entries[i].value > entries[j].value
+	})
+	for i := range entries {
+		items[i] = entries[i].item
+	}
+}
+
+// paginate keeps the top N items and returns the page after the offset, the number of the
+// items before paginating and the continue token of the next page
+func paginate[T any](items []T, page *CostListPage) ([]T, int, string) {
+	if page.TopN > 0 && len(items) > page.TopN {
+		items = items[:page.TopN]
+	}
+	total := len(items)
+	if page.offset >= total {
+		return []T{}, total, ""
+	}
+	items = items[page.offset:]
+	if page.Limit == 0 || len(items) <= page.Limit {
+		return items, total, ""
+	}
+	next := strconv.Itoa(page.offset + page.Limit)
+	return items[:page.Limit], total, base64.RawURLEncoding.EncodeToString([]byte(next))
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package implementation
+
+import (
+	"context"
+
+	"github.com/kubefin/kubefin/pkg/query"
+)
+
+// CostSeries holds the cost of every step of the cluster, its namespaces and workloads,
+// the series are keyed by timestamp and the workloads are keyed by WorkloadKey
+type CostSeries struct {
+	Cluster    map[int64]float64
+	Namespaces map[string]map[int64]float64
+	Workloads  map[string]map[int64]float64
+}
+
+// QueryCostSeries queries the cost of every step in the cluster between start and end
+func QueryCostSeries(ctx context.Context, backend query.QueryBackend, clusterId string, start, end, stepSeconds int64) (*CostSeries, error) {
+	series := &CostSeries{}
+
+	err := runQueries(ctx,
+		func(ctx context.Context) (err error) {
+			series.Cluster, err = queryNodeTotalCost(ctx, backend, clusterId, start, end, stepSeconds)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			series.Namespaces, err = queryNamespaceTotalCost(ctx, backend, clusterId, "", start, end, stepSeconds)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			series.Workloads, err = queryHighLevelWorkloadTotalCost(ctx, backend, clusterId, "",
+				"deployment|statefulset|daemonset", start, end, stepSeconds)
+			return err
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return series, nil
+}
+
+// ParseWorkloadKey parses the key of CostSeries.Workloads
+func ParseWorkloadKey(key string) (namespace, name, workloadType string) {
+	workloadType, namespace, name = parseWorkloadTypeNamespaceName(key)
+	return namespace, name, workloadType
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package implementation
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"k8s.io/klog/v2"
+
+	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/query"
+	"github.com/kubefin/kubefin/pkg/values"
+)
+
+// unitPrices are the average cpu core and ram GiB hourly prices of a cluster in every step
+type unitPrices struct {
+	cpu map[int64]float64
+	ram map[int64]float64
+}
+
+func queryClusterUnitPrices(ctx context.Context, backend query.QueryBackend, clusterId string,
+	start, end, stepSeconds int64) (*unitPrices, error) {
+	prices := &unitPrices{}
+	err := runQueries(ctx,
+		func(ctx context.Context) (err error) {
+			prices.cpu, err = queryClusterUnitPriceSeries(ctx, backend, query.QlClusterAvgCPUCoreHourlyCostWithTimeRange,
+				clusterId, start, end, stepSeconds)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			prices.ram, err = queryClusterUnitPriceSeries(ctx, backend, query.QlClusterAvgRAMGBHourlyCostWithTimeRange,
+				clusterId, start, end, stepSeconds)
+			return err
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return prices, nil
+}
+
+func queryClusterUnitPriceSeries(ctx context.Context, backend query.QueryBackend, promqlTemplate, clusterId string,
+	start, end, stepSeconds int64) (map[int64]float64, error) {
+	prices := make(map[int64]float64)
+	promql := fmt.Sprintf(promqlTemplate, query.ClusterMatcher(clusterId), stepSeconds)
+	ret, err := backend.QueryRangeWithStep(ctx, promql, start, end, stepSeconds)
+	if err != nil {
+		klog.Errorf("Query cluster(%s) unit price error:%v", clusterId, err)
+		return nil, err
+	}
+	if len(ret) == 0 {
+		return prices, nil
+	}
+	for _, v := range ret[0].Values {
+		prices[v.Timestamp.Unix()] = float64(v.Value)
+	}
+	return prices, nil
+}
+
+// at returns the prices of the step, the prices are 0 if they're not known
+func (p *unitPrices) at(timestamp int64) (cpuPrice, ramPrice float64) {
+	if p == nil {
+		return 0, 0
+	}
+	return p.cpu[timestamp], p.ram[timestamp]
+}
+
+// resourceEfficiency is how efficiently the average resources requested in a step are used
+type resourceEfficiency struct {
+	cpu        float64
+	ram        float64
+	cost       float64
+	wastedCost float64
+}
+
+// computeEfficiency computes the usage/request ratios, the cost efficiency weights them by the unit prices.
+// The resources used beyond the requests are not counted as negative waste.
+func computeEfficiency(cpuRequest, cpuUsage, ramRequest, ramUsage, cpuPrice, ramPrice float64,
+	stepSeconds int64) resourceEfficiency {
+	hours := float64(stepSeconds) / values.HourInSeconds
+	return resourceEfficiency{
+		cpu:  safeRatio(cpuUsage, cpuRequest),
+		ram:  safeRatio(ramUsage, ramRequest),
+		cost: safeRatio(cpuUsage*cpuPrice+ramUsage*ramPrice, cpuRequest*cpuPrice+ramRequest*ramPrice),
+		wastedCost: (math.Max(cpuRequest-cpuUsage, 0)*cpuPrice +
+			math.Max(ramRequest-ramUsage, 0)*ramPrice) * hours,
+	}
+}
+
+func setWorkloadsEfficiency(items []*api.ClusterWorkloadCost, prices *unitPrices, stepSeconds int64) {
+	for _, item := range items {
+		for _, detail := range item.CostList {
+			cpuPrice, ramPrice := prices.at(detail.Timestamp)
+			efficiency := computeEfficiency(detail.CPUCoreRequest, detail.CPUCoreUsage, detail.RAMGiBRequest,
+				detail.RAMGiBUsage, cpuPrice, ramPrice, stepSeconds)
+			detail.CPUEfficiency = efficiency.cpu
+			detail.RAMEfficiency = efficiency.ram
+			detail.CostEfficiency = efficiency.cost
+			detail.WastedCost = efficiency.wastedCost
+		}
+	}
+}
+
+func setNamespacesEfficiency(items []*api.ClusterNamespaceCost, prices *unitPrices, stepSeconds int64) {
+	for _, item := range items {
+		for _, detail := range item.CostList {
+			cpuPrice, ramPrice := prices.at(detail.Timestamp)
+			efficiency := computeEfficiency(detail.CPUCoreRequest, detail.CPUCoreUsage, detail.RAMGiBRequest,
+				detail.RAMGiBUsage, cpuPrice, ramPrice, stepSeconds)
+			detail.CPUEfficiency = efficiency.cpu
+			detail.RAMEfficiency = efficiency.ram
+			detail.CostEfficiency = efficiency.cost
+			detail.WastedCost = efficiency.wastedCost
+		}
+	}
+}
+
+// QueryWorkloadsEfficiency queries the workloads selected by the filter over the whole period and lists the
+// topN wasting the most, the deployments, statefulsets and daemonsets are listed if the workload type is not set.
+// keep is the second guard on the workloads the caller could access.
+func QueryWorkloadsEfficiency(ctx context.Context, backend query.QueryBackend, clusterId string, filter *CostListFilter,
+	start, end int64, topN int, keep func(*api.ClusterWorkloadCost) bool) (*api.WorkloadEfficiencyList, error) {
+	stepSeconds := end - start
+	var workloads []*api.ClusterWorkloadCost
+	var prices *unitPrices
+	err := runQueries(ctx,
+		func(ctx context.Context) (err error) {
+			if filter.WorkloadType == api.AggregateByPod {
+				workloads, err = queryPodCostsWithTimeRange(ctx, backend, clusterId, filter.matchers(values.PodNameLabelKey),
+					end, end, stepSeconds)
+				return err
+			}
+			aggregateBy := api.AggregateByAll
+			if filter.WorkloadType != "" {
+				aggregateBy = filter.WorkloadType
+			}
+			workloads, err = queryHighLevelWorkloadCostsWithTimeRange(ctx, backend, clusterId, filter,
+				end, end, stepSeconds, aggregateBy)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			prices, err = queryClusterUnitPrices(ctx, backend, clusterId, end, end, stepSeconds)
+			return err
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	setWorkloadsEfficiency(workloads, prices, stepSeconds)
+
+	ret := &api.WorkloadEfficiencyList{
+		ClusterId: clusterId,
+		StartTime: start,
+		EndTime:   end,
+		Items:     []*api.WorkloadEfficiency{},
+	}
+	for _, workload := range workloads {
+		if len(workload.CostList) == 0 || (keep != nil && !keep(workload)) {
+			continue
+		}
+		ret.TotalWastedCost += workload.CostList[0].WastedCost
+		ret.Items = append(ret.Items, &api.WorkloadEfficiency{
+			Namespace:                 workload.Namespace,
+			WorkloadName:              workload.WorkloadName,
+			WorkloadType:              workload.WorkloadType,
+			ClusterWorkloadCostDetail: *workload.CostList[0],
+		})
+	}
+	sort.Slice(ret.Items, func(i, j int) bool {
+		a, b := ret.Items[i], ret.Items[j]
+		if a.WastedCost != b.WastedCost {
+			return a.WastedCost > b.WastedCost
+		}
+		return a.Namespace+"/"+a.WorkloadType+"/"+a.WorkloadName < b.Namespace+"/"+b.WorkloadType+"/"+b.WorkloadName
+	})
+	if topN > 0 && len(ret.Items) > topN {
+		ret.Items = ret.Items[:topN]
+	}
+	return ret, nil
+}
@@ -0,0 +1,261 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package implementation
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"k8s.io/klog/v2"
+
+	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/forecast"
+	"github.com/kubefin/kubefin/pkg/query"
+	"github.com/kubefin/kubefin/pkg/values"
+)
+
+const daySeconds = 24 * int64(values.HourInSeconds)
+
+// QueryClusterCostForecast forecasts the cost of the cluster and its namespaces matching namespaceRe,
+// the cluster forecast is skipped if clusterScope is false
+func QueryClusterCostForecast(ctx context.Context, backend query.QueryBackend, clusterId, namespaceRe string, clusterScope bool, historyDays int64) (*api.ClusterCostForecast, error) {
+	now := time.Now()
+	monthStart := monthStartOf(now)
+	end := forecast.StartOfDay(now).Unix()
+	start := end - historyDays*daySeconds
+
+	var clusterHistory []forecast.Point
+	var clusterMonthCost float64
+	var nsCosts map[string]map[int64]float64
+	var nsMonthCosts map[string]float64
+
+	queries := []func(ctx context.Context) error{
+		func(ctx context.Context) (err error) {
+			nsCosts, err = queryNamespaceTotalCost(ctx, backend, clusterId, query.NamespaceMatcher(namespaceRe), start, end, daySeconds)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			nsMonthCosts, err = queryNamespacesCostWithTimeRange(ctx, backend, clusterId, namespaceRe, monthStart.Unix(), now.Unix())
+			return err
+		},
+	}
+	if clusterScope {
+		queries = append(queries,
+			func(ctx context.Context) (err error) {
+				clusterHistory, err = queryClusterDailyHistory(ctx, backend, clusterId, start, end)
+				return err
+			},
+			func(ctx context.Context) (err error) {
+				clusterMonthCost, err = queryClusterCurrentMonthCost(ctx, backend, clusterId, monthStart.Unix(), now.Unix())
+				return err
+			})
+	}
+	if err := runQueries(ctx, queries...); err != nil {
+		return nil, err
+	}
+
+	ret := &api.ClusterCostForecast{
+		ClusterId:       clusterId,
+		HistoryDays:     historyDays,
+		ConfidenceLevel: forecast.ConfidenceLevel,
+		Namespaces:      []*api.CostForecast{},
+	}
+	if clusterScope && len(clusterHistory) > 0 {
+		m := forecast.Fit(clusterHistory, true)
+		nodes := clusterHistory[len(clusterHistory)-1].Nodes
+		ret.Cluster = newCostForecast(m, clusterMonthCost, now, nodes)
+		for day := 0; day < 30; day++ {
+			dayStart := time.Unix(end, 0).AddDate(0, 0, day)
+			value := m.Sum(dayStart, dayStart.AddDate(0, 0, 1), nodes)
+			ret.Cluster.DailyForecast = append(ret.Cluster.DailyForecast, &api.CostForecastPoint{
+				Timestamp:         dayStart.Unix(),
+				CostForecastValue: convertForecastValue(value, 0),
+			})
+		}
+	}
+	for namespace, costs := range nsCosts {
+		history := convertToDailyPoints(costs, nil)
+		if len(history) == 0 {
+			continue
+		}
+		nsForecast := newCostForecast(forecast.Fit(history, false), nsMonthCosts[namespace], now, 0)
+		nsForecast.Namespace = namespace
+		ret.Namespaces = append(ret.Namespaces, nsForecast)
+	}
+	sort.Slice(ret.Namespaces, func(i, j int) bool {
+		return ret.Namespaces[i].Namespace < ret.Namespaces[j].Namespace
+	})
+
+	return ret, nil
+}
+
+// queryClusterMonthForecast forecasts the month end cost of the cluster from the cost of current month
+func queryClusterMonthForecast(ctx context.Context, backend query.QueryBackend, clusterId string, monthCost float64) (*api.CostForecastValue, error) {
+	now := time.Now()
+	end := forecast.StartOfDay(now).Unix()
+	history, err := queryClusterDailyHistory(ctx, backend, clusterId, end-values.DefaultForecastHistoryDays*daySeconds, end)
+	if err != nil {
+		return nil, err
+	}
+	if len(history) == 0 {
+		return nil, nil
+	}
+	return &newCostForecast(forecast.Fit(history, true), monthCost, now, history[len(history)-1].Nodes).MonthEnd, nil
+}
+
+// queryAllClustersMonthForecast forecasts the month end cost of all clusters from the cost of current month
+func queryAllClustersMonthForecast(ctx context.Context, backend query.QueryBackend, monthCosts map[string]float64) (map[string]*api.CostForecastValue, error) {
+	now := time.Now()
+	end := forecast.StartOfDay(now).Unix()
+	histories, err := queryAllClustersDailyHistory(ctx, backend, end-values.DefaultForecastHistoryDays*daySeconds, end)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(map[string]*api.CostForecastValue)
+	for clusterId, history := range histories {
+		if len(history) == 0 {
+			continue
+		}
+		clusterForecast := newCostForecast(forecast.Fit(history, true), monthCosts[clusterId], now, history[len(history)-1].Nodes)
+		ret[clusterId] = &clusterForecast.MonthEnd
+	}
+	return ret, nil
+}
+
+func newCostForecast(m *forecast.Model, monthCost float64, now time.Time, nodes float64) *api.CostForecast {
+	monthEnd := monthStartOf(now).AddDate(0, 1, 0)
+	return &api.CostForecast{
+		Model:            m.Name,
+		MonthCostCurrent: monthCost,
+		MonthEnd:         convertForecastValue(m.Sum(now, monthEnd, nodes), monthCost),
+		Next30Days:       convertForecastValue(m.Sum(now, now.AddDate(0, 0, 30), nodes), 0),
+		Next90Days:       convertForecastValue(m.Sum(now, now.AddDate(0, 0, 90), nodes), 0),
+	}
+}
+
+// convertForecastValue adds the cost already spent to the forecast
+func convertForecastValue(value forecast.Value, spent float64) api.CostForecastValue {
+	return api.CostForecastValue{
+		Cost:  spent + value.Cost,
+		Lower: spent + value.Lower,
+		Upper: spent + value.Upper,
+	}
+}
+
+func monthStartOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
+
+func queryClusterDailyHistory(ctx context.Context, backend query.QueryBackend, clusterId string, start, end int64) ([]forecast.Point, error) {
+	costs, err := queryNodeTotalCost(ctx, backend, clusterId, start, end, daySeconds)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[int64]float64)
+	promql := fmt.Sprintf(query.QlNodesAvgCountFromClusterWithTimeRange, query.ClusterMatcher(clusterId), daySeconds, daySeconds)
+	ret, err := backend.QueryRangeWithStep(ctx, promql, start, end, daySeconds)
+	if err != nil {
+		klog.Errorf("Query cluster(%s) daily node count error:%v", clusterId, err)
+		return nil, err
+	}
+	if len(ret) != 0 {
+		for _, v := range ret[0].Values {
+			nodes[v.Timestamp.Unix()] = float64(v.Value)
+		}
+	}
+	return convertToDailyPoints(costs, nodes), nil
+}
+
+func queryAllClustersDailyHistory(ctx context.Context, backend query.QueryBackend, start, end int64) (map[string][]forecast.Point, error) {
+	costs := make(map[string]map[int64]float64)
+	promql := fmt.Sprintf(query.QlNodesTotalCostsWithTimeRange, daySeconds)
+	if series, rangeSeconds, ok := queryOptionsFrom(ctx).RollupSelector.Select(ctx, backend, query.RollupKindNode, "", start-daySeconds, daySeconds); ok {
+		promql = fmt.Sprintf(query.QlNodesTotalCostsRollupWithTimeRange, series, rangeSeconds)
+	}
+	ret, err := backend.QueryRangeWithStep(ctx, promql, start, end, daySeconds)
+	if err != nil {
+		klog.Errorf("Query clusters daily cost error:%v", err)
+		return nil, err
+	}
+	for _, cluster := range ret {
+		clusterId := string(cluster.Metric[model.LabelName(values.ClusterIdLabelKey)])
+		costs[clusterId] = make(map[int64]float64)
+		for _, v := range cluster.Values {
+			costs[clusterId][v.Timestamp.Unix()] = float64(v.Value)
+		}
+	}
+
+	nodes := make(map[string]map[int64]float64)
+	promql = fmt.Sprintf(query.QlNodesAvgCountWithTimeRange, daySeconds, daySeconds)
+	ret, err = backend.QueryRangeWithStep(ctx, promql, start, end, daySeconds)
+	if err != nil {
+		klog.Errorf("Query clusters daily node count error:%v", err)
+		return nil, err
+	}
+	for _, cluster := range ret {
+		clusterId := string(cluster.Metric[model.LabelName(values.ClusterIdLabelKey)])
+		nodes[clusterId] = make(map[int64]float64)
+		for _, v := range cluster.Values {
+			nodes[clusterId][v.Timestamp.Unix()] = float64(v.Value)
+		}
+	}
+
+	histories := make(map[string][]forecast.Point)
+	for clusterId, clusterCosts := range costs {
+		histories[clusterId] = convertToDailyPoints(clusterCosts, nodes[clusterId])
+	}
+	return histories, nil
+}
+
+func queryNamespacesCostWithTimeRange(ctx context.Context, backend query.QueryBackend, clusterId, namespaceRe string, start, end int64) (map[string]float64, error) {
+	totalCosts := make(map[string]float64)
+	promql := fmt.Sprintf(query.QlNSTotalCostFromClusterWithTimeRange, query.ClusterMatcher(clusterId), query.NamespaceMatcher(namespaceRe), end-start)
+	if series, rangeSeconds, ok := queryOptionsFrom(ctx).RollupSelector.Select(ctx, backend, query.RollupKindNamespace, clusterId, start, end-start); ok {
+		promql = fmt.Sprintf(query.QlNSTotalCostFromClusterRollupWithTimeRange, series, query.ClusterMatcher(clusterId), query.NamespaceMatcher(namespaceRe), rangeSeconds)
+	}
+	ret, err := backend.QueryInstantWithTime(ctx, promql, end)
+	if err != nil {
+		klog.Errorf("Query cluster(%s) namespaces cost error:%v", clusterId, err)
+		return nil, err
+	}
+	for _, ns := range ret {
+		totalCosts[string(ns.Metric[model.LabelName(values.NamespaceLabelKey)])] = float64(ns.Value)
+	}
+	return totalCosts, nil
+}
+
+// convertToDailyPoints converts the daily series keyed by the end of the day to points ordered by time,
+// the day is taken from the middle of the window so a daylight saving shift does not move it
+func convertToDailyPoints(costs, nodes map[int64]float64) []forecast.Point {
+	points := make([]forecast.Point, 0, len(costs))
+	for timestamp, cost := range costs {
+		points = append(points, forecast.Point{
+			Time:  forecast.StartOfDay(time.Unix(timestamp-daySeconds/2, 0)),
+			Cost:  cost,
+			Nodes: nodes[timestamp],
+		})
+	}
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].Time.Before(points[j].Time)
+	})
+	return points
+}
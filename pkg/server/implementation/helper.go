@@ -17,15 +17,77 @@ limitations under the License.
 package implementation
 
 import (
+	"context"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"golang.org/x/sync/errgroup"
 	"k8s.io/klog/v2"
 
 	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/query"
 	"github.com/kubefin/kubefin/pkg/utils"
+	"github.com/kubefin/kubefin/pkg/values"
 )
 
+// QueryOptions tunes how the costs are queried from the backend
+type QueryOptions struct {
+	// RollupSelector picks the rollups the cost of the long ranges is summed from, nil means
+	// the raw metrics are always queried
+	RollupSelector *query.RollupSelector
+	// MaxConcurrentQueries bounds the queries sent to the backend concurrently by one call
+	MaxConcurrentQueries int
+	// Ledger serves the finalized total cost of the workloads, namespaces and clusters, nil means
+	// the cost is always queried from the backend
+	Ledger CostLedger
+}
+
+var defaultQueryOptions = &QueryOptions{MaxConcurrentQueries: values.DefaultMaxConcurrentQueries}
+
+type queryOptionsKey struct{}
+
+// WithQueryOptions returns the context the queries under it are tuned by opts
+func WithQueryOptions(ctx context.Context, opts *QueryOptions) context.Context {
+	return context.WithValue(ctx, queryOptionsKey{}, opts)
+}
+
+// queryOptionsFrom returns the default options if the context has none
+func queryOptionsFrom(ctx context.Context) *QueryOptions {
+	if opts, ok := ctx.Value(queryOptionsKey{}).(*QueryOptions); ok && opts != nil {
+		return opts
+	}
+	return defaultQueryOptions
+}
+
+// runQueries runs the queries concurrently and returns the first error, the context passed
+// to the queries is canceled once one of them fails so the others are not waited for
+func runQueries(ctx context.Context, queries ...func(ctx context.Context) error) error {
+	group, groupCtx := errgroup.WithContext(ctx)
+	limit := queryOptionsFrom(ctx).MaxConcurrentQueries
+	if limit <= 0 {
+		limit = values.DefaultMaxConcurrentQueries
+	}
+	group.SetLimit(limit)
+	for i := range queries {
+		queryFunc := queries[i]
+		group.Go(func() error {
+			return queryFunc(groupCtx)
+		})
+	}
+	return group.Wait()
+}
+
+// optionalQuery never fails runQueries, the result of the query is left empty if it failed
+// so the response is still returned partially
+func optionalQuery(name string, queryFunc func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if err := queryFunc(ctx); err != nil {
+			klog.Warningf("Query %s error, the result is returned without it:%v", name, err)
+		}
+		return nil
+	}
+}
+
 func GetStartEndStepsTimeFromCtx(ctx *gin.Context, stepSecondsIfNone int64) (int64, int64, int64, error) {
 	// the time format should be unix format
 	startTime, endTime, err := GetStartEndTimeFromCtx(ctx)
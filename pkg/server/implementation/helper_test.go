@@ -0,0 +1,71 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package implementation
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kubefin/kubefin/pkg/values"
+)
+
+func TestRunQueriesConcurrency(t *testing.T) {
+	tests := []struct {
+		name string
+		opts *QueryOptions
+		want int
+	}{
+		{name: "no options", want: values.DefaultMaxConcurrentQueries},
+		{name: "limit of the options", opts: &QueryOptions{MaxConcurrentQueries: 2}, want: 2},
+		{name: "unset limit", opts: &QueryOptions{}, want: values.DefaultMaxConcurrentQueries},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.opts != nil {
+				ctx = WithQueryOptions(ctx, tt.opts)
+			}
+
+			var mutex sync.Mutex
+			running, maxRunning := 0, 0
+			queries := make([]func(ctx context.Context) error, 2*values.DefaultMaxConcurrentQueries)
+			for i := range queries {
+				queries[i] = func(ctx context.Context) error {
+					mutex.Lock()
+					running++
+					if running > maxRunning {
+						maxRunning = running
+					}
+					mutex.Unlock()
+					time.Sleep(50 * time.Millisecond)
+					mutex.Lock()
+					running--
+					mutex.Unlock()
+					return nil
+				}
+			}
+			if err := runQueries(ctx, queries...); err != nil {
+				t.Fatalf("runQueries error:%v", err)
+			}
+			if maxRunning != tt.want {
+				t.Errorf("max concurrent queries = %d, want %d", maxRunning, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package implementation
+
+import (
+	"context"
+
+	"k8s.io/klog/v2"
+
+	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/ledger"
+	"github.com/kubefin/kubefin/pkg/values"
+)
+
+const hourSeconds = int64(values.HourInSeconds)
+
+// CostLedger reads the finalized cost recorded by the cost ledger for the tenant of the request
+type CostLedger interface {
+	// Coverage returns the start of the first hour and the end of the last hour recorded without gap,
+	// 0 means nothing is recorded yet
+	Coverage(ctx context.Context) (int64, int64, error)
+	Records(ctx context.Context, filter *ledger.Filter) ([]*api.CostLedgerRecord, error)
+}
+
+// ledgerSteps returns the granularity of the records the steps are summed from, and the first and
+// the last step covered by the ledger as a whole. Only the steps aligned to the hours are served by
+// the ledger, the ones aligned to the UTC days are summed from the daily records.
+func ledgerSteps(start, end, stepSeconds, recordedFrom, finalizedUntil int64) (string, int64, int64, bool) {
+	if stepSeconds <= 0 || stepSeconds%hourSeconds != 0 || start%hourSeconds != 0 {
+		return "", 0, 0, false
+	}
+	if end > finalizedUntil {
+		end = finalizedUntil
+	}
+	if end < start {
+		return "", 0, 0, false
+	}
+	last := start + (end-start)/stepSeconds*stepSeconds
+	// The step at t sums from t-stepSeconds, the steps starting before the first record are partly recorded
+	first := start
+	if from := recordedFrom + stepSeconds; first < from {
+		first = start + (from-start+stepSeconds-1)/stepSeconds*stepSeconds
+	}
+	if first > last {
+		return "", 0, 0, false
+	}
+	if stepSeconds%daySeconds == 0 && start%daySeconds == 0 {
+		return api.LedgerGranularityDaily, first, last, true
+	}
+	return api.LedgerGranularityHourly, first, last, true
+}
+
+// mergeLedgerCosts replaces the total costs of the finalized steps with the ones summed from the ledger
+// records, so the history is not changed by the retention or the gaps of the backend. The records
+// are put into totalCosts by the key keyOf returns, the records it skips, the steps without records and
+// the steps partly recorded keep the cost from the backend. The backend cost is kept as well if the
+// ledger could not be read.
+func mergeLedgerCosts(ctx context.Context, clusterId, kind string, start, end, stepSeconds int64,
+	totalCosts map[string]map[int64]float64, keyOf func(record *api.CostLedgerRecord) (string, bool)) {
+	costLedger := queryOptionsFrom(ctx).Ledger
+	if costLedger == nil {
+		return
+	}
+	recordedFrom, finalizedUntil, err := costLedger.Coverage(ctx)
+	if err != nil {
+		klog.Warningf("Read cost ledger progress error, the %s cost is returned from the backend:%v", kind, err)
+		return
+	}
+	granularity, first, last, ok := ledgerSteps(start, end, stepSeconds, recordedFrom, finalizedUntil)
+	if !ok {
+		return
+	}
+	// The step at t sums the records starting from t-stepSeconds to t
+	records, err := costLedger.Records(ctx, &ledger.Filter{
+		ClusterId:   clusterId,
+		Kind:        kind,
+		Granularity: granularity,
+		Start:       first - stepSeconds,
+		End:         last,
+	})
+	if err != nil {
+		klog.Warningf("Read cost ledger records error, the %s cost is returned from the backend:%v", kind, err)
+		return
+	}
+
+	ledgerCosts := make(map[string]map[int64]float64)
+	for _, record := range records {
+		key, ok := keyOf(record)
+		if !ok {
+			continue
+		}
+		if ledgerCosts[key] == nil {
+			ledgerCosts[key] = make(map[int64]float64)
+		}
+		// The record is summed into the first step after its start
+		timestamp := start + (record.StartTime-(start-stepSeconds))/stepSeconds*stepSeconds
+		ledgerCosts[key][timestamp] += record.Cost
+	}
+	for key, costs := range ledgerCosts {
+		if totalCosts[key] == nil {
+			totalCosts[key] = make(map[int64]float64)
+		}
+		for timestamp, cost := range costs {
+			totalCosts[key][timestamp] = cost
+		}
+	}
+}
@@ -0,0 +1,285 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package implementation
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/ledger"
+)
+
+func TestLedgerSteps(t *testing.T) {
+	const hour, day = int64(3600), int64(86400)
+	tests := []struct {
+		name            string
+		start           int64
+		end             int64
+		stepSeconds     int64
+		recordedFrom    int64
+		finalizedUntil  int64
+		wantGranularity string
+		wantFirst       int64
+		wantLast        int64
+		wantOk          bool
+	}{
+		{
+			name:  "hourly steps",
+			start: 10 * hour, end: 20 * hour, stepSeconds: hour, finalizedUntil: 30 * hour,
+			wantGranularity: api.LedgerGranularityHourly, wantFirst: 10 * hour, wantLast: 20 * hour, wantOk: true,
+		},
+		{
+			name:  "steps after the finalized hour",
+			start: 10 * hour, end: 20 * hour, stepSeconds: 2 * hour, finalizedUntil: 15 * hour,
+			wantGranularity: api.LedgerGranularityHourly, wantFirst: 10 * hour, wantLast: 14 * hour, wantOk: true,
+		},
+		{
+			name:  "daily steps",
+			start: day, end: 5 * day, stepSeconds: day, finalizedUntil: 10 * day,
+			wantGranularity: api.LedgerGranularityDaily, wantFirst: day, wantLast: 5 * day, wantOk: true,
+		},
+		{
+			name:  "daily steps not aligned to the days",
+			start: day + hour, end: 5*day + hour, stepSeconds: day, finalizedUntil: 10 * day,
+			wantGranularity: api.LedgerGranularityHourly, wantFirst: day + hour, wantLast: 5*day + hour, wantOk: true,
+		},
+		{
+			name:  "hourly steps partly recorded",
+			start: 10 * hour, end: 20 * hour, stepSeconds: 2 * hour, recordedFrom: 13 * hour, finalizedUntil: 30 * hour,
+			wantGranularity: api.LedgerGranularityHourly, wantFirst: 16 * hour, wantLast: 20 * hour, wantOk: true,
+		},
+		{
+			name:  "daily steps of the day partly recorded",
+			start: day, end: 5 * day, stepSeconds: day, recordedFrom: day + 12*hour, finalizedUntil: 10 * day,
+			wantGranularity: api.LedgerGranularityDaily, wantFirst: 3 * day, wantLast: 5 * day, wantOk: true,
+		},
+		{
+			name:  "steps recorded after the range",
+			start: 10 * hour, end: 20 * hour, stepSeconds: hour, recordedFrom: 20 * hour, finalizedUntil: 30 * hour,
+		},
+		{
+			name:  "nothing finalized",
+			start: 10 * hour, end: 20 * hour, stepSeconds: hour, finalizedUntil: 0,
+		},
+		{
+			name:  "step shorter than an hour",
+			start: 10 * hour, end: 20 * hour, stepSeconds: 600, finalizedUntil: 30 * hour,
+		},
+		{
+			name:  "start not aligned to the hours",
+			start: 10*hour + 60, end: 20 * hour, stepSeconds: hour, finalizedUntil: 30 * hour,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			granularity, first, last, ok := ledgerSteps(tt.start, tt.end, tt.stepSeconds, tt.recordedFrom, tt.finalizedUntil)
+			if ok != tt.wantOk {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOk)
+			}
+			if ok && (granularity != tt.wantGranularity || first != tt.wantFirst || last != tt.wantLast) {
+				t.Errorf("ledgerSteps() = %s, %d, %d, want %s, %d, %d", granularity, first, last,
+					tt.wantGranularity, tt.wantFirst, tt.wantLast)
+			}
+		})
+	}
+}
+
+// fakeLedger returns the records matching the kind, granularity and time range of the filter
+type fakeLedger struct {
+	recordedFrom   int64
+	finalizedUntil int64
+	records        []*api.CostLedgerRecord
+	err            error
+}
+
+func (l *fakeLedger) Coverage(ctx context.Context) (int64, int64, error) {
+	return l.recordedFrom, l.finalizedUntil, nil
+}
+
+func (l *fakeLedger) Records(ctx context.Context, filter *ledger.Filter) ([]*api.CostLedgerRecord, error) {
+	if l.err != nil {
+		return nil, l.err
+	}
+	var ret []*api.CostLedgerRecord
+	for _, record := range l.records {
+		if record.Kind == filter.Kind && record.Granularity == filter.Granularity && record.ClusterId == filter.ClusterId &&
+			record.StartTime >= filter.Start && record.StartTime < filter.End {
+			ret = append(ret, record)
+		}
+	}
+	return ret, nil
+}
+
+func TestMergeLedgerCosts(t *testing.T) {
+	const hour = int64(3600)
+	namespaceRecord := func(namespace string, granularity string, start int64, cost float64) *api.CostLedgerRecord {
+		return &api.CostLedgerRecord{ClusterId: "cluster-1", Kind: api.LedgerKindNamespace, Granularity: granularity,
+			Namespace: namespace, StartTime: start, Cost: cost}
+	}
+	records := []*api.CostLedgerRecord{
+		namespaceRecord("default", api.LedgerGranularityHourly, 0, 1),
+		namespaceRecord("default", api.LedgerGranularityHourly, hour, 2),
+		namespaceRecord("default", api.LedgerGranularityHourly, 2*hour, 4),
+		namespaceRecord("default", api.LedgerGranularityHourly, 3*hour, 8),
+		namespaceRecord("kube-system", api.LedgerGranularityHourly, hour, 5),
+		namespaceRecord("default", api.LedgerGranularityDaily, 0, 24),
+	}
+
+	tests := []struct {
+		name        string
+		ledger      CostLedger
+		start       int64
+		end         int64
+		stepSeconds int64
+		want        map[string]map[int64]float64
+	}{
+		{
+			name:  "no ledger",
+			start: hour, end: 4 * hour, stepSeconds: hour,
+			want: map[string]map[int64]float64{"default": {hour: 10, 2 * hour: 10, 3 * hour: 10, 4 * hour: 10}},
+		},
+		{
+			name:   "finalized hours replaced",
+			ledger: &fakeLedger{finalizedUntil: 3 * hour, records: records},
+			start:  hour, end: 4 * hour, stepSeconds: hour,
+			want: map[string]map[int64]float64{
+				"default":     {hour: 1, 2 * hour: 2, 3 * hour: 4, 4 * hour: 10},
+				"kube-system": {2 * hour: 5},
+			},
+		},
+		{
+			name:   "records summed into the steps",
+			ledger: &fakeLedger{finalizedUntil: 4 * hour, records: records},
+			start:  2 * hour, end: 4 * hour, stepSeconds: 2 * hour,
+			want: map[string]map[int64]float64{
+				"default":     {hour: 10, 2 * hour: 3, 3 * hour: 10, 4 * hour: 12},
+				"kube-system": {2 * hour: 5},
+			},
+		},
+		{
+			name:   "daily records",
+			ledger: &fakeLedger{finalizedUntil: 48 * hour, records: records},
+			start:  24 * hour, end: 24 * hour, stepSeconds: 24 * hour,
+			want: map[string]map[int64]float64{"default": {hour: 10, 2 * hour: 10, 3 * hour: 10, 4 * hour: 10, 24 * hour: 24}},
+		},
+		{
+			name:   "backend cost kept if the ledger fails",
+			ledger: &fakeLedger{finalizedUntil: 4 * hour, err: errors.New("database is locked")},
+			start:  hour, end: 4 * hour, stepSeconds: hour,
+			want: map[string]map[int64]float64{"default": {hour: 10, 2 * hour: 10, 3 * hour: 10, 4 * hour: 10}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.ledger != nil {
+				ctx = WithQueryOptions(ctx, &QueryOptions{Ledger: tt.ledger})
+			}
+			totalCosts := map[string]map[int64]float64{"default": {hour: 10, 2 * hour: 10, 3 * hour: 10, 4 * hour: 10}}
+			mergeLedgerCosts(ctx, "cluster-1", api.LedgerKindNamespace, tt.start, tt.end, tt.stepSeconds, totalCosts,
+				func(record *api.CostLedgerRecord) (string, bool) {
+					return record.Namespace, true
+				})
+			if !reflect.DeepEqual(totalCosts, tt.want) {
+				t.Errorf("total costs = %v, want %v", totalCosts, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeLedgerCostsStartedMidDay(t *testing.T) {
+	const hour, day = int64(3600), int64(86400)
+	// The ledger started at 12:00 of the first day, the daily record of the day sums its last 12 hours only
+	var records []*api.CostLedgerRecord
+	for start := 12 * hour; start < 2*day; start += hour {
+		records = append(records, &api.CostLedgerRecord{ClusterId: "cluster-1", Kind: api.LedgerKindCluster,
+			Granularity: api.LedgerGranularityHourly, StartTime: start, Cost: 2})
+	}
+	records = append(records,
+		&api.CostLedgerRecord{ClusterId: "cluster-1", Kind: api.LedgerKindCluster,
+			Granularity: api.LedgerGranularityDaily, StartTime: 0, Cost: 24},
+		&api.CostLedgerRecord{ClusterId: "cluster-1", Kind: api.LedgerKindCluster,
+			Granularity: api.LedgerGranularityDaily, StartTime: day, Cost: 48})
+	costLedger := &fakeLedger{recordedFrom: 12 * hour, finalizedUntil: 2 * day, records: records}
+
+	tests := []struct {
+		name        string
+		start       int64
+		end         int64
+		stepSeconds int64
+		totalCosts  map[int64]float64
+		want        map[int64]float64
+	}{
+		{
+			name:  "hourly steps",
+			start: 11 * hour, end: 14 * hour, stepSeconds: hour,
+			totalCosts: map[int64]float64{11 * hour: 30, 12 * hour: 30, 13 * hour: 30, 14 * hour: 30},
+			want:       map[int64]float64{11 * hour: 30, 12 * hour: 30, 13 * hour: 2, 14 * hour: 2},
+		},
+		{
+			name:  "daily steps",
+			start: day, end: 2 * day, stepSeconds: day,
+			totalCosts: map[int64]float64{day: 30, 2 * day: 30},
+			want:       map[int64]float64{day: 30, 2 * day: 48},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := WithQueryOptions(context.Background(), &QueryOptions{Ledger: costLedger})
+			totalCosts := map[string]map[int64]float64{"cluster-1": tt.totalCosts}
+			mergeLedgerCosts(ctx, "cluster-1", api.LedgerKindCluster, tt.start, tt.end, tt.stepSeconds, totalCosts,
+				func(record *api.CostLedgerRecord) (string, bool) {
+					return record.ClusterId, true
+				})
+			if !reflect.DeepEqual(totalCosts["cluster-1"], tt.want) {
+				t.Errorf("total costs = %v, want %v", totalCosts["cluster-1"], tt.want)
+			}
+		})
+	}
+}
+
+func TestCostListFilterNameMatcher(t *testing.T) {
+	tests := []struct {
+		name      string
+		filter    *CostListFilter
+		namespace string
+		item      string
+		want      bool
+	}{
+		{name: "no filter", namespace: "default", item: "web", want: true},
+		{name: "namespace", filter: &CostListFilter{Namespace: "default"}, namespace: "default", item: "web", want: true},
+		{name: "other namespace", filter: &CostListFilter{Namespace: "default"}, namespace: "prod", item: "web", want: false},
+		{name: "allowed namespaces", filter: &CostListFilter{NamespaceRe: "team-.*"}, namespace: "team-a", item: "web", want: true},
+		{name: "namespace regex fully matched", filter: &CostListFilter{NamespaceRe: "team"}, namespace: "team-a", item: "web", want: false},
+		{name: "name regex", filter: &CostListFilter{NameRegex: "web|api"}, namespace: "default", item: "api", want: true},
+		{name: "name regex fully matched", filter: &CostListFilter{NameRegex: "web"}, namespace: "default", item: "web-2", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, err := tt.filter.nameMatcher()
+			if err != nil {
+				t.Fatalf("nameMatcher() error:%v", err)
+			}
+			if got := match(tt.namespace, tt.item); got != tt.want {
+				t.Errorf("match(%s, %s) = %v, want %v", tt.namespace, tt.item, got, tt.want)
+			}
+		})
+	}
+}
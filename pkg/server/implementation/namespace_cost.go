@@ -17,11 +17,10 @@ limitations under the License.
 package implementation
 
 import (
+	"context"
 	"fmt"
 	"sort"
-	"sync"
 
-	"k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/klog/v2"
 
 	"github.com/kubefin/kubefin/pkg/api"
@@ -30,45 +29,53 @@ import (
 	"github.com/prometheus/common/model"
 )
 
-func QueryNamespaceCostsWithTimeRange(tenantId, clusterId string,
+// QueryNamespaceCostsWithTimeRange queries the namespaces selected by the filter, the name regex matches the namespace
+func QueryNamespaceCostsWithTimeRange(ctx context.Context, backend query.QueryBackend, clusterId string, filter *CostListFilter,
 	start, end, stepSeconds int64) (*api.ClusterNamespaceCostList, error) {
+	matcher := filter.matchers(values.NamespaceLabelKey)
 	var totalCosts map[string]map[int64]float64
 	var podCount map[string]map[int64]float64
 	var cpuRequest map[string]map[int64]float64
 	var ramRequest map[string]map[int64]float64
 	var cpuUsage map[string]map[int64]float64
 	var ramUsage map[string]map[int64]float64
+	var prices *unitPrices
 
-	var wg sync.WaitGroup
-	var err error
-	var errs []error
-
-	wg.Add(4)
-	go func() {
-		defer wg.Done()
-		totalCosts, err = queryNamespaceTotalCost(tenantId, clusterId, start, end, stepSeconds)
-		errs = append(errs, err)
-	}()
-	go func() {
-		defer wg.Done()
-		podCount, err = queryNamespacePodCount(tenantId, clusterId, start, end, stepSeconds)
-		errs = append(errs, err)
-	}()
-	go func() {
-		defer wg.Done()
-		cpuRequest, ramRequest, err = queryNamespaceResourceRequest(tenantId, clusterId, start, end, stepSeconds)
-		errs = append(errs, err)
-	}()
-	go func() {
-		defer wg.Done()
-		cpuUsage, ramUsage, err = queryNamespaceResourceUsage(tenantId, clusterId, start, end, stepSeconds)
-		errs = append(errs, err)
-	}()
+	err := runQueries(ctx,
+		func(ctx context.Context) (err error) {
+			totalCosts, err = queryNamespaceTotalCost(ctx, backend, clusterId, matcher, start, end, stepSeconds)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			podCount, err = queryNamespacePodCount(ctx, backend, clusterId, matcher, start, end, stepSeconds)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			cpuRequest, ramRequest, err = queryNamespaceResourceRequest(ctx, backend, clusterId, matcher, start, end, stepSeconds)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			cpuUsage, ramUsage, err = queryNamespaceResourceUsage(ctx, backend, clusterId, matcher, start, end, stepSeconds)
+			return err
+		},
+		// The wasted cost is left empty without the prices, the others are still returned
+		optionalQuery("unit prices", func(ctx context.Context) (err error) {
+			prices, err = queryClusterUnitPrices(ctx, backend, clusterId, start, end, stepSeconds)
+			return err
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
 
-	wg.Wait()
-	if errors.NewAggregate(errs) != nil {
-		return nil, errors.NewAggregate(errs)
+	matchName, err := filter.nameMatcher()
+	if err != nil {
+		return nil, err
 	}
+	mergeLedgerCosts(ctx, clusterId, api.LedgerKindNamespace, start, end, stepSeconds, totalCosts,
+		func(record *api.CostLedgerRecord) (string, bool) {
+			return record.Namespace, matchName(record.Namespace, record.Namespace)
+		})
 
 	nsCost := make(map[string]map[int64]*api.ClusterNamespaceCostDetail)
 	parseNamepsaceTotalCosts(nsCost, totalCosts)
@@ -77,6 +84,7 @@ func QueryNamespaceCostsWithTimeRange(tenantId, clusterId string,
 	parseNamespaceResourceUsage(nsCost, cpuUsage, ramUsage, stepSeconds)
 
 	nsCosts := convertClusterNSCostToList(nsCost)
+	setNamespacesEfficiency(nsCosts, prices, stepSeconds)
 	ret := &api.ClusterNamespaceCostList{ClusterId: clusterId, Items: []*api.ClusterNamespaceCost{}}
 	ret.Items = append(ret.Items, nsCosts...)
 	return ret, nil
@@ -180,10 +188,35 @@ func parseNamespaceResourceUsage(nsCost map[string]map[int64]*api.ClusterNamespa
 	}
 }
 
-func queryNamespaceTotalCost(tenantId, clusterId string, start, end, stepSeconds int64) (map[string]map[int64]float64, error) {
+// QueryNamespacesTotalCost queries the total cost of every namespace between start and end,
+// all namespaces are returned if namespace is empty
+func QueryNamespacesTotalCost(ctx context.Context, backend query.QueryBackend, clusterId, namespace string, start, end int64) (map[string]float64, error) {
+	totalCosts := make(map[string]float64)
+	matcher := query.LabelMatcher(values.NamespaceLabelKey, namespace)
+	promql := fmt.Sprintf(query.QlNSTotalCostFromClusterWithTimeRange, query.ClusterMatcher(clusterId), matcher, end-start)
+	if series, rangeSeconds, ok := queryOptionsFrom(ctx).RollupSelector.Select(ctx, backend, query.RollupKindNamespace, clusterId, start, end-start); ok {
+		promql = fmt.Sprintf(query.QlNSTotalCostFromClusterRollupWithTimeRange, series, query.ClusterMatcher(clusterId), matcher, rangeSeconds)
+	}
+	ret, err := backend.QueryInstantWithTime(ctx, promql, end)
+	if err != nil {
+		klog.Errorf("Query cluster(%s) namespaces total cost error:%v", clusterId, err)
+		return nil, err
+	}
+	for _, ns := range ret {
+		key := ns.Metric[model.LabelName(values.NamespaceLabelKey)]
+		totalCosts[string(key)] = float64(ns.Value)
+	}
+
+	return totalCosts, nil
+}
+
+func queryNamespaceTotalCost(ctx context.Context, backend query.QueryBackend, clusterId, matcher string, start, end, stepSeconds int64) (map[string]map[int64]float64, error) {
 	totalCosts := make(map[string]map[int64]float64)
-	promql := fmt.Sprintf(query.QlNSTotalCostFromClusterWithTimeRange, clusterId, stepSeconds)
-	ret, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryRangeWithStep(promql, start, end, stepSeconds)
+	promql := fmt.Sprintf(query.QlNSTotalCostFromClusterWithTimeRange, query.ClusterMatcher(clusterId), matcher, stepSeconds)
+	if series, rangeSeconds, ok := queryOptionsFrom(ctx).RollupSelector.Select(ctx, backend, query.RollupKindNamespace, clusterId, start-stepSeconds, stepSeconds); ok {
+		promql = fmt.Sprintf(query.QlNSTotalCostFromClusterRollupWithTimeRange, series, query.ClusterMatcher(clusterId), matcher, rangeSeconds)
+	}
+	ret, err := backend.QueryRangeWithStep(ctx, promql, start, end, stepSeconds)
 	if err != nil {
 		klog.Errorf("Query cluster(%s) namespace cost error:%v", clusterId, err)
 		return nil, err
@@ -199,10 +232,10 @@ func queryNamespaceTotalCost(tenantId, clusterId string, start, end, stepSeconds
 	return totalCosts, nil
 }
 
-func queryNamespacePodCount(tenantId, clusterId string, start, end, stepSeconds int64) (map[string]map[int64]float64, error) {
+func queryNamespacePodCount(ctx context.Context, backend query.QueryBackend, clusterId, matcher string, start, end, stepSeconds int64) (map[string]map[int64]float64, error) {
 	podCount := make(map[string]map[int64]float64)
-	promql := fmt.Sprintf(query.QlNSPodFromClusterWithTimeRange, clusterId, stepSeconds)
-	ret, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryRangeWithStep(promql, start, end, stepSeconds)
+	promql := fmt.Sprintf(query.QlNSPodFromClusterWithTimeRange, query.ClusterMatcher(clusterId), matcher, stepSeconds)
+	ret, err := backend.QueryRangeWithStep(ctx, promql, start, end, stepSeconds)
 	if err != nil {
 		klog.Errorf("Query cluster(%s) namespace pod count error:%v", clusterId, err)
 		return nil, err
@@ -218,12 +251,12 @@ func queryNamespacePodCount(tenantId, clusterId string, start, end, stepSeconds
 	return podCount, nil
 }
 
-func queryNamespaceResourceRequest(tenantId, clusterId string,
+func queryNamespaceResourceRequest(ctx context.Context, backend query.QueryBackend, clusterId, matcher string,
 	start, end, stepSeconds int64) (map[string]map[int64]float64, map[string]map[int64]float64, error) {
 	cpuRequest := make(map[string]map[int64]float64)
 	ramRequest := make(map[string]map[int64]float64)
-	promql := fmt.Sprintf(query.QlNSResourceRequestFromClusterWithTimeRange, clusterId, stepSeconds)
-	ret, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryRangeWithStep(promql, start, end, stepSeconds)
+	promql := fmt.Sprintf(query.QlNSResourceRequestFromClusterWithTimeRange, query.ClusterMatcher(clusterId), matcher, stepSeconds)
+	ret, err := backend.QueryRangeWithStep(ctx, promql, start, end, stepSeconds)
 	if err != nil {
 		klog.Errorf("Query cluster(%s) namespace resource request error:%v", clusterId, err)
 		return nil, nil, err
@@ -248,12 +281,12 @@ func queryNamespaceResourceRequest(tenantId, clusterId string,
 	return cpuRequest, ramRequest, nil
 }
 
-func queryNamespaceResourceUsage(tenantId, clusterId string,
+func queryNamespaceResourceUsage(ctx context.Context, backend query.QueryBackend, clusterId, matcher string,
 	start, end, stepSeconds int64) (map[string]map[int64]float64, map[string]map[int64]float64, error) {
 	cpuUsage := make(map[string]map[int64]float64)
 	ramUsage := make(map[string]map[int64]float64)
-	promql := fmt.Sprintf(query.QlNSResourceUsageFromClusterWithTimeRange, clusterId, stepSeconds)
-	ret, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryRangeWithStep(promql, start, end, stepSeconds)
+	promql := fmt.Sprintf(query.QlNSResourceUsageFromClusterWithTimeRange, query.ClusterMatcher(clusterId), matcher, stepSeconds)
+	ret, err := backend.QueryRangeWithStep(ctx, promql, start, end, stepSeconds)
 	if err != nil {
 		klog.Errorf("Query cluster(%s) namespace resource usage error:%v", clusterId, err)
 		return nil, nil, err
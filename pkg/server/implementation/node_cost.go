@@ -0,0 +1,289 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package implementation
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/prometheus/common/model"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/query"
+	"github.com/kubefin/kubefin/pkg/values"
+)
+
+// QueryNodeCostsWithTimeRange queries the cost and utilization of every node in the cluster,
+// instanceType and billingMode filter the nodes if they are not empty
+func QueryNodeCostsWithTimeRange(ctx context.Context, backend query.QueryBackend, clusterId, instanceType, billingMode string,
+	start, end, stepSeconds int64) (*api.ClusterNodeCostList, error) {
+	costMatcher := query.LabelMatcher(values.NodeInstanceTypeLabelKey, instanceType) +
+		query.LabelMatcher(values.BillingModeLabelKey, billingMode)
+	// The resource metrics have no instance type label, the nodes are filtered by the cost series
+	resourceMatcher := query.LabelMatcher(values.BillingModeLabelKey, billingMode)
+
+	var nodeCosts map[string]*api.ClusterNodeCost
+	var totalCosts map[string]map[int64]float64
+	var hourlyPrice map[string]map[int64]float64
+	var resourceCosts map[string]map[string]map[int64]float64
+	var resourceTotal map[string]map[string]map[int64]float64
+	var resourceSystemTaken map[string]map[string]map[int64]float64
+	var resourceAvailable map[string]map[string]map[int64]float64
+	var resourceUsage map[string]map[string]map[int64]float64
+
+	err := runQueries(ctx,
+		func(ctx context.Context) (err error) {
+			nodeCosts, totalCosts, err = queryNodeTotalCostWithTimeRange(ctx, backend, clusterId, costMatcher, start, end, stepSeconds)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			promql := fmt.Sprintf(query.QlNodeHourlyPriceFromClusterWithTimeRange, query.ClusterMatcher(clusterId), costMatcher, stepSeconds)
+			hourlyPrice, err = queryNodeSeries(ctx, backend, clusterId, promql, start, end, stepSeconds)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			promql := fmt.Sprintf(query.QlNodeResourceCostFromClusterWithTimeRange, query.ClusterMatcher(clusterId), costMatcher, stepSeconds)
+			resourceCosts, err = queryNodeResourceSeries(ctx, backend, clusterId, promql, start, end, stepSeconds)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			promql := fmt.Sprintf(query.QlNodeResourceAvgFromClusterWithTimeRange,
+				values.NodeResourceTotalMetricsName, query.ClusterMatcher(clusterId), resourceMatcher, stepSeconds)
+			resourceTotal, err = queryNodeResourceSeries(ctx, backend, clusterId, promql, start, end, stepSeconds)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			promql := fmt.Sprintf(query.QlNodeResourceAvgFromClusterWithTimeRange,
+				values.NodeResourceSystemTakenName, query.ClusterMatcher(clusterId), resourceMatcher, stepSeconds)
+			resourceSystemTaken, err = queryNodeResourceSeries(ctx, backend, clusterId, promql, start, end, stepSeconds)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			promql := fmt.Sprintf(query.QlNodeResourceAvgFromClusterWithTimeRange,
+				values.NodeResourceAvailableMetricsName, query.ClusterMatcher(clusterId), resourceMatcher, stepSeconds)
+			resourceAvailable, err = queryNodeResourceSeries(ctx, backend, clusterId, promql, start, end, stepSeconds)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			promql := fmt.Sprintf(query.QlNodeResourceAvgFromClusterWithTimeRange,
+				values.NodeResourceUsageMetricsName, query.ClusterMatcher(clusterId), resourceMatcher, stepSeconds)
+			resourceUsage, err = queryNodeResourceSeries(ctx, backend, clusterId, promql, start, end, stepSeconds)
+			return err
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for nodeName, node := range nodeCosts {
+		for timeStamp, totalCost := range totalCosts[nodeName] {
+			detail := &api.ClusterNodeCostDetail{
+				Timestamp:   timeStamp,
+				TotalCost:   totalCost,
+				HourlyPrice: hourlyPrice[nodeName][timeStamp],
+			}
+			parseNodeResourceRatio(detail, nodeName, timeStamp,
+				resourceTotal, resourceSystemTaken, resourceAvailable, resourceUsage)
+			cpuCost := resourceCosts[string(corev1.ResourceCPU)][nodeName][timeStamp]
+			ramCost := resourceCosts[string(corev1.ResourceMemory)][nodeName][timeStamp]
+			detail.IdleCost = cpuCost*(1-math.Min(detail.CPURequestRatio, 1)) +
+				ramCost*(1-math.Min(detail.RAMRequestRatio, 1))
+			node.CostList = append(node.CostList, detail)
+		}
+		sort.Slice(node.CostList, func(i, j int) bool {
+			return node.CostList[i].Timestamp < node.CostList[j].Timestamp
+		})
+	}
+
+	ret := &api.ClusterNodeCostList{ClusterId: clusterId, Items: []*api.ClusterNodeCost{}}
+	for _, node := range nodeCosts {
+		ret.Items = append(ret.Items, node)
+	}
+	return ret, nil
+}
+
+// parseNodeResourceRatio calculates the request ratio against the allocatable resource,
+// which excludes the resource taken by system, and the usage ratio against the total resource
+func parseNodeResourceRatio(detail *api.ClusterNodeCostDetail, nodeName string, timeStamp int64,
+	resourceTotal, resourceSystemTaken, resourceAvailable, resourceUsage map[string]map[string]map[int64]float64) {
+	for _, resourceType := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+		total := resourceTotal[string(resourceType)][nodeName][timeStamp]
+		allocatable := total - resourceSystemTaken[string(resourceType)][nodeName][timeStamp]
+		requested := allocatable - resourceAvailable[string(resourceType)][nodeName][timeStamp]
+		requestRatio := safeRatio(requested, allocatable)
+		usageRatio := safeRatio(resourceUsage[string(resourceType)][nodeName][timeStamp], total)
+
+		switch resourceType {
+		case corev1.ResourceCPU:
+			detail.CPURequestRatio, detail.CPUUsageRatio = requestRatio, usageRatio
+		case corev1.ResourceMemory:
+			detail.RAMRequestRatio, detail.RAMUsageRatio = requestRatio, usageRatio
+		}
+	}
+}
+
+func safeRatio(numerator, denominator float64) float64 {
+	if denominator <= 0 || numerator <= 0 {
+		return 0
+	}
+	return numerator / denominator
+}
+
+func queryNodeTotalCostWithTimeRange(ctx context.Context, backend query.QueryBackend, clusterId, matcher string,
+	start, end, stepSeconds int64) (map[string]*api.ClusterNodeCost, map[string]map[int64]float64, error) {
+	nodeCosts := make(map[string]*api.ClusterNodeCost)
+	totalCosts := make(map[string]map[int64]float64)
+	promql := fmt.Sprintf(query.QlNodeTotalCostFromClusterWithTimeRange, query.ClusterMatcher(clusterId), matcher, stepSeconds)
+	if series, rangeSeconds, ok := queryOptionsFrom(ctx).RollupSelector.Select(ctx, backend, query.RollupKindNode, clusterId, start-stepSeconds, stepSeconds); ok {
+		promql = fmt.Sprintf(query.QlNodeTotalCostFromClusterRollupWithTimeRange, series, query.ClusterMatcher(clusterId), matcher, rangeSeconds)
+	}
+	ret, err := backend.QueryRangeWithStep(ctx, promql, start, end, stepSeconds)
+	if err != nil {
+		klog.Errorf("Query cluster(%s) node cost error:%v", clusterId, err)
+		return nil, nil, err
+	}
+	for _, node := range ret {
+		nodeName := string(node.Metric[model.LabelName(values.NodeNameLabelKey)])
+		// A node may be reported by several series, e.g. when its billing mode changed in the range
+		if _, ok := nodeCosts[nodeName]; !ok {
+			nodeCosts[nodeName] = &api.ClusterNodeCost{
+				NodeName:     nodeName,
+				InstanceType: string(node.Metric[model.LabelName(values.NodeInstanceTypeLabelKey)]),
+				BillingMode:  string(node.Metric[model.LabelName(values.BillingModeLabelKey)]),
+				Region:       string(node.Metric[model.LabelName(values.RegionLabelKey)]),
+				CostList:     []*api.ClusterNodeCostDetail{},
+			}
+			totalCosts[nodeName] = make(map[int64]float64)
+		}
+		for _, v := range node.Values {
+			totalCosts[nodeName][v.Timestamp.Unix()] += float64(v.Value)
+		}
+	}
+
+	return nodeCosts, totalCosts, nil
+}
+
+func queryNodeSeries(ctx context.Context, backend query.QueryBackend, clusterId, promql string,
+	start, end, stepSeconds int64) (map[string]map[int64]float64, error) {
+	nodeSeries := make(map[string]map[int64]float64)
+	ret, err := backend.QueryRangeWithStep(ctx, promql, start, end, stepSeconds)
+	if err != nil {
+		klog.Errorf("Query cluster(%s) node data error:%v", clusterId, err)
+		return nil, err
+	}
+	for _, node := range ret {
+		nodeName := string(node.Metric[model.LabelName(values.NodeNameLabelKey)])
+		nodeSeries[nodeName] = make(map[int64]float64)
+		for _, v := range node.Values {
+			nodeSeries[nodeName][v.Timestamp.Unix()] = float64(v.Value)
+		}
+	}
+
+	return nodeSeries, nil
+}
+
+// queryNodeResourceSeries returns the data in map[cpu/memory][node][timestamp]
+func queryNodeResourceSeries(ctx context.Context, backend query.QueryBackend, clusterId, promql string,
+	start, end, stepSeconds int64) (map[string]map[string]map[int64]float64, error) {
+	resourceSeries := make(map[string]map[string]map[int64]float64)
+	ret, err := backend.QueryRangeWithStep(ctx, promql, start, end, stepSeconds)
+	if err != nil {
+		klog.Errorf("Query cluster(%s) node resource data error:%v", clusterId, err)
+		return nil, err
+	}
+	for _, node := range ret {
+		resourceType := string(node.Metric[model.LabelName(values.ResourceTypeLabelKey)])
+		nodeName := string(node.Metric[model.LabelName(values.NodeNameLabelKey)])
+		if _, ok := resourceSeries[resourceType]; !ok {
+			resourceSeries[resourceType] = make(map[string]map[int64]float64)
+		}
+		resourceSeries[resourceType][nodeName] = make(map[int64]float64)
+		for _, v := range node.Values {
+			resourceSeries[resourceType][nodeName][v.Timestamp.Unix()] = float64(v.Value)
+		}
+	}
+
+	return resourceSeries, nil
+}
+
+// SortNodeCosts sorts the nodes by name or the sum of the cost in the time range,
+// costs are sorted from the highest by default
+func SortNodeCosts(nodeCosts *api.ClusterNodeCostList, sortBy, sortOrder string) error {
+	if sortBy == "" {
+		sortBy = api.SortByName
+	}
+	if sortOrder == "" {
+		sortOrder = api.SortOrderDesc
+		if sortBy == api.SortByName {
+			sortOrder = api.SortOrderAsc
+		}
+	}
+	if sortOrder != api.SortOrderAsc && sortOrder != api.SortOrderDesc {
+		return fmt.Errorf("sort order %s is not supported, should be asc or desc", sortOrder)
+	}
+
+	var value func(node *api.ClusterNodeCost) float64
+	switch sortBy {
+	case api.SortByName:
+	case api.SortByTotalCost:
+		value = func(node *api.ClusterNodeCost) float64 {
+			return sumNodeCostDetail(node, func(d *api.ClusterNodeCostDetail) float64 { return d.TotalCost })
+		}
+	case api.SortByIdleCost:
+		value = func(node *api.ClusterNodeCost) float64 {
+			return sumNodeCostDetail(node, func(d *api.ClusterNodeCostDetail) float64 { return d.IdleCost })
+		}
+	case api.SortByHourlyPrice:
+		value = func(node *api.ClusterNodeCost) float64 {
+			return sumNodeCostDetail(node, func(d *api.ClusterNodeCostDetail) float64 { return d.HourlyPrice }) /
+				math.Max(float64(len(node.CostList)), 1)
+		}
+	default:
+		return fmt.Errorf("sort by %s is not supported, should be one of name/totalCost/idleCost/hourlyPrice", sortBy)
+	}
+
+	// Sort by name first so the nodes with the same cost are in a stable order
+	items := nodeCosts.Items
+	sort.Slice(items, func(i, j int) bool {
+		if sortBy == api.SortByName && sortOrder == api.SortOrderDesc {
+			return items[i].NodeName > items[j].NodeName
+		}
+		return items[i].NodeName < items[j].NodeName
+	})
+	if value == nil {
+		return nil
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if sortOrder == api.SortOrderAsc {
+			return value(items[i]) < value(items[j])
+		}
+		return value(items[i]) > value(items[j])
+	})
+	return nil
+}
+
+func sumNodeCostDetail(node *api.ClusterNodeCost, field func(*api.ClusterNodeCostDetail) float64) float64 {
+	sum := 0.0
+	for _, detail := range node.CostList {
+		sum += field(detail)
+	}
+	return sum
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package implementation
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/prometheus/common/model"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/query"
+	"github.com/kubefin/kubefin/pkg/simulation"
+	"github.com/kubefin/kubefin/pkg/utils"
+	"github.com/kubefin/kubefin/pkg/values"
+)
+
+// QueryNodeSimulationSnapshot takes the current nodes and pod requests of the cluster, the nodes
+// are grouped into node pools by instance type and billing mode
+func QueryNodeSimulationSnapshot(ctx context.Context, backend query.QueryBackend, clusterId string, config *simulation.Config) (*api.NodeSimulationSnapshot, error) {
+	var prices []*model.Sample
+	var resourceTotal, resourceSystemTaken map[string]map[string]float64
+	var podRequests []*model.Sample
+
+	queries := []func(ctx context.Context) error{
+		func(ctx context.Context) (err error) {
+			promql := fmt.Sprintf(query.QlNodeHourlyPriceFromCluster, query.ClusterMatcher(clusterId))
+			prices, err = backend.QueryInstant(ctx, promql)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			resourceTotal, err = queryNodeResource(ctx, backend, clusterId, values.NodeResourceTotalMetricsName)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			resourceSystemTaken, err = queryNodeResource(ctx, backend, clusterId, values.NodeResourceSystemTakenName)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			promql := fmt.Sprintf(query.QlPodResourceRequestByNodeFromCluster, query.ClusterMatcher(clusterId))
+			podRequests, err = backend.QueryInstant(ctx, promql)
+			return err
+		},
+	}
+	if err := runQueries(ctx, queries...); err != nil {
+		klog.Errorf("Query cluster(%s) node simulation snapshot error:%v", clusterId, err)
+		return nil, err
+	}
+
+	snapshot := &api.NodeSimulationSnapshot{
+		ClusterId: clusterId,
+		Timestamp: utils.GetCurrentTime(),
+		NodePools: []*api.NodePoolSnapshot{},
+	}
+	pools := make(map[string]*api.NodePoolSnapshot)
+	nodePools := make(map[string]*api.NodePoolSnapshot)
+	for _, sample := range prices {
+		instanceType := string(sample.Metric[model.LabelName(values.NodeInstanceTypeLabelKey)])
+		billingMode := string(sample.Metric[model.LabelName(values.BillingModeLabelKey)])
+		name := instanceType + "/" + billingMode
+		pool, ok := pools[name]
+		if !ok {
+			pool = &api.NodePoolSnapshot{
+				Name:         name,
+				InstanceType: instanceType,
+				BillingMode:  billingMode,
+				Region:       string(sample.Metric[model.LabelName(values.RegionLabelKey)]),
+				Nodes:        []*api.SimulationNode{},
+				Pods:         []*api.SimulationPod{},
+			}
+			pools[name] = pool
+			snapshot.NodePools = append(snapshot.NodePools, pool)
+		}
+		nodeName := utils.GetNodeName(sample.Metric)
+		cpu, ram := string(corev1.ResourceCPU), string(corev1.ResourceMemory)
+		pool.Nodes = append(pool.Nodes, &api.SimulationNode{
+			NodeName:    nodeName,
+			CPUCores:    resourceTotal[cpu][nodeName] - resourceSystemTaken[cpu][nodeName],
+			RAMGiB:      resourceTotal[ram][nodeName] - resourceSystemTaken[ram][nodeName],
+			HourlyPrice: float64(sample.Value),
+		})
+		nodePools[nodeName] = pool
+	}
+
+	pods := make(map[string]*api.SimulationPod)
+	podNodes := make(map[string]string)
+	for _, sample := range podRequests {
+		namespace := utils.GetNamespace(sample.Metric)
+		podName := string(sample.Metric[model.LabelName(values.PodNameLabelKey)])
+		key := namespace + "/" + podName
+		pod, ok := pods[key]
+		if !ok {
+			pod = &api.SimulationPod{Namespace: namespace, PodName: podName}
+			pods[key] = pod
+		}
+		if nodeName := utils.GetNodeName(sample.Metric); nodeName != "" {
+			podNodes[key] = nodeName
+		}
+		switch string(sample.Metric[model.LabelName(values.ResourceTypeLabelKey)]) {
+		case string(corev1.ResourceCPU):
+			pod.CPUCores += float64(sample.Value)
+		case string(corev1.ResourceMemory):
+			pod.RAMGiB += float64(sample.Value)
+		}
+	}
+	for key, pod := range pods {
+		if pool, ok := nodePools[podNodes[key]]; ok {
+			pool.Pods = append(pool.Pods, pod)
+			continue
+		}
+		snapshot.UnscheduledPods = append(snapshot.UnscheduledPods, pod)
+	}
+
+	for _, pool := range snapshot.NodePools {
+		sort.Slice(pool.Nodes, func(i, j int) bool {
+			return pool.Nodes[i].NodeName < pool.Nodes[j].NodeName
+		})
+		sortSimulationPods(pool.Pods)
+	}
+	sort.Slice(snapshot.NodePools, func(i, j int) bool {
+		return snapshot.NodePools[i].Name < snapshot.NodePools[j].Name
+	})
+	sortSimulationPods(snapshot.UnscheduledPods)
+	snapshot.Catalog = simulation.BuildCatalog(config, snapshot.NodePools)
+
+	return snapshot, nil
+}
+
+func queryNodeResource(ctx context.Context, backend query.QueryBackend, clusterId, metricsName string) (map[string]map[string]float64, error) {
+	promql := fmt.Sprintf(query.QlNodeResourceFromCluster, metricsName, query.ClusterMatcher(clusterId))
+	ret, err := backend.QueryInstant(ctx, promql)
+	if err != nil {
+		return nil, err
+	}
+
+	resource := make(map[string]map[string]float64)
+	for _, sample := range ret {
+		resourceType := string(sample.Metric[model.LabelName(values.ResourceTypeLabelKey)])
+		if _, ok := resource[resourceType]; !ok {
+			resource[resourceType] = make(map[string]float64)
+		}
+		resource[resourceType][utils.GetNodeName(sample.Metric)] = float64(sample.Value)
+	}
+	return resource, nil
+}
+
+func sortSimulationPods(pods []*api.SimulationPod) {
+	sort.Slice(pods, func(i, j int) bool {
+		if pods[i].Namespace != pods[j].Namespace {
+			return pods[i].Namespace < pods[j].Namespace
+		}
+		return pods[i].PodName < pods[j].PodName
+	})
+}
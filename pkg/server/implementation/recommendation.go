@@ -0,0 +1,273 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package implementation
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/prometheus/common/model"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/query"
+	"github.com/kubefin/kubefin/pkg/recommendation"
+	"github.com/kubefin/kubefin/pkg/values"
+)
+
+// containerUsage is the per pod usage of all workload containers, keyed by the workload key
+// and the container name
+type containerUsage map[string]map[string]*recommendation.Usage
+
+// QueryWorkloadRecommendations recommends the requests of every workload container from the usage
+// percentiles in the window, the savings are estimated with the average unit prices of the cluster
+func QueryWorkloadRecommendations(ctx context.Context, backend query.QueryBackend, clusterId, matchers string,
+	config *recommendation.Config) (*api.WorkloadRecommendationList, error) {
+	window := int64(config.Window.Seconds())
+	resolution := int64(config.Resolution.Seconds())
+
+	var cpuUsage, ramUsage containerUsage
+	var cpuRequest, ramRequest map[string]map[string]float64
+	var podCount map[string]float64
+	var cpuPrice, ramPrice float64
+
+	queries := []func(ctx context.Context) error{
+		func(ctx context.Context) (err error) {
+			cpuUsage, err = queryContainerUsage(ctx, backend, clusterId, matchers, corev1.ResourceCPU, window, resolution)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			ramUsage, err = queryContainerUsage(ctx, backend, clusterId, matchers, corev1.ResourceMemory, window, resolution)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			cpuRequest, err = queryContainerRequest(ctx, backend, clusterId, matchers, corev1.ResourceCPU)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			ramRequest, err = queryContainerRequest(ctx, backend, clusterId, matchers, corev1.ResourceMemory)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			podCount, err = queryWorkloadAvgPodCount(ctx, backend, clusterId, matchers, window)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			cpuPrice, err = queryClusterAvgUnitPrice(ctx, backend, query.QlClusterAvgCPUCoreHourlyCostWithTimeRange, clusterId, window)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			ramPrice, err = queryClusterAvgUnitPrice(ctx, backend, query.QlClusterAvgRAMGBHourlyCostWithTimeRange, clusterId, window)
+			return err
+		},
+	}
+	if err := runQueries(ctx, queries...); err != nil {
+		return nil, err
+	}
+
+	list := &api.WorkloadRecommendationList{
+		ClusterId:     clusterId,
+		WindowSeconds: window,
+		Items:         []*api.WorkloadRecommendation{},
+	}
+	workloadKeys := make(map[string]bool)
+	for key := range cpuUsage {
+		workloadKeys[key] = true
+	}
+	for key := range ramUsage {
+		workloadKeys[key] = true
+	}
+	for key := range workloadKeys {
+		workload := &api.WorkloadRecommendation{PodCount: podCount[key]}
+		workload.WorkloadType, workload.Namespace, workload.WorkloadName = parseWorkloadTypeNamespaceName(key)
+
+		containerNames := make(map[string]bool)
+		for name := range cpuUsage[key] {
+			containerNames[name] = true
+		}
+		for name := range ramUsage[key] {
+			containerNames[name] = true
+		}
+		for name := range containerNames {
+			container := &api.ContainerRecommendation{
+				ContainerName: name,
+				CPU:           newCPURecommendation(config, cpuUsage[key][name], cpuRequest[key][name]),
+				Memory:        newMemoryRecommendation(config, ramUsage[key][name], ramRequest[key][name]),
+			}
+			container.MonthlySavings = workload.PodCount * values.MonthInHours *
+				((container.CPU.CurrentRequest-container.CPU.RecommendedRequest)*cpuPrice +
+					(container.Memory.CurrentRequest-container.Memory.RecommendedRequest)*ramPrice)
+			workload.MonthlySavings += container.MonthlySavings
+			workload.Containers = append(workload.Containers, container)
+		}
+		sort.Slice(workload.Containers, func(i, j int) bool {
+			return workload.Containers[i].ContainerName < workload.Containers[j].ContainerName
+		})
+		list.MonthlySavings += workload.MonthlySavings
+		list.Items = append(list.Items, workload)
+	}
+	sort.Slice(list.Items, func(i, j int) bool {
+		return list.Items[i].MonthlySavings > list.Items[j].MonthlySavings
+	})
+
+	return list, nil
+}
+
+func newCPURecommendation(config *recommendation.Config, usage *recommendation.Usage, request float64) api.ResourceRecommendation {
+	if usage == nil {
+		usage = &recommendation.Usage{}
+	}
+	recommendedRequest, recommendedLimit := config.RecommendCPU(usage)
+	ret := api.ResourceRecommendation{
+		CurrentRequest:             request,
+		UsageP50:                   usage.P50,
+		UsageP95:                   usage.P95,
+		UsageP99:                   usage.P99,
+		UsagePeak:                  usage.Peak,
+		RecommendedRequest:         recommendedRequest,
+		RecommendedLimit:           recommendedLimit,
+		RecommendedRequestQuantity: recommendation.CPUQuantity(recommendedRequest),
+		RecommendedLimitQuantity:   recommendation.CPUQuantity(recommendedLimit),
+	}
+	return ret
+}
+
+func newMemoryRecommendation(config *recommendation.Config, usage *recommendation.Usage, request float64) api.ResourceRecommendation {
+	if usage == nil {
+		usage = &recommendation.Usage{}
+	}
+	recommendedRequest, recommendedLimit := config.RecommendMemory(usage)
+	ret := api.ResourceRecommendation{
+		CurrentRequest:             request,
+		UsageP50:                   usage.P50,
+		UsageP95:                   usage.P95,
+		UsageP99:                   usage.P99,
+		UsagePeak:                  usage.Peak,
+		RecommendedRequest:         recommendedRequest,
+		RecommendedLimit:           recommendedLimit,
+		RecommendedRequestQuantity: recommendation.MemoryQuantity(recommendedRequest),
+		RecommendedLimitQuantity:   recommendation.MemoryQuantity(recommendedLimit),
+	}
+	return ret
+}
+
+// queryContainerUsage queries the P50/P95/P99 and peak per pod usage of every workload container
+func queryContainerUsage(ctx context.Context, backend query.QueryBackend, clusterId, matchers string, resourceType corev1.ResourceName,
+	window, resolution int64) (containerUsage, error) {
+	perPodUsage := fmt.Sprintf(query.QlWorkloadContainerPerPodResource, values.WorkloadResourceUsageMetricsName,
+		query.ClusterMatcher(clusterId), resourceType, matchers, query.ClusterMatcher(clusterId), matchers)
+	promqls := []string{
+		fmt.Sprintf(query.QlQuantileOverTime, 0.5, perPodUsage, window, resolution),
+		fmt.Sprintf(query.QlQuantileOverTime, 0.95, perPodUsage, window, resolution),
+		fmt.Sprintf(query.QlQuantileOverTime, 0.99, perPodUsage, window, resolution),
+		fmt.Sprintf(query.QlMaxOverTime, perPodUsage, window, resolution),
+	}
+
+	results := make([][]*model.Sample, len(promqls))
+	queries := make([]func(ctx context.Context) error, len(promqls))
+	for i := range promqls {
+		i := i
+		queries[i] = func(ctx context.Context) (err error) {
+			results[i], err = backend.QueryInstant(ctx, promqls[i])
+			return err
+		}
+	}
+	if err := runQueries(ctx, queries...); err != nil {
+		klog.Errorf("Query cluster(%s) container %s usage error:%v", clusterId, resourceType, err)
+		return nil, err
+	}
+
+	usage := make(containerUsage)
+	for i, samples := range results {
+		for _, sample := range samples {
+			u := getContainerUsage(usage, sample.Metric)
+			switch i {
+			case 0:
+				u.P50 = float64(sample.Value)
+			case 1:
+				u.P95 = float64(sample.Value)
+			case 2:
+				u.P99 = float64(sample.Value)
+			case 3:
+				u.Peak = float64(sample.Value)
+			}
+		}
+	}
+	return usage, nil
+}
+
+// queryContainerRequest queries the current per pod request of every workload container
+func queryContainerRequest(ctx context.Context, backend query.QueryBackend, clusterId, matchers string, resourceType corev1.ResourceName) (map[string]map[string]float64, error) {
+	promql := fmt.Sprintf(query.QlWorkloadContainerPerPodResource, values.WorkloadResourceRequestMetricsName,
+		query.ClusterMatcher(clusterId), resourceType, matchers, query.ClusterMatcher(clusterId), matchers)
+	ret, err := backend.QueryInstant(ctx, promql)
+	if err != nil {
+		klog.Errorf("Query cluster(%s) container %s request error:%v", clusterId, resourceType, err)
+		return nil, err
+	}
+
+	request := make(map[string]map[string]float64)
+	for _, sample := range ret {
+		key := generateWorkloadNamespaceNameKey(sample.Metric)
+		if _, ok := request[key]; !ok {
+			request[key] = make(map[string]float64)
+		}
+		request[key][string(sample.Metric[model.LabelName(values.ContainerNameLabelKey)])] = float64(sample.Value)
+	}
+	return request, nil
+}
+
+func getContainerUsage(usage containerUsage, metric model.Metric) *recommendation.Usage {
+	key := generateWorkloadNamespaceNameKey(metric)
+	container := string(metric[model.LabelName(values.ContainerNameLabelKey)])
+	if _, ok := usage[key]; !ok {
+		usage[key] = make(map[string]*recommendation.Usage)
+	}
+	if _, ok := usage[key][container]; !ok {
+		usage[key][container] = &recommendation.Usage{}
+	}
+	return usage[key][container]
+}
+
+func queryWorkloadAvgPodCount(ctx context.Context, backend query.QueryBackend, clusterId, matchers string, window int64) (map[string]float64, error) {
+	podCount := make(map[string]float64)
+	promql := fmt.Sprintf(query.QlWorkloadAvgPodCountWithTimeRange, query.ClusterMatcher(clusterId), matchers, window)
+	ret, err := backend.QueryInstant(ctx, promql)
+	if err != nil {
+		klog.Errorf("Query cluster(%s) workload pod count error:%v", clusterId, err)
+		return nil, err
+	}
+	for _, sample := range ret {
+		podCount[generateWorkloadNamespaceNameKey(sample.Metric)] = float64(sample.Value)
+	}
+	return podCount, nil
+}
+
+func queryClusterAvgUnitPrice(ctx context.Context, backend query.QueryBackend, promqlTemplate, clusterId string, window int64) (float64, error) {
+	promql := fmt.Sprintf(promqlTemplate, query.ClusterMatcher(clusterId), window)
+	ret, err := backend.QueryInstant(ctx, promql)
+	if err != nil {
+		klog.Errorf("Query cluster(%s) unit price error:%v", clusterId, err)
+		return 0, err
+	}
+	if len(ret) == 0 {
+		return 0, nil
+	}
+	return float64(ret[0].Value), nil
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package implementation
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/common/model"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/kubefin/kubefin/pkg/query"
+	"github.com/kubefin/kubefin/pkg/query/querytest"
+)
+
+func TestQueryContainerRequest(t *testing.T) {
+	const promql = "sum(kubefin_workload_resource_request{cluster_id='cluster-1',resource='cpu',namespace=~'default'}) " +
+		"by (namespace,workload_type,workload_name,container) / on(namespace,workload_type,workload_name) group_left " +
+		"sum(kubefin_workload_pod_count{cluster_id='cluster-1',namespace=~'default'}) by (namespace,workload_type,workload_name)"
+	if err := querytest.ValidatePromql(promql); err != nil {
+		t.Fatalf("Expected promql is invalid:%v", err)
+	}
+
+	backend := querytest.NewBackend()
+	backend.SetInstant(promql, &model.Sample{
+		Metric: model.Metric{
+			"namespace":     "default",
+			"workload_type": "deployment",
+			"workload_name": "web",
+			"container":     "nginx",
+		},
+		Value: 0.5,
+	})
+	got, err := queryContainerRequest(context.Background(), backend, "cluster-1",
+		query.NamespaceMatcher("default"), corev1.ResourceCPU)
+	if err != nil {
+		t.Fatalf("Query container request error:%v", err)
+	}
+	want := map[string]map[string]float64{"deployment/default/web": {"nginx": 0.5}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected request %v, got %v", want, got)
+	}
+	if queries := backend.Queries(); len(queries) != 1 || queries[0].Promql != promql {
+		t.Errorf("Expected the query %q, got %v", promql, queries)
+	}
+}
+
+func TestQueryContainerUsage(t *testing.T) {
+	backend := querytest.NewBackend()
+	if _, err := queryContainerUsage(context.Background(), backend, "cluster-1",
+		query.NamespaceMatcher("default"), corev1.ResourceMemory, 86400, 300); err != nil {
+		t.Fatalf("Query container usage error:%v", err)
+	}
+
+	queries := backend.Queries()
+	if len(queries) != 4 {
+		t.Fatalf("Expected 4 queries, got %d", len(queries))
+	}
+	for _, q := range queries {
+		if err := querytest.ValidatePromql(q.Promql); err != nil {
+			t.Errorf("Query container usage with invalid promql:%v", err)
+		}
+	}
+}
@@ -17,12 +17,11 @@ limitations under the License.
 package implementation
 
 import (
+	"context"
 	"fmt"
 	"sort"
-	"sync"
 
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/klog/v2"
 
 	"github.com/kubefin/kubefin/pkg/api"
@@ -32,7 +31,7 @@ import (
 )
 
 // QueryClusterResourceCost queries cluster resource cots
-func QueryClusterResourceCost(tenantId, clusterId string,
+func QueryClusterResourceCost(ctx context.Context, backend query.QueryBackend, clusterId string,
 	start, end, stepSeconds int64) (*api.ClusterResourceCostList, error) {
 	var totalCosts map[int64]float64
 	var billingModeCosts map[string]map[int64]float64
@@ -41,61 +40,73 @@ func QueryClusterResourceCost(tenantId, clusterId string,
 	var cpuUsageHourCount map[int64]float64
 	var ramTotalHourCount map[int64]float64
 	var ramUsageHourCount map[int64]float64
+	var cpuRequestHourCount map[int64]float64
+	var ramRequestHourCount map[int64]float64
+	var prices *unitPrices
 
-	var wg sync.WaitGroup
-	var err error
-	var errs []error
-
-	wg.Add(7)
-	go func() {
-		defer wg.Done()
-		totalCosts, err = queryNodeTotalCost(tenantId, clusterId, start, end, stepSeconds)
-		errs = append(errs, err)
-	}()
-	go func() {
-		defer wg.Done()
-		billingModeCosts, err = queryNodeBillingModeCost(tenantId, clusterId, start, end, stepSeconds)
-		errs = append(errs, err)
-	}()
-	go func() {
-		defer wg.Done()
-		resourceTotalCost, err = queryNodeResourceTotalCost(tenantId, clusterId, start, end, stepSeconds)
-		errs = append(errs, err)
-	}()
-	go func() {
-		defer wg.Done()
-		cpuTotalHourCount, err = queryNodeCPUTotalHour(tenantId, clusterId, start, end, stepSeconds)
-		errs = append(errs, err)
-	}()
-	go func() {
-		defer wg.Done()
-		cpuUsageHourCount, err = queryNodeCPUUsageHour(tenantId, clusterId, start, end, stepSeconds)
-		errs = append(errs, err)
-	}()
-	go func() {
-		defer wg.Done()
-		ramTotalHourCount, err = queryNodeRAMTotalHour(tenantId, clusterId, start, end, stepSeconds)
-		errs = append(errs, err)
-	}()
-	go func() {
-		defer wg.Done()
-		ramUsageHourCount, err = queryNodeRAMUsageHour(tenantId, clusterId, start, end, stepSeconds)
-		errs = append(errs, err)
-	}()
-
-	wg.Wait()
-	if errors.NewAggregate(errs) != nil {
-		return nil, errors.NewAggregate(errs)
+	err := runQueries(ctx,
+		func(ctx context.Context) (err error) {
+			totalCosts, err = queryNodeTotalCost(ctx, backend, clusterId, start, end, stepSeconds)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			billingModeCosts, err = queryNodeBillingModeCost(ctx, backend, clusterId, start, end, stepSeconds)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			resourceTotalCost, err = queryNodeResourceTotalCost(ctx, backend, clusterId, start, end, stepSeconds)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			cpuTotalHourCount, err = queryNodeCPUTotalHour(ctx, backend, clusterId, start, end, stepSeconds)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			cpuUsageHourCount, err = queryNodeCPUUsageHour(ctx, backend, clusterId, start, end, stepSeconds)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			ramTotalHourCount, err = queryNodeRAMTotalHour(ctx, backend, clusterId, start, end, stepSeconds)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			ramUsageHourCount, err = queryNodeRAMUsageHour(ctx, backend, clusterId, start, end, stepSeconds)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			cpuRequestHourCount, err = queryPodResourceRequestHour(ctx, backend, clusterId, corev1.ResourceCPU, start, end, stepSeconds)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			ramRequestHourCount, err = queryPodResourceRequestHour(ctx, backend, clusterId, corev1.ResourceMemory, start, end, stepSeconds)
+			return err
+		},
+		// The wasted cost is left empty without the prices, the others are still returned
+		optionalQuery("unit prices", func(ctx context.Context) (err error) {
+			prices, err = queryClusterUnitPrices(ctx, backend, clusterId, start, end, stepSeconds)
+			return err
+		}),
+	)
+	if err != nil {
+		return nil, err
 	}
 
+	clusterTotalCosts := map[string]map[int64]float64{clusterId: totalCosts}
+	mergeLedgerCosts(ctx, clusterId, api.LedgerKindCluster, start, end, stepSeconds, clusterTotalCosts,
+		func(record *api.CostLedgerRecord) (string, bool) {
+			return clusterId, true
+		})
+
 	clusterResourceCost := make(map[int64]*api.ClusterResourceCost)
-	parseResourceTotalCost(clusterResourceCost, totalCosts)
+	parseResourceTotalCost(clusterResourceCost, clusterTotalCosts[clusterId])
 	parseResourceBillingModeCost(clusterResourceCost, billingModeCosts)
 	parseNodeResourceTotalCost(clusterResourceCost, resourceTotalCost)
 	parseResourceCPUTotalHour(clusterResourceCost, cpuTotalHourCount, stepSeconds)
 	parseResourceRAMTotalHour(clusterResourceCost, ramTotalHourCount, stepSeconds)
 	parseResourceCPUUsageHour(clusterResourceCost, cpuUsageHourCount, stepSeconds)
 	parseResourceRAMUsageHour(clusterResourceCost, ramUsageHourCount, stepSeconds)
+	parseResourceRequestHour(clusterResourceCost, cpuRequestHourCount, ramRequestHourCount, stepSeconds)
+	parseResourceEfficiency(clusterResourceCost, prices, stepSeconds)
 
 	return convertClusterResourceCostToList(clusterId, clusterResourceCost), nil
 }
@@ -214,10 +225,49 @@ func parseResourceRAMUsageHour(clusterResourceCost map[int64]*api.ClusterResourc
 	}
 }
 
-func queryNodeTotalCost(tenantId, clusterId string, start, end, stepSeconds int64) (map[int64]float64, error) {
+func parseResourceRequestHour(clusterResourceCost map[int64]*api.ClusterResourceCost,
+	cpuRequestHourCount, ramRequestHourCount map[int64]float64, stepSeconds int64) {
+	for timeStamp, v := range cpuRequestHourCount {
+		cost, ok := clusterResourceCost[timeStamp]
+		if !ok {
+			cost = &api.ClusterResourceCost{
+				Timestamp: timeStamp,
+			}
+		}
+		cost.CPUCoreRequest = v / float64(stepSeconds) * values.HourInSeconds
+		clusterResourceCost[timeStamp] = cost
+	}
+	for timeStamp, v := range ramRequestHourCount {
+		cost, ok := clusterResourceCost[timeStamp]
+		if !ok {
+			cost = &api.ClusterResourceCost{
+				Timestamp: timeStamp,
+			}
+		}
+		cost.RAMGiBRequest = v / float64(stepSeconds) * values.HourInSeconds
+		clusterResourceCost[timeStamp] = cost
+	}
+}
+
+func parseResourceEfficiency(clusterResourceCost map[int64]*api.ClusterResourceCost, prices *unitPrices, stepSeconds int64) {
+	for timeStamp, cost := range clusterResourceCost {
+		cpuPrice, ramPrice := prices.at(timeStamp)
+		efficiency := computeEfficiency(cost.CPUCoreRequest, cost.CPUCoreUsage, cost.RAMGiBRequest, cost.RAMGiBUsage,
+			cpuPrice, ramPrice, stepSeconds)
+		cost.CPUEfficiency = efficiency.cpu
+		cost.RAMEfficiency = efficiency.ram
+		cost.CostEfficiency = efficiency.cost
+		cost.WastedCost = efficiency.wastedCost
+	}
+}
+
+func queryNodeTotalCost(ctx context.Context, backend query.QueryBackend, clusterId string, start, end, stepSeconds int64) (map[int64]float64, error) {
 	totalCosts := make(map[int64]float64)
-	promql := fmt.Sprintf(query.QlNodesTotalHourlyCostFromClusterWithTimeRange, clusterId, stepSeconds)
-	ret, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryRangeWithStep(promql, start, end, stepSeconds)
+	promql := fmt.Sprintf(query.QlNodesTotalHourlyCostFromClusterWithTimeRange, query.ClusterMatcher(clusterId), stepSeconds)
+	if series, rangeSeconds, ok := queryOptionsFrom(ctx).RollupSelector.Select(ctx, backend, query.RollupKindNode, clusterId, start-stepSeconds, stepSeconds); ok {
+		promql = fmt.Sprintf(query.QlTotalCostFromClusterRollupWithTimeRange, series, query.ClusterMatcher(clusterId), "", rangeSeconds)
+	}
+	ret, err := backend.QueryRangeWithStep(ctx, promql, start, end, stepSeconds)
 	if err != nil {
 		klog.Errorf("Query cluster(%s) total node cost error:%v", clusterId, err)
 		return nil, err
@@ -231,10 +281,13 @@ func queryNodeTotalCost(tenantId, clusterId string, start, end, stepSeconds int6
 	return totalCosts, nil
 }
 
-func queryNodeBillingModeCost(tenantId, clusterId string, start, end, stepSeconds int64) (map[string]map[int64]float64, error) {
+func queryNodeBillingModeCost(ctx context.Context, backend query.QueryBackend, clusterId string, start, end, stepSeconds int64) (map[string]map[int64]float64, error) {
 	billingModeCosts := make(map[string]map[int64]float64)
-	promql := fmt.Sprintf(query.QlNodesTotalHourlyBillingModeCostFromClusterWithTimeRange, clusterId, stepSeconds)
-	ret, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryRangeWithStep(promql, start, end, stepSeconds)
+	promql := fmt.Sprintf(query.QlNodesTotalHourlyBillingModeCostFromClusterWithTimeRange, query.ClusterMatcher(clusterId), stepSeconds)
+	if series, rangeSeconds, ok := queryOptionsFrom(ctx).RollupSelector.Select(ctx, backend, query.RollupKindNode, clusterId, start-stepSeconds, stepSeconds); ok {
+		promql = fmt.Sprintf(query.QlNodesBillingModeCostFromClusterRollupWithTimeRange, series, query.ClusterMatcher(clusterId), rangeSeconds)
+	}
+	ret, err := backend.QueryRangeWithStep(ctx, promql, start, end, stepSeconds)
 	if err != nil {
 		klog.Errorf("Query cluster(%s) billing mode cost error:%v", clusterId, err)
 		return nil, err
@@ -249,11 +302,11 @@ func queryNodeBillingModeCost(tenantId, clusterId string, start, end, stepSecond
 	return billingModeCosts, nil
 }
 
-func queryNodeResourceTotalCost(tenantId, clusterId string, start, end, stepSeconds int64) (map[string]map[int64]float64, error) {
+func queryNodeResourceTotalCost(ctx context.Context, backend query.QueryBackend, clusterId string, start, end, stepSeconds int64) (map[string]map[int64]float64, error) {
 	// maps [cpu/memory][timestamp]cost
 	resourceTotalCost := make(map[string]map[int64]float64)
-	promal := fmt.Sprintf(query.QlNodeResourceTotalCostFromClusterWithTimeRange, clusterId, stepSeconds)
-	ret, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryRangeWithStep(promal, start, end, stepSeconds)
+	promal := fmt.Sprintf(query.QlNodeResourceTotalCostFromClusterWithTimeRange, query.ClusterMatcher(clusterId), stepSeconds)
+	ret, err := backend.QueryRangeWithStep(ctx, promal, start, end, stepSeconds)
 	if err != nil {
 		klog.Errorf("Query cluster(%s) cpu total cost error:%v", clusterId, err)
 		return nil, err
@@ -279,10 +332,10 @@ func queryNodeResourceTotalCost(tenantId, clusterId string, start, end, stepSeco
 	return resourceTotalCost, nil
 }
 
-func queryNodeCPUTotalHour(tenantId, clusterId string, start, end, stepSeconds int64) (map[int64]float64, error) {
+func queryNodeCPUTotalHour(ctx context.Context, backend query.QueryBackend, clusterId string, start, end, stepSeconds int64) (map[int64]float64, error) {
 	cpuTotalHourCount := make(map[int64]float64)
-	promql := fmt.Sprintf(query.QlNodeResourceTotalCountFromClusterWithTimeRange, clusterId, corev1.ResourceCPU, stepSeconds)
-	ret, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryRangeWithStep(promql, start, end, stepSeconds)
+	promql := fmt.Sprintf(query.QlNodeResourceTotalCountFromClusterWithTimeRange, query.ClusterMatcher(clusterId), corev1.ResourceCPU, stepSeconds)
+	ret, err := backend.QueryRangeWithStep(ctx, promql, start, end, stepSeconds)
 	if err != nil {
 		klog.Errorf("Query cluster(%s) cpu core hour cost error:%v", clusterId, err)
 		return nil, err
@@ -296,10 +349,10 @@ func queryNodeCPUTotalHour(tenantId, clusterId string, start, end, stepSeconds i
 	return cpuTotalHourCount, nil
 }
 
-func queryNodeCPUUsageHour(tenantId, clusterId string, start, end, stepSeconds int64) (map[int64]float64, error) {
+func queryNodeCPUUsageHour(ctx context.Context, backend query.QueryBackend, clusterId string, start, end, stepSeconds int64) (map[int64]float64, error) {
 	cpuUsageHourCount := make(map[int64]float64)
-	promql := fmt.Sprintf(query.QlNodeResourceUsageCountFromClusterWithTimeRange, clusterId, corev1.ResourceCPU, stepSeconds)
-	ret, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryRangeWithStep(promql, start, end, stepSeconds)
+	promql := fmt.Sprintf(query.QlNodeResourceUsageCountFromClusterWithTimeRange, query.ClusterMatcher(clusterId), corev1.ResourceCPU, stepSeconds)
+	ret, err := backend.QueryRangeWithStep(ctx, promql, start, end, stepSeconds)
 	if err != nil {
 		klog.Errorf("Query cluster(%s) cpu usage hour cost error:%v", clusterId, err)
 		return nil, err
@@ -313,10 +366,10 @@ func queryNodeCPUUsageHour(tenantId, clusterId string, start, end, stepSeconds i
 	return cpuUsageHourCount, nil
 }
 
-func queryNodeRAMTotalHour(tenantId, clusterId string, start, end, stepSeconds int64) (map[int64]float64, error) {
+func queryNodeRAMTotalHour(ctx context.Context, backend query.QueryBackend, clusterId string, start, end, stepSeconds int64) (map[int64]float64, error) {
 	ramTotalHourCount := make(map[int64]float64)
-	promql := fmt.Sprintf(query.QlNodeResourceTotalCountFromClusterWithTimeRange, clusterId, corev1.ResourceMemory, stepSeconds)
-	ret, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryRangeWithStep(promql, start, end, stepSeconds)
+	promql := fmt.Sprintf(query.QlNodeResourceTotalCountFromClusterWithTimeRange, query.ClusterMatcher(clusterId), corev1.ResourceMemory, stepSeconds)
+	ret, err := backend.QueryRangeWithStep(ctx, promql, start, end, stepSeconds)
 	if err != nil {
 		klog.Errorf("Query cluster(%s) ram GB hour cost error:%v", clusterId, err)
 		return nil, err
@@ -330,10 +383,10 @@ func queryNodeRAMTotalHour(tenantId, clusterId string, start, end, stepSeconds i
 	return ramTotalHourCount, nil
 }
 
-func queryNodeRAMUsageHour(tenantId, clusterId string, start, end, stepSeconds int64) (map[int64]float64, error) {
+func queryNodeRAMUsageHour(ctx context.Context, backend query.QueryBackend, clusterId string, start, end, stepSeconds int64) (map[int64]float64, error) {
 	ramUsageHourCount := make(map[int64]float64)
-	promql := fmt.Sprintf(query.QlNodeResourceUsageCountFromClusterWithTimeRange, clusterId, corev1.ResourceMemory, stepSeconds)
-	ret, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryRangeWithStep(promql, start, end, stepSeconds)
+	promql := fmt.Sprintf(query.QlNodeResourceUsageCountFromClusterWithTimeRange, query.ClusterMatcher(clusterId), corev1.ResourceMemory, stepSeconds)
+	ret, err := backend.QueryRangeWithStep(ctx, promql, start, end, stepSeconds)
 	if err != nil {
 		klog.Errorf("Query cluster(%s) ram usage hour cost error:%v", clusterId, err)
 		return nil, err
@@ -347,6 +400,24 @@ func queryNodeRAMUsageHour(tenantId, clusterId string, start, end, stepSeconds i
 	return ramUsageHourCount, nil
 }
 
+func queryPodResourceRequestHour(ctx context.Context, backend query.QueryBackend, clusterId string,
+	resourceType corev1.ResourceName, start, end, stepSeconds int64) (map[int64]float64, error) {
+	requestHourCount := make(map[int64]float64)
+	promql := fmt.Sprintf(query.QlPodResourceRequestCountFromClusterWithTimeRange, query.ClusterMatcher(clusterId), resourceType, stepSeconds)
+	ret, err := backend.QueryRangeWithStep(ctx, promql, start, end, stepSeconds)
+	if err != nil {
+		klog.Errorf("Query cluster(%s) %s request hour error:%v", clusterId, resourceType, err)
+		return nil, err
+	}
+	if len(ret) == 0 {
+		return nil, nil
+	}
+	for _, v := range ret[0].Values {
+		requestHourCount[v.Timestamp.Unix()] = float64(v.Value)
+	}
+	return requestHourCount, nil
+}
+
 func convertClusterResourceCostToList(clusterId string, nodeCost map[int64]*api.ClusterResourceCost) *api.ClusterResourceCostList {
 	ret := &api.ClusterResourceCostList{
 		ClusterId: clusterId,
@@ -0,0 +1,153 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package implementation
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/common/model"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/query"
+	"github.com/kubefin/kubefin/pkg/spot"
+	"github.com/kubefin/kubefin/pkg/values"
+)
+
+// QuerySpotSavings prices the average requests of the deployments/statefulsets with the on-demand
+// and spot unit prices, the suitability of the workloads is classified by the agent. The workloads
+// already running on spot nodes are priced the same, so their savings are overestimated.
+func QuerySpotSavings(ctx context.Context, backend query.QueryBackend, clusterId, matchers string, config *spot.Config) (*api.SpotSavingsAnalysis, error) {
+	window := int64(config.Window.Seconds())
+
+	var suitability, requests []*model.Sample
+	var podCount, cpuPrices, ramPrices map[string]float64
+
+	queries := []func(ctx context.Context) error{
+		func(ctx context.Context) (err error) {
+			promql := fmt.Sprintf(query.QlWorkloadSpotSuitableFromCluster, query.ClusterMatcher(clusterId), matchers)
+			suitability, err = backend.QueryInstant(ctx, promql)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			promql := fmt.Sprintf(query.QlWorkloadAvgResourceRequestFromClusterWithTimeRange, query.ClusterMatcher(clusterId), matchers, window)
+			requests, err = backend.QueryInstant(ctx, promql)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			podCount, err = queryWorkloadAvgPodCount(ctx, backend, clusterId, matchers, window)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			cpuPrices, err = queryClusterAvgUnitPriceByBillingMode(ctx, backend,
+				query.QlClusterAvgCPUCoreHourlyCostByBillingModeWithTimeRange, clusterId, window)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			ramPrices, err = queryClusterAvgUnitPriceByBillingMode(ctx, backend,
+				query.QlClusterAvgRAMGBHourlyCostByBillingModeWithTimeRange, clusterId, window)
+			return err
+		},
+	}
+	if err := runQueries(ctx, queries...); err != nil {
+		klog.Errorf("Query cluster(%s) spot savings error:%v", clusterId, err)
+		return nil, err
+	}
+
+	cpuRequest, ramRequest := make(map[string]float64), make(map[string]float64)
+	for _, sample := range requests {
+		key := generateWorkloadNamespaceNameKey(sample.Metric)
+		switch string(sample.Metric[model.LabelName(values.ResourceTypeLabelKey)]) {
+		case string(corev1.ResourceCPU):
+			cpuRequest[key] += float64(sample.Value)
+		case string(corev1.ResourceMemory):
+			ramRequest[key] += float64(sample.Value)
+		}
+	}
+
+	analysis := &api.SpotSavingsAnalysis{
+		ClusterId:     clusterId,
+		WindowSeconds: window,
+		Rates:         config.Rates(cpuPrices, ramPrices),
+		Namespaces:    []*api.NamespaceSpotSavings{},
+		Workloads:     []*api.WorkloadSpotSavings{},
+	}
+	namespaces := make(map[string]*api.NamespaceSpotSavings)
+	for _, sample := range suitability {
+		key := generateWorkloadNamespaceNameKey(sample.Metric)
+		workload := &api.WorkloadSpotSavings{
+			Suitable:   sample.Value > 0,
+			Blockers:   []string{},
+			PodCount:   podCount[key],
+			CPURequest: cpuRequest[key],
+			RAMRequest: ramRequest[key],
+		}
+		workload.WorkloadType, workload.Namespace, workload.WorkloadName = parseWorkloadTypeNamespaceName(key)
+		if blockers := string(sample.Metric[model.LabelName(values.SpotBlockersLabelKey)]); blockers != "" {
+			workload.Blockers = strings.Split(blockers, ",")
+		}
+		workload.OnDemandMonthlyCost, workload.SpotMonthlyCost = spot.Price(analysis.Rates,
+			workload.CPURequest, workload.RAMRequest)
+		workload.MonthlySavings = workload.OnDemandMonthlyCost - workload.SpotMonthlyCost
+
+		namespace, ok := namespaces[workload.Namespace]
+		if !ok {
+			namespace = &api.NamespaceSpotSavings{Namespace: workload.Namespace}
+			namespaces[workload.Namespace] = namespace
+			analysis.Namespaces = append(analysis.Namespaces, namespace)
+		}
+		namespace.Workloads++
+		analysis.OnDemandMonthlyCost += workload.OnDemandMonthlyCost
+		if workload.Suitable {
+			namespace.SuitableWorkloads++
+			namespace.MonthlySavings += workload.MonthlySavings
+			analysis.MonthlySavings += workload.MonthlySavings
+			analysis.SpotMonthlyCost += workload.SpotMonthlyCost
+		} else {
+			namespace.BlockedMonthlySavings += workload.MonthlySavings
+			analysis.BlockedMonthlySavings += workload.MonthlySavings
+			analysis.SpotMonthlyCost += workload.OnDemandMonthlyCost
+		}
+		analysis.Workloads = append(analysis.Workloads, workload)
+	}
+
+	sort.Slice(analysis.Workloads, func(i, j int) bool {
+		return analysis.Workloads[i].MonthlySavings > analysis.Workloads[j].MonthlySavings
+	})
+	sort.Slice(analysis.Namespaces, func(i, j int) bool {
+		return analysis.Namespaces[i].MonthlySavings > analysis.Namespaces[j].MonthlySavings
+	})
+	return analysis, nil
+}
+
+func queryClusterAvgUnitPriceByBillingMode(ctx context.Context, backend query.QueryBackend, promqlTemplate, clusterId string, window int64) (map[string]float64, error) {
+	promql := fmt.Sprintf(promqlTemplate, query.ClusterMatcher(clusterId), window)
+	ret, err := backend.QueryInstant(ctx, promql)
+	if err != nil {
+		klog.Errorf("Query cluster(%s) unit price error:%v", clusterId, err)
+		return nil, err
+	}
+	prices := make(map[string]float64)
+	for _, sample := range ret {
+		prices[string(sample.Metric[model.LabelName(values.BillingModeLabelKey)])] = float64(sample.Value)
+	}
+	return prices, nil
+}
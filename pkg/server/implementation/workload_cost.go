@@ -17,13 +17,13 @@ limitations under the License.
 package implementation
 
 import (
+	"context"
 	"fmt"
 	"sort"
 	"strings"
-	"sync"
 
 	"github.com/prometheus/common/model"
-	"k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2"
 
 	"github.com/kubefin/kubefin/pkg/api"
@@ -46,7 +46,9 @@ func generateWorkloadNamespaceNameKey(labels model.Metric) string {
 	return fmt.Sprintf("%s/%s/%s", workloadType, namespace, name)
 }
 
-func parseWorkloadNamespaceNameType(key string) (namespace, name, workloadType string) {
+// parseWorkloadTypeNamespaceName parses the key generated by generateWorkloadNamespaceNameKey,
+// the values are returned in the order they are in the key
+func parseWorkloadTypeNamespaceName(key string) (workloadType, namespace, name string) {
 	parts := strings.Split(key, "/")
 	workloadType = parts[0]
 	namespace = parts[1]
@@ -55,78 +57,73 @@ func parseWorkloadNamespaceNameType(key string) (namespace, name, workloadType s
 	return workloadType, namespace, name
 }
 
-func QueryWorkloadCostsWithTimeRange(tenantId, clusterId string,
+// QueryWorkloadCostsWithTimeRange queries the pods and workloads selected by the filter, the filter is
+// matched by the queries, the workload type narrows aggregateBy
+func QueryWorkloadCostsWithTimeRange(ctx context.Context, backend query.QueryBackend, clusterId string, filter *CostListFilter,
 	start, end, stepSeconds int64, aggregateBy string) (*api.ClusterWorkloadCostList, error) {
-	var errs []error
-	var err error
-	var wg sync.WaitGroup
-
-	var podCosts []*api.ClusterWorkloadCost
+	if filter.WorkloadType != "" {
+		if aggregateBy != api.AggregateByAll && aggregateBy != filter.WorkloadType {
+			return &api.ClusterWorkloadCostList{ClusterId: clusterId, Items: []*api.ClusterWorkloadCost{}}, nil
+		}
+		aggregateBy = filter.WorkloadType
+	}
+	var podCosts, workloadCosts []*api.ClusterWorkloadCost
+	var prices *unitPrices
+	// The wasted cost is left empty without the prices, the others are still returned
+	queries := []func(ctx context.Context) error{
+		optionalQuery("unit prices", func(ctx context.Context) (err error) {
+			prices, err = queryClusterUnitPrices(ctx, backend, clusterId, start, end, stepSeconds)
+			return err
+		}),
+	}
 	if aggregateBy == api.AggregateByPod || aggregateBy == api.AggregateByAll {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			podCosts, err = queryPodCostsWithTimeRange(tenantId, clusterId, start, end, stepSeconds)
-			if err != nil {
-				errs = append(errs, err)
-			}
-		}()
+		queries = append(queries, func(ctx context.Context) (err error) {
+			podCosts, err = queryPodCostsWithTimeRange(ctx, backend, clusterId, filter.matchers(values.PodNameLabelKey),
+				start, end, stepSeconds)
+			return err
+		})
 	}
-
-	var workloadCosts []*api.ClusterWorkloadCost
 	if aggregateBy != api.AggregateByPod {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			workloadCosts, err = queryHighLevelWorkloadCostsWithTimeRange(tenantId, clusterId, start, end, stepSeconds, aggregateBy)
-			if err != nil {
-				errs = append(errs, err)
-			}
-		}()
+		queries = append(queries, func(ctx context.Context) (err error) {
+			workloadCosts, err = queryHighLevelWorkloadCostsWithTimeRange(ctx, backend, clusterId, filter,
+				start, end, stepSeconds, aggregateBy)
+			return err
+		})
 	}
-
-	wg.Wait()
-	if len(errs) > 0 {
-		return nil, errors.NewAggregate(errs)
+	if err := runQueries(ctx, queries...); err != nil {
+		return nil, err
 	}
 
 	ret := &api.ClusterWorkloadCostList{ClusterId: clusterId, Items: []*api.ClusterWorkloadCost{}}
 	ret.Items = append(ret.Items, podCosts...)
 	ret.Items = append(ret.Items, workloadCosts...)
+	setWorkloadsEfficiency(ret.Items, prices, stepSeconds)
 	return ret, nil
 }
 
-func queryPodCostsWithTimeRange(tenantId, clusterId string, start, end, stepSeconds int64) ([]*api.ClusterWorkloadCost, error) {
+func queryPodCostsWithTimeRange(ctx context.Context, backend query.QueryBackend, clusterId, matcher string, start, end, stepSeconds int64) ([]*api.ClusterWorkloadCost, error) {
 	var totalCosts map[string]map[int64]float64
 	var cpuRequest map[string]map[int64]float64
 	var ramRequest map[string]map[int64]float64
 	var cpuUsage map[string]map[int64]float64
 	var ramUsage map[string]map[int64]float64
 
-	var wg sync.WaitGroup
-	var err error
-	var errs []error
-
-	wg.Add(3)
-	go func() {
-		defer wg.Done()
-		totalCosts, err = queryPodTotalCostsWithTimeRange(tenantId, clusterId, start, end, stepSeconds)
-		errs = append(errs, err)
-	}()
-	go func() {
-		defer wg.Done()
-		cpuRequest, ramRequest, err = queryPodResourceRequest(tenantId, clusterId, start, end, stepSeconds)
-		errs = append(errs, err)
-	}()
-	go func() {
-		defer wg.Done()
-		cpuUsage, ramUsage, err = queryPodResourceUsage(tenantId, clusterId, start, end, stepSeconds)
-		errs = append(errs, err)
-	}()
-
-	wg.Wait()
-	if errors.NewAggregate(errs) != nil {
-		return nil, errors.NewAggregate(errs)
+	err := runQueries(ctx,
+		func(ctx context.Context) (err error) {
+			totalCosts, err = queryPodTotalCostsWithTimeRange(ctx, backend, clusterId, matcher, start, end, stepSeconds)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			cpuRequest, ramRequest, err = queryPodResourceRequest(ctx, backend, clusterId, matcher, start, end, stepSeconds)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			cpuUsage, ramUsage, err = queryPodResourceUsage(ctx, backend, clusterId, matcher, start, end, stepSeconds)
+			return err
+		},
+	)
+	if err != nil {
+		return nil, err
 	}
 	podWorkloadCost := make(map[string]map[int64]*api.ClusterWorkloadCostDetail)
 	parsePodTotalCost(podWorkloadCost, totalCosts)
@@ -224,10 +221,10 @@ func parsePodResourceUsage(podWorkloadCost map[string]map[int64]*api.ClusterWork
 	}
 }
 
-func queryPodTotalCostsWithTimeRange(tenantId, clusterId string, start, end, stepSeconds int64) (map[string]map[int64]float64, error) {
+func queryPodTotalCostsWithTimeRange(ctx context.Context, backend query.QueryBackend, clusterId, matcher string, start, end, stepSeconds int64) (map[string]map[int64]float64, error) {
 	totalCosts := make(map[string]map[int64]float64)
-	promql := fmt.Sprintf(query.QlPodTotalCostFromClusterWithTimeRange, clusterId, stepSeconds)
-	ret, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryRangeWithStep(promql, start, end, stepSeconds)
+	promql := fmt.Sprintf(query.QlPodTotalCostFromClusterWithTimeRange, query.ClusterMatcher(clusterId), matcher, stepSeconds)
+	ret, err := backend.QueryRangeWithStep(ctx, promql, start, end, stepSeconds)
 	if err != nil {
 		klog.Errorf("Query cluster(%s) pod costs error:%v", clusterId, err)
 		return nil, err
@@ -243,12 +240,12 @@ func queryPodTotalCostsWithTimeRange(tenantId, clusterId string, start, end, ste
 	return totalCosts, nil
 }
 
-func queryPodResourceRequest(tenantId, clusterId string,
+func queryPodResourceRequest(ctx context.Context, backend query.QueryBackend, clusterId, matcher string,
 	start, end, stepSeconds int64) (map[string]map[int64]float64, map[string]map[int64]float64, error) {
 	cpuRequest := make(map[string]map[int64]float64)
 	ramRequest := make(map[string]map[int64]float64)
-	promql := fmt.Sprintf(query.QlPodResourceRequestFromClusterWithTimeRange, clusterId, stepSeconds)
-	ret, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryRangeWithStep(promql, start, end, stepSeconds)
+	promql := fmt.Sprintf(query.QlPodResourceRequestFromClusterWithTimeRange, query.ClusterMatcher(clusterId), matcher, stepSeconds)
+	ret, err := backend.QueryRangeWithStep(ctx, promql, start, end, stepSeconds)
 	if err != nil {
 		klog.Errorf("Query cluster(%s) pod resource request error:%v", err)
 		return nil, nil, err
@@ -272,12 +269,12 @@ func queryPodResourceRequest(tenantId, clusterId string,
 	return cpuRequest, ramRequest, nil
 }
 
-func queryPodResourceUsage(tenantId, clusterId string,
+func queryPodResourceUsage(ctx context.Context, backend query.QueryBackend, clusterId, matcher string,
 	start, end, stepSeconds int64) (map[string]map[int64]float64, map[string]map[int64]float64, error) {
 	cpuUsage := make(map[string]map[int64]float64)
 	ramUsage := make(map[string]map[int64]float64)
-	promql := fmt.Sprintf(query.QlPodResourceUsageFromClusterWithTimeRange, clusterId, stepSeconds)
-	ret, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryRangeWithStep(promql, start, end, stepSeconds)
+	promql := fmt.Sprintf(query.QlPodResourceUsageFromClusterWithTimeRange, query.ClusterMatcher(clusterId), matcher, stepSeconds)
+	ret, err := backend.QueryRangeWithStep(ctx, promql, start, end, stepSeconds)
 	if err != nil {
 		klog.Errorf("Query cluster(%s) pod resoruce usage error:%v", err)
 		return nil, nil, err
@@ -302,11 +299,18 @@ func queryPodResourceUsage(tenantId, clusterId string,
 	return cpuUsage, ramUsage, nil
 }
 
-func queryHighLevelWorkloadCostsWithTimeRange(tenantId, clusterId string, start, end, stepSeconds int64, aggregateBy string) ([]*api.ClusterWorkloadCost, error) {
+func queryHighLevelWorkloadCostsWithTimeRange(ctx context.Context, backend query.QueryBackend, clusterId string, filter *CostListFilter,
+	start, end, stepSeconds int64, aggregateBy string) ([]*api.ClusterWorkloadCost, error) {
 	queryRe := aggregateBy
 	if aggregateBy == api.AggregateByAll {
 		queryRe = "deployment|statefulset|daemonset"
 	}
+	workloadTypes := sets.NewString(strings.Split(queryRe, "|")...)
+	matcher := filter.matchers(values.WorkloadNameLabelKey)
+	matchName, err := filter.nameMatcher()
+	if err != nil {
+		return nil, err
+	}
 
 	var totalCosts map[string]map[int64]float64
 	var podCount map[string]map[int64]float64
@@ -315,36 +319,37 @@ func queryHighLevelWorkloadCostsWithTimeRange(tenantId, clusterId string, start,
 	var cpuUsage map[string]map[int64]float64
 	var ramUsage map[string]map[int64]float64
 
-	var wg sync.WaitGroup
-	var err error
-	var errs []error
-
-	wg.Add(4)
-	go func() {
-		defer wg.Done()
-		totalCosts, err = queryHighLevelWorkloadTotalCost(tenantId, clusterId, queryRe, start, end, stepSeconds)
-		errs = append(errs, err)
-	}()
-	go func() {
-		defer wg.Done()
-		podCount, err = queryHighLevelWorkloadPodCount(tenantId, clusterId, queryRe, start, end, stepSeconds)
-		errs = append(errs, err)
-	}()
-	go func() {
-		defer wg.Done()
-		cpuRequest, ramRequest, err = queryHighLevelWorkloadResourceRequest(tenantId, clusterId, queryRe, start, end, stepSeconds)
-		errs = append(errs, err)
-	}()
-	go func() {
-		defer wg.Done()
-		cpuUsage, ramUsage, err = queryHighLevelWorkloadResourceUsage(tenantId, clusterId, queryRe, start, end, stepSeconds)
-		errs = append(errs, err)
-	}()
-
-	wg.Wait()
-	if errors.NewAggregate(errs) != nil {
-		return nil, errors.NewAggregate(errs)
+	err = runQueries(ctx,
+		func(ctx context.Context) (err error) {
+			totalCosts, err = queryHighLevelWorkloadTotalCost(ctx, backend, clusterId, matcher, queryRe, start, end, stepSeconds)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			podCount, err = queryHighLevelWorkloadPodCount(ctx, backend, clusterId, matcher, queryRe, start, end, stepSeconds)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			cpuRequest, ramRequest, err = queryHighLevelWorkloadResourceRequest(ctx, backend, clusterId, matcher, queryRe, start, end, stepSeconds)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			cpuUsage, ramUsage, err = queryHighLevelWorkloadResourceUsage(ctx, backend, clusterId, matcher, queryRe, start, end, stepSeconds)
+			return err
+		},
+	)
+	if err != nil {
+		return nil, err
 	}
+	// The workloads are recorded in the ledger as type/name
+	mergeLedgerCosts(ctx, clusterId, api.LedgerKindWorkload, start, end, stepSeconds, totalCosts,
+		func(record *api.CostLedgerRecord) (string, bool) {
+			workloadType, name, ok := strings.Cut(record.Name, "/")
+			if !ok || !workloadTypes.Has(workloadType) || !matchName(record.Namespace, name) {
+				return "", false
+			}
+			return fmt.Sprintf("%s/%s/%s", workloadType, record.Namespace, name), true
+		})
+
 	workloadCost := make(map[string]map[int64]*api.ClusterWorkloadCostDetail)
 	parseHighLevelWorkloadTotalCost(workloadCost, totalCosts)
 	parseHighLevelWorkloadPodCount(workloadCost, podCount, stepSeconds)
@@ -453,10 +458,14 @@ func parseHighLevelWorkloadResourceUsage(workloadCost map[string]map[int64]*api.
 	}
 }
 
-func queryHighLevelWorkloadTotalCost(tenantId, clusterId, queryRe string, start, end, stepSeconds int64) (map[string]map[int64]float64, error) {
+func queryHighLevelWorkloadTotalCost(ctx context.Context, backend query.QueryBackend, clusterId, matcher, queryRe string, start, end, stepSeconds int64) (map[string]map[int64]float64, error) {
 	totalCosts := make(map[string]map[int64]float64)
-	promql := fmt.Sprintf(query.QlWorkloadTotalCostFromClusterWithTimeRange, clusterId, queryRe, stepSeconds)
-	ret, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryRangeWithStep(promql, start, end, stepSeconds)
+	promql := fmt.Sprintf(query.QlWorkloadTotalCostFromClusterWithTimeRange, query.ClusterMatcher(clusterId), queryRe, matcher, stepSeconds)
+	if series, rangeSeconds, ok := queryOptionsFrom(ctx).RollupSelector.Select(ctx, backend, query.RollupKindWorkload, clusterId, start-stepSeconds, stepSeconds); ok {
+		promql = fmt.Sprintf(query.QlWorkloadTotalCostFromClusterRollupWithTimeRange, series, query.ClusterMatcher(clusterId), queryRe,
+			matcher, rangeSeconds)
+	}
+	ret, err := backend.QueryRangeWithStep(ctx, promql, start, end, stepSeconds)
 	if err != nil {
 		klog.Errorf("Query cluster(%s) total workload costs error:%v", err)
 		return nil, err
@@ -471,10 +480,10 @@ func queryHighLevelWorkloadTotalCost(tenantId, clusterId, queryRe string, start,
 	return totalCosts, nil
 }
 
-func queryHighLevelWorkloadPodCount(tenantId, clusterId, queryRe string, start, end, stepSeconds int64) (map[string]map[int64]float64, error) {
+func queryHighLevelWorkloadPodCount(ctx context.Context, backend query.QueryBackend, clusterId, matcher, queryRe string, start, end, stepSeconds int64) (map[string]map[int64]float64, error) {
 	podCount := make(map[string]map[int64]float64)
-	promql := fmt.Sprintf(query.QlWorkloadPodFromClusterWithTimeRange, clusterId, queryRe, stepSeconds)
-	ret, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryRangeWithStep(promql, start, end, stepSeconds)
+	promql := fmt.Sprintf(query.QlWorkloadPodFromClusterWithTimeRange, query.ClusterMatcher(clusterId), queryRe, matcher, stepSeconds)
+	ret, err := backend.QueryRangeWithStep(ctx, promql, start, end, stepSeconds)
 	if err != nil {
 		klog.Errorf("Query cluster(%s) pod count error:%v", err)
 		return nil, err
@@ -489,12 +498,12 @@ func queryHighLevelWorkloadPodCount(tenantId, clusterId, queryRe string, start,
 	return podCount, nil
 }
 
-func queryHighLevelWorkloadResourceRequest(tenantId, clusterId, queryRe string,
+func queryHighLevelWorkloadResourceRequest(ctx context.Context, backend query.QueryBackend, clusterId, matcher, queryRe string,
 	start, end, stepSeconds int64) (map[string]map[int64]float64, map[string]map[int64]float64, error) {
 	cpuRequest := make(map[string]map[int64]float64)
 	ramRequest := make(map[string]map[int64]float64)
-	promql := fmt.Sprintf(query.QlWorkloadResourceRequestFromClusterWithTimeRange, clusterId, queryRe, stepSeconds)
-	ret, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryRangeWithStep(promql, start, end, stepSeconds)
+	promql := fmt.Sprintf(query.QlWorkloadResourceRequestFromClusterWithTimeRange, query.ClusterMatcher(clusterId), queryRe, matcher, stepSeconds)
+	ret, err := backend.QueryRangeWithStep(ctx, promql, start, end, stepSeconds)
 	if err != nil {
 		klog.Errorf("Query cluster(%s) resource request error:%v", err)
 		return nil, nil, err
@@ -519,12 +528,12 @@ func queryHighLevelWorkloadResourceRequest(tenantId, clusterId, queryRe string,
 	return cpuRequest, ramRequest, nil
 }
 
-func queryHighLevelWorkloadResourceUsage(tenantId, clusterId, queryRe string,
+func queryHighLevelWorkloadResourceUsage(ctx context.Context, backend query.QueryBackend, clusterId, matcher, queryRe string,
 	start, end, stepSeconds int64) (map[string]map[int64]float64, map[string]map[int64]float64, error) {
 	cpuUsage := make(map[string]map[int64]float64)
 	ramUsage := make(map[string]map[int64]float64)
-	promql := fmt.Sprintf(query.QlWorkloadResourceUsageFromClusterWithTimeRange, clusterId, queryRe, stepSeconds)
-	ret, err := query.GetPromQueryClient().WithTenantId(tenantId).QueryRangeWithStep(promql, start, end, stepSeconds)
+	promql := fmt.Sprintf(query.QlWorkloadResourceUsageFromClusterWithTimeRange, query.ClusterMatcher(clusterId), queryRe, matcher, stepSeconds)
+	ret, err := backend.QueryRangeWithStep(ctx, promql, start, end, stepSeconds)
 	if err != nil {
 		klog.Errorf("Query cluster(%s) resource usage error:%v", err)
 		return nil, nil, err
@@ -552,7 +561,7 @@ func queryHighLevelWorkloadResourceUsage(tenantId, clusterId, queryRe string,
 func convertClusterWorkloadCostToList(workloadCost map[string]map[int64]*api.ClusterWorkloadCostDetail) []*api.ClusterWorkloadCost {
 	ret := []*api.ClusterWorkloadCost{}
 	for workloadKey, details := range workloadCost {
-		workloadType, namesapce, name := parseWorkloadNamespaceNameType(workloadKey)
+		workloadType, namesapce, name := parseWorkloadTypeNamespaceName(workloadKey)
 		cost := &api.ClusterWorkloadCost{
 			Namespace:    namesapce,
 			WorkloadName: name,
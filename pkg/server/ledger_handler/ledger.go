@@ -0,0 +1,116 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ledger_handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/klog/v2"
+
+	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/auth"
+	"github.com/kubefin/kubefin/pkg/ledger"
+	"github.com/kubefin/kubefin/pkg/server/implementation"
+	"github.com/kubefin/kubefin/pkg/utils"
+)
+
+// LedgerCostsHandler  godoc
+//
+//	@Summary		Get cost ledger records
+//	@Description	Get the finalized hourly or daily cost recorded in the cost ledger, the records are kept beyond the query backend retention
+//	@Tags			Ledger
+//	@Produce		json
+//	@Param			startTime	query		uint64	false	"The start time to query"
+//	@Param			endTime		query		uint64	false	"The end time to query"
+//	@Param			kind		query		string	false	"The kind of the records, cluster/node/namespace/workload/label, cluster by default"
+//	@Param			granularity	query		string	false	"The granularity of the records, hourly/daily, daily by default"
+//	@Param			clusterId	query		string	false	"Only return the records of this cluster"
+//	@Param			namespace	query		string	false	"Only return the records of this namespace"
+//	@Param			labelKey	query		string	false	"Only return the label records of the pods with this label"
+//	@Param			labelValue	query		string	false	"The value of the label"
+//	@Success		200			{object}	api.CostLedgerRecordList
+//	@Failure		400			{object}	api.StatusError
+//	@Failure		404			{object}	api.StatusError
+//	@Failure		500			{object}	api.StatusError
+//	@Router			/ledger/costs [get]
+func (h *Handler) LedgerCostsHandler(ctx *gin.Context) {
+	klog.V(6).Info("Start to query cost ledger records")
+	costLedger := h.Ledger()
+	if costLedger == nil {
+		utils.ForwardStatusError(ctx, http.StatusNotFound,
+			api.LedgerDisabledStatus, api.LedgerDisabledReason, "enable the cost ledger with --ledger-enabled")
+		return
+	}
+	startTime, endTime, err := implementation.GetStartEndTimeFromCtx(ctx)
+	if err != nil {
+		utils.ForwardStatusError(ctx, http.StatusBadRequest,
+			api.QueryParaErrorStatus, api.QueryParaErrorReason, err.Error())
+		return
+	}
+
+	filter := &ledger.Filter{
+		ClusterId:   ctx.Query(api.QueryClusterIdPara),
+		Kind:        ctx.DefaultQuery(api.QueryKindPara, api.LedgerKindCluster),
+		Granularity: ctx.DefaultQuery(api.QueryGranularityPara, api.LedgerGranularityDaily),
+		Namespace:   ctx.Query(api.QueryNamespacePara),
+		LabelKey:    ctx.Query(api.QueryLabelKeyPara),
+		LabelValue:  ctx.Query(api.QueryLabelValuePara),
+		Start:       startTime,
+		End:         endTime,
+	}
+	switch filter.Kind {
+	case api.LedgerKindCluster, api.LedgerKindNode, api.LedgerKindNamespace, api.LedgerKindWorkload, api.LedgerKindLabel:
+	default:
+		utils.ForwardStatusError(ctx, http.StatusBadRequest, api.QueryParaErrorStatus, api.QueryParaErrorReason,
+			fmt.Sprintf("kind %s is not supported", filter.Kind))
+		return
+	}
+	if filter.Granularity != api.LedgerGranularityHourly && filter.Granularity != api.LedgerGranularityDaily {
+		utils.ForwardStatusError(ctx, http.StatusBadRequest, api.QueryParaErrorStatus, api.QueryParaErrorReason,
+			fmt.Sprintf("granularity %s is not supported", filter.Granularity))
+		return
+	}
+	if filter.LabelKey != "" && filter.Kind != api.LedgerKindLabel {
+		utils.ForwardStatusError(ctx, http.StatusBadRequest, api.QueryParaErrorStatus, api.QueryParaErrorReason,
+			"labelKey only filters the label records")
+		return
+	}
+
+	records, err := costLedger.Records(h.QueryContext(ctx), utils.ParserTenantIdFromCtx(ctx), filter)
+	if err != nil {
+		klog.Errorf("Query cost ledger records error:%v", err)
+		utils.ForwardStatusError(ctx, http.StatusInternalServerError,
+			api.LedgerFailedStatus, api.LedgerFailedReason, err.Error())
+		return
+	}
+
+	if access := auth.AccessFromContext(ctx); access != nil {
+		allowed := make([]*api.CostLedgerRecord, 0, len(records))
+		for _, record := range records {
+			clusterScope := record.Kind == api.LedgerKindCluster || record.Kind == api.LedgerKindNode
+			if clusterScope && access.ClusterScopeAllowed(record.ClusterId) ||
+				!clusterScope && access.NamespaceAllowed(record.ClusterId, record.Namespace) {
+				allowed = append(allowed, record)
+			}
+		}
+		records = allowed
+	}
+
+	ctx.JSON(http.StatusOK, &api.CostLedgerRecordList{Items: records})
+}
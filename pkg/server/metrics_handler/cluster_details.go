@@ -44,9 +44,9 @@ import (
 //	@Success		200			{object}	api.ClusterResourceMetrics
 //	@Failure		500			{object}	api.StatusError
 //	@Router			/metrics/clusters/{cluster_id}/cpu [get]
-func ClusterCPUMetricsHandler(ctx *gin.Context) {
+func (h *Handler) ClusterCPUMetricsHandler(ctx *gin.Context) {
 	klog.Info("Start to query cluster CPU metrics")
-	clusterMetricsHandler(ctx, v1.ResourceCPU)
+	h.clusterMetricsHandler(ctx, v1.ResourceCPU)
 }
 
 // ClusterMemoryMetricsHandler   godoc
@@ -62,13 +62,13 @@ func ClusterCPUMetricsHandler(ctx *gin.Context) {
 //	@Success		200			{object}	api.ClusterResourceMetrics
 //	@Failure		500			{object}	api.StatusError
 //	@Router			/metrics/clusters/{cluster_id}/memory [get]
-func ClusterMemoryMetricsHandler(ctx *gin.Context) {
+func (h *Handler) ClusterMemoryMetricsHandler(ctx *gin.Context) {
 	klog.Info("Start to query cluster Memory metrics")
-	clusterMetricsHandler(ctx, v1.ResourceMemory)
+	h.clusterMetricsHandler(ctx, v1.ResourceMemory)
 }
 
-func clusterMetricsHandler(ctx *gin.Context, resourceType v1.ResourceName) {
-	tenantId := utils.ParserTenantIdFromCtx(ctx)
+func (h *Handler) clusterMetricsHandler(ctx *gin.Context, resourceType v1.ResourceName) {
+	backend := h.QueryBackend(ctx)
 	startTime, endTime, stepSeconds, err := implementation.GetStartEndStepsTimeFromCtx(ctx, values.DefaultDetailStepSeconds)
 	if err != nil {
 		utils.ForwardStatusError(ctx, http.StatusInternalServerError,
@@ -81,10 +81,9 @@ func clusterMetricsHandler(ctx *gin.Context, resourceType v1.ResourceName) {
 			api.QueryParaErrorStatus, api.QueryParaErrorReason, "")
 		return
 	}
-	clusterMemoryMetrics, err := implementation.QueryClusterMetricsSummaryWithTimeRange(tenantId, clusterId, resourceType, startTime, endTime, stepSeconds)
+	clusterMemoryMetrics, err := implementation.QueryClusterMetricsSummaryWithTimeRange(h.QueryContext(ctx), backend, clusterId, resourceType, startTime, endTime, stepSeconds)
 	if err != nil {
-		utils.ForwardStatusError(ctx, http.StatusInternalServerError,
-			api.QueryFailedStatus, api.QueryFailedReason, err.Error())
+		utils.ForwardQueryError(ctx, err)
 		return
 	}
 	if clusterMemoryMetrics == nil {
@@ -24,6 +24,7 @@ import (
 	"k8s.io/klog/v2"
 
 	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/auth"
 	implementation "github.com/kubefin/kubefin/pkg/server/implementation"
 	"github.com/kubefin/kubefin/pkg/utils"
 )
@@ -37,23 +38,22 @@ import (
 //	@Success		200	{object}	api.ClusterMetricsSummaryList
 //	@Failure		500	{object}	api.StatusError
 //	@Router			/metrics/summary [get]
-func ClustersMetricsSummaryHandler(ctx *gin.Context) {
+func (h *Handler) ClustersMetricsSummaryHandler(ctx *gin.Context) {
 	klog.Infof("Start to query clusters metrics summary")
-	tenantId := utils.ParserTenantIdFromCtx(ctx)
+	backend := h.QueryBackend(ctx)
 	// If data not comes up in two-month period, we will ignore it
 	start, end := utils.GetCurrentTwoMonthStartEndTime()
-	allClustersProperty, err := implementation.QueryAllClustersBasicProperty(tenantId, start, end)
+	allClustersProperty, err := implementation.QueryAllClustersBasicProperty(h.QueryContext(ctx), backend, start, end)
 	if err != nil {
-		utils.ForwardStatusError(ctx, http.StatusInternalServerError,
-			api.QueryFailedStatus, api.QueryFailedReason, err.Error())
+		utils.ForwardQueryError(ctx, err)
 		return
 	}
-	allClustersSummary, err := implementation.QueryAllClustersCurrentMetrics(tenantId)
+	allClustersSummary, err := implementation.QueryAllClustersCurrentMetrics(h.QueryContext(ctx), backend)
 	if err != nil {
-		utils.ForwardStatusError(ctx, http.StatusInternalServerError,
-			api.QueryFailedStatus, api.QueryFailedReason, err.Error())
+		utils.ForwardQueryError(ctx, err)
 		return
 	}
+	auth.FilterClusters(ctx, allClustersSummary)
 	summaries := implementation.ConvertToMultiClustersMetricsList(allClustersSummary, allClustersProperty)
 	bodyBytes, err := json.Marshal(summaries)
 	if err != nil {
@@ -74,22 +74,20 @@ func ClustersMetricsSummaryHandler(ctx *gin.Context) {
 //	@Success		200			{object}	api.ClusterMetricsSummary
 //	@Failure		500			{object}	api.StatusError
 //	@Router			/metrics/clusters/{cluster_id}/summary [get]
-func ClusterMetricsSummaryHandler(ctx *gin.Context) {
+func (h *Handler) ClusterMetricsSummaryHandler(ctx *gin.Context) {
 	klog.Infof("Start to query specific cluster metrics summary")
 	clusterId := utils.ParseClusterFromCtx(ctx)
-	tenantId := utils.ParserTenantIdFromCtx(ctx)
+	backend := h.QueryBackend(ctx)
 	// If data not comes up in two-month period, we will ignore it
 	start, end := utils.GetCurrentTwoMonthStartEndTime()
-	clustersProperty, err := implementation.QueryClusterBasicProperty(tenantId, clusterId, start, end)
+	clustersProperty, err := implementation.QueryClusterBasicProperty(h.QueryContext(ctx), backend, clusterId, start, end)
 	if err != nil {
-		utils.ForwardStatusError(ctx, http.StatusInternalServerError,
-			api.QueryFailedStatus, api.QueryFailedReason, err.Error())
+		utils.ForwardQueryError(ctx, err)
 		return
 	}
-	clusterSummary, err := implementation.QueryClusterCurrentMetrics(tenantId, clusterId)
+	clusterSummary, err := implementation.QueryClusterCurrentMetrics(h.QueryContext(ctx), backend, clusterId)
 	if err != nil {
-		utils.ForwardStatusError(ctx, http.StatusInternalServerError,
-			api.QueryFailedStatus, api.QueryFailedReason, err.Error())
+		utils.ForwardQueryError(ctx, err)
 		return
 	}
 	clusterSummary.ClusterBasicProperty = *clustersProperty
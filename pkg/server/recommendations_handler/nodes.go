@@ -0,0 +1,86 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package recommendations_handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/klog/v2"
+
+	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/server/implementation"
+	"github.com/kubefin/kubefin/pkg/simulation"
+	"github.com/kubefin/kubefin/pkg/utils"
+)
+
+// NodesRecommendationsHandler  godoc
+//
+//	@Summary		Get specific cluster node instance type recommendations
+//	@Description	Simulate packing the current pod requests of every node pool on the instance types of the catalog, and propose the instance type mixes cheaper than the current nodes
+//	@Tags			Recommendations
+//	@Produce		json
+//	@Param			cluster_id	path		string	true	"Cluster Id"
+//	@Success		200			{object}	api.NodeSimulationResult
+//	@Failure		500			{object}	api.StatusError
+//	@Router			/recommendations/clusters/{cluster_id}/nodes [get]
+func (h *Handler) NodesRecommendationsHandler(ctx *gin.Context) {
+	klog.V(6).Info("Start to simulate cluster node instance types")
+	snapshot, ok := h.queryNodeSimulationSnapshot(ctx)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, simulation.Simulate(snapshot, h.SimulationConfig().MaxProposals))
+}
+
+// NodesSimulationSnapshotHandler  godoc
+//
+//	@Summary		Get specific cluster node simulation snapshot
+//	@Description	Get the current nodes, pod requests and instance type catalog of the cluster, the snapshot could be simulated offline with "kubefin-cost-analyzer simulate"
+//	@Tags			Recommendations
+//	@Produce		json
+//	@Param			cluster_id	path		string	true	"Cluster Id"
+//	@Success		200			{object}	api.NodeSimulationSnapshot
+//	@Failure		500			{object}	api.StatusError
+//	@Router			/recommendations/clusters/{cluster_id}/nodes/snapshot [get]
+func (h *Handler) NodesSimulationSnapshotHandler(ctx *gin.Context) {
+	klog.V(6).Info("Start to take cluster node simulation snapshot")
+	snapshot, ok := h.queryNodeSimulationSnapshot(ctx)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, snapshot)
+}
+
+func (h *Handler) queryNodeSimulationSnapshot(ctx *gin.Context) (*api.NodeSimulationSnapshot, bool) {
+	backend := h.QueryBackend(ctx)
+	clusterId := utils.ParseClusterFromCtx(ctx)
+	if clusterId == "" {
+		utils.ForwardStatusError(ctx, http.StatusBadRequest,
+			api.QueryParaErrorStatus, api.QueryParaErrorReason, "")
+		return nil, false
+	}
+
+	snapshot, err := implementation.QueryNodeSimulationSnapshot(h.QueryContext(ctx), backend, clusterId, h.SimulationConfig())
+	if err != nil {
+		utils.ForwardQueryError(ctx, err)
+		return nil, false
+	}
+	return snapshot, true
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package recommendations_handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/klog/v2"
+
+	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/auth"
+	"github.com/kubefin/kubefin/pkg/query"
+	"github.com/kubefin/kubefin/pkg/server/implementation"
+	"github.com/kubefin/kubefin/pkg/utils"
+	"github.com/kubefin/kubefin/pkg/values"
+)
+
+// SpotRecommendationsHandler  godoc
+//
+//	@Summary		Get specific cluster spot adoption savings
+//	@Description	Classify whether the deployments/statefulsets could run on spot nodes (replicas > 1, stateless, no local volumes, PodDisruptionBudget present), and price their requests with the spot and on-demand unit prices
+//	@Tags			Recommendations
+//	@Produce		json
+//	@Param			cluster_id	path		string	true	"Cluster Id"
+//	@Param			namespace	query		string	false	"Only return the workloads in this namespace"
+//	@Success		200			{object}	api.SpotSavingsAnalysis
+//	@Failure		500			{object}	api.StatusError
+//	@Router			/recommendations/clusters/{cluster_id}/spot [get]
+func (h *Handler) SpotRecommendationsHandler(ctx *gin.Context) {
+	klog.V(6).Info("Start to analyze cluster spot savings")
+	backend := h.QueryBackend(ctx)
+	clusterId := utils.ParseClusterFromCtx(ctx)
+	if clusterId == "" {
+		utils.ForwardStatusError(ctx, http.StatusBadRequest,
+			api.QueryParaErrorStatus, api.QueryParaErrorReason, "")
+		return
+	}
+
+	matchers := query.NamespaceMatcher(auth.NamespaceRegexFromContext(ctx, clusterId)) +
+		query.LabelMatcher(values.NamespaceLabelKey, ctx.Query(api.QueryNamespacePara))
+	analysis, err := implementation.QuerySpotSavings(h.QueryContext(ctx), backend, clusterId, matchers, h.SpotConfig())
+	if err != nil {
+		utils.ForwardQueryError(ctx, err)
+		return
+	}
+	analysis.Workloads = auth.FilterNamespaces(ctx, clusterId, analysis.Workloads,
+		func(item *api.WorkloadSpotSavings) string {
+			return item.Namespace
+		})
+	analysis.Namespaces = auth.FilterNamespaces(ctx, clusterId, analysis.Namespaces,
+		func(item *api.NamespaceSpotSavings) string {
+			return item.Namespace
+		})
+
+	ctx.JSON(http.StatusOK, analysis)
+}
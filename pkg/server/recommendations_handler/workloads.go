@@ -0,0 +1,68 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package recommendations_handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/klog/v2"
+
+	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/auth"
+	"github.com/kubefin/kubefin/pkg/query"
+	"github.com/kubefin/kubefin/pkg/server/implementation"
+	"github.com/kubefin/kubefin/pkg/utils"
+	"github.com/kubefin/kubefin/pkg/values"
+)
+
+// WorkloadsRecommendationsHandler  godoc
+//
+//	@Summary		Get specific cluster workloads request recommendations
+//	@Description	Recommend the cpu/memory requests and limits of every workload container from its usage percentiles, with the estimated monthly savings
+//	@Tags			Recommendations
+//	@Produce		json
+//	@Param			cluster_id	path		string	true	"Cluster Id"
+//	@Param			namespace	query		string	false	"Only return the workloads in this namespace"
+//	@Success		200			{object}	api.WorkloadRecommendationList
+//	@Failure		500			{object}	api.StatusError
+//	@Router			/recommendations/clusters/{cluster_id}/workloads [get]
+func (h *Handler) WorkloadsRecommendationsHandler(ctx *gin.Context) {
+	klog.V(6).Info("Start to recommend cluster workloads requests")
+	backend := h.QueryBackend(ctx)
+	clusterId := utils.ParseClusterFromCtx(ctx)
+	if clusterId == "" {
+		utils.ForwardStatusError(ctx, http.StatusBadRequest,
+			api.QueryParaErrorStatus, api.QueryParaErrorReason, "")
+		return
+	}
+
+	matchers := query.NamespaceMatcher(auth.NamespaceRegexFromContext(ctx, clusterId)) +
+		query.LabelMatcher(values.NamespaceLabelKey, ctx.Query(api.QueryNamespacePara))
+	recommendations, err := implementation.QueryWorkloadRecommendations(h.QueryContext(ctx), backend, clusterId, matchers,
+		h.RecommendationConfig())
+	if err != nil {
+		utils.ForwardQueryError(ctx, err)
+		return
+	}
+	recommendations.Items = auth.FilterNamespaces(ctx, clusterId, recommendations.Items,
+		func(item *api.WorkloadRecommendation) string {
+			return item.Namespace
+		})
+
+	ctx.JSON(http.StatusOK, recommendations)
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kubefin/kubefin/pkg/anomaly"
+	"github.com/kubefin/kubefin/pkg/budget"
+	"github.com/kubefin/kubefin/pkg/ledger"
+	"github.com/kubefin/kubefin/pkg/query"
+	"github.com/kubefin/kubefin/pkg/recommendation"
+	"github.com/kubefin/kubefin/pkg/server/implementation"
+	"github.com/kubefin/kubefin/pkg/simulation"
+	"github.com/kubefin/kubefin/pkg/spot"
+	"github.com/kubefin/kubefin/pkg/utils"
+	"github.com/kubefin/kubefin/pkg/values"
+)
+
+// Config holds the dependencies shared by the API handlers
+type Config struct {
+	// QueryBackend is the backend the handlers query the metrics from
+	QueryBackend query.QueryBackend
+	// QueryOptions tunes the queries of every request, nil means the defaults
+	QueryOptions *implementation.QueryOptions
+	// Evaluator is nil if no budget is configured
+	Evaluator *budget.Evaluator
+	// Detector is nil if anomaly detection is disabled
+	Detector *anomaly.Detector
+	// Ledger is nil if the cost ledger is disabled
+	Ledger *ledger.Ledger
+	// Recommendation, Simulation and Spot are the defaults if nil
+	Recommendation *recommendation.Config
+	Simulation     *simulation.Config
+	Spot           *spot.Config
+}
+
+// Server holds the dependencies shared by the API handlers
+type Server struct {
+	backend        query.QueryBackend
+	queryOptions   *implementation.QueryOptions
+	evaluator      *budget.Evaluator
+	detector       *anomaly.Detector
+	ledger         *ledger.Ledger
+	recommendation *recommendation.Config
+	simulation     *simulation.Config
+	spot           *spot.Config
+}
+
+func NewServer(config *Config) *Server {
+	s := &Server{
+		backend:        config.QueryBackend,
+		queryOptions:   config.QueryOptions,
+		evaluator:      config.Evaluator,
+		detector:       config.Detector,
+		ledger:         config.Ledger,
+		recommendation: config.Recommendation,
+		simulation:     config.Simulation,
+		spot:           config.Spot,
+	}
+	if s.queryOptions == nil {
+		s.queryOptions = &implementation.QueryOptions{MaxConcurrentQueries: values.DefaultMaxConcurrentQueries}
+	}
+	if s.recommendation == nil {
+		s.recommendation = recommendation.NewDefaultConfig()
+	}
+	if s.simulation == nil {
+		s.simulation = simulation.NewDefaultConfig()
+	}
+	if s.spot == nil {
+		s.spot = spot.NewDefaultConfig()
+	}
+	return s
+}
+
+// QueryContext returns the context of the request carrying the query options, the queries
+// are canceled with the request and read the ledger records of the request tenant
+func (s *Server) QueryContext(ctx *gin.Context) context.Context {
+	opts := s.queryOptions
+	if s.ledger != nil {
+		tenantOpts := *s.queryOptions
+		tenantOpts.Ledger = s.ledger.ForTenant(utils.ParserTenantIdFromCtx(ctx))
+		opts = &tenantOpts
+	}
+	return implementation.WithQueryOptions(ctx.Request.Context(), opts)
+}
+
+// Evaluator returns nil if no budget is configured
+func (s *Server) Evaluator() *budget.Evaluator {
+	return s.evaluator
+}
+
+// Detector returns nil if anomaly detection is disabled
+func (s *Server) Detector() *anomaly.Detector {
+	return s.detector
+}
+
+// Ledger returns nil if the cost ledger is disabled
+func (s *Server) Ledger() *ledger.Ledger {
+	return s.ledger
+}
+
+func (s *Server) RecommendationConfig() *recommendation.Config {
+	return s.recommendation
+}
+
+func (s *Server) SimulationConfig() *simulation.Config {
+	return s.simulation
+}
+
+func (s *Server) SpotConfig() *spot.Config {
+	return s.spot
+}
+
+// QueryBackend returns the backend querying with the tenant id of the request, the warnings
+// of the queries are returned in the Warning headers of the response, as kube-apiserver does
+func (s *Server) QueryBackend(ctx *gin.Context) query.QueryBackend {
+	var mutex sync.Mutex
+	added := make(map[string]bool)
+	return s.backend.WithTenantId(utils.ParserTenantIdFromCtx(ctx)).
+		WithWarningHandler(func(warnings []string) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			for _, warning := range warnings {
+				if added[warning] {
+					continue
+				}
+				added[warning] = true
+				ctx.Writer.Header().Add(values.WarningHeader, fmt.Sprintf("%d - %q", values.WarningCode, warning))
+			}
+		})
+}
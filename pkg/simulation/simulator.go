@@ -0,0 +1,287 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulation
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/values"
+)
+
+// epsilon ignores the float error when checking whether a pod fits in a node
+const epsilon = 1e-9
+
+// bin is a simulated node with the pods packed in it
+type bin struct {
+	instanceType *api.InstanceTypeSpec
+	cpu          float64
+	ram          float64
+}
+
+// BuildCatalog merges the instance types of the node pools and the configured ones, the
+// observed allocatable resource and prices take precedence over the configured ones
+func BuildCatalog(config *Config, nodePools []*api.NodePoolSnapshot) []*api.InstanceTypeSpec {
+	catalog := make(map[string]*api.InstanceTypeSpec)
+	for instanceType, spec := range config.NodeSpecs {
+		price, ok := config.NodePrices[instanceType]
+		if !ok {
+			continue
+		}
+		catalog[instanceType] = &api.InstanceTypeSpec{
+			InstanceType: instanceType,
+			CPUCores:     spec.CPUCount * (1 - config.SystemReservedRatio),
+			RAMGiB:       spec.RAMGBCount * (1 - config.SystemReservedRatio),
+			HourlyPrice:  price,
+		}
+	}
+
+	// The spot nodes are only used if no other billing mode has the instance type
+	nodes, spotNodes := make(map[string][]*api.SimulationNode), make(map[string][]*api.SimulationNode)
+	for _, pool := range nodePools {
+		if pool.BillingMode == values.BillingModeSpot {
+			spotNodes[pool.InstanceType] = append(spotNodes[pool.InstanceType], pool.Nodes...)
+			continue
+		}
+		nodes[pool.InstanceType] = append(nodes[pool.InstanceType], pool.Nodes...)
+	}
+	for instanceType, instanceNodes := range spotNodes {
+		if len(nodes[instanceType]) == 0 {
+			nodes[instanceType] = instanceNodes
+		}
+	}
+	for instanceType, instanceNodes := range nodes {
+		if len(instanceNodes) == 0 {
+			continue
+		}
+		spec := &api.InstanceTypeSpec{InstanceType: instanceType}
+		for _, node := range instanceNodes {
+			spec.CPUCores += node.CPUCores
+			spec.RAMGiB += node.RAMGiB
+			spec.HourlyPrice += node.HourlyPrice
+		}
+		count := float64(len(instanceNodes))
+		spec.CPUCores /= count
+		spec.RAMGiB /= count
+		spec.HourlyPrice /= count
+		catalog[instanceType] = spec
+	}
+
+	ret := make([]*api.InstanceTypeSpec, 0, len(catalog))
+	for _, spec := range catalog {
+		ret = append(ret, spec)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].InstanceType < ret[j].InstanceType
+	})
+	return ret
+}
+
+// Simulate packs the pods of every node pool on the instance types of the catalog, and proposes
+// the placements cheaper than the current nodes. The pods are packed first-fit decreasing on one
+// instance type, then every node is swapped to the cheapest instance type holding its pods, so
+// the proposals could mix instance types. The pool's own instance type keeps its observed price.
+// DaemonSet pods are packed like the other pods, so the projected nodes are slightly optimistic.
+func Simulate(snapshot *api.NodeSimulationSnapshot, maxProposals int) *api.NodeSimulationResult {
+	result := &api.NodeSimulationResult{
+		ClusterId:       snapshot.ClusterId,
+		Timestamp:       snapshot.Timestamp,
+		NodePools:       []*api.NodePoolSimulation{},
+		UnscheduledPods: len(snapshot.UnscheduledPods),
+	}
+	for _, pool := range snapshot.NodePools {
+		simulation := simulateNodePool(pool, snapshot.Catalog, maxProposals)
+		result.CurrentMonthlyCost += simulation.Current.MonthlyCost
+		result.ProposedMonthlyCost += simulation.Current.MonthlyCost
+		if len(simulation.Proposals) > 0 {
+			result.ProposedMonthlyCost -= simulation.Proposals[0].MonthlySavings
+		}
+		result.NodePools = append(result.NodePools, simulation)
+	}
+	result.MonthlySavings = result.CurrentMonthlyCost - result.ProposedMonthlyCost
+	sort.Slice(result.NodePools, func(i, j int) bool {
+		return result.NodePools[i].Name < result.NodePools[j].Name
+	})
+	return result
+}
+
+func simulateNodePool(pool *api.NodePoolSnapshot, catalog []*api.InstanceTypeSpec, maxProposals int) *api.NodePoolSimulation {
+	simulation := &api.NodePoolSimulation{
+		Name:         pool.Name,
+		InstanceType: pool.InstanceType,
+		BillingMode:  pool.BillingMode,
+		Region:       pool.Region,
+		PodCount:     len(pool.Pods),
+		Proposals:    []*api.NodePoolPlacement{},
+	}
+
+	var podCPU, podRAM, nodeCPU, nodeRAM float64
+	for _, pod := range pool.Pods {
+		podCPU += pod.CPUCores
+		podRAM += pod.RAMGiB
+	}
+	simulation.Current = api.NodePoolPlacement{InstanceTypes: map[string]int{}}
+	for _, node := range pool.Nodes {
+		simulation.Current.InstanceTypes[pool.InstanceType]++
+		simulation.Current.NodeCount++
+		simulation.Current.HourlyCost += node.HourlyPrice
+		nodeCPU += node.CPUCores
+		nodeRAM += node.RAMGiB
+	}
+	simulation.Current.MonthlyCost = simulation.Current.HourlyCost * values.MonthInHours
+	simulation.Current.CPURequestRatio = ratio(podCPU, nodeCPU)
+	simulation.Current.RAMRequestRatio = ratio(podRAM, nodeRAM)
+
+	candidates := poolCatalog(pool, catalog)
+	seen := make(map[string]bool)
+	for _, instanceType := range candidates {
+		bins, ok := pack(pool.Pods, instanceType)
+		if !ok {
+			continue
+		}
+		for _, placement := range []*api.NodePoolPlacement{
+			newPlacement(bins, podCPU, podRAM),
+			newPlacement(downsize(bins, candidates), podCPU, podRAM),
+		} {
+			key := placementKey(placement)
+			if seen[key] || placement.HourlyCost >= simulation.Current.HourlyCost-epsilon {
+				continue
+			}
+			seen[key] = true
+			placement.MonthlySavings = simulation.Current.MonthlyCost - placement.MonthlyCost
+			simulation.Proposals = append(simulation.Proposals, placement)
+		}
+	}
+	sort.Slice(simulation.Proposals, func(i, j int) bool {
+		if math.Abs(simulation.Proposals[i].HourlyCost-simulation.Proposals[j].HourlyCost) > epsilon {
+			return simulation.Proposals[i].HourlyCost < simulation.Proposals[j].HourlyCost
+		}
+		return simulation.Proposals[i].NodeCount < simulation.Proposals[j].NodeCount
+	})
+	if maxProposals > 0 && len(simulation.Proposals) > maxProposals {
+		simulation.Proposals = simulation.Proposals[:maxProposals]
+	}
+	return simulation
+}
+
+// poolCatalog returns the usable instance types, the pool's own instance type is priced
+// with the average price of its nodes
+func poolCatalog(pool *api.NodePoolSnapshot, catalog []*api.InstanceTypeSpec) []*api.InstanceTypeSpec {
+	ret := make([]*api.InstanceTypeSpec, 0, len(catalog))
+	for _, spec := range catalog {
+		if spec.CPUCores <= 0 || spec.RAMGiB <= 0 || spec.HourlyPrice <= 0 {
+			continue
+		}
+		if spec.InstanceType == pool.InstanceType && len(pool.Nodes) > 0 {
+			price := 0.0
+			for _, node := range pool.Nodes {
+				price += node.HourlyPrice
+			}
+			own := *spec
+			own.HourlyPrice = price / float64(len(pool.Nodes))
+			spec = &own
+		}
+		ret = append(ret, spec)
+	}
+	return ret
+}
+
+// pack places the pods first-fit decreasing on the nodes of the instance type, it fails if
+// any pod is larger than the node
+func pack(pods []*api.SimulationPod, instanceType *api.InstanceTypeSpec) ([]*bin, bool) {
+	sorted := append([]*api.SimulationPod{}, pods...)
+	share := func(pod *api.SimulationPod) float64 {
+		return math.Max(pod.CPUCores/instanceType.CPUCores, pod.RAMGiB/instanceType.RAMGiB)
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return share(sorted[i]) > share(sorted[j])
+	})
+
+	var bins []*bin
+	for _, pod := range sorted {
+		if !fits(0, 0, pod, instanceType) {
+			return nil, false
+		}
+		placed := false
+		for _, b := range bins {
+			if fits(b.cpu, b.ram, pod, instanceType) {
+				b.cpu += pod.CPUCores
+				b.ram += pod.RAMGiB
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			bins = append(bins, &bin{instanceType: instanceType, cpu: pod.CPUCores, ram: pod.RAMGiB})
+		}
+	}
+	return bins, true
+}
+
+// downsize swaps every node to the cheapest instance type holding its pods
+func downsize(bins []*bin, candidates []*api.InstanceTypeSpec) []*bin {
+	ret := make([]*bin, 0, len(bins))
+	for _, b := range bins {
+		cheapest := b.instanceType
+		for _, instanceType := range candidates {
+			if instanceType.HourlyPrice < cheapest.HourlyPrice &&
+				b.cpu <= instanceType.CPUCores+epsilon && b.ram <= instanceType.RAMGiB+epsilon {
+				cheapest = instanceType
+			}
+		}
+		ret = append(ret, &bin{instanceType: cheapest, cpu: b.cpu, ram: b.ram})
+	}
+	return ret
+}
+
+func fits(cpu, ram float64, pod *api.SimulationPod, instanceType *api.InstanceTypeSpec) bool {
+	return cpu+pod.CPUCores <= instanceType.CPUCores+epsilon && ram+pod.RAMGiB <= instanceType.RAMGiB+epsilon
+}
+
+func newPlacement(bins []*bin, podCPU, podRAM float64) *api.NodePoolPlacement {
+	placement := &api.NodePoolPlacement{InstanceTypes: map[string]int{}, NodeCount: len(bins)}
+	var nodeCPU, nodeRAM float64
+	for _, b := range bins {
+		placement.InstanceTypes[b.instanceType.InstanceType]++
+		placement.HourlyCost += b.instanceType.HourlyPrice
+		nodeCPU += b.instanceType.CPUCores
+		nodeRAM += b.instanceType.RAMGiB
+	}
+	placement.MonthlyCost = placement.HourlyCost * values.MonthInHours
+	placement.CPURequestRatio = ratio(podCPU, nodeCPU)
+	placement.RAMRequestRatio = ratio(podRAM, nodeRAM)
+	return placement
+}
+
+func placementKey(placement *api.NodePoolPlacement) string {
+	keys := make([]string, 0, len(placement.InstanceTypes))
+	for instanceType, count := range placement.InstanceTypes {
+		keys = append(keys, fmt.Sprintf("%s=%d", instanceType, count))
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+func ratio(numerator, denominator float64) float64 {
+	if denominator <= 0 {
+		return 0
+	}
+	return numerator / denominator
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulation
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/values"
+)
+
+func pods(requests ...[2]float64) []*api.SimulationPod {
+	ret := make([]*api.SimulationPod, 0, len(requests))
+	for _, request := range requests {
+		ret = append(ret, &api.SimulationPod{CPUCores: request[0], RAMGiB: request[1]})
+	}
+	return ret
+}
+
+func TestPack(t *testing.T) {
+	large := &api.InstanceTypeSpec{InstanceType: "large", CPUCores: 4, RAMGiB: 16, HourlyPrice: 0.4}
+	tests := []struct {
+		name   string
+		pods   []*api.SimulationPod
+		wantOK bool
+		// wantBins is the cpu and ram packed in every node
+		wantBins [][2]float64
+	}{
+		{
+			name:     "no pod",
+			wantOK:   true,
+			wantBins: nil,
+		},
+		{
+			name:     "fits in one node exactly",
+			pods:     pods([2]float64{2, 8}, [2]float64{2, 8}),
+			wantOK:   true,
+			wantBins: [][2]float64{{4, 16}},
+		},
+		{
+			name:     "largest pods first",
+			pods:     pods([2]float64{1, 1}, [2]float64{3, 4}, [2]float64{2, 2}, [2]float64{2, 2}),
+			wantOK:   true,
+			wantBins: [][2]float64{{4, 5}, {4, 4}},
+		},
+		{
+			name:     "ram bound pods",
+			pods:     pods([2]float64{0.5, 10}, [2]float64{0.5, 10}, [2]float64{0.5, 6}),
+			wantOK:   true,
+			wantBins: [][2]float64{{1, 16}, {0.5, 10}},
+		},
+		{
+			name:   "pod larger than the node",
+			pods:   pods([2]float64{1, 1}, [2]float64{5, 1}),
+			wantOK: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bins, ok := pack(tt.pods, large)
+			if ok != tt.wantOK {
+				t.Fatalf("pack() ok = %v, want %v", ok, tt.wantOK)
+			}
+			var got [][2]float64
+			for _, b := range bins {
+				got = append(got, [2]float64{b.cpu, b.ram})
+			}
+			if !reflect.DeepEqual(got, tt.wantBins) {
+				t.Errorf("pack() = %v, want %v", got, tt.wantBins)
+			}
+		})
+	}
+}
+
+func TestDownsize(t *testing.T) {
+	small := &api.InstanceTypeSpec{InstanceType: "small", CPUCores: 2, RAMGiB: 8, HourlyPrice: 0.2}
+	large := &api.InstanceTypeSpec{InstanceType: "large", CPUCores: 4, RAMGiB: 16, HourlyPrice: 0.4}
+	highmem := &api.InstanceTypeSpec{InstanceType: "highmem", CPUCores: 2, RAMGiB: 16, HourlyPrice: 0.3}
+
+	bins := []*bin{
+		{instanceType: large, cpu: 4, ram: 16},
+		{instanceType: large, cpu: 2, ram: 12},
+		{instanceType: large, cpu: 1, ram: 2},
+	}
+	got := downsize(bins, []*api.InstanceTypeSpec{highmem, large, small})
+	want := []string{"large", "highmem", "small"}
+	for i, b := range got {
+		if b.instanceType.InstanceType != want[i] {
+			t.Errorf("node %d is downsized to %s, want %s", i, b.instanceType.InstanceType, want[i])
+		}
+		if b.cpu != bins[i].cpu || b.ram != bins[i].ram {
+			t.Errorf("node %d pods changed to %v/%v", i, b.cpu, b.ram)
+		}
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	small := &api.InstanceTypeSpec{InstanceType: "small", CPUCores: 2, RAMGiB: 8, HourlyPrice: 0.2}
+	large := &api.InstanceTypeSpec{InstanceType: "large", CPUCores: 4, RAMGiB: 16, HourlyPrice: 0.4}
+	nodes := func(count int, price float64) []*api.SimulationNode {
+		ret := make([]*api.SimulationNode, 0, count)
+		for i := 0; i < count; i++ {
+			ret = append(ret, &api.SimulationNode{CPUCores: 4, RAMGiB: 16, HourlyPrice: price})
+		}
+		return ret
+	}
+
+	tests := []struct {
+		name string
+		pool *api.NodePoolSnapshot
+		// wantProposal is the instance types of the cheapest proposal, nil if there is no proposal
+		wantProposal map[string]int
+		wantSavings  float64
+	}{
+		{
+			name: "consolidates the underused nodes",
+			pool: &api.NodePoolSnapshot{
+				Name: "pool", InstanceType: "large", Nodes: nodes(3, 0.4),
+				Pods: pods([2]float64{2, 4}, [2]float64{1, 4}, [2]float64{1, 4}),
+			},
+			wantProposal: map[string]int{"large": 1},
+			wantSavings:  0.8 * values.MonthInHours,
+		},
+		{
+			name: "mixes instance types for the leftovers",
+			pool: &api.NodePoolSnapshot{
+				Name: "pool", InstanceType: "large", Nodes: nodes(3, 0.4),
+				Pods: pods([2]float64{3, 12}, [2]float64{1, 2}, [2]float64{1, 2}),
+			},
+			wantProposal: map[string]int{"large": 1, "small": 1},
+			wantSavings:  0.6 * values.MonthInHours,
+		},
+		{
+			name: "no proposal for the packed nodes",
+			pool: &api.NodePoolSnapshot{
+				Name: "pool", InstanceType: "large", Nodes: nodes(1, 0.4),
+				Pods: pods([2]float64{3, 12}),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Simulate(&api.NodeSimulationSnapshot{
+				NodePools: []*api.NodePoolSnapshot{tt.pool},
+				Catalog:   []*api.InstanceTypeSpec{large, small},
+			}, 3)
+			simulation := result.NodePools[0]
+			if tt.wantProposal == nil {
+				if len(simulation.Proposals) != 0 {
+					t.Fatalf("Simulate() proposals = %v, want none", simulation.Proposals[0].InstanceTypes)
+				}
+				return
+			}
+			if len(simulation.Proposals) == 0 {
+				t.Fatalf("Simulate() has no proposal, want %v", tt.wantProposal)
+			}
+			if got := simulation.Proposals[0].InstanceTypes; !reflect.DeepEqual(got, tt.wantProposal) {
+				t.Errorf("cheapest proposal = %v, want %v", got, tt.wantProposal)
+			}
+			if diff := result.MonthlySavings - tt.wantSavings; diff > 1e-6 || diff < -1e-6 {
+				t.Errorf("monthly savings = %v, want %v", result.MonthlySavings, tt.wantSavings)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulation
+
+import (
+	cloudpriceapis "github.com/kubefin/kubefin/pkg/cloudprice/apis"
+)
+
+// Config holds the instance type catalog the node pools are simulated against, the instance
+// types running in the cluster are always in the catalog with their observed prices
+type Config struct {
+	// NodeSpecs maps [instance type]NodeSpec, the spec is the capacity of the node
+	NodeSpecs map[string]cloudpriceapis.NodeSpec `json:"nodeSpecs,omitempty"`
+	// NodePrices maps [instance type]hourly price
+	NodePrices map[string]float64 `json:"nodePrices,omitempty"`
+	// SystemReservedRatio is the fraction of the NodeSpecs capacity taken by system and not allocatable
+	SystemReservedRatio float64 `json:"systemReservedRatio,omitempty"`
+	// MaxProposals limits the proposals of every node pool
+	MaxProposals int `json:"maxProposals,omitempty"`
+}
+
+func NewDefaultConfig() *Config {
+	return &Config{
+		SystemReservedRatio: 0.1,
+		MaxProposals:        3,
+	}
+}
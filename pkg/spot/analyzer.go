@@ -0,0 +1,92 @@
+/*
+Copyright 2023 The KubeFin Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spot
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/values"
+)
+
+// Config holds the settings of the spot adoption savings analysis
+type Config struct {
+	// Window is how far back the requests and the unit prices are averaged
+	Window metav1.Duration `json:"window,omitempty"`
+	// Discount is the fraction spot nodes are cheaper than on-demand nodes, it's only used
+	// if the cluster has no spot nodes to observe the spot prices from
+	Discount float64 `json:"discount,omitempty"`
+}
+
+func NewDefaultConfig() *Config {
+	return &Config{
+		Window:   metav1.Duration{Duration: 7 * 24 * time.Hour},
+		Discount: 0.65,
+	}
+}
+
+// Rates resolves the on-demand and spot unit prices from the average unit prices of the cluster
+// nodes keyed by billing mode. The nodes of other billing modes stand in for the on-demand ones if
+// there are none, and the missing side is derived with the discount if only one side is observed.
+func (c *Config) Rates(cpuPrices, ramPrices map[string]float64) api.SpotRates {
+	rates := api.SpotRates{}
+	rates.OnDemandCPUCoreHourlyPrice = onDemandPrice(cpuPrices)
+	rates.OnDemandRAMGiBHourlyPrice = onDemandPrice(ramPrices)
+
+	spotCPU, cpuOk := cpuPrices[values.BillingModeSpot]
+	spotRAM, ramOk := ramPrices[values.BillingModeSpot]
+	if cpuOk && ramOk {
+		rates.SpotCPUCoreHourlyPrice, rates.SpotRAMGiBHourlyPrice = spotCPU, spotRAM
+		rates.Observed = true
+		// All the nodes are spot nodes, the on-demand prices are reverted from the discount
+		if rates.OnDemandCPUCoreHourlyPrice == 0 && rates.OnDemandRAMGiBHourlyPrice == 0 && c.Discount < 1 {
+			rates.OnDemandCPUCoreHourlyPrice = spotCPU / (1 - c.Discount)
+			rates.OnDemandRAMGiBHourlyPrice = spotRAM / (1 - c.Discount)
+		}
+		return rates
+	}
+	rates.SpotCPUCoreHourlyPrice = rates.OnDemandCPUCoreHourlyPrice * (1 - c.Discount)
+	rates.SpotRAMGiBHourlyPrice = rates.OnDemandRAMGiBHourlyPrice * (1 - c.Discount)
+	return rates
+}
+
+// Price returns the on-demand and spot monthly costs of the requests, in cores and GiB
+func Price(rates api.SpotRates, cpuRequest, ramRequest float64) (onDemand, spot float64) {
+	onDemand = (cpuRequest*rates.OnDemandCPUCoreHourlyPrice + ramRequest*rates.OnDemandRAMGiBHourlyPrice) * values.MonthInHours
+	spot = (cpuRequest*rates.SpotCPUCoreHourlyPrice + ramRequest*rates.SpotRAMGiBHourlyPrice) * values.MonthInHours
+	return onDemand, spot
+}
+
+func onDemandPrice(prices map[string]float64) float64 {
+	if price, ok := prices[values.BillingModeOnDemand]; ok {
+		return price
+	}
+	total, count := 0.0, 0
+	for billingMode, price := range prices {
+		if billingMode == values.BillingModeSpot {
+			continue
+		}
+		total += price
+		count++
+	}
+	if count == 0 {
+		return 0
+	}
+	return total / float64(count)
+}
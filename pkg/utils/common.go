@@ -20,13 +20,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/common/model"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
 
 	"github.com/kubefin/kubefin/pkg/api"
+	"github.com/kubefin/kubefin/pkg/query"
 	"github.com/kubefin/kubefin/pkg/values"
 )
 
@@ -40,6 +43,28 @@ func ForwardStatusError(ctx *gin.Context, httpCode int, status, reason, message
 	ctx.Data(httpCode, "application/json", forwardRaw)
 }
 
+// ForwardQueryError forwards the error of the query backend with the status of its type,
+// the other errors are forwarded as QueryFailed
+func ForwardQueryError(ctx *gin.Context, err error) {
+	switch query.ErrorTypeOf(err) {
+	case query.ErrorTypeBadQuery:
+		ForwardStatusError(ctx, http.StatusBadRequest,
+			api.QueryBadQueryStatus, api.QueryBadQueryReason, err.Error())
+	case query.ErrorTypeTimeout:
+		ForwardStatusError(ctx, http.StatusGatewayTimeout,
+			api.QueryTimeoutStatus, api.QueryTimeoutReason, err.Error())
+	case query.ErrorTypeUnavailable:
+		ForwardStatusError(ctx, http.StatusServiceUnavailable,
+			api.QueryBackendUnavailableStatus, api.QueryBackendUnavailableReason, err.Error())
+	case query.ErrorTypePartialData:
+		ForwardStatusError(ctx, http.StatusBadGateway,
+			api.QueryPartialDataStatus, api.QueryPartialDataReason, err.Error())
+	default:
+		ForwardStatusError(ctx, http.StatusInternalServerError,
+			api.QueryFailedStatus, api.QueryFailedReason, err.Error())
+	}
+}
+
 func newStatusError(status, reason, message string, httpCode int) *api.StatusError {
 	return &api.StatusError{
 		APIVersion: api.KubeFinAPIVersion,
@@ -89,6 +114,15 @@ func ParseClusterFromCtx(ctx *gin.Context) string {
 	return ctx.Param(values.ClusterIdQueryParameter)
 }
 
+// ValidateClusterId checks the cluster id is a DNS-1123 subdomain like the kube-system UID and
+// the cloud cluster ids, the ids out of the charset are rejected before they're authorized or queried
+func ValidateClusterId(clusterId string) error {
+	if errs := validation.IsDNS1123Subdomain(clusterId); len(errs) > 0 {
+		return fmt.Errorf("cluster id %s is invalid:%s", clusterId, strings.Join(errs, ","))
+	}
+	return nil
+}
+
 func GetCurrentTime() int64 {
 	return time.Now().Unix()
 }
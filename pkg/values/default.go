@@ -29,11 +29,20 @@ const (
 	// DefaultRetryPeriod is the defaultcloud RetryPeriod for leader election.
 	DefaultRetryPeriod = 5 * time.Second
 
+	// DefaultMetricsBindAddress is the default address kubefin-agent serves metrics on
+	DefaultMetricsBindAddress = ":8080"
+	// DefaultAnalyzerBindAddress is the default address kubefin-cost-analyzer serves the API on
+	DefaultAnalyzerBindAddress = ":8080"
+	// DefaultQueryBackendTimeout is the default timeout of requests sent to the query backend
+	DefaultQueryBackendTimeout = 30 * time.Second
+
 	LostConnectionTimeoutThreshold = time.Minute * 3 / time.Second
 
-	GBInBytes              = 1024.0 * 1024.0 * 1024.0
-	CoreInMCore            = 1000.0
-	HourInSeconds          = 3600.0
+	GBInBytes     = 1024.0 * 1024.0 * 1024.0
+	CoreInMCore   = 1000.0
+	HourInSeconds = 3600.0
+	// MonthInHours is the average hours of one month
+	MonthInHours           = 730.0
 	MetricsPeriodInSeconds = 15.0
 
 	BillingModeOnDemand = "ondemand"
@@ -42,24 +51,49 @@ const (
 	BillingModeSpot     = "spot"
 	BillingModeFallback = "fallback"
 
+	// The reasons a workload is not suitable to run on spot nodes
+	SpotBlockerSingleReplica = "single_replica"
+	SpotBlockerStateful      = "stateful"
+	SpotBlockerLocalVolume   = "local_volume"
+	SpotBlockerNoPDB         = "no_pdb"
+
 	ClusterStateRunning        = "running"
 	ClusterStateLostConnection = "connect_failed"
 
-	CloudProviderEnv          = "CLOUD_PROVIDER"
-	ClusterNameEnv            = "CLUSTER_NAME"
-	ClusterIdEnv              = "CLUSTER_ID"
-	LeaderElectionIDEnv       = "LEADER_ELECTION_ID"
-	QueryBackendEndpointEnv   = "QUERY_BACKEND_ENDPOINT"
-	NodeCPUDeviationEnv       = "NODE_CPU_DEVIATION"
-	NodeRAMDeviationEnv       = "NODE_RAM_DEVIATION"
-	CPUMemoryCostRatioEnv     = "CPUCORE_RAMGB_PRICE_RATIO"
-	CustomCPUCoreHourPriceEnv = "CUSTOM_CPU_CORE_HOUR_PRICE"
-	CustomRAMGBHourPriceEnv   = "CUSTOM_RAM_GB_HOUR_PRICE"
+	CloudProviderEnv             = "CLOUD_PROVIDER"
+	ClusterNameEnv               = "CLUSTER_NAME"
+	ClusterIdEnv                 = "CLUSTER_ID"
+	LeaderElectionIDEnv          = "LEADER_ELECTION_ID"
+	QueryBackendEndpointEnv      = "QUERY_BACKEND_ENDPOINT"
+	QueryBackendTimeoutEnv       = "QUERY_BACKEND_TIMEOUT"
+	QueryBackendDefaultTenantEnv = "QUERY_BACKEND_DEFAULT_TENANT"
+	LedgerDSNEnv                 = "LEDGER_DSN"
+	NodeCPUDeviationEnv          = "NODE_CPU_DEVIATION"
+	NodeRAMDeviationEnv          = "NODE_RAM_DEVIATION"
+	CPUMemoryCostRatioEnv        = "CPUCORE_RAMGB_PRICE_RATIO"
+	CustomCPUCoreHourPriceEnv    = "CUSTOM_CPU_CORE_HOUR_PRICE"
+	CustomRAMGBHourPriceEnv      = "CUSTOM_RAM_GB_HOUR_PRICE"
+
+	// RightSizingAnnotationKey opts the workload in the right-sizing controller with value "enabled"
+	RightSizingAnnotationKey = "kubefin.io/rightsizing"
+	// RightSizingLastUpdateAnnotationKey records when the right-sizing controller changed the workload last time
+	RightSizingLastUpdateAnnotationKey = "kubefin.io/rightsizing-last-update"
 
-	MultiTenantHeader       = "X-Scope-OrgID"
-	ClusterIdQueryParameter = "cluster_id"
+	MultiTenantHeader  = "X-Scope-OrgID"
+	ThanosTenantHeader = "THANOS-TENANT"
+	// WarningHeader carries the warnings of the query backend in the format "<WarningCode> - <quoted text>"
+	WarningHeader            = "Warning"
+	WarningCode              = 299
+	ClusterIdQueryParameter  = "cluster_id"
+	BudgetNameQueryParameter = "budget_name"
 
 	DefaultStepSeconds = 3600
+	// DefaultMaxConcurrentQueries is the number of queries one API request sends to the backend concurrently
+	DefaultMaxConcurrentQueries = 8
+	// DefaultForecastHistoryDays is the days of history the forecast is fitted on
+	DefaultForecastHistoryDays = 90
+	// DefaultEfficiencyTopN is the number of the least efficient workloads listed
+	DefaultEfficiencyTopN = 10
 	// DefaultDetailStepSeconds is used to show the fine-grained line chart of cpu/memory data
 	DefaultDetailStepSeconds = 600
 )
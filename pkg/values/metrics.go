@@ -39,12 +39,23 @@ var (
 	WorkloadResourceRequestMetricsName = "kubefin_workload_resource_request"
 	WorkloadResourceUsageMetricsName   = "kubefin_workload_resource_usage"
 	WorkloadPodCountMetricsName        = "kubefin_workload_pod_count"
+	// WorkloadSpotSuitableMetricsName is 1 if the workload could run on spot nodes, the blockers are in the labels
+	WorkloadSpotSuitableMetricsName = "kubefin_workload_spot_suitable"
 
 	// Pod level metrics name
 	PodResourceRequestMetricsName = "kubefin_pod_resource_request"
 	PodResourceUsageMetricsName   = "kubefin_pod_resource_usage"
 	PodResoueceCostMetricsName    = "kubefin_pod_resource_cost"
 
+	// RightSizingChangesMetricsName counts the request changes made by the right-sizing controller
+	RightSizingChangesMetricsName = "kubefin_rightsizing_changes_total"
+	// QueryCacheRequestsMetricsName counts the lookups of the analyzer query result cache
+	QueryCacheRequestsMetricsName = "kubefin_query_cache_requests_total"
+	// QueryRetriesMetricsName counts the queries sent again after a transient failure of the query backend
+	QueryRetriesMetricsName = "kubefin_query_retries_total"
+	// QueryCircuitBreakerOpenMetricsName is 1 if the queries fast-fail as the query backend is down
+	QueryCircuitBreakerOpenMetricsName = "kubefin_query_circuit_breaker_open"
+
 	// metrics labels
 	ClusterNameLabelKey       = "cluster_name"
 	ClusterIdLabelKey         = "cluster_id"
@@ -62,4 +73,8 @@ var (
 	CloudProviderLabelKey     = "cloud_provider"
 	PodNameLabelKey           = "pod"
 	PodScheduledKey           = "scheduled"
+	ResultLabelKey            = "result"
+	SpotBlockersLabelKey      = "spot_blockers"
+	QueryTypeLabelKey         = "query_type"
+	ErrorTypeLabelKey         = "error_type"
 )